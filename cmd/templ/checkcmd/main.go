@@ -0,0 +1,498 @@
+package checkcmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/parse"
+	"github.com/a-h/templ/cmd/templ/generatecmd/modcheck"
+	"github.com/a-h/templ/cmd/templ/layout"
+	"github.com/a-h/templ/generator"
+	"github.com/a-h/templ/parser/v2"
+)
+
+type Arguments struct {
+	// Path to check the templ files within.
+	Path string
+	// Vet runs the go vet analyzers on the generated code.
+	Vet bool
+	// JSON writes each finding as a JSON object on its own line.
+	JSON bool
+}
+
+// Finding is a problem within a templ file, or within the Go code of its package.
+type Finding struct {
+	// FileName of the templ file, or Go file, relative to the working directory.
+	FileName string `json:"file"`
+	// Line is the one-based line of the finding.
+	Line int `json:"line"`
+	// Col is the one-based column of the finding, in bytes.
+	Col int `json:"col"`
+	// Message describes the problem.
+	Message string `json:"message"`
+	// Source is "templ" for templ parsing errors, "typecheck" for type errors, or the name of
+	// the vet analyzer that reported the finding.
+	Source string `json:"source"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", f.FileName, f.Line, f.Col, f.Message)
+}
+
+// ErrProblemsFound is returned by Run if any problems were found.
+var ErrProblemsFound = errors.New("problems found")
+
+// Run generates Go code for the templ files within the path in memory, type-checks the
+// packages that contain them, and writes the problems that were found, at their positions
+// within the templ files.
+func Run(ctx context.Context, w io.Writer, args Arguments) (err error) {
+	dir, err := filepath.Abs(args.Path)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	c := &checker{
+		fset:      token.NewFileSet(),
+		layouts:   make(map[string]layout.Layout),
+		generated: make(map[string]generatedFile),
+		combined:  make(map[string][]generator.CombineFile),
+		checked:   make(map[string]*types.Package),
+		vet:       args.Vet,
+		facts:     newFacts(),
+	}
+	if c.wd, err = os.Getwd(); err != nil {
+		return err
+	}
+
+	// Generate the code for each templ file.
+	packageDirs := make(map[string]bool)
+	err = filepath.WalkDir(dir, func(fileName string, info os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && fileName != dir && shouldSkipDir(info.Name()) {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || !strings.HasSuffix(fileName, ".templ") {
+			return nil
+		}
+		l, err := c.layoutOf(filepath.Dir(fileName))
+		if err != nil {
+			return err
+		}
+		packageDirs[filepath.Dir(l.GoFileName(fileName))] = true
+		return c.generate(l, fileName)
+	})
+	if err != nil {
+		return err
+	}
+	c.combine()
+
+	// List the packages within each module, in dependency order. The imports of the generated
+	// code are listed too, because the Go tool can't see the generated code.
+	moduleDirs := make(map[string][]string)
+	moduleImports := make(map[string]map[string]bool)
+	for packageDir := range packageDirs {
+		moduleDir, err := modcheck.WalkUp(packageDir)
+		if err != nil {
+			return err
+		}
+		moduleDirs[moduleDir] = append(moduleDirs[moduleDir], packageDir)
+	}
+	imports := make(map[string]bool)
+	for fileName, gf := range c.generated {
+		moduleDir, err := modcheck.WalkUp(filepath.Dir(fileName))
+		if err != nil {
+			return err
+		}
+		if moduleImports[moduleDir] == nil {
+			moduleImports[moduleDir] = make(map[string]bool)
+		}
+		for _, imp := range gf.imports {
+			moduleImports[moduleDir][imp] = true
+			imports[imp] = true
+		}
+	}
+	var pkgs []goPackage
+	for moduleDir, modulePackageDirs := range moduleDirs {
+		sort.Strings(modulePackageDirs)
+		modulePkgs, err := goList(ctx, moduleDir, modulePackageDirs, sortedKeys(moduleImports[moduleDir]))
+		if err != nil {
+			return err
+		}
+		pkgs = append(pkgs, modulePkgs...)
+	}
+
+	// Type-check the packages.
+	exports := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		exports[pkg.ImportPath] = pkg.Export
+	}
+	c.gc = importer.ForCompiler(c.fset, "gc", func(path string) (io.ReadCloser, error) {
+		if exports[path] == "" {
+			return nil, fmt.Errorf("no export data for %q", path)
+		}
+		return os.Open(exports[path])
+	})
+	for _, pkg := range pkgs {
+		// Imports that can't be found are reported by the type checker.
+		if pkg.Dir == "" && pkg.Error != nil && !imports[pkg.ImportPath] {
+			return fmt.Errorf("failed to list package %s: %s", pkg.ImportPath, pkg.Error.Err)
+		}
+		if !pkg.DepOnly && packageDirs[pkg.Dir] {
+			if err = c.check(pkg); err != nil {
+				return err
+			}
+		}
+	}
+
+	sort.SliceStable(c.findings, func(i, j int) bool {
+		a, b := c.findings[i], c.findings[j]
+		if a.FileName != b.FileName {
+			return a.FileName < b.FileName
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, f := range c.findings {
+		if args.JSON {
+			err = enc.Encode(f)
+		} else {
+			_, err = fmt.Fprintln(w, f.String())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(c.findings) > 0 {
+		return fmt.Errorf("%w: %d", ErrProblemsFound, len(c.findings))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]bool) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shouldSkipDir returns true for directories that are ignored by the Go tool.
+func shouldSkipDir(name string) bool {
+	return name == "vendor" || name == "node_modules" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// generatedFile is the code generated from a templ file, or when the code of a directory is
+// combined, from each of its templ files.
+type generatedFile struct {
+	templFileNames []string
+	code           []byte
+	// sourceMaps are the source maps of each of the templ files.
+	sourceMaps []*parser.SourceMap
+	imports    []string
+}
+
+type checker struct {
+	wd   string
+	fset *token.FileSet
+	// layouts are the layouts of the generated code, by the directory of the templ files.
+	layouts   map[string]layout.Layout
+	generated map[string]generatedFile
+	// combined is the code of each templ file, by the name of the combined file that it's
+	// written to.
+	combined map[string][]generator.CombineFile
+	// gc imports the dependencies from their export data.
+	gc types.Importer
+	// checked are the packages that have been type-checked, by import path.
+	checked  map[string]*types.Package
+	vet      bool
+	facts    *facts
+	findings []Finding
+}
+
+func (c *checker) layoutOf(dir string) (l layout.Layout, err error) {
+	if l, ok := c.layouts[dir]; ok {
+		return l, nil
+	}
+	if l, err = layout.Find(dir); err != nil {
+		return l, err
+	}
+	c.layouts[dir] = l
+	return l, nil
+}
+
+// generate Go code for a templ file, and add it to the generated files, or to the files to
+// combine.
+func (c *checker) generate(l layout.Layout, templFileName string) (err error) {
+	t, err := parser.Parse(templFileName)
+	if err != nil {
+		errs := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errs = joined.Unwrap()
+		}
+		for _, err := range errs {
+			var pe parse.ParseError
+			if !errors.As(err, &pe) {
+				return fmt.Errorf("%s parsing error: %w", templFileName, err)
+			}
+			msg := pe.Msg
+			if info, ok := parser.ErrorCodeOf(err); ok {
+				msg = fmt.Sprintf("%s [%s]", msg, info.Code)
+			}
+			c.add(templFileName, int(pe.Pos.Line)+1, int(pe.Pos.Col)+1, msg, "templ")
+		}
+		return nil
+	}
+	errorMessageFileName, err := filepath.Rel(c.wd, templFileName)
+	if err != nil {
+		errorMessageFileName = templFileName
+	}
+	opts := []generator.GenerateOpt{generator.WithFileName(errorMessageFileName)}
+	if l.Package != "" {
+		opts = append(opts, generator.WithPackageName(l.Package))
+	}
+	var b bytes.Buffer
+	sm, _, err := generator.Generate(t, &b, opts...)
+	if err != nil {
+		return fmt.Errorf("%s generation error: %w", templFileName, err)
+	}
+	code := b.Bytes()
+	// Format the code, so that positions that aren't mapped to the templ file are the same as
+	// the positions within the code that templ generate writes. Code with syntax errors can't
+	// be formatted.
+	if formatted, formattedSourceMap, err := generator.Format(code, sm); err == nil {
+		code, sm = formatted, formattedSourceMap
+	}
+	if l.Combine {
+		goFileName := l.GoFileName(templFileName)
+		c.combined[goFileName] = append(c.combined[goFileName], generator.CombineFile{Name: templFileName, Code: code, SourceMap: sm})
+		return nil
+	}
+	c.addGenerated(l.GoFileName(templFileName), []string{templFileName}, code, []*parser.SourceMap{sm})
+	return nil
+}
+
+// combine the code of the templ files of each directory into the files that templ generate
+// writes. Code with syntax errors can't be combined, so it's checked as separate files, which
+// reports the syntax errors.
+func (c *checker) combine() {
+	for goFileName, files := range c.combined {
+		sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+		code, sourceMaps, _, err := generator.Combine(files)
+		if err == nil {
+			templFileNames := make([]string, len(files))
+			for i, f := range files {
+				templFileNames[i] = f.Name
+			}
+			c.addGenerated(goFileName, templFileNames, code, sourceMaps)
+			continue
+		}
+		separate := c.layouts[filepath.Dir(files[0].Name)]
+		separate.Combine = false
+		for _, f := range files {
+			c.addGenerated(separate.GoFileName(f.Name), []string{f.Name}, f.Code, []*parser.SourceMap{f.SourceMap})
+		}
+	}
+}
+
+func (c *checker) addGenerated(goFileName string, templFileNames []string, code []byte, sourceMaps []*parser.SourceMap) {
+	gf := generatedFile{templFileNames: templFileNames, code: code, sourceMaps: sourceMaps}
+	if f, err := goparser.ParseFile(token.NewFileSet(), "", code, goparser.ImportsOnly); err == nil {
+		for _, imp := range f.Imports {
+			if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+				gf.imports = append(gf.imports, path)
+			}
+		}
+	}
+	c.generated[goFileName] = gf
+}
+
+// check parses and type-checks a package, using the generated code in place of the generated
+// files on disk.
+func (c *checker) check(pkg goPackage) (err error) {
+	fileNames := make([]string, 0, len(pkg.GoFiles)+len(pkg.CgoFiles))
+	for _, name := range append(pkg.GoFiles, pkg.CgoFiles...) {
+		fileName := filepath.Join(pkg.Dir, name)
+		if _, ok := c.generated[fileName]; !ok {
+			fileNames = append(fileNames, fileName)
+		}
+	}
+	for fileName := range c.generated {
+		if filepath.Dir(fileName) == pkg.Dir {
+			fileNames = append(fileNames, fileName)
+		}
+	}
+	sort.Strings(fileNames)
+
+	var files []*ast.File
+	var syntaxErrors bool
+	for _, fileName := range fileNames {
+		var src any
+		if gf, ok := c.generated[fileName]; ok {
+			src = gf.code
+		}
+		f, err := goparser.ParseFile(c.fset, fileName, src, goparser.ParseComments)
+		var errs scanner.ErrorList
+		if errors.As(err, &errs) {
+			for _, e := range errs {
+				c.addGo(e.Pos, e.Msg, "typecheck")
+			}
+			syntaxErrors = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	if syntaxErrors {
+		return nil
+	}
+
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Instances:  make(map[*ast.Ident]types.Instance),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	var typeErrors bool
+	conf := types.Config{
+		Importer:    importerFunc(c.importPackage),
+		Sizes:       types.SizesFor("gc", runtime.GOARCH),
+		FakeImportC: true,
+		Error: func(err error) {
+			if te, ok := err.(types.Error); ok {
+				c.addGo(te.Fset.Position(te.Pos), te.Msg, "typecheck")
+				typeErrors = true
+			}
+		},
+	}
+	typesPkg, _ := conf.Check(pkg.ImportPath, c.fset, files, info)
+	c.checked[pkg.ImportPath] = typesPkg
+	if typeErrors || !c.vet {
+		return nil
+	}
+	diagnostics, err := runAnalyzers(c.fset, files, typesPkg, info, conf.Sizes, c.facts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", pkg.ImportPath, err)
+	}
+	for _, d := range diagnostics {
+		// Only report the problems within templates, go vet reports the rest.
+		pos := c.fset.Position(d.Pos)
+		if _, ok := c.generated[pos.Filename]; ok {
+			c.addGo(pos, d.Message, d.Analyzer)
+		}
+	}
+	return nil
+}
+
+func (c *checker) importPackage(path string) (*types.Package, error) {
+	if pkg, ok := c.checked[path]; ok {
+		return pkg, nil
+	}
+	return c.gc.Import(path)
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+// addGo adds a finding at a position within a Go file, mapped to the templ file if the Go
+// file was generated.
+func (c *checker) addGo(pos token.Position, msg, source string) {
+	gf, ok := c.generated[pos.Filename]
+	if !ok {
+		c.add(pos.Filename, pos.Line, pos.Column, msg, source)
+		return
+	}
+	for i, sm := range gf.sourceMaps {
+		if src, ok := sm.SourcePositionFromTarget(uint32(pos.Line-1), uint32(pos.Column-1)); ok {
+			c.add(gf.templFileNames[i], int(src.Line)+1, int(src.Col)+1, msg, source)
+			return
+		}
+	}
+	c.add(pos.Filename, pos.Line, pos.Column, msg, source)
+}
+
+func (c *checker) add(fileName string, line, col int, msg, source string) {
+	if rel, err := filepath.Rel(c.wd, fileName); err == nil {
+		fileName = rel
+	}
+	c.findings = append(c.findings, Finding{
+		FileName: filepath.ToSlash(fileName),
+		Line:     line,
+		Col:      col,
+		Message:  msg,
+		Source:   source,
+	})
+}
+
+type goPackage struct {
+	Dir        string
+	ImportPath string
+	Export     string
+	GoFiles    []string
+	CgoFiles   []string
+	DepOnly    bool
+	Error      *struct {
+		Err string
+	}
+}
+
+// goList lists the packages within the directories, the imported packages, and their
+// dependencies, in dependency order. The dependencies are compiled, so that they can be imported from their export data.
+func goList(ctx context.Context, dir string, packageDirs, imports []string) (pkgs []goPackage, err error) {
+	args := []string{"list", "-e", "-export", "-deps", "-json=Dir,ImportPath,Export,GoFiles,CgoFiles,DepOnly,Error"}
+	for _, packageDir := range packageDirs {
+		rel, err := filepath.Rel(dir, packageDir)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "./"+path.Clean(filepath.ToSlash(rel)))
+	}
+	args = append(args, imports...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list failed: %w: %s", err, stderr.String())
+	}
+	dec := json.NewDecoder(bytes.NewReader(stdout))
+	for dec.More() {
+		var pkg goPackage
+		if err = dec.Decode(&pkg); err != nil {
+			return nil, fmt.Errorf("failed to decode go list output: %w", err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
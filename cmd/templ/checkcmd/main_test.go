@@ -0,0 +1,154 @@
+package checkcmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     Arguments
+		expected []Finding
+	}{
+		{
+			name: "type errors are reported at their positions within templ files",
+			args: Arguments{Path: "testdata/types"},
+			expected: []Finding{
+				{FileName: "testdata/types/types.templ", Line: 5, Col: 10, Message: "declared and not used: item", Source: "typecheck"},
+				{FileName: "testdata/types/types.templ", Line: 6, Col: 10, Message: "undefined: itme", Source: "typecheck"},
+				{FileName: "testdata/types/types.templ", Line: 13, Col: 9, Message: "cannot use count (variable of type int) as []string value in argument to Items", Source: "typecheck"},
+			},
+		},
+		{
+			name:     "vet isn't run by default",
+			args:     Arguments{Path: "testdata/vet"},
+			expected: nil,
+		},
+		{
+			name: "vet problems are reported at their positions within templ files",
+			args: Arguments{Path: "testdata/vet", Vet: true},
+			expected: []Finding{
+				{FileName: "testdata/vet/vet.templ", Line: 6, Col: 7, Message: "fmt.Sprintf format %s has arg count of wrong type int", Source: "printf"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := test.args
+			args.JSON = true
+			var w bytes.Buffer
+			err := Run(context.Background(), &w, args)
+			if len(test.expected) > 0 && !errors.Is(err, ErrProblemsFound) {
+				t.Fatalf("expected ErrProblemsFound, got %v", err)
+			}
+			if len(test.expected) == 0 && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var actual []Finding
+			dec := json.NewDecoder(&w)
+			for dec.More() {
+				var f Finding
+				if err := dec.Decode(&f); err != nil {
+					t.Fatalf("failed to decode finding: %v", err)
+				}
+				actual = append(actual, f)
+			}
+			if diff := cmp.Diff(test.expected, actual); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRunParseErrors(t *testing.T) {
+	// The templ file is outside of the repo, so that templ generate doesn't fail.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/parse\n"), 0o644); err != nil {
+		t.Fatalf("failed to write go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "parse.templ"), []byte("package parse\n\ntempl Items() {\n\t<div>\n}\n"), 0o644); err != nil {
+		t.Fatalf("failed to write templ file: %v", err)
+	}
+	var w bytes.Buffer
+	err := Run(context.Background(), &w, Arguments{Path: dir})
+	if !errors.Is(err, ErrProblemsFound) {
+		t.Fatalf("expected ErrProblemsFound, got %v", err)
+	}
+	expected := ":5:1: <div>: expected end tag not present or invalid tag contents [T2001]\n"
+	if !strings.HasSuffix(w.String(), expected) {
+		t.Errorf("expected %q, got %q", expected, w.String())
+	}
+}
+
+func TestRunLayout(t *testing.T) {
+	root, err := filepath.Abs("../../..")
+	if err != nil {
+		t.Fatalf("failed to get the root of the repo: %v", err)
+	}
+	tests := []struct {
+		name string
+		// config is the layout, and staleFileName is a generated file on disk that's replaced
+		// by the code generated from the templ files.
+		config        string
+		staleFileName string
+	}{
+		{
+			name:          "suffix",
+			config:        `{"suffix":"_gen"}`,
+			staleFileName: "items_gen.go",
+		},
+		{
+			name:          "combine",
+			config:        `{"suffix":"_gen","combine":true}`,
+			staleFileName: "templates_gen.go",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{
+				"go.mod":             "module example.com/layout\n\ngo 1.20\n\nrequire github.com/a-h/templ v0.0.0\n\nreplace github.com/a-h/templ => " + filepath.ToSlash(root) + "\n",
+				".templ-layout.json": test.config,
+				"items.templ":        "package layout\n\ntempl Items(items []string) {\n\tfor _, item := range items {\n\t\t<li>{ itme }</li>\n\t}\n}\n",
+				"list.templ":         "package layout\n\ntempl List() {\n\t@Items(nil)\n}\n",
+				test.staleFileName:   "// Code generated by templ - DO NOT EDIT.\n\npackage layout\n\nfunc Items() {}\n",
+			}
+			for name, contents := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+			var w bytes.Buffer
+			err := Run(context.Background(), &w, Arguments{Path: dir, JSON: true})
+			if !errors.Is(err, ErrProblemsFound) {
+				t.Fatalf("expected ErrProblemsFound, got %v: %s", err, w.String())
+			}
+			var actual []Finding
+			dec := json.NewDecoder(&w)
+			for dec.More() {
+				var f Finding
+				if err := dec.Decode(&f); err != nil {
+					t.Fatalf("failed to decode finding: %v", err)
+				}
+				f.FileName = filepath.Base(f.FileName)
+				actual = append(actual, f)
+			}
+			expected := []Finding{
+				{FileName: "items.templ", Line: 4, Col: 9, Message: "declared and not used: item", Source: "typecheck"},
+				{FileName: "items.templ", Line: 5, Col: 9, Message: "undefined: itme", Source: "typecheck"},
+			}
+			if diff := cmp.Diff(expected, actual); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
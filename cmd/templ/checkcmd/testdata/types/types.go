@@ -0,0 +1,4 @@
+package types
+
+// Title is used by the templates.
+const Title = "Items"
@@ -0,0 +1,162 @@
+package checkcmd
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/assign"
+	"golang.org/x/tools/go/analysis/passes/atomic"
+	"golang.org/x/tools/go/analysis/passes/bools"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/copylock"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/httpresponse"
+	"golang.org/x/tools/go/analysis/passes/ifaceassert"
+	"golang.org/x/tools/go/analysis/passes/loopclosure"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilfunc"
+	"golang.org/x/tools/go/analysis/passes/printf"
+	"golang.org/x/tools/go/analysis/passes/shift"
+	"golang.org/x/tools/go/analysis/passes/sigchanyzer"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+	"golang.org/x/tools/go/analysis/passes/stringintconv"
+	"golang.org/x/tools/go/analysis/passes/structtag"
+	"golang.org/x/tools/go/analysis/passes/unmarshal"
+	"golang.org/x/tools/go/analysis/passes/unreachable"
+	"golang.org/x/tools/go/analysis/passes/unsafeptr"
+	"golang.org/x/tools/go/analysis/passes/unusedresult"
+)
+
+// analyzers are the go vet analyzers that apply to Go source code.
+var analyzers = []*analysis.Analyzer{
+	assign.Analyzer,
+	atomic.Analyzer,
+	bools.Analyzer,
+	composite.Analyzer,
+	copylock.Analyzer,
+	errorsas.Analyzer,
+	httpresponse.Analyzer,
+	ifaceassert.Analyzer,
+	loopclosure.Analyzer,
+	lostcancel.Analyzer,
+	nilfunc.Analyzer,
+	printf.Analyzer,
+	shift.Analyzer,
+	sigchanyzer.Analyzer,
+	stdmethods.Analyzer,
+	stringintconv.Analyzer,
+	structtag.Analyzer,
+	unmarshal.Analyzer,
+	unreachable.Analyzer,
+	unsafeptr.Analyzer,
+	unusedresult.Analyzer,
+}
+
+type vetDiagnostic struct {
+	Pos      token.Pos
+	Message  string
+	Analyzer string
+}
+
+// facts are the facts that analyzers export about objects and packages, e.g. that a function
+// is a printf wrapper. Facts about the packages that have already been checked are available
+// to the packages that import them.
+type facts struct {
+	objects  map[types.Object]map[reflect.Type]analysis.Fact
+	packages map[*types.Package]map[reflect.Type]analysis.Fact
+}
+
+func newFacts() *facts {
+	return &facts{
+		objects:  make(map[types.Object]map[reflect.Type]analysis.Fact),
+		packages: make(map[*types.Package]map[reflect.Type]analysis.Fact),
+	}
+}
+
+// get copies the stored fact of the same type as fact into fact.
+func get[K comparable](m map[K]map[reflect.Type]analysis.Fact, key K, fact analysis.Fact) bool {
+	stored, ok := m[key][reflect.TypeOf(fact)]
+	if ok {
+		reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(stored).Elem())
+	}
+	return ok
+}
+
+func set[K comparable](m map[K]map[reflect.Type]analysis.Fact, key K, fact analysis.Fact) {
+	if m[key] == nil {
+		m[key] = make(map[reflect.Type]analysis.Fact)
+	}
+	m[key][reflect.TypeOf(fact)] = fact
+}
+
+// runAnalyzers runs the analyzers, and the analyzers that they require, on a type-checked
+// package.
+func runAnalyzers(fset *token.FileSet, files []*ast.File, pkg *types.Package, info *types.Info, sizes types.Sizes, f *facts) (diagnostics []vetDiagnostic, err error) {
+	results := make(map[*analysis.Analyzer]any)
+	var run func(a *analysis.Analyzer) (result any, err error)
+	run = func(a *analysis.Analyzer) (result any, err error) {
+		if result, ok := results[a]; ok {
+			return result, nil
+		}
+		pass := &analysis.Pass{
+			Analyzer:   a,
+			Fset:       fset,
+			Files:      files,
+			Pkg:        pkg,
+			TypesInfo:  info,
+			TypesSizes: sizes,
+			ResultOf:   make(map[*analysis.Analyzer]any, len(a.Requires)),
+			Report: func(d analysis.Diagnostic) {
+				diagnostics = append(diagnostics, vetDiagnostic{Pos: d.Pos, Message: d.Message, Analyzer: a.Name})
+			},
+			ImportObjectFact: func(obj types.Object, fact analysis.Fact) bool {
+				return get(f.objects, obj, fact)
+			},
+			ImportPackageFact: func(pkg *types.Package, fact analysis.Fact) bool {
+				return get(f.packages, pkg, fact)
+			},
+			ExportObjectFact: func(obj types.Object, fact analysis.Fact) {
+				set(f.objects, obj, fact)
+			},
+			ExportPackageFact: func(fact analysis.Fact) {
+				set(f.packages, pkg, fact)
+			},
+			AllObjectFacts: func() (all []analysis.ObjectFact) {
+				for obj, m := range f.objects {
+					for _, fact := range m {
+						all = append(all, analysis.ObjectFact{Object: obj, Fact: fact})
+					}
+				}
+				return all
+			},
+			AllPackageFacts: func() (all []analysis.PackageFact) {
+				for pkg, m := range f.packages {
+					for _, fact := range m {
+						all = append(all, analysis.PackageFact{Package: pkg, Fact: fact})
+					}
+				}
+				return all
+			},
+		}
+		for _, req := range a.Requires {
+			if pass.ResultOf[req], err = run(req); err != nil {
+				return nil, err
+			}
+		}
+		if result, err = a.Run(pass); err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Name, err)
+		}
+		results[a] = result
+		return result, nil
+	}
+	for _, a := range analyzers {
+		if _, err = run(a); err != nil {
+			return nil, err
+		}
+	}
+	return diagnostics, nil
+}
@@ -0,0 +1,407 @@
+package coveragecmd
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/a-h/templ/generator"
+	"github.com/a-h/templ/parser/v2"
+	"golang.org/x/tools/cover"
+)
+
+// FileCoverage is the coverage of the lines and branches of a templ file.
+type FileCoverage struct {
+	// FileName of the templ file.
+	FileName string
+	// Lines of the templ file.
+	Lines []string
+	// Counts is the number of times that each line was executed, or -1 if the line doesn't
+	// contain code, e.g. it's blank, or only contains a closing brace.
+	Counts []int
+	// Branches of the if, for and switch statements within the file.
+	Branches []Branch
+}
+
+// Branch is a branch of an if, for or switch statement.
+type Branch struct {
+	// Line is the zero-based line of the statement.
+	Line int
+	// Block is the index of the statement within the file.
+	Block int
+	// Branch is the index of the branch within the statement.
+	Branch int
+	// Count is the number of times that the branch was taken, or -1 if the statement was
+	// never executed.
+	Count int
+}
+
+// LinesCovered returns the number of lines that contain code, and the number of those lines
+// that were executed.
+func (fc FileCoverage) LinesCovered() (found, hit int) {
+	for _, count := range fc.Counts {
+		if count < 0 {
+			continue
+		}
+		found++
+		if count > 0 {
+			hit++
+		}
+	}
+	return found, hit
+}
+
+// BranchesCovered returns the number of branches, and the number of those branches that
+// were taken.
+func (fc FileCoverage) BranchesCovered() (found, hit int) {
+	for _, b := range fc.Branches {
+		found++
+		if b.Count > 0 {
+			hit++
+		}
+	}
+	return found, hit
+}
+
+// ErrOutOfDate is returned by Compute when the generated Go code wasn't generated from the
+// templ file.
+var ErrOutOfDate = fmt.Errorf("the generated code is out of date, run templ generate")
+
+// Compute attributes the cover blocks of the generated Go code to the lines of the templ
+// file that it was generated from. The body of each template, and of each if, for and
+// switch branch, takes the count of the first cover block within the matching Go block. The
+// options are those that the Go code was generated with, e.g. its package name.
+func Compute(templFileName string, templContents, goContents []byte, profile *cover.Profile, opts ...generator.GenerateOpt) (fc FileCoverage, err error) {
+	tf, err := parser.ParseString(string(templContents))
+	if err != nil {
+		return fc, fmt.Errorf("%s parsing error: %w", templFileName, err)
+	}
+	var generated bytes.Buffer
+	sm, _, err := generator.Generate(tf, &generated, opts...)
+	if err != nil {
+		return fc, fmt.Errorf("%s generation error: %w", templFileName, err)
+	}
+	if sm, err = generator.RemapSourceMap(generated.Bytes(), goContents, sm); err != nil {
+		return fc, fmt.Errorf("%s: %w", templFileName, ErrOutOfDate)
+	}
+	fset := token.NewFileSet()
+	goFile, err := goparser.ParseFile(fset, profile.FileName, goContents, 0)
+	if err != nil {
+		return fc, fmt.Errorf("%s: %w", profile.FileName, err)
+	}
+
+	c := &coverage{
+		sm:      sm,
+		fset:    fset,
+		tokFile: fset.File(goFile.Pos()),
+		goFile:  goFile,
+		blocks:  profile.Blocks,
+	}
+	c.FileName = templFileName
+	c.Lines = strings.Split(string(templContents), "\n")
+	c.Counts = make([]int, len(c.Lines))
+	for i := range c.Counts {
+		c.Counts[i] = -1
+	}
+	for _, n := range tf.Nodes {
+		switch n := n.(type) {
+		case parser.HTMLTemplate:
+			c.template(n.Expression, n.Range, n.Children)
+		case parser.XMLTemplate:
+			c.template(n.Expression, n.Range, n.Children)
+		case parser.TextTemplate:
+			c.template(n.Expression, n.Range, n.Children)
+		}
+	}
+	for i, line := range c.Lines {
+		if !isCode(line) {
+			c.Counts[i] = -1
+		}
+	}
+	return c.FileCoverage, nil
+}
+
+// isCode returns false for lines that don't contain anything that's executed.
+func isCode(line string) bool {
+	line = strings.TrimSpace(strings.Trim(line, " \t{}"))
+	return line != "" && line != "else" && !strings.HasPrefix(line, "//")
+}
+
+type coverage struct {
+	FileCoverage
+	sm      *parser.SourceMap
+	fset    *token.FileSet
+	tokFile *token.File
+	goFile  *ast.File
+	blocks  []cover.ProfileBlock
+	// block is the index of the next if, for or switch statement.
+	block int
+}
+
+func (c *coverage) template(expr parser.Expression, r parser.Range, children []parser.Node) {
+	var count int
+	if fn, ok := c.innermost(expr, isFuncDecl).(*ast.FuncDecl); ok && fn.Body != nil {
+		// The template renders within the first function literal, i.e. templ.ComponentFunc.
+		var found bool
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.FuncLit); ok && !found {
+				count, found = c.count(lit.Body.Lbrace+1, lit.Body.Rbrace, 0), true
+			}
+			return !found
+		})
+	}
+	c.set(int(r.From.Line), toLine(r), count)
+	c.nodes(children, count)
+}
+
+func (c *coverage) nodes(nodes []parser.Node, count int) {
+	for _, n := range nodes {
+		parser.Inspect(n, func(n any) bool {
+			switch n := n.(type) {
+			case parser.TemplElementExpression:
+				childrenCount := count
+				if body, ok := c.childrenBody(n.Expression); ok {
+					childrenCount = c.count(body.Lbrace+1, body.Rbrace, count)
+				}
+				c.body(n.Children, childrenCount)
+				return false
+			case parser.IfExpression:
+				c.ifExpression(n, count)
+				return false
+			case parser.ForExpression:
+				block := c.nextBlock()
+				bodyCount := count
+				switch stmt := c.innermost(n.Expression, isFor).(type) {
+				case *ast.ForStmt:
+					bodyCount = c.count(stmt.Body.Lbrace+1, stmt.Body.Rbrace, count)
+				case *ast.RangeStmt:
+					bodyCount = c.count(stmt.Body.Lbrace+1, stmt.Body.Rbrace, count)
+				}
+				c.branch(n.Expression, block, 0, count, bodyCount)
+				c.body(n.Children, bodyCount)
+				return false
+			case parser.SwitchExpression:
+				block := c.nextBlock()
+				for i, cs := range n.Cases {
+					caseCount := count
+					if clause, ok := c.innermost(cs.Expression, isCaseClause).(*ast.CaseClause); ok {
+						caseCount = c.count(clause.Colon+1, clause.End(), count)
+					}
+					c.branch(n.Expression, block, i, count, caseCount)
+					c.body(cs.Children, caseCount)
+				}
+				return false
+			}
+			return true
+		})
+	}
+}
+
+func (c *coverage) ifExpression(n parser.IfExpression, count int) {
+	block := c.nextBlock()
+	// The bodies of the Go if statement, followed by the bodies of its else if and else
+	// statements.
+	var bodies []*ast.BlockStmt
+	if stmt, ok := c.innermost(n.Expression, isIf).(*ast.IfStmt); ok {
+		bodies = append(bodies, stmt.Body)
+		for e := stmt.Else; e != nil; {
+			switch s := e.(type) {
+			case *ast.IfStmt:
+				bodies, e = append(bodies, s.Body), s.Else
+			case *ast.BlockStmt:
+				bodies, e = append(bodies, s), nil
+			}
+		}
+	}
+	bodyCount := func(i int) int {
+		if i >= len(bodies) {
+			return count
+		}
+		return c.count(bodies[i].Lbrace+1, bodies[i].Rbrace, count)
+	}
+
+	thenCount := bodyCount(0)
+	c.branch(n.Expression, block, 0, count, thenCount)
+	c.body(n.Then, thenCount)
+	for i, elseIf := range n.ElseIfs {
+		elseIfCount := bodyCount(i + 1)
+		c.branch(n.Expression, block, i+1, count, elseIfCount)
+		c.body(elseIf.Then, elseIfCount)
+	}
+	if len(n.Else) > 0 {
+		elseCount := bodyCount(len(n.ElseIfs) + 1)
+		c.branch(n.Expression, block, len(n.ElseIfs)+1, count, elseCount)
+		c.body(n.Else, elseCount)
+	}
+}
+
+// body sets the count of the lines of the nodes, and the nodes within them.
+func (c *coverage) body(nodes []parser.Node, count int) {
+	from, to := -1, -1
+	for _, n := range nodes {
+		r, ok := nodeRange(n)
+		if !ok {
+			continue
+		}
+		if from < 0 {
+			from = int(r.From.Line)
+		}
+		to = toLine(r)
+	}
+	if from >= 0 {
+		c.set(from, to, count)
+	}
+	c.nodes(nodes, count)
+}
+
+func (c *coverage) set(from, to, count int) {
+	for i := from; i <= to && i < len(c.Counts); i++ {
+		c.Counts[i] = count
+	}
+}
+
+func (c *coverage) nextBlock() (block int) {
+	block = c.block
+	c.block++
+	return block
+}
+
+func (c *coverage) branch(expr parser.Expression, block, branch, count, taken int) {
+	if count == 0 {
+		taken = -1
+	}
+	c.Branches = append(c.Branches, Branch{
+		Line:   int(expr.Range.From.Line),
+		Block:  block,
+		Branch: branch,
+		Count:  taken,
+	})
+}
+
+// count returns the count of the first cover block that starts within the range, or def if
+// there isn't one.
+func (c *coverage) count(from, to token.Pos, def int) int {
+	start, end := c.fset.Position(from), c.fset.Position(to)
+	i := sort.Search(len(c.blocks), func(i int) bool {
+		b := c.blocks[i]
+		return b.StartLine > start.Line || (b.StartLine == start.Line && b.StartCol >= start.Column)
+	})
+	if i == len(c.blocks) {
+		return def
+	}
+	b := c.blocks[i]
+	if b.StartLine > end.Line || (b.StartLine == end.Line && b.StartCol > end.Column) {
+		return def
+	}
+	return b.Count
+}
+
+// pos returns the position within the Go code that the start of the expression is mapped to.
+func (c *coverage) pos(expr parser.Expression) (pos token.Pos, ok bool) {
+	tgt, ok := c.sm.TargetPositionFromSource(expr.Range.From.Line, expr.Range.From.Col)
+	if !ok || int(tgt.Index) >= c.tokFile.Size() {
+		return token.NoPos, false
+	}
+	return c.tokFile.Pos(int(tgt.Index)), true
+}
+
+// innermost returns the innermost Go node that contains the start of the expression, and
+// matches f, or nil if there isn't one.
+func (c *coverage) innermost(expr parser.Expression, f func(n ast.Node) bool) (match ast.Node) {
+	pos, ok := c.pos(expr)
+	if !ok {
+		return nil
+	}
+	ast.Inspect(c.goFile, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || pos >= n.End() {
+			return false
+		}
+		if f(n) {
+			match = n
+		}
+		return true
+	})
+	return match
+}
+
+func isFuncDecl(n ast.Node) bool {
+	_, ok := n.(*ast.FuncDecl)
+	return ok
+}
+
+func isIf(n ast.Node) bool {
+	_, ok := n.(*ast.IfStmt)
+	return ok
+}
+
+func isFor(n ast.Node) bool {
+	switch n.(type) {
+	case *ast.ForStmt, *ast.RangeStmt:
+		return true
+	}
+	return false
+}
+
+func isCaseClause(n ast.Node) bool {
+	_, ok := n.(*ast.CaseClause)
+	return ok
+}
+
+func isBlock(n ast.Node) bool {
+	_, ok := n.(*ast.BlockStmt)
+	return ok
+}
+
+// childrenBody returns the body of the function that renders the children of a templ
+// element, e.g. @layout() { children }, which is assigned in the statement before the
+// component is rendered.
+func (c *coverage) childrenBody(expr parser.Expression) (body *ast.BlockStmt, ok bool) {
+	block, ok := c.innermost(expr, isBlock).(*ast.BlockStmt)
+	if !ok {
+		return nil, false
+	}
+	pos, _ := c.pos(expr)
+	for i, stmt := range block.List {
+		if pos < stmt.Pos() || pos >= stmt.End() || i == 0 {
+			continue
+		}
+		assign, isAssign := block.List[i-1].(*ast.AssignStmt)
+		if !isAssign || len(assign.Rhs) != 1 {
+			return nil, false
+		}
+		call, isCall := assign.Rhs[0].(*ast.CallExpr)
+		if !isCall || len(call.Args) != 1 {
+			return nil, false
+		}
+		lit, isLit := call.Args[0].(*ast.FuncLit)
+		if !isLit {
+			return nil, false
+		}
+		return lit.Body, true
+	}
+	return nil, false
+}
+
+// toLine returns the last line of the range, which ends at the start of the following line
+// if the range includes the newline.
+func toLine(r parser.Range) int {
+	if r.To.Col == 0 && r.To.Line > r.From.Line {
+		return int(r.To.Line) - 1
+	}
+	return int(r.To.Line)
+}
+
+// nodeRange returns the range of a node, or false if the node is whitespace.
+func nodeRange(n parser.Node) (r parser.Range, ok bool) {
+	switch n := n.(type) {
+	case parser.Text:
+		return n.Range, strings.TrimSpace(n.Value) != ""
+	case parser.Whitespace:
+		return r, false
+	}
+	return parser.RangeOf(n)
+}
@@ -0,0 +1,156 @@
+package coveragecmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/a-h/templ/cmd/templ/generatecmd/modcheck"
+	"github.com/a-h/templ/cmd/templ/layout"
+	"github.com/a-h/templ/generator"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/cover"
+)
+
+type Arguments struct {
+	// ProfileFileName is the Go cover profile, e.g. created by go test -coverprofile=cover.out
+	ProfileFileName string
+	// Path within the Go module that was tested.
+	Path string
+	// Format of the report, lcov or html.
+	Format string
+	// Output file name.
+	Output string
+}
+
+// Run reads a Go cover profile, and writes the coverage of the templ files that the
+// generated Go files within the profile were generated from.
+func Run(w io.Writer, args Arguments) (err error) {
+	var write func(w io.Writer, files []FileCoverage) error
+	switch args.Format {
+	case "lcov":
+		write = writeLCOV
+	case "html":
+		write = writeHTML
+	default:
+		return fmt.Errorf("unknown format %q, expected lcov or html", args.Format)
+	}
+
+	profiles, err := cover.ParseProfiles(args.ProfileFileName)
+	if err != nil {
+		return fmt.Errorf("failed to read cover profile: %w", err)
+	}
+	moduleDir, err := modcheck.WalkUp(args.Path)
+	if err != nil {
+		return err
+	}
+	goMod, err := os.ReadFile(filepath.Join(moduleDir, "go.mod"))
+	if err != nil {
+		return fmt.Errorf("failed to read go.mod file: %w", err)
+	}
+	modulePath := modfile.ModulePath(goMod)
+
+	layouts := map[string]layout.Layout{}
+	var files []FileCoverage
+	for _, profile := range profiles {
+		relativeFileName, ok := strings.CutPrefix(profile.FileName, modulePath+"/")
+		if !ok {
+			// The layout of other modules isn't known, so only report files with the default suffix.
+			if strings.HasSuffix(profile.FileName, layout.DefaultSuffix+".go") {
+				fmt.Fprintf(w, "(!) Skipping %s, which isn't within module %s\n", profile.FileName, modulePath)
+			}
+			continue
+		}
+		goFileName := filepath.Join(moduleDir, filepath.FromSlash(relativeFileName))
+		l, ok := layouts[filepath.Dir(goFileName)]
+		if !ok {
+			if l, err = layout.Find(filepath.Dir(goFileName)); err != nil {
+				return err
+			}
+			layouts[filepath.Dir(goFileName)] = l
+		}
+		templFileName, combined, ok := l.Source(goFileName)
+		if !ok {
+			continue
+		}
+		if combined {
+			fmt.Fprintf(w, "(!) Skipping %s: the coverage of combined files isn't supported\n", relativeFileName)
+			continue
+		}
+		var opts []generator.GenerateOpt
+		if l.Package != "" {
+			opts = append(opts, generator.WithPackageName(l.Package))
+		}
+		fc, err := computeFile(moduleDir, templFileName, goFileName, profile, opts...)
+		if errors.Is(err, ErrOutOfDate) || errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(w, "(!) Skipping %s: %v\n", relativeFileName, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, fc)
+	}
+
+	f, err := os.Create(args.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", args.Output, err)
+	}
+	defer f.Close()
+	if err = write(f, files); err != nil {
+		return fmt.Errorf("failed to write %q: %w", args.Output, err)
+	}
+	fmt.Fprintf(w, "(✓) Wrote coverage of %d templ files to %s\n", len(files), args.Output)
+	return nil
+}
+
+func computeFile(moduleDir, templFileName, goFileName string, profile *cover.Profile, opts ...generator.GenerateOpt) (fc FileCoverage, err error) {
+	templContents, err := os.ReadFile(templFileName)
+	if err != nil {
+		return fc, err
+	}
+	goContents, err := os.ReadFile(goFileName)
+	if err != nil {
+		return fc, err
+	}
+	relativeFileName, err := filepath.Rel(moduleDir, templFileName)
+	if err != nil {
+		return fc, err
+	}
+	return Compute(filepath.ToSlash(relativeFileName), templContents, goContents, profile, opts...)
+}
+
+// writeLCOV writes the coverage in the lcov tracefile format, see
+// https://github.com/linux-test-project/lcov/blob/master/man/geninfo.1
+func writeLCOV(w io.Writer, files []FileCoverage) (err error) {
+	for _, fc := range files {
+		fmt.Fprintf(w, "TN:\nSF:%s\n", fc.FileName)
+		for _, b := range fc.Branches {
+			taken := "-"
+			if b.Count >= 0 {
+				taken = fmt.Sprint(b.Count)
+			}
+			fmt.Fprintf(w, "BRDA:%d,%d,%d,%s\n", b.Line+1, b.Block, b.Branch, taken)
+		}
+		found, hit := fc.BranchesCovered()
+		fmt.Fprintf(w, "BRF:%d\nBRH:%d\n", found, hit)
+		for i, count := range fc.Counts {
+			if count >= 0 {
+				fmt.Fprintf(w, "DA:%d,%d\n", i+1, count)
+			}
+		}
+		found, hit = fc.LinesCovered()
+		if _, err = fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", found, hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeHTML(w io.Writer, files []FileCoverage) (err error) {
+	return report(files).Render(context.Background(), w)
+}
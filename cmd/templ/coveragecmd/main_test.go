@@ -0,0 +1,137 @@
+package coveragecmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/cover"
+)
+
+func TestRunLCOV(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "templ.lcov")
+	var stdout bytes.Buffer
+	err := Run(&stdout, Arguments{
+		ProfileFileName: "testdata/cover.out",
+		Path:            "testdata",
+		Format:          "lcov",
+		Output:          output,
+	})
+	if err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+	expected, err := os.ReadFile("testdata/expected.lcov")
+	if err != nil {
+		t.Fatalf("failed to read expected output: %v", err)
+	}
+	actual, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if diff := cmp.Diff(string(expected), string(actual)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestRunLCOVWithLayout(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"testdata/go.mod":              "go.mod",
+		"testdata/views/page.templ":    "views/page.templ",
+		"testdata/views/page_templ.go": "views/page_gen.go",
+		"testdata/views/wrap.templ":    "views/wrap.templ",
+		"testdata/views/wrap_templ.go": "views/wrap_gen.go",
+	}
+	for src, dst := range files {
+		contents, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", src, err)
+		}
+		if err = os.MkdirAll(filepath.Join(dir, filepath.Dir(dst)), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, dst), contents, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", dst, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".templ-layout.json"), []byte(`{"suffix":"_gen"}`), 0o644); err != nil {
+		t.Fatalf("failed to write layout: %v", err)
+	}
+	profile, err := os.ReadFile("testdata/cover.out")
+	if err != nil {
+		t.Fatalf("failed to read profile: %v", err)
+	}
+	profileFileName := filepath.Join(dir, "cover.out")
+	if err = os.WriteFile(profileFileName, []byte(strings.ReplaceAll(string(profile), "_templ.go:", "_gen.go:")), 0o644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+
+	output := filepath.Join(dir, "templ.lcov")
+	var stdout bytes.Buffer
+	err = Run(&stdout, Arguments{
+		ProfileFileName: profileFileName,
+		Path:            dir,
+		Format:          "lcov",
+		Output:          output,
+	})
+	if err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+	expected, err := os.ReadFile("testdata/expected.lcov")
+	if err != nil {
+		t.Fatalf("failed to read expected output: %v", err)
+	}
+	actual, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if diff := cmp.Diff(string(expected), string(actual)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestRunHTML(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "templ-coverage.html")
+	var stdout bytes.Buffer
+	err := Run(&stdout, Arguments{
+		ProfileFileName: "testdata/cover.out",
+		Path:            "testdata",
+		Format:          "html",
+		Output:          output,
+	})
+	if err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+	actual, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	for _, expected := range []string{
+		`<td>70.6% (12/17)</td><td>50.0% (3/6)</td>`,
+		`<div class="uncovered"><span class="line-number">10</span><span class="count">0</span>		&lt;p&gt;Admin&lt;/p&gt;</div>`,
+	} {
+		if !strings.Contains(string(actual), expected) {
+			t.Errorf("expected output to contain %q", expected)
+		}
+	}
+}
+
+func TestComputeOutOfDate(t *testing.T) {
+	profiles, err := cover.ParseProfiles("testdata/cover.out")
+	if err != nil {
+		t.Fatalf("failed to read profile: %v", err)
+	}
+	goContents, err := os.ReadFile("testdata/views/page_templ.go")
+	if err != nil {
+		t.Fatalf("failed to read Go file: %v", err)
+	}
+	templContents := "package views\n\ntempl Page() {\n\t<p>Changed</p>\n}\n"
+	_, err = Compute("views/page.templ", []byte(templContents), goContents, profiles[0])
+	if !errors.Is(err, ErrOutOfDate) {
+		t.Errorf("expected ErrOutOfDate, got %v", err)
+	}
+}
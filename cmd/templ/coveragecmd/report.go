@@ -0,0 +1,31 @@
+package coveragecmd
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// percentage returns the percentage of items that were hit, e.g. "75.0% (3/4)".
+func percentage(found, hit int) string {
+	if found == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%% (%d/%d)", float64(hit)*100/float64(found), hit, found)
+}
+
+func lineClass(count int) string {
+	switch {
+	case count < 0:
+		return ""
+	case count == 0:
+		return "uncovered"
+	}
+	return "covered"
+}
+
+func countText(count int) string {
+	if count < 0 {
+		return ""
+	}
+	return strconv.Itoa(count)
+}
@@ -0,0 +1,47 @@
+package explaincmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+type Arguments struct {
+	Code string
+}
+
+// Run prints the explanation of an error code, or lists all of the error codes if no code
+// is provided.
+func Run(w io.Writer, args Arguments) (err error) {
+	if args.Code == "" {
+		for _, info := range parser.ErrorCodes {
+			fmt.Fprintf(w, "%s  %s\n", info.Code, info.Title)
+		}
+		return nil
+	}
+	info, ok := parser.LookupErrorCode(args.Code)
+	if !ok {
+		return fmt.Errorf("unknown error code %q, run `templ explain` to list all error codes", args.Code)
+	}
+	fmt.Fprintf(w, "%s: %s\n\n", info.Code, info.Title)
+	fmt.Fprintf(w, "%s\n\n", info.Explanation)
+	if info.Example != "" {
+		fmt.Fprintf(w, "Example:\n\n%s\n\n", indent(info.Example))
+	}
+	if info.Fixed != "" {
+		fmt.Fprintf(w, "Fixed:\n\n%s\n\n", indent(info.Fixed))
+	}
+	fmt.Fprintf(w, "Fix: %s\n", info.Fix)
+	fmt.Fprintf(w, "See: %s\n", info.URL())
+	return nil
+}
+
+func indent(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = "    " + strings.ReplaceAll(l, "\t", "  ")
+	}
+	return strings.Join(lines, "\n")
+}
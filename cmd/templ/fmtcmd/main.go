@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/a-h/templ/cmd/templ/jsonoutput"
 	"github.com/a-h/templ/cmd/templ/processor"
 	parser "github.com/a-h/templ/parser/v2"
 	"github.com/natefinch/atomic"
@@ -15,22 +17,40 @@ import (
 
 const workerCount = 4
 
-func Run(w io.Writer, args []string) (err error) {
-	if len(args) > 0 {
-		return formatDir(w, args[0])
+type Arguments struct {
+	// Path to format. If empty, stdin is formatted to w.
+	Path string
+	// WhitespaceSensitiveElements are the names of elements, in addition to <pre>, <textarea>
+	// and <code>, whose contents are not reformatted.
+	WhitespaceSensitiveElements []string
+	// JSON writes newline-delimited JSON events to w, instead of log messages.
+	JSON bool
+}
+
+func Run(w io.Writer, args Arguments) (err error) {
+	opts := []parser.ParseOpt{parser.WithWhitespaceSensitiveElements(args.WhitespaceSensitiveElements...)}
+	if args.Path != "" {
+		var jw *jsonoutput.Writer
+		if args.JSON {
+			jw = jsonoutput.New(w, "fmt")
+		}
+		return formatDir(w, jw, args.Path, opts)
 	}
-	return formatReader(w, os.Stdin)
+	if args.JSON {
+		return fmt.Errorf("cannot write JSON when formatting stdin, set a path to format")
+	}
+	return formatReader(w, os.Stdin, opts)
 }
 
-func formatReader(w io.Writer, r io.Reader) (err error) {
+func formatReader(w io.Writer, r io.Reader, opts []parser.ParseOpt) (err error) {
 	var bytes []byte
 	bytes, err = io.ReadAll(r)
 	if err != nil {
 		return
 	}
-	t, err := parser.ParseString(string(bytes))
+	t, err := parser.ParseString(string(bytes), opts...)
 	if err != nil {
-		return fmt.Errorf("parsing error: %w", err)
+		return fmt.Errorf("parsing error: %w", parser.WithErrorCodes(err))
 	}
 	err = t.Write(w)
 	if err != nil {
@@ -39,44 +59,76 @@ func formatReader(w io.Writer, r io.Reader) (err error) {
 	return nil
 }
 
-func formatDir(w io.Writer, dir string) (err error) {
+func formatDir(w io.Writer, jw *jsonoutput.Writer, dir string, opts []parser.ParseOpt) (err error) {
 	start := time.Now()
+	jw.Start(dir)
+	// formatted are the files that have been changed.
+	var m sync.Mutex
+	formatted := make(map[string]bool)
+	formatFile := func(fileName string) error {
+		changed, err := format(fileName, opts)
+		if changed {
+			m.Lock()
+			formatted[fileName] = true
+			m.Unlock()
+		}
+		return err
+	}
 	results := make(chan processor.Result)
-	go processor.Process(dir, format, workerCount, results)
+	go processor.Process(dir, formatFile, workerCount, results)
 	var successCount, errorCount int
 	for r := range results {
 		if r.Error != nil {
 			err = errors.Join(err, fmt.Errorf("%s: %w", r.FileName, r.Error))
 			errorCount++
+			if r.FileName == "" {
+				jw.Error(r.Error)
+				continue
+			}
+			jw.File(r.FileName, jsonoutput.StatusError, r.Duration, nil, r.Error)
 			continue
 		}
-		fmt.Printf("%s complete in %v\n", r.FileName, r.Duration)
 		successCount++
+		if jw == nil {
+			fmt.Printf("%s complete in %v\n", r.FileName, r.Duration)
+			continue
+		}
+		m.Lock()
+		status := jsonoutput.StatusUnchanged
+		if formatted[r.FileName] {
+			status = jsonoutput.StatusFormatted
+		}
+		m.Unlock()
+		jw.File(r.FileName, status, r.Duration, nil, nil)
 	}
-	fmt.Printf("Formatted %d templates with %d errors in %s\n", successCount+errorCount, errorCount, time.Since(start))
+	if jw == nil {
+		fmt.Printf("Formatted %d templates with %d errors in %s\n", successCount+errorCount, errorCount, time.Since(start))
+	}
+	jw.Summary(time.Since(start))
 	return
 }
 
-func format(fileName string) (err error) {
+// format formats a templ file, and returns true if the file was changed.
+func format(fileName string, opts []parser.ParseOpt) (changed bool, err error) {
 	contents, err := os.ReadFile(fileName)
 	if err != nil {
-		return fmt.Errorf("failed to read file %q: %w", fileName, err)
+		return false, fmt.Errorf("failed to read file %q: %w", fileName, err)
 	}
-	t, err := parser.ParseString(string(contents))
+	t, err := parser.ParseString(string(contents), opts...)
 	if err != nil {
-		return fmt.Errorf("%s parsing error: %w", fileName, err)
+		return false, fmt.Errorf("%s parsing error: %w", fileName, parser.WithErrorCodes(err))
 	}
 	w := new(bytes.Buffer)
 	err = t.Write(w)
 	if err != nil {
-		return fmt.Errorf("%s formatting error: %w", fileName, err)
+		return false, fmt.Errorf("%s formatting error: %w", fileName, err)
 	}
 	if string(contents) == w.String() {
-		return nil
+		return false, nil
 	}
 	err = atomic.WriteFile(fileName, w)
 	if err != nil {
-		return fmt.Errorf("%s file write error: %w", fileName, err)
+		return false, fmt.Errorf("%s file write error: %w", fileName, err)
 	}
-	return
+	return true, nil
 }
@@ -0,0 +1,182 @@
+// Package buildcache records the templ files that code has been generated for, so that
+// unchanged files can be skipped by later runs of templ generate.
+package buildcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/natefinch/atomic"
+)
+
+// version of the format of the cache file. Cache files with a different version are ignored.
+const version = 1
+
+// DefaultDir returns the directory of the cache within the user's cache directory, e.g.
+// $XDG_CACHE_HOME/templ on Linux.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "templ"), nil
+}
+
+type file struct {
+	Version int              `json:"version"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// Entry is the result of generating code for a templ file.
+type Entry struct {
+	// Key is derived from the contents of the templ file, and the options that code was
+	// generated with.
+	Key string `json:"key"`
+	// Outputs are the SHA-256 hashes of the files that were generated, by file name.
+	Outputs map[string]string `json:"outputs"`
+}
+
+// Cache of the code that has been generated for the templ files within a path. File names are
+// stored relative to the path.
+type Cache struct {
+	fileName string
+	path     string
+	options  string
+
+	m        sync.Mutex
+	previous map[string]Entry
+	current  map[string]Entry
+	hits     int
+	misses   int
+}
+
+// Open the cache of the path within dir. The options describe everything other than the
+// contents of a templ file that affects the generated code, e.g. the templ version. If the
+// cache doesn't exist, or can't be read, the cache is empty.
+func Open(dir, path, options string) (c *Cache, err error) {
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+	// Each path has its own cache file, so that the cache directory can be shared.
+	pathHash := sha256.Sum256([]byte(path))
+	c = &Cache{
+		fileName: filepath.Join(dir, "generate-"+hex.EncodeToString(pathHash[:8])+".json"),
+		path:     path,
+		options:  options,
+		previous: make(map[string]Entry),
+		current:  make(map[string]Entry),
+	}
+	data, err := os.ReadFile(c.fileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("failed to read build cache: %w", err)
+	}
+	var f file
+	if err = json.Unmarshal(data, &f); err != nil || f.Version != version {
+		// The cache is rebuilt.
+		return c, nil
+	}
+	if f.Entries != nil {
+		c.previous = f.Entries
+	}
+	return c, nil
+}
+
+// Key returns a key for the contents of a templ file, and the options of the cache.
+func (c *Cache) Key(contents []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, c.options)
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write(contents)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Lookup returns true if code has been generated for the templ file with the key, and the
+// generated files haven't been changed or deleted since.
+func (c *Cache) Lookup(fileName, key string) bool {
+	name := c.name(fileName)
+	c.m.Lock()
+	e, ok := c.previous[name]
+	c.m.Unlock()
+	ok = ok && e.Key == key && c.verify(e)
+	c.m.Lock()
+	defer c.m.Unlock()
+	if !ok {
+		c.misses++
+		return false
+	}
+	c.hits++
+	c.current[name] = e
+	return true
+}
+
+func (c *Cache) verify(e Entry) bool {
+	for output, hash := range e.Outputs {
+		data, err := os.ReadFile(filepath.Join(c.path, filepath.FromSlash(output)))
+		if err != nil {
+			return false
+		}
+		actual := sha256.Sum256(data)
+		if hex.EncodeToString(actual[:]) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// Store records the hashes of the files that were generated for the templ file.
+func (c *Cache) Store(fileName, key string, outputs map[string][sha256.Size]byte) {
+	e := Entry{
+		Key:     key,
+		Outputs: make(map[string]string, len(outputs)),
+	}
+	for output, hash := range outputs {
+		e.Outputs[c.name(output)] = hex.EncodeToString(hash[:])
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.current[c.name(fileName)] = e
+}
+
+// Stats returns the number of templ files that were found in the cache, and the number that
+// weren't.
+func (c *Cache) Stats() (hits, misses int) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.hits, c.misses
+}
+
+// Save writes the entries that were looked up or stored. Entries of templ files that weren't
+// seen, e.g. because they've been deleted, are removed.
+func (c *Cache) Save() error {
+	c.m.Lock()
+	data, err := json.Marshal(file{Version: version, Entries: c.current})
+	c.m.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.fileName), 0o755); err != nil {
+		return fmt.Errorf("failed to create build cache directory: %w", err)
+	}
+	if err = atomic.WriteFile(c.fileName, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to write build cache: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) name(fileName string) string {
+	if rel, err := filepath.Rel(c.path, fileName); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return fileName
+}
@@ -0,0 +1,98 @@
+package buildcache
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	path := t.TempDir()
+	templFile := filepath.Join(path, "a.templ")
+	goFile := filepath.Join(path, "a_templ.go")
+	if err := os.WriteFile(goFile, []byte("package a"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	open := func(options string) *Cache {
+		t.Helper()
+		c, err := Open(cacheDir, path, options)
+		if err != nil {
+			t.Fatalf("failed to open cache: %v", err)
+		}
+		return c
+	}
+	save := func(c *Cache) {
+		t.Helper()
+		if err := c.Save(); err != nil {
+			t.Fatalf("failed to save cache: %v", err)
+		}
+	}
+
+	c := open("v1")
+	key := c.Key([]byte("templ A() {}"))
+	if c.Lookup(templFile, key) {
+		t.Fatal("expected a miss in an empty cache")
+	}
+	c.Store(templFile, key, map[string][sha256.Size]byte{goFile: sha256.Sum256([]byte("package a"))})
+	save(c)
+
+	t.Run("unchanged files are hits", func(t *testing.T) {
+		c := open("v1")
+		if !c.Lookup(templFile, c.Key([]byte("templ A() {}"))) {
+			t.Error("expected a hit")
+		}
+		if hits, misses := c.Stats(); hits != 1 || misses != 0 {
+			t.Errorf("expected 1 hit and 0 misses, got %d and %d", hits, misses)
+		}
+	})
+	t.Run("changed files are misses", func(t *testing.T) {
+		c := open("v1")
+		if c.Lookup(templFile, c.Key([]byte("templ B() {}"))) {
+			t.Error("expected a miss")
+		}
+	})
+	t.Run("changed options are misses", func(t *testing.T) {
+		c := open("v2")
+		if c.Lookup(templFile, c.Key([]byte("templ A() {}"))) {
+			t.Error("expected a miss")
+		}
+	})
+	t.Run("changed outputs are misses", func(t *testing.T) {
+		if err := os.WriteFile(goFile, []byte("package b"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		defer os.WriteFile(goFile, []byte("package a"), 0o644)
+		c := open("v1")
+		if c.Lookup(templFile, c.Key([]byte("templ A() {}"))) {
+			t.Error("expected a miss")
+		}
+	})
+	t.Run("deleted outputs are misses", func(t *testing.T) {
+		if err := os.Rename(goFile, goFile+".bak"); err != nil {
+			t.Fatalf("failed to rename file: %v", err)
+		}
+		defer os.Rename(goFile+".bak", goFile)
+		c := open("v1")
+		if c.Lookup(templFile, c.Key([]byte("templ A() {}"))) {
+			t.Error("expected a miss")
+		}
+	})
+	t.Run("entries that aren't used are removed when the cache is saved", func(t *testing.T) {
+		save(open("v1"))
+		c := open("v1")
+		if c.Lookup(templFile, c.Key([]byte("templ A() {}"))) {
+			t.Error("expected a miss")
+		}
+	})
+	t.Run("caches that can't be parsed are ignored", func(t *testing.T) {
+		c := open("v1")
+		if err := os.WriteFile(c.fileName, []byte("{"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := Open(cacheDir, path, "v1"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package generatecmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a-h/templ/cmd/templ/generatecmd/diff"
+	"github.com/a-h/templ/cmd/templ/layout"
+	"github.com/a-h/templ/generator"
+)
+
+// checkProblem is a generated file that doesn't match the code that templ generate would write.
+type checkProblem struct {
+	fileName string
+	message  string
+	// diff changes the file on disk into the generated code.
+	diff string
+}
+
+// check compares the code that templ generate would write with the generated files on disk,
+// without writing any files. A unified diff is printed for each file that's out of date.
+func check(ctx context.Context, w io.Writer, l layout.Layout, args Arguments, opts []generator.GenerateOpt) error {
+	start := time.Now()
+
+	// Code for a single file is generated with file names that are relative to the working
+	// directory, rather than the path.
+	basePath := args.Path
+	var templFileNames []string
+	var problems []checkProblem
+	if args.FileName != "" {
+		basePath = ""
+		templFileNames = append(templFileNames, args.FileName)
+	} else {
+		walk := func(fileName string, info os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			if info.IsDir() && shouldSkipDir(fileName) {
+				return filepath.SkipDir
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if strings.HasSuffix(fileName, ".templ") {
+				templFileNames = append(templFileNames, fileName)
+				return nil
+			}
+			if !args.KeepOrphanedFiles && isOrphaned(l, fileName) {
+				problems = append(problems, checkProblem{fileName: fileName, message: "is orphaned, because its templ file doesn't exist"})
+			}
+			return nil
+		}
+		err := filepath.WalkDir(args.Path, walk)
+		if outputDir := l.Dir(args.Path); err == nil && !isWithin(args.Path, outputDir) {
+			if _, statErr := os.Stat(outputDir); statErr == nil {
+				err = filepath.WalkDir(outputDir, walk)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Generate the code, but limit to WorkerCount.
+	sem := make(chan struct{}, args.WorkerCount)
+	var wg sync.WaitGroup
+	var m sync.Mutex
+	var errs []error
+	expected := make(map[string][]byte)
+	out := newOutput(l, parseOpts(args))
+	// failed are the combined files that can't be checked, because of errors in their templ
+	// files.
+	failed := make(map[string]bool)
+	for _, fileName := range templFileNames {
+		fileName := fileName
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			files, err := expectedFiles(ctx, out, basePath, fileName, args.GenerateSourceMaps, opts)
+			m.Lock()
+			for name, contents := range files {
+				expected[name] = contents
+			}
+			if err != nil {
+				errs = append(errs, err)
+				failed[l.GoFileName(fileName)] = true
+			}
+			m.Unlock()
+			<-sem
+		}()
+	}
+	wg.Wait()
+
+	for goFileName, parts := range out.parts {
+		if failed[goFileName] {
+			continue
+		}
+		code, sourceMaps, _, err := combine(parts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to combine %s: %w", goFileName, err))
+			continue
+		}
+		expected[goFileName] = code
+		for templFileName, sourceMap := range sourceMaps {
+			if !args.GenerateSourceMaps {
+				continue
+			}
+			if expected[l.SourceMapFileName(templFileName)], err = json.Marshal(sourceMap); err != nil {
+				errs = append(errs, fmt.Errorf("%s source map error: %w", templFileName, err))
+			}
+		}
+	}
+	for fileName, contents := range expected {
+		problem, err := compareGeneratedFile(basePath, fileName, contents)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if problem != nil {
+			problems = append(problems, *problem)
+		}
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].fileName < problems[j].fileName
+	})
+	for _, p := range problems {
+		logError(w, "%s %s\n", displayName(args.Path, p.fileName), p.message)
+		fmt.Fprint(w, p.diff)
+	}
+	for _, err := range errs {
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		logError(w, "%v\n", err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to check %d of %d templ files", len(errs), len(templFileNames))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d generated files are out of date, run templ generate to update them", len(problems))
+	}
+	logSuccess(w, "Generated code for %d templates is up to date, checked in %s\n", len(templFileNames), time.Since(start))
+	return nil
+}
+
+// expectedFiles generates the code for a templ file in memory, and returns the contents of the
+// files that templ generate would write, by file name. When the code is combined, it's added
+// to out instead.
+func expectedFiles(ctx context.Context, out *output, basePath, fileName string, generateSourceMaps bool, opts []generator.GenerateOpt) (files map[string][]byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	_, goCode, sourceMap, _, err := generateCode(out, basePath, fileName, generateSourceMaps, opts)
+	if err != nil {
+		return nil, fileError{fileName: fileName, err: err}
+	}
+	if out.layout.Combine {
+		out.add(fileName, generator.CombineFile{Name: fileName, Code: goCode, SourceMap: sourceMap})
+		return nil, nil
+	}
+	files = map[string][]byte{
+		out.layout.GoFileName(fileName): goCode,
+	}
+	if generateSourceMaps {
+		mapJSON, err := json.Marshal(sourceMap)
+		if err != nil {
+			return nil, fmt.Errorf("%s source map error: %w", fileName, err)
+		}
+		files[out.layout.SourceMapFileName(fileName)] = mapJSON
+	}
+	return files, nil
+}
+
+// compareGeneratedFile returns a problem if the file on disk doesn't match the expected
+// contents.
+func compareGeneratedFile(basePath, fileName string, expected []byte) (*checkProblem, error) {
+	actual, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return &checkProblem{fileName: fileName, message: "is missing"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generated file: %w", err)
+	}
+	expected, actual = withoutVaryingComments(expected, actual)
+	if bytes.Equal(expected, actual) {
+		return nil, nil
+	}
+	name := filepath.ToSlash(displayName(basePath, fileName))
+	return &checkProblem{
+		fileName: fileName,
+		message:  "is out of date",
+		diff:     diff.Unified("a/"+name, "b/"+name, string(actual), string(expected)),
+	}, nil
+}
+
+const (
+	versionComment   = "// templ: version: "
+	timestampComment = "// templ: generated: "
+)
+
+// withoutVaryingComments removes the comments in the header of generated code that don't
+// affect its behaviour: the timestamp, and the version if only one of the files includes it,
+// i.e. they were generated with different -include-version flags.
+func withoutVaryingComments(expected, actual []byte) ([]byte, []byte) {
+	prefixes := []string{timestampComment}
+	if hasHeaderComment(expected, versionComment) != hasHeaderComment(actual, versionComment) {
+		prefixes = append(prefixes, versionComment)
+	}
+	return removeHeaderComments(expected, prefixes), removeHeaderComments(actual, prefixes)
+}
+
+// headerLines returns the lines of Go code before the package clause, and the rest of the code.
+func headerLines(code []byte) (lines [][]byte, rest []byte) {
+	for len(code) > 0 && !bytes.HasPrefix(code, []byte("package ")) {
+		line := code
+		if i := bytes.IndexByte(code, '\n'); i >= 0 {
+			line = code[:i+1]
+		}
+		lines = append(lines, line)
+		code = code[len(line):]
+	}
+	return lines, code
+}
+
+func hasHeaderComment(code []byte, prefix string) bool {
+	lines, _ := headerLines(code)
+	for _, line := range lines {
+		if bytes.HasPrefix(line, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func removeHeaderComments(code []byte, prefixes []string) []byte {
+	lines, rest := headerLines(code)
+	var result []byte
+outer:
+	for _, line := range lines {
+		for _, prefix := range prefixes {
+			if bytes.HasPrefix(line, []byte(prefix)) {
+				continue outer
+			}
+		}
+		result = append(result, line...)
+	}
+	return append(result, rest...)
+}
+
+// displayName returns the file name relative to the base path, if it's within it.
+func displayName(basePath, fileName string) string {
+	if basePath == "" {
+		return fileName
+	}
+	if rel, err := filepath.Rel(basePath, fileName); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return fileName
+}
@@ -0,0 +1,161 @@
+package generatecmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const checkTestTemplate = `package views
+
+templ Hello(name string) {
+	<div>Hello, { name }</div>
+}
+`
+
+func TestCheck(t *testing.T) {
+	setup := func(t *testing.T) (dir string) {
+		dir = t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "hello.templ"), []byte(checkTestTemplate), 0o644); err != nil {
+			t.Fatalf("failed to write templ file: %v", err)
+		}
+		return dir
+	}
+	generate := func(t *testing.T, args Arguments) {
+		args.CacheDir = t.TempDir()
+		if err := Run(context.Background(), &bytes.Buffer{}, args); err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+	}
+	check := func(args Arguments) (output string, err error) {
+		args.Check = true
+		var w bytes.Buffer
+		err = Run(context.Background(), &w, args)
+		return w.String(), err
+	}
+
+	t.Run("missing generated files are reported", func(t *testing.T) {
+		dir := setup(t)
+		output, err := check(Arguments{Path: dir})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if !strings.Contains(output, "hello_templ.go is missing") {
+			t.Errorf("expected the missing file to be reported, got:\n%s", output)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "hello_templ.go")); err == nil {
+			t.Error("expected no files to be written")
+		}
+	})
+	t.Run("up to date generated files pass", func(t *testing.T) {
+		dir := setup(t)
+		generate(t, Arguments{Path: dir, IncludeVersion: true})
+		output, err := check(Arguments{Path: dir, IncludeVersion: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v\n%s", err, output)
+		}
+	})
+	t.Run("the version comment is ignored if -include-version differs", func(t *testing.T) {
+		dir := setup(t)
+		generate(t, Arguments{Path: dir, IncludeVersion: false})
+		output, err := check(Arguments{Path: dir, IncludeVersion: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v\n%s", err, output)
+		}
+	})
+	t.Run("out of date generated files are reported with a diff", func(t *testing.T) {
+		dir := setup(t)
+		generate(t, Arguments{Path: dir})
+		templ := strings.Replace(checkTestTemplate, "Hello, ", "Hi, ", 1)
+		if err := os.WriteFile(filepath.Join(dir, "hello.templ"), []byte(templ), 0o644); err != nil {
+			t.Fatalf("failed to write templ file: %v", err)
+		}
+		before, err := os.ReadFile(filepath.Join(dir, "hello_templ.go"))
+		if err != nil {
+			t.Fatalf("failed to read generated file: %v", err)
+		}
+		output, err := check(Arguments{Path: dir})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		for _, expected := range []string{
+			"hello_templ.go is out of date",
+			"--- a/hello_templ.go\n+++ b/hello_templ.go\n",
+			`-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("<div>Hello, ")`,
+			`+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("<div>Hi, ")`,
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("expected output to contain %q, got:\n%s", expected, output)
+			}
+		}
+		after, err := os.ReadFile(filepath.Join(dir, "hello_templ.go"))
+		if err != nil {
+			t.Fatalf("failed to read generated file: %v", err)
+		}
+		if !bytes.Equal(before, after) {
+			t.Error("expected the generated file not to be changed")
+		}
+	})
+	t.Run("orphaned generated files are reported", func(t *testing.T) {
+		dir := setup(t)
+		generate(t, Arguments{Path: dir})
+		if err := os.WriteFile(filepath.Join(dir, "removed_templ.go"), []byte("// Code generated by templ - DO NOT EDIT.\n\npackage views\n"), 0o644); err != nil {
+			t.Fatalf("failed to write orphaned file: %v", err)
+		}
+		output, err := check(Arguments{Path: dir})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if !strings.Contains(output, "removed_templ.go is orphaned") {
+			t.Errorf("expected the orphaned file to be reported, got:\n%s", output)
+		}
+		if output, err = check(Arguments{Path: dir, KeepOrphanedFiles: true}); err != nil {
+			t.Errorf("expected orphaned files to be ignored with -keep-orphaned-files: %v\n%s", err, output)
+		}
+	})
+	t.Run("templ files with errors are reported", func(t *testing.T) {
+		dir := setup(t)
+		if err := os.WriteFile(filepath.Join(dir, "broken.templ"), []byte("package views\n\ntempl Broken() {\n\t<div>\n}\n"), 0o644); err != nil {
+			t.Fatalf("failed to write templ file: %v", err)
+		}
+		generate(t, Arguments{Path: dir})
+		output, err := check(Arguments{Path: dir})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if !strings.Contains(output, "broken.templ parsing error") {
+			t.Errorf("expected the parsing error to be reported, got:\n%s", output)
+		}
+	})
+}
+
+func TestWithoutVaryingComments(t *testing.T) {
+	withVersion := "// Code generated by templ - DO NOT EDIT.\n\n// templ: version: v0.2.1\npackage views\n"
+	withOtherVersion := "// Code generated by templ - DO NOT EDIT.\n\n// templ: version: v0.2.2\npackage views\n"
+	withoutVersion := "// Code generated by templ - DO NOT EDIT.\n\npackage views\n"
+	withTimestamp := "// Code generated by templ - DO NOT EDIT.\n\n// templ: generated: 2024-01-01T00:00:00Z\npackage views\n"
+
+	tests := []struct {
+		name          string
+		expected      string
+		actual        string
+		expectedEqual bool
+	}{
+		{name: "version is ignored if only the expected code includes it", expected: withVersion, actual: withoutVersion, expectedEqual: true},
+		{name: "version is ignored if only the actual code includes it", expected: withoutVersion, actual: withVersion, expectedEqual: true},
+		{name: "different versions are compared", expected: withVersion, actual: withOtherVersion, expectedEqual: false},
+		{name: "timestamps are ignored", expected: withoutVersion, actual: withTimestamp, expectedEqual: true},
+		{name: "comments after the package clause are compared", expected: withoutVersion + "// templ: version: v1\n", actual: withoutVersion, expectedEqual: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, actual := withoutVaryingComments([]byte(tt.expected), []byte(tt.actual))
+			if equal := bytes.Equal(expected, actual); equal != tt.expectedEqual {
+				t.Errorf("expected equal to be %v, got %v\nexpected: %q\nactual: %q", tt.expectedEqual, equal, expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Package diff creates unified diffs of text files.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines that are shown around each change.
+const contextLines = 3
+
+// maxEdits limits the work done to find the smallest diff. If the files have more differences,
+// the diff replaces all of the lines that differ.
+const maxEdits = 1000
+
+type op int
+
+const (
+	equal op = iota
+	deleted
+	inserted
+)
+
+// edit is a line that's unchanged, deleted from a, or inserted from b.
+type edit struct {
+	op op
+	// a and b are the indexes of the line within a and b. For deleted lines, b is the index of
+	// the line in b that the deleted line precedes, and for inserted lines, a is the index of
+	// the line in a that the inserted line precedes.
+	a, b int
+}
+
+// Unified returns a unified diff that changes a into b, or an empty string if they're equal.
+func Unified(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	aLines, bLines := splitLines(a), splitLines(b)
+	edits := lineEdits(aLines, bLines)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	for _, h := range hunks(edits) {
+		first, last := edits[h.from], edits[h.to-1]
+		aStart, aCount := first.a, last.a-first.a
+		bStart, bCount := first.b, last.b-first.b
+		if last.op != inserted {
+			aCount++
+		}
+		if last.op != deleted {
+			bCount++
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, e := range edits[h.from:h.to] {
+			switch e.op {
+			case equal:
+				writeLine(&sb, " ", aLines[e.a])
+			case deleted:
+				writeLine(&sb, "-", aLines[e.a])
+			case inserted:
+				writeLine(&sb, "+", bLines[e.b])
+			}
+		}
+	}
+	return sb.String()
+}
+
+// splitLines splits s into lines, which include their line endings.
+func splitLines(s string) (lines []string) {
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			return append(lines, s)
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+func writeLine(sb *strings.Builder, prefix, line string) {
+	sb.WriteString(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// hunkRange formats the one-based range of lines of a hunk. Empty ranges start at the line
+// before the hunk.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+type hunk struct {
+	// from and to are the range of edits in the hunk.
+	from, to int
+}
+
+// hunks groups the changes, and the unchanged lines around them. Changes that are separated by
+// a few unchanged lines are in the same hunk.
+func hunks(edits []edit) (hs []hunk) {
+	for i := 0; i < len(edits); i++ {
+		if edits[i].op == equal {
+			continue
+		}
+		from := i - contextLines
+		if from < 0 {
+			from = 0
+		}
+		// Find the end of the changes, including the unchanged lines between them.
+		end := i
+		for j := i; j < len(edits) && j <= end+2*contextLines; j++ {
+			if edits[j].op != equal {
+				end = j
+			}
+		}
+		to := end + 1 + contextLines
+		if to > len(edits) {
+			to = len(edits)
+		}
+		hs = append(hs, hunk{from: from, to: to})
+		i = end
+	}
+	return hs
+}
+
+// lineEdits returns the edits that change a into b, using Myers' algorithm.
+func lineEdits(a, b []string) (edits []edit) {
+	// Lines at the start and end that are the same don't need to be searched.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{op: equal, a: i, b: i})
+	}
+	for _, e := range middleEdits(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		e.a += prefix
+		e.b += prefix
+		edits = append(edits, e)
+	}
+	for i := suffix; i > 0; i-- {
+		edits = append(edits, edit{op: equal, a: len(a) - i, b: len(b) - i})
+	}
+	return edits
+}
+
+func middleEdits(a, b []string) []edit {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > maxEdits {
+		max = maxEdits
+	}
+	// v holds the furthest x that's been reached on each diagonal k = x - y, offset by max.
+	v := make([]int, 2*max+2)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, max, n, m)
+			}
+		}
+	}
+	// The files are too different, so replace all of the lines.
+	edits := make([]edit, 0, n+m)
+	for i := 0; i < n; i++ {
+		edits = append(edits, edit{op: deleted, a: i, b: 0})
+	}
+	for i := 0; i < m; i++ {
+		edits = append(edits, edit{op: inserted, a: n, b: i})
+	}
+	return edits
+}
+
+func backtrack(trace [][]int, offset, x, y int) []edit {
+	var edits []edit
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{op: equal, a: x, b: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, edit{op: inserted, a: x, b: prevY})
+			} else {
+				edits = append(edits, edit{op: deleted, a: prevX, b: y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
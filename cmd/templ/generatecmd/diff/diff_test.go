@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{
+			name:     "equal files have no diff",
+			a:        "a\nb\n",
+			b:        "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "changed lines are shown with context",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expected: `--- a
++++ b
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+		},
+		{
+			name: "lines can be inserted at the start",
+			a:    "b\nc\n",
+			b:    "a\nb\nc\n",
+			expected: `--- a
++++ b
+@@ -1,2 +1,3 @@
++a
+ b
+ c
+`,
+		},
+		{
+			name: "lines can be deleted from the end",
+			a:    "a\nb\nc\n",
+			b:    "a\nb\n",
+			expected: `--- a
++++ b
+@@ -1,3 +1,2 @@
+ a
+ b
+-c
+`,
+		},
+		{
+			name: "changes that are far apart are in separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expected: `--- a
++++ b
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+`,
+		},
+		{
+			name: "changes that are close together are in the same hunk",
+			a:    "1\n2\n3\n4\n5\n6\n",
+			b:    "one\n2\n3\n4\n5\nsix\n",
+			expected: `--- a
++++ b
+@@ -1,6 +1,6 @@
+-1
++one
+ 2
+ 3
+ 4
+ 5
+-6
++six
+`,
+		},
+		{
+			name: "a missing newline at the end of the file is shown",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			expected: `--- a
++++ b
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := Unified("a", "b", tt.a, tt.b)
+			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestUnifiedLargeChanges(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 2000; i++ {
+		a.WriteString("a\n")
+		b.WriteString("b\n")
+	}
+	actual := Unified("a", "b", a.String(), b.String())
+	if !strings.HasPrefix(actual, "--- a\n+++ b\n@@ -1,2000 +1,2000 @@\n-a\n") {
+		t.Errorf("unexpected diff header: %q", actual[:50])
+	}
+	if lines := strings.Count(actual, "\n"); lines != 4003 {
+		t.Errorf("expected 4003 lines, got %d", lines)
+	}
+}
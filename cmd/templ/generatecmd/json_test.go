@@ -0,0 +1,77 @@
+package generatecmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/a-h/templ/cmd/templ/jsonoutput"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSON(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.templ"), []byte(checkTestTemplate), 0o644); err != nil {
+		t.Fatalf("failed to write templ file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "removed_templ.go"), []byte("// Code generated by templ - DO NOT EDIT.\n\npackage views\n"), 0o644); err != nil {
+		t.Fatalf("failed to write orphaned file: %v", err)
+	}
+	args := Arguments{Path: dir, CacheDir: t.TempDir(), JSON: true}
+
+	type event struct {
+		Type     jsonoutput.Type           `json:"type"`
+		File     string                    `json:"file"`
+		Status   jsonoutput.Status         `json:"status"`
+		Statuses map[jsonoutput.Status]int `json:"statuses"`
+	}
+	run := func(t *testing.T) (events []event) {
+		var w bytes.Buffer
+		if err := Run(context.Background(), &w, args); err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+		dec := json.NewDecoder(&w)
+		for dec.More() {
+			var e event
+			if err := dec.Decode(&e); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+			events = append(events, e)
+		}
+		// Files are processed concurrently, so the file events are in any order.
+		order := map[jsonoutput.Type]int{jsonoutput.TypeStart: 0, jsonoutput.TypeFile: 1, jsonoutput.TypeSummary: 2}
+		sort.SliceStable(events, func(i, j int) bool {
+			if order[events[i].Type] != order[events[j].Type] {
+				return order[events[i].Type] < order[events[j].Type]
+			}
+			return events[i].File < events[j].File
+		})
+		return events
+	}
+
+	t.Run("generated and deleted files are written as events", func(t *testing.T) {
+		expected := []event{
+			{Type: jsonoutput.TypeStart},
+			{Type: jsonoutput.TypeFile, File: "hello.templ", Status: jsonoutput.StatusGenerated},
+			{Type: jsonoutput.TypeFile, File: "removed_templ.go", Status: jsonoutput.StatusDeleted},
+			{Type: jsonoutput.TypeSummary, Statuses: map[jsonoutput.Status]int{jsonoutput.StatusGenerated: 1, jsonoutput.StatusDeleted: 1}},
+		}
+		if diff := cmp.Diff(expected, run(t)); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("cached files are written as events", func(t *testing.T) {
+		expected := []event{
+			{Type: jsonoutput.TypeStart},
+			{Type: jsonoutput.TypeFile, File: "hello.templ", Status: jsonoutput.StatusCached},
+			{Type: jsonoutput.TypeSummary, Statuses: map[jsonoutput.Status]int{jsonoutput.StatusCached: 1}},
+		}
+		if diff := cmp.Diff(expected, run(t)); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
@@ -16,16 +16,22 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	_ "net/http/pprof"
 
+	"github.com/a-h/parse"
 	"github.com/a-h/templ"
+	"github.com/a-h/templ/cmd/templ/generatecmd/buildcache"
 	"github.com/a-h/templ/cmd/templ/generatecmd/modcheck"
 	"github.com/a-h/templ/cmd/templ/generatecmd/proxy"
 	"github.com/a-h/templ/cmd/templ/generatecmd/run"
+	"github.com/a-h/templ/cmd/templ/generatecmd/watcher"
+	"github.com/a-h/templ/cmd/templ/jsonoutput"
+	"github.com/a-h/templ/cmd/templ/layout"
 	"github.com/a-h/templ/cmd/templ/visualize"
 	"github.com/a-h/templ/generator"
 	"github.com/a-h/templ/parser/v2"
@@ -44,11 +50,45 @@ type Arguments struct {
 	Proxy                           string
 	WorkerCount                     int
 	GenerateSourceMapVisualisations bool
+	GenerateSourceMaps              bool
+	LineDirectives                  bool
 	IncludeVersion                  bool
 	IncludeTimestamp                bool
 	// PPROFPort is the port to run the pprof server on.
 	PPROFPort         int
 	KeepOrphanedFiles bool
+	// CommandWatch are glob patterns of files, relative to the path, that restart the command
+	// when they change in watch mode, e.g. "*.go".
+	CommandWatch []string
+	// CommandStopTimeout is how long to wait for the command to exit after it's asked to stop,
+	// before it's killed.
+	CommandStopTimeout time.Duration
+	// WhitespaceSensitiveElements are the names of elements, in addition to <pre>, <textarea>
+	// and <code>, whose whitespace is preserved.
+	WhitespaceSensitiveElements []string
+	// ProxyBind is the address that the proxy listens on. Defaults to 127.0.0.1.
+	ProxyBind string
+	// ProxyHTTPS serves the proxy over HTTPS, with certificates that are issued by a local
+	// certificate authority.
+	ProxyHTTPS bool
+	// ProxyRoutes map path prefixes to the URLs that they're proxied to, instead of Proxy, e.g.
+	// "/api/" to "http://localhost:8081".
+	ProxyRoutes map[string]string
+	// CacheDir is the directory of the build cache, which is used to skip the templ files that
+	// haven't changed since code was last generated for them. If empty, the cache isn't used.
+	CacheDir string
+	// Check compares the code that would be generated with the generated files, without writing
+	// any files, and returns an error if they're out of date.
+	Check bool
+	// JSON writes newline-delimited JSON events to the writer, instead of log messages, which
+	// are written to stderr.
+	JSON bool
+	// OutputDir, OutputPackage, OutputSuffix and OutputCombine override the layout of the
+	// generated files that's configured by the .templ-layout.json file, see layout.Config.
+	OutputDir     string
+	OutputPackage string
+	OutputSuffix  string
+	OutputCombine bool
 }
 
 var defaultWorkerCount = runtime.NumCPU()
@@ -60,20 +100,36 @@ func Run(ctx context.Context, w io.Writer, args Arguments) (err error) {
 		}()
 	}
 
-	err = runCmd(ctx, w, args)
+	var jw *jsonoutput.Writer
+	if args.JSON {
+		jw = jsonoutput.New(w, "generate")
+		w = os.Stderr
+	}
+
+	err = runCmd(ctx, w, jw, args)
 	if errors.Is(err, context.Canceled) {
 		return nil
 	}
+	// Errors in templ files have already been written as file events.
+	if err != nil && !errors.As(err, &fileError{}) {
+		jw.Error(err)
+	}
 
 	return err
 }
 
-func runCmd(ctx context.Context, w io.Writer, args Arguments) error {
+func runCmd(ctx context.Context, w io.Writer, jw *jsonoutput.Writer, args Arguments) error {
 	var err error
 
 	if args.Watch && args.FileName != "" {
 		return fmt.Errorf("cannot watch a single file, remove the -f or -watch flag")
 	}
+	if args.Watch && args.Check {
+		return fmt.Errorf("cannot watch and check the generated code, remove the -check or -watch flag")
+	}
+	if args.Check && args.JSON {
+		return fmt.Errorf("cannot write JSON when checking the generated code, remove the -check or -json flag")
+	}
 	var opts []generator.GenerateOpt
 	if args.IncludeVersion {
 		opts = append(opts, generator.WithVersion(templ.Version()))
@@ -81,134 +137,531 @@ func runCmd(ctx context.Context, w io.Writer, args Arguments) error {
 	if args.IncludeTimestamp {
 		opts = append(opts, generator.WithTimestamp(time.Now()))
 	}
-	if args.FileName != "" {
-		return processSingleFile(ctx, w, "", args.FileName, nil, args.GenerateSourceMapVisualisations, opts)
+	if args.LineDirectives {
+		opts = append(opts, generator.WithLineDirectives())
 	}
-	var target *url.URL
-	if args.Proxy != "" {
-		target, err = url.Parse(args.Proxy)
+	if !path.IsAbs(args.Path) {
+		args.Path, err = filepath.Abs(args.Path)
 		if err != nil {
-			return fmt.Errorf("failed to parse proxy URL: %w", err)
+			return err
 		}
 	}
+	l, err := findLayout(args)
+	if err != nil {
+		return err
+	}
+	if l.Combine && args.FileName != "" {
+		return fmt.Errorf("cannot generate a single file into a combined file, remove the -f flag")
+	}
+	if l.Combine && args.LineDirectives {
+		return fmt.Errorf("line directives aren't supported in combined files, remove the -line-directives flag")
+	}
+	if l.Package != "" {
+		opts = append(opts, generator.WithPackageName(l.Package))
+	}
+	if args.FileName != "" && !args.Check {
+		start := time.Now()
+		jw.Start(args.Path)
+		_, err = processSingleFile(ctx, w, jw, newOutput(l, parseOpts(args)), "", args.FileName, nil, args.GenerateSourceMapVisualisations, args.GenerateSourceMaps, opts)
+		jw.Summary(time.Since(start))
+		return err
+	}
+	if args.Proxy == "" && len(args.ProxyRoutes) > 0 {
+		return fmt.Errorf("proxy routes require the -proxy flag to be set")
+	}
 	if args.ProxyPort == 0 {
 		args.ProxyPort = 7331
 	}
+	if args.CommandWatch == nil {
+		args.CommandWatch = []string{"*.go"}
+	}
 
 	if args.WorkerCount == 0 {
 		args.WorkerCount = defaultWorkerCount
 	}
-	if !path.IsAbs(args.Path) {
-		args.Path, err = filepath.Abs(args.Path)
-		if err != nil {
-			return err
-		}
+	if args.Check {
+		return check(ctx, w, l, args, opts)
 	}
 
 	var p *proxy.Handler
 	if args.Proxy != "" {
-		p = proxy.New(args.ProxyPort, target)
+		if p, err = newProxy(args); err != nil {
+			return err
+		}
 	}
 	fmt.Fprintln(w, "Processing path:", args.Path)
+	jw.Start(args.Path)
 
 	if err := modcheck.Check(args.Path); err != nil {
 		logWarning(w, "templ version check failed: %v\n", err)
 	}
 
 	if args.Watch {
-		err = generateWatched(ctx, w, args, opts, p)
+		err = generateWatched(ctx, w, jw, l, args, opts, p)
 		if err != nil && !errors.Is(err, context.Canceled) {
 			return err
 		}
 	}
 
-	return generateProduction(context.Background(), w, args, opts, p)
+	return generateProduction(context.Background(), w, jw, l, args, opts, p)
+}
+
+// parseOpts returns the options used to parse templ files.
+func parseOpts(args Arguments) []parser.ParseOpt {
+	return []parser.ParseOpt{parser.WithWhitespaceSensitiveElements(args.WhitespaceSensitiveElements...)}
+}
+
+// findLayout returns the layout that's configured for the path, with the -output flags applied.
+func findLayout(args Arguments) (l layout.Layout, err error) {
+	if l, err = layout.Find(args.Path); err != nil {
+		return l, err
+	}
+	c := l.Config
+	if args.OutputDir != "" {
+		c.OutputDir = args.OutputDir
+	}
+	if args.OutputPackage != "" {
+		c.Package = args.OutputPackage
+	}
+	if args.OutputSuffix != "" {
+		c.Suffix = args.OutputSuffix
+	}
+	if args.OutputCombine {
+		c.Combine = true
+	}
+	return layout.New(l.Root, c)
+}
+
+func newProxy(args Arguments) (p *proxy.Handler, err error) {
+	opts := proxy.Options{
+		Bind:  args.ProxyBind,
+		Port:  args.ProxyPort,
+		HTTPS: args.ProxyHTTPS,
+	}
+	if opts.Target, err = url.Parse(args.Proxy); err != nil {
+		return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+	}
+	if len(args.ProxyRoutes) > 0 {
+		opts.Routes = make(map[string]*url.URL, len(args.ProxyRoutes))
+	}
+	for prefix, target := range args.ProxyRoutes {
+		if opts.Routes[prefix], err = url.Parse(target); err != nil {
+			return nil, fmt.Errorf("failed to parse proxy URL of route %q: %w", prefix, err)
+		}
+	}
+	if p, err = proxy.New(opts); err != nil {
+		return nil, fmt.Errorf("failed to create proxy: %w", err)
+	}
+	return p, nil
+}
+
+func sortedKeys(m map[string]string) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
-func generateWatched(ctx context.Context, w io.Writer, args Arguments, opts []generator.GenerateOpt, p *proxy.Handler) error {
+func generateWatched(ctx context.Context, w io.Writer, jw *jsonoutput.Writer, l layout.Layout, args Arguments, opts []generator.GenerateOpt, p *proxy.Handler) error {
 	fmt.Fprintln(w, "Generating dev code:", args.Path)
 	start := time.Now()
 
-	bo := backoff.NewExponentialBackOff()
-	bo.InitialInterval = time.Millisecond * 500
-	bo.MaxInterval = time.Second * 3
-	bo.MaxElapsedTime = 0
+	// Start watching before the first run, so that changes made during it aren't missed.
+	watch, err := watcher.New(args.Path, watcher.Options{
+		SkipDir: shouldSkipDir,
+		Match: func(fileName string) bool {
+			return watcher.IsTemplFile(fileName) || isCommandWatchFile(l, args, fileName)
+		},
+		Warnf: func(format string, a ...any) {
+			logWarning(w, format, a...)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch path: %w", err)
+	}
 
-	var firstRunComplete bool
-	fileNameToLastModTime := make(map[string]time.Time)
+	out := newOutput(l, parseOpts(args))
 	fileNameToHash := make(map[string][sha256.Size]byte)
+	changesFound, errs := processChanges(
+		ctx, w, jw, out,
+		nil, fileNameToHash, nil,
+		args.Path, args.GenerateSourceMapVisualisations, args.GenerateSourceMaps,
+		opts, args.WorkerCount, true, args.KeepOrphanedFiles)
+	if len(errs) > 0 {
+		if errors.Is(errs[0], context.Canceled) {
+			return errs[0]
+		}
+		logError(w, "Error processing path: %v\n", errors.Join(errs...))
+	}
+	if changesFound > 0 {
+		logGenerated(w, changesFound, errs, start)
+	}
+	writeErrorEvents(jw, errs)
+	jw.Summary(time.Since(start))
+	if p != nil {
+		p.SetErrors(generateErrorSource, buildErrors(args.Path, errs))
+		go func() {
+			fmt.Fprintf(w, "Proxying from %s to target: %s\n", p.URL, p.Target.String())
+			for _, prefix := range sortedKeys(args.ProxyRoutes) {
+				fmt.Fprintf(w, "Proxying %s to target: %s\n", prefix, args.ProxyRoutes[prefix])
+			}
+			if p.CertificateAuthority != "" {
+				fmt.Fprintf(w, "Serving HTTPS with certificates issued by the local certificate authority: %s\n", p.CertificateAuthority)
+			}
+			if err := p.ListenAndServe(); err != nil {
+				fmt.Fprintf(w, "Error starting proxy: %v\n", err)
+			}
+		}()
+		if args.OpenBrowser {
+			go func() {
+				fmt.Fprintf(w, "Opening URL: %s\n", p.Target.String())
+				if err := openURL(w, p.URL); err != nil {
+					fmt.Fprintf(w, "Error opening URL: %v\n", err)
+				}
+			}()
+		}
+	}
 
-	for !firstRunComplete || args.Watch {
-		changesFound, errs := processChanges(
-			ctx, w,
-			fileNameToLastModTime, fileNameToHash,
-			args.Path, args.GenerateSourceMapVisualisations,
-			opts, args.WorkerCount, true, args.KeepOrphanedFiles)
+	// reload restarts the command, which reloads the browser once it's ready, or reloads the
+	// browser straight away if there's no command.
+	reloads := &browserReload{}
+	reload := func() {
+		if p != nil {
+			reloads.send(p)
+		}
+	}
+	if args.Command != "" {
+		supervisor := newSupervisor(w, args, p, reloads)
+		supervisorDone := make(chan struct{})
+		go func() {
+			defer close(supervisorDone)
+			_ = supervisor.Run(ctx)
+		}()
+		// Stop the command before returning.
+		defer func() { <-supervisorDone }()
+		reload = supervisor.Restart
+	}
+
+	return watch.Run(ctx, func(events []watcher.Event) {
+		start := time.Now()
+		var templEvents []watcher.Event
+		var commandWatchFilesChanged bool
+		// Only the stylesheets are reloaded if only stylesheets and css templates have changed.
+		cssOnly := true
+		for _, e := range events {
+			if watcher.IsTemplFile(e.FileName) {
+				templEvents = append(templEvents, e)
+				continue
+			}
+			commandWatchFilesChanged = true
+			cssOnly = cssOnly && strings.EqualFold(filepath.Ext(e.FileName), ".css")
+		}
+		changesFound, errs := processEvents(
+			ctx, w, jw, out,
+			templEvents, fileNameToHash,
+			args.Path, args.GenerateSourceMapVisualisations, args.GenerateSourceMaps,
+			opts, args.WorkerCount, args.KeepOrphanedFiles)
 		if len(errs) > 0 {
 			if errors.Is(errs[0], context.Canceled) {
-				return errs[0]
-			}
-			if !args.Watch {
-				return fmt.Errorf("failed to process path: %v", errors.Join(errs...))
+				return
 			}
 			logError(w, "Error processing path: %v\n", errors.Join(errs...))
 		}
 		if changesFound > 0 {
-			if len(errs) > 0 {
-				logError(w, "Generated code for %d templates with %d errors in %s\n", changesFound, len(errs), time.Since(start))
-			} else {
-				logSuccess(w, "Generated code for %d templates with %d errors in %s\n", changesFound, len(errs), time.Since(start))
+			logGenerated(w, changesFound, errs, start)
+			writeErrorEvents(jw, errs)
+			jw.Summary(time.Since(start))
+		}
+		if p != nil {
+			p.SetErrors(generateErrorSource, buildErrors(args.Path, errs))
+		}
+		if changesFound > 0 || commandWatchFilesChanged {
+			for _, e := range templEvents {
+				cssOnly = cssOnly && e.Op == watcher.Changed && out.onlyCSSChanged(e.FileName)
 			}
-			if args.Command != "" {
-				fmt.Fprintf(w, "Executing command: %s\n", args.Command)
-				if _, err := run.Run(ctx, args.Path, args.Command); err != nil {
-					fmt.Fprintf(w, "Error starting command: %v\n", err)
-				}
+			reloads.add(cssOnly)
+		}
+		// Don't reload until the errors are fixed, so that the overlay stays visible.
+		if len(errs) > 0 {
+			return
+		}
+		if changesFound > 0 || commandWatchFilesChanged {
+			reload()
+		}
+	})
+}
+
+// isCommandWatchFile returns true if the file matches the -cmd-watch patterns. Generated Go
+// code is excluded, because the command is restarted after code is generated. Deleted files
+// with the name of generated code are also excluded, because their contents can't be checked.
+func isCommandWatchFile(l layout.Layout, args Arguments, fileName string) bool {
+	if args.Command == "" {
+		return false
+	}
+	if _, _, ok := l.Source(fileName); ok {
+		if _, err := os.Stat(fileName); err != nil || layout.IsGenerated(fileName) {
+			return false
+		}
+	}
+	rel, err := filepath.Rel(args.Path, fileName)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range args.CommandWatch {
+		if watcher.MatchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// newSupervisor creates a supervisor for the command. If there's a proxy, the browser is
+// reloaded once the target of the proxy responds after each start of the command.
+func newSupervisor(w io.Writer, args Arguments, p *proxy.Handler, reloads *browserReload) *run.Supervisor {
+	stdout, stderr := run.PrefixWriters(w, color.New(color.FgCyan).Sprint("[cmd] "), color.New(color.FgRed).Sprint("[cmd] "))
+	opts := run.Options{
+		Command:     args.Command,
+		Dir:         args.Path,
+		Stdout:      stdout,
+		Stderr:      stderr,
+		StopTimeout: args.CommandStopTimeout,
+		Infof: func(format string, a ...any) {
+			fmt.Fprintf(w, format, a...)
+		},
+		Errorf: func(format string, a ...any) {
+			logError(w, format, a...)
+		},
+	}
+	if p != nil {
+		opts.Ready = func(ctx context.Context) error {
+			return waitForTarget(ctx, p.Target.String())
+		}
+		opts.OnReady = func() {
+			p.SetErrors(args.Command, nil)
+			reloads.send(p)
+		}
+		opts.OnCrash = func(err error, stderr string) {
+			msg := err.Error()
+			if stderr = strings.TrimSpace(stderr); stderr != "" {
+				msg += "\n\n" + stderr
+			}
+			p.SetErrors(args.Command, []proxy.BuildError{{Source: args.Command, Message: msg}})
+		}
+	}
+	return run.New(opts)
+}
+
+// generateErrorSource is the source of the errors in code generation that are shown in the
+// browser.
+const generateErrorSource = "templ generate"
+
+// fileError is an error in generating the code for a templ file.
+type fileError struct {
+	fileName string
+	err      error
+}
+
+func (fe fileError) Error() string {
+	return fe.err.Error()
+}
+
+func (fe fileError) Unwrap() error {
+	return fe.err
+}
+
+// buildErrors converts the errors from code generation into errors that can be shown in the
+// browser. Parse errors include the location of the error, and an excerpt of the templ file.
+func buildErrors(basePath string, errs []error) (buildErrs []proxy.BuildError) {
+	for _, err := range errs {
+		var fe fileError
+		if !errors.As(err, &fe) {
+			buildErrs = append(buildErrs, proxy.BuildError{Source: generateErrorSource, Message: err.Error()})
+			continue
+		}
+		fileName := fe.fileName
+		if rel, err := filepath.Rel(basePath, fe.fileName); err == nil {
+			fileName = rel
+		}
+		for _, problem := range joinedErrors(fe.err) {
+			var pe parse.ParseError
+			if !errors.As(problem, &pe) {
+				buildErrs = append(buildErrs, proxy.BuildError{Source: generateErrorSource, FileName: fileName, Message: problem.Error()})
+				continue
 			}
-			// Send server-sent event.
-			if p != nil {
-				p.SendSSE("message", "reload")
+			msg := pe.Msg
+			if info, ok := parser.ErrorCodeOf(problem); ok {
+				msg = fmt.Sprintf("%s [%s]", msg, info.Code)
 			}
+			line := int(pe.Pos.Line) + 1
+			buildErrs = append(buildErrs, proxy.BuildError{
+				Source:   generateErrorSource,
+				FileName: fileName,
+				Line:     line,
+				Col:      int(pe.Pos.Col) + 1,
+				Message:  msg,
+				Excerpt:  sourceExcerpt(fe.fileName, line),
+			})
+		}
+	}
+	return buildErrs
+}
 
-			if !firstRunComplete && p != nil {
-				go func() {
-					fmt.Fprintf(w, "Proxying from %s to target: %s\n", p.URL, p.Target.String())
-					if err := http.ListenAndServe(fmt.Sprintf("127.0.0.1:%d", args.ProxyPort), p); err != nil {
-						fmt.Fprintf(w, "Error starting proxy: %v\n", err)
-					}
-				}()
-				if args.OpenBrowser {
-					go func() {
-						fmt.Fprintf(w, "Opening URL: %s\n", p.Target.String())
-						if err := openURL(w, p.URL); err != nil {
-							fmt.Fprintf(w, "Error opening URL: %v\n", err)
-						}
-					}()
-				}
+// joinedErrors returns the errors that are joined within the chain of err, or err if there
+// aren't any.
+func joinedErrors(err error) []error {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if joined, ok := e.(interface{ Unwrap() []error }); ok {
+			var errs []error
+			for _, err := range joined.Unwrap() {
+				errs = append(errs, joinedErrors(err)...)
 			}
+			return errs
+		}
+	}
+	return []error{err}
+}
+
+// sourceExcerpt returns the lines of a file around the 1-based line number.
+func sourceExcerpt(fileName string, line int) (excerpt []proxy.SourceLine) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(string(data), "\n")
+	for i := line - 3; i <= line+2; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		excerpt = append(excerpt, proxy.SourceLine{Line: i, Text: strings.TrimSuffix(lines[i-1], "\r")})
+	}
+	return excerpt
+}
+
+// waitForTarget returns once the target responds to HTTP requests, with any status code.
+func waitForTarget(ctx context.Context, target string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	client := http.Client{Timeout: time.Second}
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
 		}
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s didn't respond within 30s: %w", target, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// writeErrorEvents writes the errors that aren't specific to a templ file. The errors in templ
+// files are written as file events.
+func writeErrorEvents(jw *jsonoutput.Writer, errs []error) {
+	for _, err := range errs {
+		if !errors.As(err, &fileError{}) {
+			jw.Error(err)
+		}
+	}
+}
+
+func logGenerated(w io.Writer, changesFound int, errs []error, start time.Time) {
+	if len(errs) > 0 {
+		logError(w, "Generated code for %d templates with %d errors in %s\n", changesFound, len(errs), time.Since(start))
+		return
+	}
+	logSuccess(w, "Generated code for %d templates with %d errors in %s\n", changesFound, len(errs), time.Since(start))
+}
+
+// processEvents generates the code for templ files that have changed, and removes the
+// generated files of templ files that have been removed.
+func processEvents(ctx context.Context, stdout io.Writer, jw *jsonoutput.Writer, out *output, events []watcher.Event, hashes map[string][sha256.Size]byte, path string, generateSourceMapVisualisations, generateSourceMaps bool, opts []generator.GenerateOpt, maxWorkerCount int, keepOrphanedFiles bool) (changesFound int, errs []error) {
+	sem := make(chan struct{}, maxWorkerCount)
+	var wg sync.WaitGroup
+	var m sync.Mutex
 
-		if firstRunComplete {
-			if changesFound > 0 {
-				bo.Reset()
+	opts = append(opts, generator.WithExtractStrings())
+
+	// Remove the generated files first, because the hashes are updated by the processors.
+	for _, e := range events {
+		if e.Op == watcher.Removed {
+			changesFound++
+			if err := removeGeneratedFiles(stdout, jw, out, e.FileName, hashes, keepOrphanedFiles); err != nil {
+				errs = append(errs, err)
 			}
-			time.Sleep(bo.NextBackOff())
 		}
+	}
+	for _, e := range events {
+		if err := ctx.Err(); err != nil {
+			m.Lock()
+			errs = append(errs, err)
+			m.Unlock()
+			break
+		}
+		if e.Op != watcher.Changed {
+			continue
+		}
+		changesFound++
 
-		firstRunComplete = true
-		start = time.Now()
+		// Start a processor, but limit to maxWorkerCount.
+		fileName := e.FileName
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := processSingleFile(ctx, stdout, jw, out, path, fileName, hashes, generateSourceMapVisualisations, generateSourceMaps, opts); err != nil {
+				m.Lock()
+				errs = append(errs, err)
+				m.Unlock()
+			}
+			<-sem
+		}()
 	}
 
+	wg.Wait()
+
+	errs = append(errs, out.writeCombined(ctx, stdout, jw, hashes, generateSourceMapVisualisations, generateSourceMaps, keepOrphanedFiles)...)
+	return changesFound, errs
+}
+
+// removeGeneratedFiles deletes the files that were generated from a templ file that has been
+// removed. Its code is removed from a combined file when the combined file is next written.
+func removeGeneratedFiles(stdout io.Writer, jw *jsonoutput.Writer, out *output, templFileName string, hashes map[string][sha256.Size]byte, keepOrphanedFiles bool) error {
+	out.removeTemplate(templFileName)
+	var fileNames []string
+	if out.layout.Combine {
+		out.remove(templFileName)
+	} else {
+		fileNames = append(fileNames, out.layout.TextFileName(templFileName))
+		if !keepOrphanedFiles {
+			fileNames = append(fileNames, out.layout.GoFileName(templFileName))
+		}
+	}
+	if !keepOrphanedFiles {
+		fileNames = append(fileNames, out.layout.SourceMapFileName(templFileName))
+	}
+	for _, fileName := range fileNames {
+		if err := removeGeneratedFile(stdout, jw, fileName, hashes); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func generateProduction(ctx context.Context, w io.Writer, args Arguments, opts []generator.GenerateOpt, p *proxy.Handler) error {
+func generateProduction(ctx context.Context, w io.Writer, jw *jsonoutput.Writer, l layout.Layout, args Arguments, opts []generator.GenerateOpt, p *proxy.Handler) error {
 	fmt.Fprintln(w, "Generating production code:", args.Path)
 	start := time.Now()
 
+	cache := openBuildCache(w, l, args)
 	changesFound, errs := processChanges(
-		ctx, w, nil, nil,
-		args.Path, args.GenerateSourceMapVisualisations,
+		ctx, w, jw, newOutput(l, parseOpts(args)), nil, nil, cache,
+		args.Path, args.GenerateSourceMapVisualisations, args.GenerateSourceMaps,
 		opts, args.WorkerCount, false, args.KeepOrphanedFiles)
 	if len(errs) > 0 {
 		if errors.Is(errs[0], context.Canceled) {
@@ -216,17 +669,26 @@ func generateProduction(ctx context.Context, w io.Writer, args Arguments, opts [
 		}
 		logError(w, "Error processing path: %v\n", errors.Join(errs...))
 	}
+	generated := changesFound
+	if cache != nil {
+		hits, misses := cache.Stats()
+		generated -= hits
+		fmt.Fprintf(w, "Build cache: %d hits, %d misses\n", hits, misses)
+		if err := cache.Save(); err != nil {
+			logWarning(w, "%v\n", err)
+		}
+	}
+	writeErrorEvents(jw, errs)
+	jw.Summary(time.Since(start))
 
 	if changesFound > 0 {
-		if len(errs) > 0 {
-			logError(w, "Generated code for %d templates with %d errors in %s\n", changesFound, len(errs), time.Since(start))
-		} else {
-			logSuccess(w, "Generated code for %d templates with %d errors in %s\n", changesFound, len(errs), time.Since(start))
-		}
-		if args.Command != "" {
+		logGenerated(w, generated, errs, start)
+		// In watch mode, the command has already been run.
+		if args.Command != "" && !args.Watch {
 			fmt.Fprintf(w, "Executing command: %s\n", args.Command)
-			if _, err := run.Run(ctx, args.Path, args.Command); err != nil {
-				fmt.Fprintf(w, "Error starting command: %v\n", err)
+			stdout, stderr := run.PrefixWriters(w, color.New(color.FgCyan).Sprint("[cmd] "), color.New(color.FgRed).Sprint("[cmd] "))
+			if err := run.Once(ctx, args.Path, args.Command, stdout, stderr); err != nil {
+				logError(w, "Command failed: %v\n", err)
 			}
 		}
 	}
@@ -234,6 +696,25 @@ func generateProduction(ctx context.Context, w io.Writer, args Arguments, opts [
 	return nil
 }
 
+// openBuildCache opens the build cache, or returns nil if the cache is disabled. The cache
+// isn't used when the generated code includes a timestamp, or when source map visualisations
+// are generated, because the output of each run is different. It isn't used for combined files
+// either, because they're generated from all of the templ files in a directory.
+func openBuildCache(w io.Writer, l layout.Layout, args Arguments) *buildcache.Cache {
+	if args.CacheDir == "" || args.IncludeTimestamp || args.GenerateSourceMapVisualisations || l.Combine {
+		return nil
+	}
+	options := fmt.Sprintf("templ=%s includeVersion=%t lineDirectives=%t sourceMaps=%t whitespaceSensitiveElements=%s outputDir=%s outputPackage=%s outputSuffix=%s",
+		templ.Version(), args.IncludeVersion, args.LineDirectives, args.GenerateSourceMaps, strings.Join(args.WhitespaceSensitiveElements, ","),
+		l.Dir(l.Root), l.Package, l.Suffix)
+	cache, err := buildcache.Open(args.CacheDir, args.Path, options)
+	if err != nil {
+		logWarning(w, "%v\n", err)
+		return nil
+	}
+	return cache
+}
+
 func shouldSkipDir(dir string) bool {
 	if dir == "." {
 		return false
@@ -249,9 +730,10 @@ func shouldSkipDir(dir string) bool {
 	return false
 }
 
-func processChanges(ctx context.Context, stdout io.Writer, fileNameToLastModTime map[string]time.Time, hashes map[string][sha256.Size]byte, path string, generateSourceMapVisualisations bool, opts []generator.GenerateOpt, maxWorkerCount int, watching, keepOrphanedFiles bool) (changesFound int, errs []error) {
+func processChanges(ctx context.Context, stdout io.Writer, jw *jsonoutput.Writer, out *output, fileNameToLastModTime map[string]time.Time, hashes map[string][sha256.Size]byte, cache *buildcache.Cache, path string, generateSourceMapVisualisations, generateSourceMaps bool, opts []generator.GenerateOpt, maxWorkerCount int, watching, keepOrphanedFiles bool) (changesFound int, errs []error) {
 	sem := make(chan struct{}, maxWorkerCount)
 	var wg sync.WaitGroup
+	var m sync.Mutex
 
 	if watching {
 		opts = append(opts, generator.WithExtractStrings())
@@ -261,7 +743,7 @@ func processChanges(ctx context.Context, stdout io.Writer, fileNameToLastModTime
 		fileNameToLastModTime = make(map[string]time.Time)
 	}
 
-	err := filepath.WalkDir(path, func(fileName string, info os.DirEntry, err error) error {
+	walk := func(fileName string, info os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -275,22 +757,20 @@ func processChanges(ctx context.Context, stdout io.Writer, fileNameToLastModTime
 			return nil
 		}
 
-		orphaned := !keepOrphanedFiles && strings.HasSuffix(fileName, "_templ.go")
-		if orphaned {
-			// Make sure the generated file is orphaned
-			// by checking if the corresponding .templ file exists.
-			if _, err := os.Stat(strings.TrimSuffix(fileName, "_templ.go") + ".templ"); err == nil {
-				orphaned = false
-			}
-		}
+		// Make sure the generated file is orphaned
+		// by checking if the corresponding .templ file exists.
+		orphaned := !keepOrphanedFiles && isOrphaned(out.layout, fileName)
 
-		devTextFile := !watching && strings.HasSuffix(fileName, "_templ.txt")
+		devTextFile := !watching && strings.HasSuffix(fileName, out.layout.Suffix+".txt") && layout.IsGenerated(fileName)
 		if orphaned || devTextFile {
-			if err = os.Remove(fileName); err != nil {
-				return fmt.Errorf("failed to remove file: %w", err)
+			// The strings of an orphaned Go file are removed with it, because they can't be
+			// recognised once the Go file has been removed.
+			if orphaned && strings.HasSuffix(fileName, ".go") {
+				if err = removeGeneratedFile(stdout, jw, strings.TrimSuffix(fileName, ".go")+".txt", nil); err != nil {
+					return err
+				}
 			}
-			logWarning(stdout, "Deleted file %q\n", fileName)
-			return nil
+			return removeGeneratedFile(stdout, jw, fileName, nil)
 		}
 
 		if strings.HasSuffix(fileName, ".templ") {
@@ -308,24 +788,46 @@ func processChanges(ctx context.Context, stdout io.Writer, fileNameToLastModTime
 				wg.Add(1)
 				go func() {
 					defer wg.Done()
-					if err := processSingleFile(ctx, stdout, path, fileName, hashes, generateSourceMapVisualisations, opts); err != nil {
+					var err error
+					if cache != nil {
+						err = processCachedFile(ctx, stdout, jw, out, path, fileName, cache, generateSourceMaps, opts)
+					} else {
+						_, err = processSingleFile(ctx, stdout, jw, out, path, fileName, hashes, generateSourceMapVisualisations, generateSourceMaps, opts)
+					}
+					if err != nil {
+						m.Lock()
 						errs = append(errs, err)
+						m.Unlock()
 					}
 					<-sem
 				}()
 			}
 		}
 		return nil
-	})
+	}
+	err := filepath.WalkDir(path, walk)
+	// Orphaned files are also removed from the output directory, if it's outside of the path.
+	if outputDir := out.layout.Dir(path); err == nil && !isWithin(path, outputDir) {
+		if _, statErr := os.Stat(outputDir); statErr == nil {
+			err = filepath.WalkDir(outputDir, walk)
+		}
+	}
 	if err != nil {
 		errs = append(errs, err)
 	}
 
 	wg.Wait()
 
+	errs = append(errs, out.writeCombined(ctx, stdout, jw, hashes, generateSourceMapVisualisations, generateSourceMaps, keepOrphanedFiles)...)
 	return changesFound, errs
 }
 
+// isWithin returns true if fileName is dir, or is within it.
+func isWithin(dir, fileName string) bool {
+	rel, err := filepath.Rel(dir, fileName)
+	return err == nil && filepath.IsLocal(rel)
+}
+
 func openURL(w io.Writer, url string) error {
 	backoff := backoff.NewExponentialBackOff()
 	backoff.InitialInterval = time.Second
@@ -344,12 +846,14 @@ func openURL(w io.Writer, url string) error {
 
 // processSingleFile generates Go code for a single template.
 // If a basePath is provided, the filename included in error messages is relative to it.
-func processSingleFile(ctx context.Context, stdout io.Writer, basePath, fileName string, hashes map[string][sha256.Size]byte, generateSourceMapVisualisations bool, opts []generator.GenerateOpt) (err error) {
+func processSingleFile(ctx context.Context, stdout io.Writer, jw *jsonoutput.Writer, out *output, basePath, fileName string, hashes map[string][sha256.Size]byte, generateSourceMapVisualisations, generateSourceMaps bool, opts []generator.GenerateOpt) (diag []parser.Diagnostic, err error) {
 	start := time.Now()
-	diag, err := generate(ctx, basePath, fileName, hashes, generateSourceMapVisualisations, opts)
+	diag, err = generate(ctx, out, basePath, fileName, hashes, generateSourceMapVisualisations, generateSourceMaps, opts)
 	if err != nil {
-		return err
+		jw.File(fileName, jsonoutput.StatusError, time.Since(start), nil, err)
+		return nil, fileError{fileName: fileName, err: err}
 	}
+	jw.File(fileName, jsonoutput.StatusGenerated, time.Since(start), diag, nil)
 	var b bytes.Buffer
 	defer func() {
 		_, _ = b.WriteTo(stdout)
@@ -357,15 +861,44 @@ func processSingleFile(ctx context.Context, stdout io.Writer, basePath, fileName
 	if len(diag) > 0 {
 		logWarning(&b, "Generated code for %q in %s\n", fileName, time.Since(start))
 		printDiagnostics(&b, fileName, diag)
-		return nil
+		return diag, nil
 	}
 	logSuccess(&b, "Generated code for %q in %s\n", fileName, time.Since(start))
+	return nil, nil
+}
+
+// processCachedFile generates Go code for a single template, unless the build cache shows that
+// the generated code is up to date. Templates with diagnostics aren't cached, so that the
+// diagnostics are shown each time.
+func processCachedFile(ctx context.Context, stdout io.Writer, jw *jsonoutput.Writer, out *output, basePath, fileName string, cache *buildcache.Cache, generateSourceMaps bool, opts []generator.GenerateOpt) error {
+	start := time.Now()
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		err = fmt.Errorf("failed to read file: %w", err)
+		jw.File(fileName, jsonoutput.StatusError, time.Since(start), nil, err)
+		return fileError{fileName: fileName, err: err}
+	}
+	key := cache.Key(contents)
+	if cache.Lookup(fileName, key) {
+		jw.File(fileName, jsonoutput.StatusCached, time.Since(start), nil, nil)
+		return nil
+	}
+	hashes := make(map[string][sha256.Size]byte)
+	diag, err := processSingleFile(ctx, stdout, jw, out, basePath, fileName, hashes, false, generateSourceMaps, opts)
+	if err != nil || len(diag) > 0 {
+		return err
+	}
+	cache.Store(fileName, key, hashes)
 	return nil
 }
 
 func printDiagnostics(w io.Writer, fileName string, diags []parser.Diagnostic) {
 	for _, d := range diags {
 		fmt.Fprint(w, "\t")
+		if d.Code != "" {
+			logWarning(w, "%s (%d:%d) [%s]\n", d.Message, d.Range.From.Line, d.Range.From.Col, d.Code)
+			continue
+		}
 		logWarning(w, "%s (%d:%d)\n", d.Message, d.Range.From.Line, d.Range.From.Col)
 	}
 	fmt.Fprintln(w)
@@ -373,7 +906,9 @@ func printDiagnostics(w io.Writer, fileName string, diags []parser.Diagnostic) {
 
 // generate Go code for a single template.
 // If a basePath is provided, the filename included in error messages is relative to it.
-func generate(ctx context.Context, basePath, fileName string, hashes map[string][sha256.Size]byte, generateSourceMapVisualisations bool, opts []generator.GenerateOpt) (diagnostics []parser.Diagnostic, err error) {
+// When the code is combined, it's written once the other templ files in the directory have
+// been generated, see output.writeCombined.
+func generate(ctx context.Context, out *output, basePath, fileName string, hashes map[string][sha256.Size]byte, generateSourceMapVisualisations, generateSourceMaps bool, opts []generator.GenerateOpt) (diagnostics []parser.Diagnostic, err error) {
 	if err = ctx.Err(); err != nil {
 		return
 	}
@@ -382,57 +917,78 @@ func generate(ctx context.Context, basePath, fileName string, hashes map[string]
 		hashes = make(map[string][sha256.Size]byte)
 	}
 
-	t, err := parser.Parse(fileName)
-	if err != nil {
-		return nil, fmt.Errorf("%s parsing error: %w", fileName, err)
-	}
-	targetFileName := strings.TrimSuffix(fileName, ".templ") + "_templ.go"
-
-	// Only use relative filenames to the basepath for filenames in runtime error messages.
-	errorMessageFileName := fileName
-	if basePath != "" {
-		errorMessageFileName, _ = filepath.Rel(basePath, fileName)
-	}
-
-	var b bytes.Buffer
-	sourceMap, literals, err := generator.Generate(t, &b, append(opts, generator.WithFileName(errorMessageFileName))...)
+	t, formattedGoCode, sourceMap, literals, err := generateCode(out, basePath, fileName, generateSourceMapVisualisations || generateSourceMaps, opts)
 	if err != nil {
-		return nil, fmt.Errorf("%s generation error: %w", fileName, err)
+		out.removeTemplate(fileName)
+		return nil, err
 	}
-
-	formattedGoCode, err := format.Source(b.Bytes())
-	if err != nil {
-		return nil, fmt.Errorf("%s source formatting error: %w", fileName, err)
+	out.setTemplate(fileName, t)
+	if out.layout.Combine {
+		out.add(fileName, generator.CombineFile{Name: fileName, Code: formattedGoCode, SourceMap: sourceMap, Literals: literals})
+		return t.Diagnostics, nil
 	}
+	targetFileName := out.layout.GoFileName(fileName)
 
 	// Hash output, and write out the file if the goCodeHash has changed.
-	goCodeHash := sha256.Sum256(formattedGoCode)
-	if hashes[targetFileName] != goCodeHash {
-		if err = os.WriteFile(targetFileName, formattedGoCode, 0o644); err != nil {
-			return nil, fmt.Errorf("failed to write target file %q: %w", targetFileName, err)
-		}
-		hashes[targetFileName] = goCodeHash
+	if err = writeIfChanged(hashes, targetFileName, formattedGoCode); err != nil {
+		return nil, fmt.Errorf("failed to write target file %q: %w", targetFileName, err)
 	}
 
 	// Add the txt file if it has changed.
 	if len(literals) > 0 {
-		txtFileName := strings.TrimSuffix(fileName, ".templ") + "_templ.txt"
-		txtHash := sha256.Sum256([]byte(literals))
-		if hashes[txtFileName] != txtHash {
-			if err = os.WriteFile(txtFileName, []byte(literals), 0o644); err != nil {
-				return nil, fmt.Errorf("failed to write string literal file %q: %w", txtFileName, err)
-			}
-			hashes[txtFileName] = txtHash
+		txtFileName := out.layout.TextFileName(fileName)
+		if err = writeIfChanged(hashes, txtFileName, []byte(literals)); err != nil {
+			return nil, fmt.Errorf("failed to write string literal file %q: %w", txtFileName, err)
+		}
+	}
+
+	// Add the source map if it has changed.
+	if generateSourceMaps {
+		if err = writeSourceMap(hashes, fileName, out.layout.SourceMapFileName(fileName), sourceMap); err != nil {
+			return nil, err
 		}
 	}
 
 	if generateSourceMapVisualisations {
-		err = generateSourceMapVisualisation(ctx, fileName, targetFileName, sourceMap)
+		err = generateSourceMapVisualisation(ctx, fileName, targetFileName, out.layout.VisualisationFileName(fileName), sourceMap)
 	}
 	return t.Diagnostics, err
 }
 
-func generateSourceMapVisualisation(ctx context.Context, templFileName, goFileName string, sourceMap *parser.SourceMap) error {
+// generateCode parses a template, and returns the formatted Go code, without writing any files.
+// If formatSourceMap is true, the source map is updated to match the formatted code.
+func generateCode(out *output, basePath, fileName string, formatSourceMap bool, opts []generator.GenerateOpt) (t parser.TemplateFile, formattedGoCode []byte, sourceMap *parser.SourceMap, literals string, err error) {
+	t, err = parser.Parse(fileName, out.parseOpts...)
+	if err != nil {
+		return t, nil, nil, "", fmt.Errorf("%s parsing error: %w", fileName, parser.WithErrorCodes(err))
+	}
+
+	// Only use relative filenames to the basepath for filenames in runtime error messages.
+	errorMessageFileName := fileName
+	if basePath != "" {
+		errorMessageFileName, _ = filepath.Rel(basePath, fileName)
+	}
+
+	var b bytes.Buffer
+	opts = append(opts, generator.WithFileName(errorMessageFileName), generator.WithGeneratedFileName(out.layout.GoFileName(fileName)))
+	sourceMap, literals, err = generator.Generate(t, &b, opts...)
+	if err != nil {
+		return t, nil, nil, "", fmt.Errorf("%s generation error: %w", fileName, err)
+	}
+
+	// Source maps are updated to match the formatted code, which takes longer.
+	if formatSourceMap {
+		formattedGoCode, sourceMap, err = generator.Format(b.Bytes(), sourceMap)
+	} else {
+		formattedGoCode, err = format.Source(b.Bytes())
+	}
+	if err != nil {
+		return t, nil, nil, "", fmt.Errorf("%s source formatting error: %w", fileName, err)
+	}
+	return t, formattedGoCode, sourceMap, literals, nil
+}
+
+func generateSourceMapVisualisation(ctx context.Context, templFileName, goFileName, targetFileName string, sourceMap *parser.SourceMap) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
@@ -456,7 +1012,6 @@ func generateSourceMapVisualisation(ctx context.Context, templFileName, goFileNa
 		return templErr
 	}
 
-	targetFileName := strings.TrimSuffix(templFileName, ".templ") + "_templ_sourcemap.html"
 	w, err := os.Create(targetFileName)
 	if err != nil {
 		return fmt.Errorf("%s sourcemap visualisation error: %w", templFileName, err)
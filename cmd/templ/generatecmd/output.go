@@ -0,0 +1,268 @@
+package generatecmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/a-h/templ/cmd/templ/jsonoutput"
+	"github.com/a-h/templ/cmd/templ/layout"
+	"github.com/a-h/templ/generator"
+	"github.com/a-h/templ/parser/v2"
+)
+
+// output is where generated code is written. When the code of the templ files of a directory
+// is combined into a single file, the code of each templ file is kept, so that the combined
+// file can be written again when one of the templ files changes.
+type output struct {
+	layout    layout.Layout
+	parseOpts []parser.ParseOpt
+
+	m sync.Mutex
+	// parts are the code of each templ file, by the name of the combined file, and the name of
+	// the templ file.
+	parts map[string]map[string]generator.CombineFile
+	// changed are the names of the combined files that need to be written.
+	changed map[string]bool
+	// withoutCSS are the hashes of the templ files without their css templates, and cssOnly
+	// is true for the templ files where only the css templates changed when they were last
+	// generated.
+	withoutCSS map[string][sha256.Size]byte
+	cssOnly    map[string]bool
+}
+
+func newOutput(l layout.Layout, parseOpts []parser.ParseOpt) *output {
+	return &output{
+		layout:     l,
+		parseOpts:  parseOpts,
+		parts:      make(map[string]map[string]generator.CombineFile),
+		changed:    make(map[string]bool),
+		withoutCSS: make(map[string][sha256.Size]byte),
+		cssOnly:    make(map[string]bool),
+	}
+}
+
+// setTemplate records the templates of a templ file, so that changes to only its css templates
+// can be recognised.
+func (out *output) setTemplate(templFileName string, t parser.TemplateFile) {
+	nodes := make([]parser.TemplateFileNode, 0, len(t.Nodes))
+	for _, n := range t.Nodes {
+		if _, ok := n.(parser.CSSTemplate); !ok {
+			nodes = append(nodes, n)
+		}
+	}
+	t.Nodes = nodes
+	h := sha256.New()
+	err := t.Write(h)
+	var hash [sha256.Size]byte
+	copy(hash[:], h.Sum(nil))
+
+	out.m.Lock()
+	defer out.m.Unlock()
+	previous, ok := out.withoutCSS[templFileName]
+	out.cssOnly[templFileName] = err == nil && ok && previous == hash
+	out.withoutCSS[templFileName] = hash
+}
+
+// removeTemplate removes the templates of a templ file that has been removed, or that can't be
+// generated.
+func (out *output) removeTemplate(templFileName string) {
+	out.m.Lock()
+	defer out.m.Unlock()
+	delete(out.withoutCSS, templFileName)
+	delete(out.cssOnly, templFileName)
+}
+
+// onlyCSSChanged returns true if only the css templates of a templ file changed when it was
+// last generated.
+func (out *output) onlyCSSChanged(templFileName string) bool {
+	out.m.Lock()
+	defer out.m.Unlock()
+	return out.cssOnly[templFileName]
+}
+
+// add the code of a templ file to its combined file.
+func (out *output) add(templFileName string, part generator.CombineFile) {
+	out.m.Lock()
+	defer out.m.Unlock()
+	goFileName := out.layout.GoFileName(templFileName)
+	if out.parts[goFileName] == nil {
+		out.parts[goFileName] = make(map[string]generator.CombineFile)
+	}
+	out.parts[goFileName][templFileName] = part
+	out.changed[goFileName] = true
+}
+
+// remove the code of a templ file from its combined file.
+func (out *output) remove(templFileName string) {
+	out.m.Lock()
+	defer out.m.Unlock()
+	goFileName := out.layout.GoFileName(templFileName)
+	delete(out.parts[goFileName], templFileName)
+	out.changed[goFileName] = true
+}
+
+// writeCombined writes the combined files that have changed since they were last written, with
+// their source maps, strings and source map visualisations. Combined files that no longer
+// contain the code of any templ files are deleted, unless keepOrphanedFiles is set.
+func (out *output) writeCombined(ctx context.Context, stdout io.Writer, jw *jsonoutput.Writer, hashes map[string][sha256.Size]byte, generateSourceMapVisualisations, generateSourceMaps, keepOrphanedFiles bool) (errs []error) {
+	out.m.Lock()
+	defer out.m.Unlock()
+	if hashes == nil {
+		hashes = make(map[string][sha256.Size]byte)
+	}
+	goFileNames := make([]string, 0, len(out.changed))
+	for goFileName := range out.changed {
+		goFileNames = append(goFileNames, goFileName)
+	}
+	sort.Strings(goFileNames)
+	for _, goFileName := range goFileNames {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+		delete(out.changed, goFileName)
+		parts := out.parts[goFileName]
+		if len(parts) == 0 {
+			delete(out.parts, goFileName)
+			if err := removeCombinedFiles(stdout, jw, goFileName, hashes, keepOrphanedFiles); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		code, sourceMaps, literals, err := combine(parts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to combine %s: %w", goFileName, err))
+			continue
+		}
+		if err = writeIfChanged(hashes, goFileName, code); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write target file %q: %w", goFileName, err))
+			continue
+		}
+		txtFileName := strings.TrimSuffix(goFileName, ".go") + ".txt"
+		if len(literals) > 0 {
+			if err = writeIfChanged(hashes, txtFileName, []byte(literals)); err != nil {
+				errs = append(errs, fmt.Errorf("failed to write string literal file %q: %w", txtFileName, err))
+			}
+		}
+		for templFileName, sourceMap := range sourceMaps {
+			if generateSourceMaps {
+				if err = writeSourceMap(hashes, templFileName, out.layout.SourceMapFileName(templFileName), sourceMap); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if generateSourceMapVisualisations {
+				if err = generateSourceMapVisualisation(ctx, templFileName, goFileName, out.layout.VisualisationFileName(templFileName), sourceMap); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// combine combines the code of the templ files, in the order of their file names, and returns
+// the source map of each templ file.
+func combine(parts map[string]generator.CombineFile) (code []byte, sourceMaps map[string]*parser.SourceMap, literals string, err error) {
+	templFileNames := make([]string, 0, len(parts))
+	for templFileName := range parts {
+		templFileNames = append(templFileNames, templFileName)
+	}
+	sort.Strings(templFileNames)
+	files := make([]generator.CombineFile, len(templFileNames))
+	for i, templFileName := range templFileNames {
+		files[i] = parts[templFileName]
+	}
+	code, combinedSourceMaps, literals, err := generator.Combine(files)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	sourceMaps = make(map[string]*parser.SourceMap, len(files))
+	for i, sm := range combinedSourceMaps {
+		if sm != nil {
+			sourceMaps[templFileNames[i]] = sm
+		}
+	}
+	return code, sourceMaps, literals, nil
+}
+
+// removeCombinedFiles deletes a combined file and its strings, once there are no templ files in
+// its directory.
+func removeCombinedFiles(stdout io.Writer, jw *jsonoutput.Writer, goFileName string, hashes map[string][sha256.Size]byte, keepOrphanedFiles bool) error {
+	fileNames := []string{strings.TrimSuffix(goFileName, ".go") + ".txt"}
+	if !keepOrphanedFiles {
+		fileNames = append(fileNames, goFileName)
+	}
+	for _, fileName := range fileNames {
+		if err := removeGeneratedFile(stdout, jw, fileName, hashes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeGeneratedFile deletes a generated file, if it exists.
+func removeGeneratedFile(stdout io.Writer, jw *jsonoutput.Writer, fileName string, hashes map[string][sha256.Size]byte) error {
+	delete(hashes, fileName)
+	err := os.Remove(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	logWarning(stdout, "Deleted file %q\n", fileName)
+	jw.File(fileName, jsonoutput.StatusDeleted, 0, nil, nil)
+	return nil
+}
+
+// writeIfChanged writes a file, and creates its directory, unless the hash of its contents
+// shows that it's already been written.
+func writeIfChanged(hashes map[string][sha256.Size]byte, fileName string, contents []byte) error {
+	hash := sha256.Sum256(contents)
+	if hashes[fileName] == hash {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(fileName, contents, 0o644); err != nil {
+		return err
+	}
+	hashes[fileName] = hash
+	return nil
+}
+
+func writeSourceMap(hashes map[string][sha256.Size]byte, templFileName, mapFileName string, sourceMap *parser.SourceMap) error {
+	mapJSON, err := json.Marshal(sourceMap)
+	if err != nil {
+		return fmt.Errorf("%s source map error: %w", templFileName, err)
+	}
+	if err = writeIfChanged(hashes, mapFileName, mapJSON); err != nil {
+		return fmt.Errorf("failed to write source map file %q: %w", mapFileName, err)
+	}
+	return nil
+}
+
+// isOrphaned returns true if a file was generated by templ, and the templ file that it was
+// generated from doesn't exist, or for combined files, there are no templ files in the
+// directory that it was generated from.
+func isOrphaned(l layout.Layout, fileName string) bool {
+	source, combined, ok := l.Source(fileName)
+	if !ok || !layout.IsGenerated(fileName) {
+		return false
+	}
+	if !combined {
+		_, err := os.Stat(source)
+		return err != nil
+	}
+	matches, err := filepath.Glob(filepath.Join(source, "*.templ"))
+	return err == nil && len(matches) == 0
+}
@@ -0,0 +1,199 @@
+package generatecmd
+
+import (
+	"bytes"
+	"context"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ/cmd/templ/layout"
+)
+
+func TestOutputLayout(t *testing.T) {
+	setup := func(t *testing.T, templFileNames ...string) (dir string) {
+		dir = t.TempDir()
+		for _, fileName := range templFileNames {
+			fileName = filepath.Join(dir, filepath.FromSlash(fileName))
+			if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+				t.Fatalf("failed to create directory: %v", err)
+			}
+			name := strings.TrimSuffix(filepath.Base(fileName), ".templ")
+			templ := strings.Replace(checkTestTemplate, "Hello", strings.ToUpper(name[:1])+name[1:], 1)
+			if err := os.WriteFile(fileName, []byte(templ), 0o644); err != nil {
+				t.Fatalf("failed to write templ file: %v", err)
+			}
+		}
+		return dir
+	}
+	generate := func(t *testing.T, args Arguments) {
+		args.CacheDir = t.TempDir()
+		if err := Run(context.Background(), &bytes.Buffer{}, args); err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+	}
+	read := func(t *testing.T, fileName string) string {
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatalf("expected %s to be generated: %v", fileName, err)
+		}
+		return string(data)
+	}
+	assertNotExists := func(t *testing.T, fileName string) {
+		if _, err := os.Stat(fileName); err == nil {
+			t.Errorf("expected %s not to exist", fileName)
+		}
+	}
+
+	t.Run("code can be generated into a mirror directory", func(t *testing.T) {
+		dir := setup(t, "views/hello.templ")
+		generate(t, Arguments{Path: dir, OutputDir: "gen", GenerateSourceMaps: true})
+		read(t, filepath.Join(dir, "gen", "views", "hello_templ.go"))
+		read(t, filepath.Join(dir, "gen", "views", "hello_templ.map.json"))
+		assertNotExists(t, filepath.Join(dir, "views", "hello_templ.go"))
+
+		if err := os.Remove(filepath.Join(dir, "views", "hello.templ")); err != nil {
+			t.Fatal(err)
+		}
+		generate(t, Arguments{Path: dir, OutputDir: "gen"})
+		assertNotExists(t, filepath.Join(dir, "gen", "views", "hello_templ.go"))
+		assertNotExists(t, filepath.Join(dir, "gen", "views", "hello_templ.map.json"))
+	})
+	t.Run("orphans are removed from a mirror directory outside of the path", func(t *testing.T) {
+		dir := setup(t, "views/hello.templ")
+		if err := os.WriteFile(filepath.Join(dir, layout.ConfigFileName), []byte(`{"outputDir":"gen"}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		generate(t, Arguments{Path: filepath.Join(dir, "views")})
+		read(t, filepath.Join(dir, "gen", "views", "hello_templ.go"))
+
+		if err := os.Remove(filepath.Join(dir, "views", "hello.templ")); err != nil {
+			t.Fatal(err)
+		}
+		generate(t, Arguments{Path: filepath.Join(dir, "views")})
+		assertNotExists(t, filepath.Join(dir, "gen", "views", "hello_templ.go"))
+	})
+	t.Run("code can be generated into a separate package", func(t *testing.T) {
+		dir := setup(t, "views/hello.templ")
+		generate(t, Arguments{Path: dir, OutputPackage: "templates"})
+		code := read(t, filepath.Join(dir, "views", "templates", "hello_templ.go"))
+		if !strings.Contains(code, "\npackage templates\n") {
+			t.Errorf("expected the package to be templates, got:\n%s", code)
+		}
+		assertNotExists(t, filepath.Join(dir, "views", "hello_templ.go"))
+	})
+	t.Run("the suffix of generated files can be configured", func(t *testing.T) {
+		dir := setup(t, "hello.templ")
+		if err := os.WriteFile(filepath.Join(dir, "removed.gen.go"), []byte(layout.Header+"\n\npackage views\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "removed_templ.go"), []byte(layout.Header+"\n\npackage views\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		generate(t, Arguments{Path: dir, OutputSuffix: ".gen"})
+		read(t, filepath.Join(dir, "hello.gen.go"))
+		assertNotExists(t, filepath.Join(dir, "removed.gen.go"))
+		// Files with the default suffix aren't generated by this layout.
+		read(t, filepath.Join(dir, "removed_templ.go"))
+	})
+	t.Run("hand-written files with the suffix of generated files are kept", func(t *testing.T) {
+		for _, tt := range []struct {
+			suffix    string
+			fileNames []string
+		}{
+			{suffix: "s", fileNames: []string{"settings.go", "requirements.txt", "errors.map.json"}},
+			{suffix: "_gen", fileNames: []string{"client_gen.go", "client_gen.txt"}},
+		} {
+			dir := setup(t, "hello.templ")
+			for _, fileName := range tt.fileNames {
+				if err := os.WriteFile(filepath.Join(dir, fileName), []byte("package views\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			generate(t, Arguments{Path: dir, OutputSuffix: tt.suffix})
+			read(t, filepath.Join(dir, "hello"+tt.suffix+".go"))
+			for _, fileName := range tt.fileNames {
+				read(t, filepath.Join(dir, fileName))
+			}
+			if output, err := runCheck(t, Arguments{Path: dir, OutputSuffix: tt.suffix}); err != nil {
+				t.Errorf("suffix %q: expected hand-written files not to be orphaned: %v\n%s", tt.suffix, err, output)
+			}
+		}
+	})
+	t.Run("the strings of orphaned files are removed", func(t *testing.T) {
+		dir := setup(t, "hello.templ")
+		if err := os.WriteFile(filepath.Join(dir, "removed_templ.go"), []byte(layout.Header+"\n\npackage views\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "removed_templ.txt"), []byte("<div>\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		generate(t, Arguments{Path: dir})
+		assertNotExists(t, filepath.Join(dir, "removed_templ.go"))
+		assertNotExists(t, filepath.Join(dir, "removed_templ.txt"))
+	})
+	t.Run("code can be combined into a single file", func(t *testing.T) {
+		dir := setup(t, "views/hello.templ", "views/goodbye.templ")
+		generate(t, Arguments{Path: dir, OutputCombine: true, GenerateSourceMaps: true})
+		code := read(t, filepath.Join(dir, "views", "templates_templ.go"))
+		if formatted, err := format.Source([]byte(code)); err != nil || string(formatted) != code {
+			t.Errorf("expected the combined code to be formatted Go code, got error %v:\n%s", err, code)
+		}
+		for _, expected := range []string{"func Hello(name string)", "func Goodbye(name string)"} {
+			if !strings.Contains(code, expected) {
+				t.Errorf("expected the combined code to contain %q, got:\n%s", expected, code)
+			}
+		}
+		read(t, filepath.Join(dir, "views", "hello_templ.map.json"))
+		read(t, filepath.Join(dir, "views", "goodbye_templ.map.json"))
+		assertNotExists(t, filepath.Join(dir, "views", "hello_templ.go"))
+
+		if output, err := runCheck(t, Arguments{Path: dir, OutputCombine: true, GenerateSourceMaps: true}); err != nil {
+			t.Errorf("expected the combined code to be up to date: %v\n%s", err, output)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "views", "goodbye.templ"), []byte(strings.ReplaceAll(checkTestTemplate, "Hello", "Goodbye")), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		output, err := runCheck(t, Arguments{Path: dir, OutputCombine: true})
+		if err == nil || !strings.Contains(output, "templates_templ.go is out of date") {
+			t.Errorf("expected the combined code to be out of date, got %v:\n%s", err, output)
+		}
+
+		for _, name := range []string{"hello.templ", "goodbye.templ"} {
+			if err := os.Remove(filepath.Join(dir, "views", name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		generate(t, Arguments{Path: dir, OutputCombine: true})
+		assertNotExists(t, filepath.Join(dir, "views", "templates_templ.go"))
+		assertNotExists(t, filepath.Join(dir, "views", "hello_templ.map.json"))
+	})
+	t.Run("a single file can't be generated into a combined file", func(t *testing.T) {
+		dir := setup(t, "hello.templ")
+		err := Run(context.Background(), &bytes.Buffer{}, Arguments{Path: dir, FileName: filepath.Join(dir, "hello.templ"), OutputCombine: true})
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+	t.Run("the layout is read from the configuration file", func(t *testing.T) {
+		dir := setup(t, "views/hello.templ")
+		if err := os.WriteFile(filepath.Join(dir, layout.ConfigFileName), []byte(`{"package":"templates","suffix":"_gen"}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		generate(t, Arguments{Path: dir})
+		read(t, filepath.Join(dir, "views", "templates", "hello_gen.go"))
+		if output, err := runCheck(t, Arguments{Path: dir}); err != nil {
+			t.Errorf("expected the generated code to be up to date: %v\n%s", err, output)
+		}
+	})
+}
+
+func runCheck(t *testing.T, args Arguments) (output string, err error) {
+	t.Helper()
+	args.Check = true
+	var w bytes.Buffer
+	err = Run(context.Background(), &w, args)
+	return w.String(), err
+}
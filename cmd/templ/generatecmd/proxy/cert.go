@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultCADir returns the directory of the local certificate authority, within the user's
+// configuration directory, e.g. ~/.config/templ/ca on Linux.
+func DefaultCADir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "templ", "ca"), nil
+}
+
+const (
+	caCertFileName = "rootCA.pem"
+	caKeyFileName  = "rootCA-key.pem"
+)
+
+// CA is a local certificate authority. It issues certificates for the host names and IP
+// addresses that the proxy is accessed with, so that browsers trust the proxy once the
+// certificate of the CA is trusted.
+type CA struct {
+	// FileName of the certificate of the CA.
+	FileName string
+	cert     *x509.Certificate
+	key      crypto.Signer
+
+	m sync.Mutex
+	// certs are the certificates that have been issued, by host.
+	certs map[string]*tls.Certificate
+}
+
+// LoadOrCreateCA loads the certificate authority within dir, or creates it if it doesn't exist.
+// The CA is kept, so that it only needs to be trusted once.
+func LoadOrCreateCA(dir string) (ca *CA, err error) {
+	ca = &CA{
+		FileName: filepath.Join(dir, caCertFileName),
+		certs:    make(map[string]*tls.Certificate),
+	}
+	keyFileName := filepath.Join(dir, caKeyFileName)
+	certPEM, err := os.ReadFile(ca.FileName)
+	if errors.Is(err, os.ErrNotExist) {
+		if err = ca.create(keyFileName); err != nil {
+			return nil, fmt.Errorf("failed to create certificate authority: %w", err)
+		}
+		return ca, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate authority: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate authority key: %w", err)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate authority %q: %w", ca.FileName, err)
+	}
+	if ca.cert, err = x509.ParseCertificate(pair.Certificate[0]); err != nil {
+		return nil, fmt.Errorf("failed to parse certificate authority %q: %w", ca.FileName, err)
+	}
+	var ok bool
+	if ca.key, ok = pair.PrivateKey.(crypto.Signer); !ok {
+		return nil, fmt.Errorf("unsupported certificate authority key %q", keyFileName)
+	}
+	return ca, nil
+}
+
+func (ca *CA) create(keyFileName string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"templ"},
+			CommonName:   "templ development CA",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return err
+	}
+	if ca.cert, err = x509.ParseCertificate(der); err != nil {
+		return err
+	}
+	ca.key = key
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(ca.FileName), 0o700); err != nil {
+		return err
+	}
+	if err = os.WriteFile(keyFileName, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(ca.FileName, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+// GetCertificate returns a certificate for the host that the client is connecting to. It's
+// used as the GetCertificate function of a tls.Config.
+func (ca *CA) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := hello.ServerName
+	if host == "" {
+		// Clients don't send the server name when they connect with an IP address.
+		host = "localhost"
+		if addr, ok := hello.Conn.LocalAddr().(*net.TCPAddr); ok {
+			host = addr.IP.String()
+		}
+	}
+	ca.m.Lock()
+	defer ca.m.Unlock()
+	if cert, ok := ca.certs[host]; ok && time.Now().Before(cert.Leaf.NotAfter) {
+		return cert, nil
+	}
+	cert, err := ca.issue(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue certificate for %q: %w", host, err)
+	}
+	ca.certs[host] = cert
+	return cert, nil
+}
+
+func (ca *CA) issue(host string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"templ"},
+			CommonName:   host,
+		},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().AddDate(0, 0, 30),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{der, ca.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
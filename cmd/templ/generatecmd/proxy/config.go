@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ConfigFileName is the name of the configuration file of the proxy, which is read from the
+// path that templ generate watches.
+const ConfigFileName = ".templ-proxy.json"
+
+// Config configures the proxy. Arguments on the command line take precedence, e.g.
+//
+//	{
+//	  "target": "http://localhost:8080",
+//	  "bind": "0.0.0.0",
+//	  "port": 7331,
+//	  "https": true,
+//	  "routes": {
+//	    "/api/": "http://localhost:8081"
+//	  }
+//	}
+type Config struct {
+	// Target is the URL to proxy requests to.
+	Target string `json:"target,omitempty"`
+	// Bind is the address to listen on.
+	Bind string `json:"bind,omitempty"`
+	// Port to listen on.
+	Port int `json:"port,omitempty"`
+	// HTTPS serves HTTPS, with certificates that are issued by a local certificate authority.
+	HTTPS bool `json:"https,omitempty"`
+	// Routes map path prefixes to the targets that they're proxied to.
+	Routes map[string]string `json:"routes,omitempty"`
+}
+
+// ReadConfig reads a configuration file.
+func ReadConfig(fileName string) (c Config, err error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return c, err
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("failed to parse %s: %w", fileName, err)
+	}
+	return c, nil
+}
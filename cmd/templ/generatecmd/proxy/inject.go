@@ -0,0 +1,249 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// injectScript modifies HTML responses to load the reload script. The body is streamed, so that
+// streaming HTML responses are sent to the browser as they're received. Responses that aren't
+// HTML, and responses that are compressed with an unsupported encoding, aren't modified.
+func injectScript(r *http.Response) error {
+	if !shouldInject(r) {
+		return nil
+	}
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "identity" {
+		encoding = ""
+	}
+	if encoding == "" {
+		r.Body = readCloser{Reader: newScriptInjector(r.Body, scriptTag), Closer: r.Body}
+	} else {
+		newDecoder, ok := newDecoders[encoding]
+		if !ok {
+			return nil
+		}
+		decoded, err := newDecoder(r.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", encoding, err)
+		}
+		r.Body = newEncodedBody(newScriptInjector(decoded, scriptTag), r.Body, newEncoders[encoding])
+	}
+
+	// The length of the body isn't known until it's been sent.
+	r.ContentLength = -1
+	r.Header.Del("Content-Length")
+	return nil
+}
+
+func shouldInject(r *http.Response) bool {
+	if r.Request != nil && r.Request.Method == http.MethodHead {
+		return false
+	}
+	if r.StatusCode < 200 || r.StatusCode == http.StatusNoContent || r.StatusCode == http.StatusNotModified {
+		return false
+	}
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "text/html")
+}
+
+// newDecoders and newEncoders support each of the content encodings that can be modified.
+var newDecoders = map[string]func(r io.Reader) (io.Reader, error){
+	"gzip": func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.Reader, error) {
+		return zlib.NewReader(r)
+	},
+	"br": func(r io.Reader) (io.Reader, error) {
+		return brotli.NewReader(r), nil
+	},
+}
+
+// encoder compresses data. Flush writes the data that has been written so far, so that it can
+// be sent to the browser.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+var newEncoders = map[string]func(w io.Writer) encoder{
+	"gzip": func(w io.Writer) encoder {
+		return gzip.NewWriter(w)
+	},
+	"deflate": func(w io.Writer) encoder {
+		return zlib.NewWriter(w)
+	},
+	"br": func(w io.Writer) encoder {
+		return brotli.NewWriter(w)
+	},
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// encodedBody compresses the data that's read from r as it's read.
+type encodedBody struct {
+	pr   *io.PipeReader
+	body io.Closer
+}
+
+func newEncodedBody(r io.Reader, body io.Closer, newEncoder func(w io.Writer) encoder) *encodedBody {
+	pr, pw := io.Pipe()
+	go func() {
+		enc := newEncoder(pw)
+		_, err := io.Copy(flushWriter{enc}, r)
+		if closeErr := enc.Close(); err == nil {
+			err = closeErr
+		}
+		// Readers receive io.EOF if err is nil.
+		pw.CloseWithError(err)
+	}()
+	return &encodedBody{pr: pr, body: body}
+}
+
+func (eb *encodedBody) Read(p []byte) (n int, err error) {
+	return eb.pr.Read(p)
+}
+
+// Close stops the compression, which is waiting to write to the pipe, or to read the body.
+func (eb *encodedBody) Close() error {
+	err := eb.body.Close()
+	_ = eb.pr.Close()
+	return err
+}
+
+// flushWriter flushes the encoder after each write, so that streamed responses aren't delayed
+// until the encoder's buffer is full.
+type flushWriter struct {
+	enc encoder
+}
+
+func (fw flushWriter) Write(p []byte) (n int, err error) {
+	if n, err = fw.enc.Write(p); err != nil {
+		return n, err
+	}
+	return n, fw.enc.Flush()
+}
+
+// scriptInjector inserts a script before the </body> tag of the HTML that's read from r. If
+// there's no </body> tag, and the HTML is a document, the script is added to the end.
+type scriptInjector struct {
+	r      io.Reader
+	err    error
+	buf    []byte
+	script []byte
+	// pending is data that has been read from r, but not returned yet.
+	pending []byte
+	// held is the end of the data that could be the start of a tag, which is held until more
+	// data has been read.
+	held     []byte
+	injected bool
+	// document is true once the start of a <html> or <body> tag has been seen.
+	document bool
+}
+
+func newScriptInjector(r io.Reader, script string) *scriptInjector {
+	return &scriptInjector{r: r, buf: make([]byte, 32*1024), script: []byte(script)}
+}
+
+var (
+	bodyEndTag = []byte("</body")
+	// documentTags show that HTML is a document, rather than a fragment, e.g. a response to an
+	// htmx request.
+	documentTags = [][]byte{[]byte("<html"), []byte("<body")}
+)
+
+func (si *scriptInjector) Read(p []byte) (n int, err error) {
+	for len(si.pending) == 0 {
+		if si.err != nil {
+			return 0, si.err
+		}
+		si.fill()
+	}
+	n = copy(p, si.pending)
+	si.pending = si.pending[n:]
+	return n, nil
+}
+
+// fill reads from r, and sets the data that's ready to be returned.
+func (si *scriptInjector) fill() {
+	read, err := si.r.Read(si.buf)
+	data := append(si.held, si.buf[:read]...)
+	si.held = nil
+	si.err = err
+	if si.injected || (err != nil && err != io.EOF) {
+		si.pending = data
+		return
+	}
+	lower := asciiLower(data)
+	if !si.document {
+		for _, tag := range documentTags {
+			if bytes.Contains(lower, tag) {
+				si.document = true
+			}
+		}
+	}
+	if index := bytes.Index(lower, bodyEndTag); index >= 0 {
+		si.pending = concat(data[:index], si.script, data[index:])
+		si.injected = true
+		return
+	}
+	if err == io.EOF {
+		si.pending = data
+		if si.document {
+			si.pending = concat(data, si.script)
+		}
+		si.injected = true
+		return
+	}
+	// Hold back the end of the data if it could be the start of a tag.
+	hold := partialTagLength(lower)
+	si.pending = data[:len(data)-hold]
+	si.held = append([]byte(nil), data[len(data)-hold:]...)
+}
+
+// partialTagLength returns the length of the longest suffix of data that is the start of a tag
+// that the injector looks for.
+func partialTagLength(data []byte) int {
+	tags := append([][]byte{bodyEndTag}, documentTags...)
+	for n := len(bodyEndTag) - 1; n > 0; n-- {
+		if n > len(data) {
+			continue
+		}
+		for _, tag := range tags {
+			if n < len(tag) && bytes.HasSuffix(data, tag[:n]) {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// asciiLower lowercases the ASCII letters of data. Unlike bytes.ToLower, the length of the data
+// doesn't change, so indexes within the result are indexes within data.
+func asciiLower(data []byte) []byte {
+	lower := make([]byte, len(data))
+	for i, b := range data {
+		if 'A' <= b && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		lower[i] = b
+	}
+	return lower
+}
+
+func concat(parts ...[]byte) (result []byte) {
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return result
+}
@@ -2,16 +2,21 @@ package proxy
 
 import (
 	"bytes"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"math"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/a-h/templ/cmd/templ/generatecmd/sse"
@@ -25,13 +30,119 @@ var script string
 const scriptTag = `<script src="/_templ/reload/script.js"></script>`
 
 type Handler struct {
-	URL    string
+	// URL that the proxy is accessed with.
+	URL string
+	// Target is the URL of requests that don't match a route.
 	Target *url.URL
+	// CertificateAuthority is the file name of the certificate of the local certificate
+	// authority that issues the certificates of the proxy, if it serves HTTPS.
+	CertificateAuthority string
+	p                    *httputil.ReverseProxy
+	routes               []route
+	addr                 string
+	ca                   *CA
+	sse                  *sse.Handler
+
+	m sync.Mutex
+	// errors are the errors shown in the browser, by source.
+	errors map[string][]BuildError
+	// sources are the sources of errors, in the order that they were first set.
+	sources []string
+}
+
+// BuildError is a problem with generating code, or running the command, that's shown in an
+// overlay in the browser.
+type BuildError struct {
+	// Source of the error, e.g. "templ generate", or the command.
+	Source string `json:"source"`
+	// FileName, Line and Col are the position of the error, if it's known. Line and Col are
+	// one-based.
+	FileName string `json:"fileName,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Col      int    `json:"col,omitempty"`
+	Message  string `json:"message"`
+	// Excerpt is the source code around the error.
+	Excerpt []SourceLine `json:"excerpt,omitempty"`
+}
+
+// SourceLine is a line of source code.
+type SourceLine struct {
+	// Line is the one-based line number.
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// Options configure the proxy.
+type Options struct {
+	// Bind is the address to listen on. Defaults to 127.0.0.1.
+	Bind string
+	Port int
+	// HTTPS serves HTTPS, with certificates that are issued by a local certificate authority.
+	HTTPS bool
+	// CADir is the directory of the local certificate authority. Defaults to DefaultCADir.
+	CADir string
+	// Target is the URL to proxy requests to.
+	Target *url.URL
+	// Routes proxy requests with paths that start with a prefix to another target, e.g.
+	// "/api/" to "http://localhost:8081". The longest matching prefix is used.
+	Routes map[string]*url.URL
+}
+
+// route proxies the requests with paths that start with the prefix.
+type route struct {
+	prefix string
+	target *url.URL
 	p      *httputil.ReverseProxy
-	sse    *sse.Handler
 }
 
-func New(port int, target *url.URL) *Handler {
+func (r route) matches(path string) bool {
+	return path == r.prefix || strings.HasPrefix(path, strings.TrimSuffix(r.prefix, "/")+"/")
+}
+
+func New(opts Options) (h *Handler, err error) {
+	if opts.Bind == "" {
+		opts.Bind = "127.0.0.1"
+	}
+	h = &Handler{
+		Target: opts.Target,
+		p:      newReverseProxy(opts.Target, opts.HTTPS),
+		addr:   net.JoinHostPort(opts.Bind, strconv.Itoa(opts.Port)),
+		sse:    sse.New(),
+		errors: make(map[string][]BuildError),
+	}
+	for prefix, target := range opts.Routes {
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("proxy route %q must start with /", prefix)
+		}
+		h.routes = append(h.routes, route{prefix: prefix, target: target, p: newReverseProxy(target, opts.HTTPS)})
+	}
+	sort.Slice(h.routes, func(i, j int) bool {
+		return len(h.routes[i].prefix) > len(h.routes[j].prefix)
+	})
+
+	scheme := "http"
+	if opts.HTTPS {
+		scheme = "https"
+		if opts.CADir == "" {
+			if opts.CADir, err = DefaultCADir(); err != nil {
+				return nil, err
+			}
+		}
+		if h.ca, err = LoadOrCreateCA(opts.CADir); err != nil {
+			return nil, err
+		}
+		h.CertificateAuthority = h.ca.FileName
+	}
+	// The proxy is accessed with a loopback address if it listens on all addresses.
+	host := opts.Bind
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	h.URL = fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(opts.Port)))
+	return h, nil
+}
+
+func newReverseProxy(target *url.URL, https bool) *httputil.ReverseProxy {
 	p := httputil.NewSingleHostReverseProxy(target)
 	p.ErrorLog = log.New(os.Stderr, "Proxy to target error: ", 0)
 	p.Transport = &roundTripper{
@@ -39,26 +150,31 @@ func New(port int, target *url.URL) *Handler {
 		initialDelay:    100 * time.Millisecond,
 		backoffExponent: 1.5,
 	}
-	p.ModifyResponse = func(r *http.Response) error {
-		if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
-			return nil
-		}
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			return err
+	p.ModifyResponse = injectScript
+	if https {
+		// Let the target know that the browser uses HTTPS, e.g. to set secure cookies.
+		director := p.Director
+		p.Director = func(r *http.Request) {
+			director(r)
+			r.Header.Set("X-Forwarded-Proto", "https")
 		}
-		updated := strings.Replace(string(body), "</body>", scriptTag+"</body>", -1)
-		r.Body = io.NopCloser(strings.NewReader(updated))
-		r.ContentLength = int64(len(updated))
-		r.Header.Set("Content-Length", strconv.Itoa(len(updated)))
-		return nil
-	}
-	return &Handler{
-		URL:    fmt.Sprintf("http://127.0.0.1:%d", port),
-		Target: target,
-		p:      p,
-		sse:    sse.New(),
 	}
+	return p
+}
+
+// ListenAndServe listens on the bind address and port of the proxy.
+func (p *Handler) ListenAndServe() error {
+	server := &http.Server{
+		Addr:    p.addr,
+		Handler: p,
+	}
+	if p.ca == nil {
+		return server.ListenAndServe()
+	}
+	server.TLSConfig = &tls.Config{
+		GetCertificate: p.ca.GetCertificate,
+	}
+	return server.ListenAndServeTLS("", "")
 }
 
 func (p *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -71,11 +187,25 @@ func (p *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	if r.URL.Path == "/_templ/reload/errors" {
+		// Provides the errors that are currently shown, for pages that have just loaded.
+		w.Header().Add("Content-Type", "application/json")
+		if _, err := w.Write(p.errorsJSON()); err != nil {
+			fmt.Printf("failed to write errors: %v\n", err)
+		}
+		return
+	}
 	if r.URL.Path == "/_templ/reload/events" {
 		// Provides a list of messages including a reload message.
 		p.sse.ServeHTTP(w, r)
 		return
 	}
+	for _, route := range p.routes {
+		if route.matches(r.URL.Path) {
+			route.p.ServeHTTP(w, r)
+			return
+		}
+	}
 	p.p.ServeHTTP(w, r)
 }
 
@@ -83,6 +213,38 @@ func (p *Handler) SendSSE(eventType string, data string) {
 	p.sse.Send(eventType, data)
 }
 
+// SetErrors shows the errors of a source in the browser, replacing its previous errors. The
+// overlay is hidden once no source has errors.
+func (p *Handler) SetErrors(source string, errs []BuildError) {
+	p.m.Lock()
+	_, known := p.errors[source]
+	if !known && len(errs) == 0 {
+		p.m.Unlock()
+		return
+	}
+	if !known {
+		p.sources = append(p.sources, source)
+	}
+	p.errors[source] = errs
+	p.m.Unlock()
+	p.sse.Send("templ-errors", string(p.errorsJSON()))
+}
+
+// errorsJSON returns the errors of all sources as a JSON array.
+func (p *Handler) errorsJSON() []byte {
+	p.m.Lock()
+	defer p.m.Unlock()
+	all := []BuildError{}
+	for _, source := range p.sources {
+		all = append(all, p.errors[source]...)
+	}
+	data, err := json.Marshal(all)
+	if err != nil {
+		return []byte("[]")
+	}
+	return data
+}
+
 type roundTripper struct {
 	maxRetries      int
 	initialDelay    time.Duration
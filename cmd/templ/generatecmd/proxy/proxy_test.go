@@ -0,0 +1,413 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestScriptInjector(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "the script is inserted before the end of the body",
+			input:    `<html><body><div>Hello</div></body></html>`,
+			expected: `<html><body><div>Hello</div>` + scriptTag + `</body></html>`,
+		},
+		{
+			name:     "the end of the body tag is matched case insensitively",
+			input:    `<HTML><BODY>Hello</BODY></HTML>`,
+			expected: `<HTML><BODY>Hello` + scriptTag + `</BODY></HTML>`,
+		},
+		{
+			name:     "the script is only inserted once",
+			input:    `<html><body>Hello</body></body></html>`,
+			expected: `<html><body>Hello` + scriptTag + `</body></body></html>`,
+		},
+		{
+			name:     "the script is added to the end of documents without a body end tag",
+			input:    `<!DOCTYPE html><html><div>Hello</div>`,
+			expected: `<!DOCTYPE html><html><div>Hello</div>` + scriptTag,
+		},
+		{
+			name:     "fragments aren't modified",
+			input:    `<div>Hello</div>`,
+			expected: `<div>Hello</div>`,
+		},
+		{
+			name:     "text that's like the end of the body isn't modified",
+			input:    `<div></bod</div>`,
+			expected: `<div></bod</div>`,
+		},
+		{
+			name:     "non-ASCII text doesn't affect the position of the script",
+			input:    `<html><body>İİİ</body></html>`,
+			expected: `<html><body>İİİ` + scriptTag + `</body></html>`,
+		},
+	}
+	readers := map[string]func(r io.Reader) io.Reader{
+		"all at once": func(r io.Reader) io.Reader { return r },
+		"one byte at a time": func(r io.Reader) io.Reader {
+			return iotest.OneByteReader(r)
+		},
+		"half at a time": func(r io.Reader) io.Reader {
+			return iotest.HalfReader(r)
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		for readerName, newReader := range readers {
+			newReader := newReader
+			t.Run(tt.name+" ("+readerName+")", func(t *testing.T) {
+				actual, err := io.ReadAll(newScriptInjector(newReader(strings.NewReader(tt.input)), scriptTag))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(actual) != tt.expected {
+					t.Errorf("expected %q, got %q", tt.expected, string(actual))
+				}
+			})
+		}
+	}
+}
+
+func TestInjectScript(t *testing.T) {
+	const page = `<html><body>Hello</body></html>`
+	const expected = `<html><body>Hello` + scriptTag + `</body></html>`
+
+	encoders := map[string]struct {
+		encode func(s string) []byte
+		decode func(r io.Reader) (io.Reader, error)
+	}{
+		"gzip": {
+			encode: func(s string) []byte {
+				var b bytes.Buffer
+				w := gzip.NewWriter(&b)
+				_, _ = w.Write([]byte(s))
+				_ = w.Close()
+				return b.Bytes()
+			},
+			decode: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+		"deflate": {
+			encode: func(s string) []byte {
+				var b bytes.Buffer
+				w := zlib.NewWriter(&b)
+				_, _ = w.Write([]byte(s))
+				_ = w.Close()
+				return b.Bytes()
+			},
+			decode: func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+		},
+		"br": {
+			encode: func(s string) []byte {
+				var b bytes.Buffer
+				w := brotli.NewWriter(&b)
+				_, _ = w.Write([]byte(s))
+				_ = w.Close()
+				return b.Bytes()
+			},
+			decode: func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		},
+	}
+	for encoding, e := range encoders {
+		e := e
+		t.Run(encoding+" responses are decoded, and encoded again", func(t *testing.T) {
+			r := newResponse(http.MethodGet, http.StatusOK, "text/html; charset=utf-8", e.encode(page))
+			r.Header.Set("Content-Encoding", encoding)
+			if err := injectScript(r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer r.Body.Close()
+			decoded, err := e.decode(r.Body)
+			if err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			actual, err := io.ReadAll(decoded)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			if string(actual) != expected {
+				t.Errorf("expected %q, got %q", expected, string(actual))
+			}
+			if r.Header.Get("Content-Encoding") != encoding {
+				t.Errorf("expected Content-Encoding %q, got %q", encoding, r.Header.Get("Content-Encoding"))
+			}
+			if r.Header.Get("Content-Length") != "" || r.ContentLength != -1 {
+				t.Errorf("expected the content length to be removed, got %q, %d", r.Header.Get("Content-Length"), r.ContentLength)
+			}
+		})
+	}
+
+	unmodified := []struct {
+		name        string
+		method      string
+		status      int
+		contentType string
+		encoding    string
+	}{
+		{name: "responses that aren't HTML", method: http.MethodGet, status: http.StatusOK, contentType: "application/json"},
+		{name: "event streams", method: http.MethodGet, status: http.StatusOK, contentType: "text/event-stream"},
+		{name: "unsupported encodings", method: http.MethodGet, status: http.StatusOK, contentType: "text/html", encoding: "zstd"},
+		{name: "HEAD requests", method: http.MethodHead, status: http.StatusOK, contentType: "text/html"},
+		{name: "not modified responses", method: http.MethodGet, status: http.StatusNotModified, contentType: "text/html"},
+		{name: "protocol upgrades", method: http.MethodGet, status: http.StatusSwitchingProtocols},
+	}
+	for _, tt := range unmodified {
+		tt := tt
+		t.Run(tt.name+" aren't modified", func(t *testing.T) {
+			r := newResponse(tt.method, tt.status, tt.contentType, []byte(page))
+			if tt.encoding != "" {
+				r.Header.Set("Content-Encoding", tt.encoding)
+			}
+			body := r.Body
+			if err := injectScript(r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r.Body != body {
+				t.Error("expected the body to be unmodified")
+			}
+			if r.Header.Get("Content-Length") == "" {
+				t.Error("expected the content length to be kept")
+			}
+		})
+	}
+}
+
+func newResponse(method string, status int, contentType string, body []byte) *http.Response {
+	r := &http.Response{
+		StatusCode:    status,
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       httptest.NewRequest(method, "/", nil),
+	}
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return r
+}
+
+func TestStreaming(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+	}{
+		{name: "HTML", contentType: "text/html"},
+		{name: "gzip encoded HTML", contentType: "text/html", encoding: "gzip"},
+		{name: "event streams", contentType: "text/event-stream"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name+" is streamed", func(t *testing.T) {
+			// The target waits for the first line to be received before sending the rest.
+			received := make(chan struct{})
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				var out io.Writer = w
+				flush := w.(http.Flusher).Flush
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+					gw := gzip.NewWriter(w)
+					defer gw.Close()
+					out = gw
+					flush = func() {
+						_ = gw.Flush()
+						w.(http.Flusher).Flush()
+					}
+				}
+				_, _ = io.WriteString(out, "<html><body>first\n")
+				flush()
+				select {
+				case <-received:
+				case <-time.After(5 * time.Second):
+					return
+				}
+				_, _ = io.WriteString(out, "second</body></html>\n")
+			}))
+			defer target.Close()
+			targetURL, err := url.Parse(target.URL)
+			if err != nil {
+				t.Fatalf("failed to parse URL: %v", err)
+			}
+			h, err := New(Options{Target: targetURL})
+			if err != nil {
+				t.Fatalf("failed to create proxy: %v", err)
+			}
+			proxy := httptest.NewServer(h)
+			defer proxy.Close()
+
+			req, err := http.NewRequest(http.MethodGet, proxy.URL, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if tt.encoding != "" {
+				// Setting the header stops the client from decoding the response.
+				req.Header.Set("Accept-Encoding", tt.encoding)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("failed to get response: %v", err)
+			}
+			defer resp.Body.Close()
+			var body io.Reader = resp.Body
+			if tt.encoding != "" {
+				if body, err = gzip.NewReader(resp.Body); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+			}
+			lines := bufio.NewReader(body)
+			first, err := lines.ReadString('\n')
+			if err != nil {
+				t.Fatalf("failed to read first line: %v", err)
+			}
+			if first != "<html><body>first\n" {
+				t.Errorf("unexpected first line: %q", first)
+			}
+			close(received)
+			rest, err := io.ReadAll(lines)
+			if err != nil {
+				t.Fatalf("failed to read the rest of the response: %v", err)
+			}
+			expected := "second" + scriptTag + "</body></html>\n"
+			if tt.contentType != "text/html" {
+				expected = "second</body></html>\n"
+			}
+			if string(rest) != expected {
+				t.Errorf("expected %q, got %q", expected, string(rest))
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	newTarget := func(name string) *url.URL {
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name+" "+r.URL.Path)
+		}))
+		t.Cleanup(target.Close)
+		u, err := url.Parse(target.URL)
+		if err != nil {
+			t.Fatalf("failed to parse URL: %v", err)
+		}
+		return u
+	}
+	h, err := New(Options{
+		Target: newTarget("app"),
+		Routes: map[string]*url.URL{
+			"/api":        newTarget("api"),
+			"/api/admin/": newTarget("admin"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create proxy: %v", err)
+	}
+	proxy := httptest.NewServer(h)
+	defer proxy.Close()
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/", expected: "app /"},
+		{path: "/api", expected: "api /api"},
+		{path: "/api/users", expected: "api /api/users"},
+		{path: "/apis", expected: "app /apis"},
+		{path: "/api/admin/users", expected: "admin /api/admin/users"},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(proxy.URL + tt.path)
+		if err != nil {
+			t.Fatalf("failed to get %q: %v", tt.path, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to read response: %v", err)
+		}
+		if string(body) != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.expected, string(body))
+		}
+	}
+
+	if _, err = New(Options{Target: newTarget("app"), Routes: map[string]*url.URL{"api": newTarget("api")}}); err == nil {
+		t.Error("expected an error for a route that doesn't start with /")
+	}
+}
+
+func TestHTTPS(t *testing.T) {
+	caDir := t.TempDir()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("X-Forwarded-Proto"))
+	}))
+	defer target.Close()
+	targetURL, err := url.Parse(target.URL)
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	h, err := New(Options{Port: 7331, HTTPS: true, CADir: caDir, Target: targetURL})
+	if err != nil {
+		t.Fatalf("failed to create proxy: %v", err)
+	}
+	if h.URL != "https://127.0.0.1:7331" {
+		t.Errorf("unexpected URL: %q", h.URL)
+	}
+
+	// The proxy uses the certificate authority to issue certificates.
+	proxy := httptest.NewUnstartedServer(h)
+	proxy.Listener = tls.NewListener(proxy.Listener, &tls.Config{GetCertificate: h.ca.GetCertificate})
+	proxy.Start()
+	defer proxy.Close()
+	caPEM, err := os.ReadFile(h.CertificateAuthority)
+	if err != nil {
+		t.Fatalf("failed to read certificate authority: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	_, port, _ := net.SplitHostPort(proxy.Listener.Addr().String())
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+		resp, err := client.Get("https://" + net.JoinHostPort(host, port))
+		if err != nil {
+			t.Fatalf("%s: failed to get response: %v", host, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to read response: %v", err)
+		}
+		if string(body) != "https" {
+			t.Errorf("expected the target to receive X-Forwarded-Proto https, got %q", string(body))
+		}
+	}
+
+	// The certificate authority is reused.
+	ca, err := LoadOrCreateCA(caDir)
+	if err != nil {
+		t.Fatalf("failed to load certificate authority: %v", err)
+	}
+	if !ca.cert.Equal(h.ca.cert) {
+		t.Error("expected the certificate authority to be reused")
+	}
+}
@@ -1,6 +1,277 @@
 let src = new EventSource("/_templ/reload/events");
 src.onmessage = (event) => {
 	if (event && event.data === "reload") {
-		window.location.reload();
+		templReload();
 	}
 };
+// Only stylesheets, or css templates, have changed.
+src.addEventListener("reload-css", () => {
+	templReloadStylesheets();
+});
+src.addEventListener("templ-errors", (event) => {
+	templShowErrors(JSON.parse(event.data));
+});
+src.onopen = () => {
+	// Errors might have been reported before the page loaded, or while it was disconnected.
+	fetch("/_templ/reload/errors")
+		.then((response) => response.json())
+		.then(templShowErrors)
+		.catch(() => {});
+};
+
+const templOverlayStyle = `
+:host { all: initial; }
+.overlay { position: fixed; inset: 0; z-index: 2147483647; overflow: auto; padding: 32px; background: rgba(0, 0, 0, 0.66); font: 14px/1.5 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+.panel { max-width: 960px; margin: 0 auto; padding: 24px; border-top: 6px solid #e5484d; border-radius: 6px; background: #1c1c1f; color: #ededef; }
+.header { display: flex; justify-content: space-between; align-items: center; margin-bottom: 16px; }
+.title { font-size: 16px; font-weight: bold; color: #ff9592; }
+button { border: 0; border-radius: 4px; padding: 4px 8px; background: #3a3a3f; color: #ededef; font: inherit; cursor: pointer; }
+.error { margin-top: 16px; }
+.source { color: #b5b3ad; }
+.location { color: #70b8ff; }
+.message { margin: 8px 0; white-space: pre-wrap; word-break: break-word; }
+.excerpt { margin: 0; padding: 8px 0; border-radius: 4px; background: #111113; overflow-x: auto; }
+.line { display: block; padding: 0 12px; white-space: pre; }
+.line.highlight { background: rgba(229, 72, 77, 0.25); }
+.number { display: inline-block; min-width: 4ch; margin-right: 12px; color: #6f6e77; text-align: right; user-select: none; }
+`;
+
+// templShowErrors renders the errors in an overlay, or removes the overlay if there are none.
+function templShowErrors(errors) {
+	let host = document.getElementById("templ-error-overlay");
+	if (!errors || errors.length === 0) {
+		if (host) {
+			host.remove();
+		}
+		return;
+	}
+	if (!host) {
+		host = document.createElement("div");
+		host.id = "templ-error-overlay";
+		host.attachShadow({ mode: "open" });
+		document.body.appendChild(host);
+	}
+	const el = (tag, className, text) => {
+		const e = document.createElement(tag);
+		if (className) {
+			e.className = className;
+		}
+		if (text !== undefined) {
+			e.textContent = text;
+		}
+		return e;
+	};
+
+	const overlay = el("div", "overlay");
+	const panel = el("div", "panel");
+	const header = el("div", "header");
+	header.appendChild(el("div", "title", errors.length === 1 ? "Build failed" : `Build failed with ${errors.length} errors`));
+	const close = el("button", "", "Close");
+	close.onclick = () => host.remove();
+	header.appendChild(close);
+	panel.appendChild(header);
+
+	for (const error of errors) {
+		const e = el("div", "error");
+		const heading = el("div");
+		heading.appendChild(el("span", "source", error.source));
+		if (error.fileName) {
+			let location = error.fileName;
+			if (error.line) {
+				location += `:${error.line}`;
+				if (error.col) {
+					location += `:${error.col}`;
+				}
+			}
+			heading.appendChild(document.createTextNode(" "));
+			heading.appendChild(el("span", "location", location));
+		}
+		e.appendChild(heading);
+		e.appendChild(el("div", "message", error.message));
+		if (error.excerpt && error.excerpt.length > 0) {
+			const excerpt = el("pre", "excerpt");
+			for (const line of error.excerpt) {
+				const l = el("span", line.line === error.line ? "line highlight" : "line");
+				l.appendChild(el("span", "number", String(line.line)));
+				l.appendChild(document.createTextNode(line.text));
+				excerpt.appendChild(l);
+			}
+			e.appendChild(excerpt);
+		}
+		panel.appendChild(e);
+	}
+	overlay.appendChild(panel);
+
+	const style = el("style", "", templOverlayStyle);
+	host.shadowRoot.replaceChildren(style, overlay);
+}
+
+// templReload fetches the current page, and morphs the document to match it, so that the scroll
+// position, form input and client state are kept. The page is reloaded if the scripts of the
+// page have changed, or if the page can't be morphed.
+async function templReload() {
+	try {
+		const response = await fetch(window.location.href, { cache: "no-store" });
+		const contentType = response.headers.get("Content-Type") || "";
+		if (!response.ok || !contentType.startsWith("text/html")) {
+			throw new Error(`unexpected response: ${response.status} ${contentType}`);
+		}
+		const next = new DOMParser().parseFromString(await response.text(), "text/html");
+		if (templScripts(document) !== templScripts(next)) {
+			throw new Error("scripts have changed");
+		}
+		const scrollX = window.scrollX;
+		const scrollY = window.scrollY;
+		templMorphAttributes(document.documentElement, next.documentElement);
+		templMorphChildren(document.head, next.head);
+		templMorphAttributes(document.body, next.body);
+		templMorphChildren(document.body, next.body);
+		templReloadStylesheets();
+		window.scrollTo(scrollX, scrollY);
+	} catch (err) {
+		console.log("templ: reloading the page, because it couldn't be updated in place:", err);
+		window.location.reload();
+	}
+}
+
+// templScripts returns a key that changes when the scripts of a document change, because
+// scripts that are added to the page by morphing aren't run.
+function templScripts(doc) {
+	return Array.from(doc.scripts)
+		.map((s) => `${s.type} ${s.src} ${s.textContent}`)
+		.join("\n");
+}
+
+// templPreserved returns true for the elements that are added to the page by templ.
+function templPreserved(node) {
+	return node.id === "templ-error-overlay";
+}
+
+// templSameNode returns true if the node can be morphed into the other node, instead of being
+// replaced.
+function templSameNode(a, b) {
+	return a.nodeType === b.nodeType && a.nodeName === b.nodeName;
+}
+
+function templMorph(from, to) {
+	if (!templSameNode(from, to)) {
+		from.replaceWith(document.importNode(to, true));
+		return;
+	}
+	if (from.nodeType !== Node.ELEMENT_NODE) {
+		if (from.nodeValue !== to.nodeValue) {
+			from.nodeValue = to.nodeValue;
+		}
+		return;
+	}
+	templMorphAttributes(from, to);
+	if (from.nodeName === "TEXTAREA") {
+		// The text of a textarea is its default value, so it's only updated if the user hasn't
+		// changed the value.
+		if (from.value === from.defaultValue && from.defaultValue !== to.defaultValue) {
+			from.defaultValue = to.defaultValue;
+		}
+		return;
+	}
+	templMorphChildren(from, to);
+}
+
+function templMorphAttributes(from, to) {
+	for (const attr of Array.from(from.attributes)) {
+		if (!to.hasAttribute(attr.name)) {
+			from.removeAttribute(attr.name);
+		}
+	}
+	for (const attr of Array.from(to.attributes)) {
+		let value = attr.value;
+		if (templIsStylesheet(from) && attr.name === "href" && templStylesheetURL(from.getAttribute("href")) === value) {
+			// Keep the stylesheet that was loaded by templReloadStylesheets.
+			continue;
+		}
+		if (from.getAttribute(attr.name) !== value) {
+			from.setAttribute(attr.name, value);
+		}
+	}
+}
+
+// templMorphChildren morphs the children of from to match the children of to. Children with an
+// id are matched by id, and other children are matched by position.
+function templMorphChildren(from, to) {
+	const ids = new Map();
+	for (const child of from.childNodes) {
+		if (child.id && !templPreserved(child)) {
+			ids.set(child.id, child);
+		}
+	}
+	const toIds = new Set();
+	for (const child of to.childNodes) {
+		if (child.id) {
+			toIds.add(child.id);
+		}
+	}
+	let cursor = from.firstChild;
+	for (const toChild of Array.from(to.childNodes)) {
+		// Skip the elements that are kept, and remove the elements with ids that have been removed,
+		// so that they don't prevent the following elements from being matched by position.
+		while (cursor && (templPreserved(cursor) || (cursor.id && !toIds.has(cursor.id)))) {
+			const next = cursor.nextSibling;
+			if (!templPreserved(cursor)) {
+				ids.delete(cursor.id);
+				cursor.remove();
+			}
+			cursor = next;
+		}
+		let match = null;
+		if (toChild.id && ids.has(toChild.id)) {
+			match = ids.get(toChild.id);
+			ids.delete(toChild.id);
+		} else if (cursor && !cursor.id && templSameNode(cursor, toChild)) {
+			match = cursor;
+		}
+		if (!match) {
+			from.insertBefore(document.importNode(toChild, true), cursor);
+			continue;
+		}
+		if (match === cursor) {
+			cursor = cursor.nextSibling;
+		} else {
+			from.insertBefore(match, cursor);
+		}
+		templMorph(match, toChild);
+	}
+	while (cursor) {
+		const next = cursor.nextSibling;
+		if (!templPreserved(cursor)) {
+			cursor.remove();
+		}
+		cursor = next;
+	}
+}
+
+function templIsStylesheet(node) {
+	return node.nodeName === "LINK" && (node.getAttribute("rel") || "").toLowerCase() === "stylesheet";
+}
+
+// templStylesheetURL removes the parameter that's added to the URL of a stylesheet to reload it.
+function templStylesheetURL(href) {
+	if (!href) {
+		return href;
+	}
+	return href.replace(/([?&])templ-reload=\d+(&|$)/, (_, before, after) => (after ? before : ""));
+}
+
+// templReloadStylesheets reloads the stylesheets of the page, so that changes to stylesheets are
+// shown without reloading the page. The previous stylesheet is removed once the new stylesheet
+// has loaded, to prevent a flash of unstyled content.
+function templReloadStylesheets() {
+	for (const link of Array.from(document.querySelectorAll('link[rel="stylesheet" i]'))) {
+		const href = templStylesheetURL(link.getAttribute("href"));
+		if (!href || new URL(href, window.location.href).origin !== window.location.origin) {
+			continue;
+		}
+		const next = link.cloneNode();
+		next.setAttribute("href", `${href}${href.includes("?") ? "&" : "?"}templ-reload=${Date.now()}`);
+		next.onload = next.onerror = () => link.remove();
+		link.after(next);
+	}
+}
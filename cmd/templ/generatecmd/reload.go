@@ -0,0 +1,40 @@
+package generatecmd
+
+import (
+	"sync"
+
+	"github.com/a-h/templ/cmd/templ/generatecmd/proxy"
+)
+
+// browserReload is the reload that's sent to the browser once the changes since the last reload
+// have been applied. If only stylesheets and css templates have changed, the browser reloads
+// the stylesheets, without updating the page.
+type browserReload struct {
+	m       sync.Mutex
+	pending bool
+	cssOnly bool
+}
+
+// add a change, which is applied by the next reload.
+func (r *browserReload) add(cssOnly bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.cssOnly = cssOnly && (r.cssOnly || !r.pending)
+	r.pending = true
+}
+
+// event returns the server-sent event of the reload, and clears the changes. The page is
+// reloaded if there are no changes, e.g. when the command is first started.
+func (r *browserReload) event() (eventType, data string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	defer func() { r.pending, r.cssOnly = false, false }()
+	if r.pending && r.cssOnly {
+		return "reload-css", "reload-css"
+	}
+	return "message", "reload"
+}
+
+func (r *browserReload) send(p *proxy.Handler) {
+	p.SendSSE(r.event())
+}
@@ -0,0 +1,82 @@
+package generatecmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ/cmd/templ/layout"
+	"github.com/a-h/templ/parser/v2"
+)
+
+func TestBrowserReload(t *testing.T) {
+	tests := []struct {
+		name     string
+		changes  []bool
+		expected string
+	}{
+		{
+			name:     "the page is reloaded when the command is first started",
+			expected: "message",
+		},
+		{
+			name:     "the page is reloaded if anything other than CSS has changed",
+			changes:  []bool{false},
+			expected: "message",
+		},
+		{
+			name:     "the stylesheets are reloaded if only CSS has changed",
+			changes:  []bool{true, true},
+			expected: "reload-css",
+		},
+		{
+			name:     "the page is reloaded if any of the changes since the last reload weren't CSS",
+			changes:  []bool{true, false, true},
+			expected: "message",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var r browserReload
+			for _, cssOnly := range test.changes {
+				r.add(cssOnly)
+			}
+			if actual, _ := r.event(); actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+			if actual, _ := r.event(); actual != "message" {
+				t.Errorf("expected the changes to be cleared, got %q", actual)
+			}
+		})
+	}
+}
+
+func TestOutputOnlyCSSChanged(t *testing.T) {
+	const page = "package views\n\ntempl Page() {\n\t<p class={ red() }>Hello</p>\n}\n"
+	const css = "\ncss red() {\n\tcolor: red;\n}\n"
+	out := newOutput(layout.Default(t.TempDir()), nil)
+	set := func(contents string) {
+		tf, err := parser.ParseString(contents)
+		if err != nil {
+			t.Fatalf("failed to parse template: %v", err)
+		}
+		out.setTemplate("page.templ", tf)
+	}
+
+	set(page + css)
+	if out.onlyCSSChanged("page.templ") {
+		t.Error("expected a new templ file not to be a change to only CSS")
+	}
+	set(page + strings.Replace(css, "red;", "blue;", 1))
+	if !out.onlyCSSChanged("page.templ") {
+		t.Error("expected a change to a css template to be a change to only CSS")
+	}
+	set(strings.Replace(page, "Hello", "World", 1) + css)
+	if out.onlyCSSChanged("page.templ") {
+		t.Error("expected a change to a HTML template not to be a change to only CSS")
+	}
+	out.removeTemplate("page.templ")
+	set(strings.Replace(page, "Hello", "World", 1) + css)
+	if out.onlyCSSChanged("page.templ") {
+		t.Error("expected a templ file that was removed not to be a change to only CSS")
+	}
+}
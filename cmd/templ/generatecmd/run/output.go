@@ -0,0 +1,72 @@
+package run
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// PrefixWriters return writers for the output, and errors, of a command. Each line is written
+// to w with a prefix, and lines written to each writer aren't interleaved.
+func PrefixWriters(w io.Writer, stdoutPrefix, stderrPrefix string) (stdout, stderr io.Writer) {
+	var m sync.Mutex
+	return &prefixWriter{m: &m, w: w, prefix: stdoutPrefix}, &prefixWriter{m: &m, w: w, prefix: stderrPrefix}
+}
+
+type prefixWriter struct {
+	m      *sync.Mutex
+	w      io.Writer
+	prefix string
+	// partial is the end of the output that doesn't end with a newline yet.
+	partial []byte
+}
+
+func (pw *prefixWriter) Write(p []byte) (n int, err error) {
+	pw.m.Lock()
+	defer pw.m.Unlock()
+	pw.partial = append(pw.partial, p...)
+	for {
+		i := bytes.IndexByte(pw.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err = io.WriteString(pw.w, pw.prefix); err != nil {
+			return 0, err
+		}
+		if _, err = pw.w.Write(pw.partial[:i+1]); err != nil {
+			return 0, err
+		}
+		pw.partial = pw.partial[i+1:]
+	}
+	// Don't keep the memory of long outputs.
+	if len(pw.partial) == 0 {
+		pw.partial = nil
+	}
+	return len(p), nil
+}
+
+// tailBuffer keeps the end of the output that's written to it.
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func (tb *tailBuffer) Write(p []byte) (n int, err error) {
+	tb.buf = append(tb.buf, p...)
+	if len(tb.buf) > tb.max {
+		tb.buf = append(tb.buf[:0], tb.buf[len(tb.buf)-tb.max:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the output, starting from the first complete line.
+func (tb *tailBuffer) String() string {
+	s := string(tb.buf)
+	if len(tb.buf) == tb.max {
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			s = s[i+1:]
+		}
+	}
+	return s
+}
@@ -0,0 +1,266 @@
+// Package run runs the command that's set by the -cmd argument of templ generate.
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// Options configure a Supervisor.
+type Options struct {
+	// Command to run, e.g. "go run .".
+	Command string
+	// Dir is the working directory of the command.
+	Dir string
+	// Stdout and Stderr receive the output of the command. Defaults to os.Stdout and os.Stderr.
+	Stdout, Stderr io.Writer
+	// StopTimeout is how long to wait for the command to exit after it's asked to stop, before
+	// it's killed. Defaults to 5s.
+	StopTimeout time.Duration
+	// Ready returns once the command is ready, e.g. once it accepts HTTP requests. If Ready is
+	// nil, the command is ready as soon as it starts.
+	Ready func(ctx context.Context) error
+	// OnReady is called each time that the command is ready after it starts.
+	OnReady func()
+	// OnCrash is called when the command exits with an error, with the end of the output that
+	// the command wrote to stderr, e.g. compiler errors.
+	OnCrash func(err error, stderr string)
+	// Infof and Errorf are called with messages about the command.
+	Infof, Errorf func(format string, a ...any)
+}
+
+// Supervisor runs a command, restarts it when asked to, and restarts it with a backoff if it
+// crashes.
+type Supervisor struct {
+	opts    Options
+	restart chan struct{}
+}
+
+// New creates a Supervisor. The command is started by Run.
+func New(opts Options) *Supervisor {
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	if opts.StopTimeout == 0 {
+		opts.StopTimeout = 5 * time.Second
+	}
+	if opts.Infof == nil {
+		opts.Infof = func(string, ...any) {}
+	}
+	if opts.Errorf == nil {
+		opts.Errorf = func(string, ...any) {}
+	}
+	return &Supervisor{
+		opts:    opts,
+		restart: make(chan struct{}, 1),
+	}
+}
+
+// Restart asks the supervisor to stop the command, and start it again. Requests that are made
+// while a restart is pending are combined.
+func (s *Supervisor) Restart() {
+	select {
+	case s.restart <- struct{}{}:
+	default:
+	}
+}
+
+// stableAfter is how long the command must run for before a crash is no longer considered to
+// be part of a crash loop, so that the backoff is reset.
+const stableAfter = 10 * time.Second
+
+// Run starts the command, and supervises it until the context is cancelled, when the command
+// is stopped.
+func (s *Supervisor) Run(ctx context.Context) error {
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = 500 * time.Millisecond
+	bo.MaxInterval = 10 * time.Second
+	bo.MaxElapsedTime = 0
+
+	for {
+		// Discard restarts that were requested before the command starts.
+		select {
+		case <-s.restart:
+		default:
+		}
+
+		p, err := s.start(ctx)
+		if err != nil {
+			s.opts.Errorf("Error starting command: %v\n", err)
+			if err = s.wait(ctx, bo.NextBackOff()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			s.stop(p)
+			return ctx.Err()
+		case <-s.restart:
+			s.opts.Infof("Restarting command: %s\n", s.opts.Command)
+			s.stop(p)
+			bo.Reset()
+		case err := <-p.exited:
+			p.cancelReady()
+			if time.Since(p.started) > stableAfter {
+				bo.Reset()
+			}
+			if err == nil {
+				// The command completed, so wait for a change before running it again.
+				s.opts.Infof("Command exited: %s\n", s.opts.Command)
+				if err = s.wait(ctx, -1); err != nil {
+					return err
+				}
+				continue
+			}
+			if s.opts.OnCrash != nil {
+				s.opts.OnCrash(err, p.stderr.String())
+			}
+			d := bo.NextBackOff()
+			s.opts.Errorf("Command crashed: %v, restarting in %s\n", err, d.Round(time.Millisecond))
+			if err = s.wait(ctx, d); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// wait returns after d, when a restart is requested, or when the context is cancelled. If d is
+// negative, it only returns when a restart is requested, or when the context is cancelled.
+func (s *Supervisor) wait(ctx context.Context, d time.Duration) error {
+	var timeout <-chan time.Time
+	if d >= 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.restart:
+	case <-timeout:
+	}
+	return nil
+}
+
+// process is a running command.
+type process struct {
+	cmd     *exec.Cmd
+	started time.Time
+	// exited receives the result of the command once it exits.
+	exited      chan error
+	cancelReady context.CancelFunc
+	stderr      *tailBuffer
+}
+
+func (s *Supervisor) start(ctx context.Context) (p *process, err error) {
+	cmd, err := command(s.opts.Dir, s.opts.Command)
+	if err != nil {
+		return nil, err
+	}
+	stderr := &tailBuffer{max: 8 * 1024}
+	cmd.Stdout = s.opts.Stdout
+	cmd.Stderr = io.MultiWriter(s.opts.Stderr, stderr)
+	s.opts.Infof("Executing command: %s\n", s.opts.Command)
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	p = &process{
+		cmd:     cmd,
+		started: time.Now(),
+		exited:  make(chan error, 1),
+		stderr:  stderr,
+	}
+	go func() {
+		p.exited <- cmd.Wait()
+	}()
+
+	var readyCtx context.Context
+	readyCtx, p.cancelReady = context.WithCancel(ctx)
+	go func() {
+		if s.opts.Ready != nil {
+			if err := s.opts.Ready(readyCtx); err != nil {
+				if readyCtx.Err() == nil {
+					s.opts.Errorf("Command isn't ready: %v\n", err)
+				}
+				return
+			}
+		}
+		if readyCtx.Err() == nil && s.opts.OnReady != nil {
+			s.opts.OnReady()
+		}
+	}()
+	return p, nil
+}
+
+// stop asks the command to exit, and kills it if it hasn't exited after the stop timeout.
+func (s *Supervisor) stop(p *process) {
+	p.cancelReady()
+	if err := terminate(p.cmd); err != nil {
+		// The command might have already exited.
+		if !errors.Is(err, os.ErrProcessDone) {
+			s.opts.Errorf("Error stopping command: %v\n", err)
+		}
+	}
+	timer := time.NewTimer(s.opts.StopTimeout)
+	defer timer.Stop()
+	select {
+	case <-p.exited:
+		return
+	case <-timer.C:
+	}
+	s.opts.Errorf("Command didn't exit within %s, killing it\n", s.opts.StopTimeout)
+	if err := kill(p.cmd); err != nil {
+		s.opts.Errorf("Error killing command: %v\n", err)
+	}
+	<-p.exited
+}
+
+// Once runs a command, and waits for it to exit.
+func Once(ctx context.Context, workingDir, input string, stdout, stderr io.Writer) error {
+	cmd, err := command(workingDir, input)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	select {
+	case err = <-exited:
+		return err
+	case <-ctx.Done():
+		_ = kill(cmd)
+		<-exited
+		return ctx.Err()
+	}
+}
+
+func command(workingDir, input string) (cmd *exec.Cmd, err error) {
+	parts := strings.Fields(input)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	cmd = exec.Command(parts[0], parts[1:]...)
+	cmd.Env = os.Environ()
+	cmd.Dir = workingDir
+	setProcessGroup(cmd)
+	return cmd, nil
+}
@@ -0,0 +1,212 @@
+//go:build unix
+
+package run
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSupervisor runs a shell script with a supervisor, and records its output and messages.
+type testSupervisor struct {
+	*Supervisor
+	m        sync.Mutex
+	output   bytes.Buffer
+	messages []string
+	ready    chan struct{}
+}
+
+func newTestSupervisor(t *testing.T, script string, stopTimeout time.Duration) *testSupervisor {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "script.sh"), []byte(script), 0o644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	ts := &testSupervisor{ready: make(chan struct{}, 10)}
+	logf := func(format string, a ...any) {
+		ts.m.Lock()
+		defer ts.m.Unlock()
+		ts.messages = append(ts.messages, strings.TrimSpace(fmt.Sprintf(format, a...)))
+	}
+	stdout, stderr := PrefixWriters(&lockedWriter{m: &ts.m, w: &ts.output}, "[cmd] ", "[cmd err] ")
+	ts.Supervisor = New(Options{
+		Command:     "sh script.sh",
+		Dir:         dir,
+		Stdout:      stdout,
+		Stderr:      stderr,
+		StopTimeout: stopTimeout,
+		OnReady: func() {
+			ts.ready <- struct{}{}
+		},
+		Infof:  logf,
+		Errorf: logf,
+	})
+	return ts
+}
+
+type lockedWriter struct {
+	m *sync.Mutex
+	w *bytes.Buffer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.m.Lock()
+	defer lw.m.Unlock()
+	return lw.w.Write(p)
+}
+
+func (ts *testSupervisor) waitFor(t *testing.T, s string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		ts.m.Lock()
+		found := strings.Contains(ts.output.String(), s) || strings.Contains(strings.Join(ts.messages, "\n"), s)
+		ts.m.Unlock()
+		if found {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ts.m.Lock()
+	defer ts.m.Unlock()
+	t.Fatalf("timed out waiting for %q\noutput:\n%s\nmessages:\n%s", s, ts.output.String(), strings.Join(ts.messages, "\n"))
+}
+
+func (ts *testSupervisor) run(t *testing.T) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ts.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	}
+}
+
+func TestSupervisor(t *testing.T) {
+	t.Run("output is prefixed", func(t *testing.T) {
+		ts := newTestSupervisor(t, "echo hello\necho world >&2\nsleep 10\n", time.Second)
+		stop := ts.run(t)
+		defer stop()
+		ts.waitFor(t, "[cmd] hello\n")
+		ts.waitFor(t, "[cmd err] world\n")
+	})
+	t.Run("the command is ready when it starts, if there's no ready check", func(t *testing.T) {
+		ts := newTestSupervisor(t, "sleep 10\n", time.Second)
+		stop := ts.run(t)
+		defer stop()
+		select {
+		case <-ts.ready:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the command to be ready")
+		}
+	})
+	t.Run("the command is asked to stop before it's restarted", func(t *testing.T) {
+		ts := newTestSupervisor(t, "trap 'echo stopping; exit 0' TERM\necho started\nwhile true; do sleep 0.05; done\n", 5*time.Second)
+		stop := ts.run(t)
+		defer stop()
+		ts.waitFor(t, "[cmd] started")
+		ts.Restart()
+		ts.waitFor(t, "[cmd] stopping")
+		ts.waitFor(t, "Restarting command")
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			ts.m.Lock()
+			starts := strings.Count(ts.output.String(), "[cmd] started")
+			ts.m.Unlock()
+			if starts == 2 {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Error("expected the command to be started again")
+	})
+	t.Run("the command is killed if it doesn't stop within the timeout", func(t *testing.T) {
+		ts := newTestSupervisor(t, "trap '' TERM\necho started\nwhile true; do sleep 0.05; done\n", 100*time.Millisecond)
+		stop := ts.run(t)
+		defer stop()
+		ts.waitFor(t, "[cmd] started")
+		ts.Restart()
+		ts.waitFor(t, "Command didn't exit within 100ms, killing it")
+	})
+	t.Run("crashes are reported, and the command is restarted", func(t *testing.T) {
+		ts := newTestSupervisor(t, "echo started\nexit 3\n", time.Second)
+		stop := ts.run(t)
+		defer stop()
+		ts.waitFor(t, "Command crashed: exit status 3, restarting in")
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			ts.m.Lock()
+			starts := strings.Count(ts.output.String(), "[cmd] started")
+			ts.m.Unlock()
+			if starts >= 2 {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Error("expected the command to be restarted")
+	})
+	t.Run("the end of stderr is passed to OnCrash", func(t *testing.T) {
+		ts := newTestSupervisor(t, "echo started\necho 'main.go:3: undefined: x' >&2\nexit 1\n", time.Second)
+		crashes := make(chan string, 10)
+		ts.opts.OnCrash = func(err error, stderr string) {
+			crashes <- err.Error() + ": " + stderr
+		}
+		stop := ts.run(t)
+		defer stop()
+		select {
+		case crash := <-crashes:
+			if expected := "exit status 1: main.go:3: undefined: x\n"; crash != expected {
+				t.Errorf("expected %q, got %q", expected, crash)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the crash")
+		}
+	})
+	t.Run("commands that complete aren't restarted until a restart is requested", func(t *testing.T) {
+		ts := newTestSupervisor(t, "echo started\n", time.Second)
+		stop := ts.run(t)
+		defer stop()
+		ts.waitFor(t, "Command exited")
+		time.Sleep(100 * time.Millisecond)
+		ts.m.Lock()
+		starts := strings.Count(ts.output.String(), "[cmd] started")
+		ts.m.Unlock()
+		if starts != 1 {
+			t.Fatalf("expected 1 start, got %d", starts)
+		}
+		ts.Restart()
+		ts.waitFor(t, "[cmd] started\n[cmd] started")
+	})
+}
+
+func TestPrefixWriters(t *testing.T) {
+	var b bytes.Buffer
+	stdout, stderr := PrefixWriters(&b, "out: ", "err: ")
+	_, _ = stdout.Write([]byte("partial "))
+	_, _ = stderr.Write([]byte("error\n"))
+	_, _ = stdout.Write([]byte("line\nsecond line\n"))
+	expected := "err: error\nout: partial line\nout: second line\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tb := &tailBuffer{max: 10}
+	_, _ = tb.Write([]byte("first\nsecond\n"))
+	_, _ = tb.Write([]byte("third\n"))
+	if expected := "third\n"; tb.String() != expected {
+		t.Errorf("expected %q, got %q", expected, tb.String())
+	}
+}
@@ -3,58 +3,32 @@
 package run
 
 import (
-	"context"
+	"errors"
 	"os"
 	"os/exec"
-	"strings"
-	"sync"
 	"syscall"
 )
 
-var m = &sync.Mutex{}
-var running = map[string]*exec.Cmd{}
+// setProcessGroup starts the command in its own process group, so that any processes that it
+// starts, e.g. the binary built by go run, are stopped with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
 
-func KillAll() (err error) {
-	m.Lock()
-	defer m.Unlock()
-	for _, cmd := range running {
-		err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-		if err != nil {
-			return err
-		}
-	}
-	running = map[string]*exec.Cmd{}
-	return
+// terminate sends SIGTERM to the process group of the command.
+func terminate(cmd *exec.Cmd) error {
+	return signalGroup(cmd, syscall.SIGTERM)
 }
 
-func Stop(cmd *exec.Cmd) (err error) {
-	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+// kill sends SIGKILL to the process group of the command.
+func kill(cmd *exec.Cmd) error {
+	return signalGroup(cmd, syscall.SIGKILL)
 }
 
-func Run(ctx context.Context, workingDir, input string) (cmd *exec.Cmd, err error) {
-	m.Lock()
-	defer m.Unlock()
-	cmd, ok := running[input]
-	if ok {
-		if err = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
-			return cmd, err
-		}
-		delete(running, input)
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	err := syscall.Kill(-cmd.Process.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
 	}
-	parts := strings.Fields(input)
-	executable := parts[0]
-	args := []string{}
-	if len(parts) > 1 {
-		args = append(args, parts[1:]...)
-	}
-
-	cmd = exec.Command(executable, args...)
-	cmd.Env = os.Environ()
-	cmd.Dir = workingDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	running[input] = cmd
-	err = cmd.Start()
-	return
+	return err
 }
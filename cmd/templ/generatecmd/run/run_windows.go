@@ -3,67 +3,28 @@
 package run
 
 import (
-	"context"
 	"os"
 	"os/exec"
 	"strconv"
-	"strings"
-	"sync"
 )
 
-var m = &sync.Mutex{}
-var running = map[string]*exec.Cmd{}
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// terminate asks the process tree of the command to close. Console applications ignore the
+// request, so they're killed once the stop timeout has passed.
+func terminate(cmd *exec.Cmd) error {
+	return taskkill(cmd, "/T", "/PID", strconv.Itoa(cmd.Process.Pid))
+}
 
-func KillAll() (err error) {
-	m.Lock()
-	defer m.Unlock()
-	for _, cmd := range running {
-		kill := exec.Command("TASKKILL", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
-		kill.Stderr = os.Stderr
-		kill.Stdout = os.Stdout
-		err := kill.Run()
-		if err != nil {
-			return err
-		}
-	}
-	running = map[string]*exec.Cmd{}
-	return
+// kill forcefully ends the process tree of the command.
+func kill(cmd *exec.Cmd) error {
+	return taskkill(cmd, "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
 }
 
-func Stop(cmd *exec.Cmd) (err error) {
-	kill := exec.Command("TASKKILL", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+func taskkill(cmd *exec.Cmd, args ...string) error {
+	kill := exec.Command("TASKKILL", args...)
 	kill.Stderr = os.Stderr
 	kill.Stdout = os.Stdout
 	return kill.Run()
 }
-
-func Run(ctx context.Context, workingDir, input string) (cmd *exec.Cmd, err error) {
-	m.Lock()
-	defer m.Unlock()
-	cmd, ok := running[input]
-	if ok {
-		kill := exec.Command("TASKKILL", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
-		kill.Stderr = os.Stderr
-		kill.Stdout = os.Stdout
-		err := kill.Run()
-		if err != nil {
-			return cmd, err
-		}
-		delete(running, input)
-	}
-	parts := strings.Fields(input)
-	executable := parts[0]
-	args := []string{}
-	if len(parts) > 1 {
-		args = append(args, parts[1:]...)
-	}
-
-	cmd = exec.Command(executable, args...)
-	cmd.Env = os.Environ()
-	cmd.Dir = workingDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	running[input] = cmd
-	err = cmd.Start()
-	return
-}
@@ -70,7 +70,9 @@ loop:
 			}
 			timer.Reset(time.Second * 5)
 		case e := <-events:
-			fmt.Println("Sending reload event...")
+			if e.Data == "reload" {
+				fmt.Println("Sending reload event...")
+			}
 			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, e.Data); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
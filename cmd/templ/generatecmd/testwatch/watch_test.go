@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"embed"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
@@ -19,6 +20,7 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/a-h/templ/cmd/templ/generatecmd"
 	"github.com/a-h/templ/cmd/templ/generatecmd/modcheck"
+	"github.com/a-h/templ/cmd/templ/generatecmd/proxy"
 )
 
 //go:embed testdata/*
@@ -239,6 +241,139 @@ loop:
 	}
 }
 
+func TestGoFileModificationsRestartTheCommand(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	args, teardown, err := Setup()
+	if err != nil {
+		t.Fatalf("failed to setup test: %v", err)
+	}
+	defer teardown(t)
+
+	// Start the SSE check.
+	events := make(chan Event)
+	go func() {
+		_ = readSSE(context.Background(), fmt.Sprintf("%s/_templ/reload/events", args.ProxyURL), events)
+	}()
+	if _, err = getHTML(args.ProxyURL); err != nil {
+		t.Fatalf("failed to read HTML: %v", err)
+	}
+
+	// Change the Go code.
+	err = replaceInFile(filepath.Join(args.AppDir, "main.go"), "var count int", "count := 100")
+	if err != nil {
+		t.Fatalf("failed to replace text in file: %v", err)
+	}
+
+	// The browser is reloaded once the command has been rebuilt, and is ready.
+	timeout := time.After(time.Second * 30)
+loop:
+	for {
+		select {
+		case event := <-events:
+			if event.Data == "reload" {
+				break loop
+			}
+		case <-timeout:
+			t.Fatal("failed to receive SSE about restart after 30 seconds")
+		}
+	}
+	doc, err := getHTML(args.AppURL)
+	if err != nil {
+		t.Fatalf("failed to read HTML: %v", err)
+	}
+	countText := doc.Find(`div[data-testid="count"]`).Text()
+	if actualCount, err := strconv.Atoi(countText); err != nil || actualCount <= 100 {
+		t.Errorf("expected count > 100, got %q", countText)
+	}
+}
+
+func TestGenerationErrorsAreSentToTheBrowser(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	args, teardown, err := Setup()
+	if err != nil {
+		t.Fatalf("failed to setup test: %v", err)
+	}
+	defer teardown(t)
+
+	// Start the SSE check.
+	events := make(chan Event)
+	go func() {
+		_ = readSSE(context.Background(), fmt.Sprintf("%s/_templ/reload/events", args.ProxyURL), events)
+	}()
+	if _, err = getHTML(args.ProxyURL); err != nil {
+		t.Fatalf("failed to read HTML: %v", err)
+	}
+	waitForErrors := func() (errs []proxy.BuildError) {
+		t.Helper()
+		timeout := time.After(time.Second * 5)
+		for {
+			select {
+			case event := <-events:
+				if event.Type != "templ-errors" {
+					continue
+				}
+				if err := json.Unmarshal([]byte(event.Data), &errs); err != nil {
+					t.Fatalf("failed to unmarshal errors %q: %v", event.Data, err)
+				}
+				return errs
+			case <-timeout:
+				t.Fatal("failed to receive errors after 5 seconds")
+			}
+		}
+	}
+
+	// Break the template.
+	templFile := filepath.Join(args.AppDir, "templates.templ")
+	err = replaceInFile(templFile,
+		`<div data-testid="modification">Original</div>`,
+		`<div data-testid="modification">Original</span>`)
+	if err != nil {
+		t.Fatalf("failed to replace text in file: %v", err)
+	}
+	errs := waitForErrors()
+	if len(errs) == 0 {
+		t.Fatal("expected errors, got none")
+	}
+	if errs[0].FileName != "templates.templ" || errs[0].Line != 14 {
+		t.Errorf("expected error at templates.templ:14, got %s:%d", errs[0].FileName, errs[0].Line)
+	}
+	if !strings.Contains(errs[0].Message, "mismatched end tag") {
+		t.Errorf("expected mismatched end tag error, got %q", errs[0].Message)
+	}
+	if len(errs[0].Excerpt) == 0 {
+		t.Error("expected an excerpt of the template")
+	}
+
+	// The errors are also available to pages that load later.
+	resp, err := http.Get(fmt.Sprintf("%s/_templ/reload/errors", args.ProxyURL))
+	if err != nil {
+		t.Fatalf("failed to get errors: %v", err)
+	}
+	defer resp.Body.Close()
+	var current []proxy.BuildError
+	if err = json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		t.Fatalf("failed to decode errors: %v", err)
+	}
+	if len(current) != len(errs) {
+		t.Errorf("expected %d errors, got %d", len(errs), len(current))
+	}
+
+	// Fixing the template clears the errors.
+	err = replaceInFile(templFile,
+		`<div data-testid="modification">Original</span>`,
+		`<div data-testid="modification">Fixed</div>`)
+	if err != nil {
+		t.Fatalf("failed to replace text in file: %v", err)
+	}
+	if errs = waitForErrors(); len(errs) != 0 {
+		t.Errorf("expected errors to be cleared, got %v", errs)
+	}
+}
+
 func NewTestArgs(modRoot, appDir string, appPort int, proxyPort int) TestArgs {
 	return TestArgs{
 		ModRoot:   modRoot,
@@ -300,6 +435,8 @@ func Setup() (args TestArgs, teardown func(t *testing.T), err error) {
 			IncludeVersion:    false,
 			IncludeTimestamp:  false,
 			KeepOrphanedFiles: false,
+			// Disable the build cache, so that the user's cache directory isn't written to.
+			CacheDir: "",
 		})
 	}()
 
@@ -0,0 +1,188 @@
+//go:build linux
+
+package watcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// dirMask is the set of inotify events that are watched for within each directory.
+const dirMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE | syscall.IN_ONLYDIR
+
+// notify receives the changes to the tree from inotify. inotify watches aren't recursive, so
+// each directory is watched, and directories that are created are added to the watch.
+type notify struct {
+	root      string
+	opts      Options
+	fd        int
+	file      *os.File
+	closeOnce sync.Once
+	// dirs maps watch descriptors to the directories that they watch.
+	dirs map[int]string
+	// files are the matching files within the tree, so that their removal can be reported when
+	// the directory that contains them is removed.
+	files map[string]bool
+}
+
+func newNotify(root string, opts Options) (n *notify, err error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+	n = &notify{
+		root: root,
+		opts: opts,
+		fd:   fd,
+		// The file is non-blocking, so reads wait in the runtime's poller, and are interrupted
+		// when the file is closed.
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		dirs:  make(map[int]string),
+		files: make(map[string]bool),
+	}
+	if err = n.addTree(root, nil); err != nil {
+		n.close()
+		return nil, err
+	}
+	return n, nil
+}
+
+// addTree watches dir and its subdirectories. If send isn't nil, the matching files within the
+// tree are sent as changes, because they could have been created before the watch started.
+func (n *notify) addTree(dir string, send func(Event)) error {
+	return walk(dir, n.opts, func(path string, d os.DirEntry) error {
+		if d.IsDir() {
+			wd, err := syscall.InotifyAddWatch(n.fd, path, dirMask)
+			if err != nil {
+				return fmt.Errorf("failed to watch %q: %w", path, err)
+			}
+			n.dirs[wd] = path
+			return nil
+		}
+		n.files[path] = true
+		if send != nil {
+			send(Event{FileName: path, Op: Changed})
+		}
+		return nil
+	})
+}
+
+// removeTree stops watching dir and its subdirectories, and sends the removal of the matching
+// files within them.
+func (n *notify) removeTree(dir string, send func(Event)) {
+	prefix := dir + string(filepath.Separator)
+	for wd, path := range n.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			// The watch has already been removed if the directory was deleted.
+			_, _ = syscall.InotifyRmWatch(n.fd, uint32(wd))
+			delete(n.dirs, wd)
+		}
+	}
+	for fileName := range n.files {
+		if strings.HasPrefix(fileName, prefix) {
+			delete(n.files, fileName)
+			send(Event{FileName: fileName, Op: Removed})
+		}
+	}
+}
+
+// rescan finds the changes that were lost when the inotify queue overflowed. All of the
+// matching files are sent as changes, because their contents could have changed.
+func (n *notify) rescan(send func(Event)) {
+	previous := n.files
+	n.files = make(map[string]bool)
+	if err := n.addTree(n.root, send); err != nil {
+		n.opts.Warnf("Failed to rescan %q: %v\n", n.root, err)
+	}
+	for fileName := range previous {
+		if !n.files[fileName] {
+			send(Event{FileName: fileName, Op: Removed})
+		}
+	}
+}
+
+func (n *notify) run(ctx context.Context, events chan<- Event) error {
+	go func() {
+		<-ctx.Done()
+		n.close()
+	}()
+	send := func(e Event) {
+		select {
+		case events <- e:
+		case <-ctx.Done():
+		}
+	}
+	buf := make([]byte, 64*1024)
+	for {
+		read, err := n.file.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read inotify events: %w", err)
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= read; {
+			e := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(e.Len)
+			name := string(bytes.TrimRight(buf[nameStart:nameEnd], "\x00"))
+			n.handle(int(e.Wd), e.Mask, name, send)
+			offset = nameEnd
+		}
+	}
+}
+
+func (n *notify) handle(wd int, mask uint32, name string, send func(Event)) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		n.rescan(send)
+		return
+	}
+	dir, ok := n.dirs[wd]
+	if !ok {
+		return
+	}
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(n.dirs, wd)
+		return
+	}
+	path := filepath.Join(dir, name)
+	if mask&syscall.IN_ISDIR != 0 {
+		switch {
+		case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+			if n.opts.SkipDir(path) {
+				return
+			}
+			if err := n.addTree(path, send); err != nil {
+				n.opts.Warnf("%v\n", err)
+			}
+		case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+			n.removeTree(path, send)
+		}
+		return
+	}
+	if !n.opts.Match(path) {
+		return
+	}
+	if mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0 {
+		delete(n.files, path)
+		send(Event{FileName: path, Op: Removed})
+		return
+	}
+	n.files[path] = true
+	send(Event{FileName: path, Op: Changed})
+}
+
+func (n *notify) close() (err error) {
+	n.closeOnce.Do(func() {
+		err = n.file.Close()
+	})
+	return err
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package watcher
+
+func newNotify(root string, opts Options) (source, error) {
+	return nil, errNotSupported
+}
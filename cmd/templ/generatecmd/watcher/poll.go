@@ -0,0 +1,78 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// poller walks the tree at an interval, and compares the modification times of matching
+// files.
+type poller struct {
+	root     string
+	opts     Options
+	modTimes map[string]time.Time
+}
+
+func newPoller(root string, opts Options) (p *poller, err error) {
+	p = &poller{root: root, opts: opts}
+	p.modTimes, err = p.scan()
+	return p, err
+}
+
+func (p *poller) scan() (modTimes map[string]time.Time, err error) {
+	modTimes = make(map[string]time.Time)
+	err = walk(p.root, p.opts, func(path string, d os.DirEntry) error {
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// The file was removed during the walk.
+			return nil
+		}
+		modTimes[path] = info.ModTime()
+		return nil
+	})
+	return modTimes, err
+}
+
+func (p *poller) run(ctx context.Context, events chan<- Event) error {
+	ticker := time.NewTicker(p.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		modTimes, err := p.scan()
+		if err != nil {
+			p.opts.Warnf("Failed to poll %q: %v\n", p.root, err)
+			continue
+		}
+		var changes []Event
+		for fileName, modTime := range modTimes {
+			if previous, ok := p.modTimes[fileName]; !ok || !modTime.Equal(previous) {
+				changes = append(changes, Event{FileName: fileName, Op: Changed})
+			}
+		}
+		for fileName := range p.modTimes {
+			if _, ok := modTimes[fileName]; !ok {
+				changes = append(changes, Event{FileName: fileName, Op: Removed})
+			}
+		}
+		p.modTimes = modTimes
+		for _, e := range changes {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func (p *poller) close() error {
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package watcher notifies of changes to the templ files, and other matching files, within a
+// directory tree.
+//
+// On Linux, changes are received from inotify. On other platforms, or if inotify can't be
+// used, e.g. because the limit of watches has been reached, the tree is polled.
+package watcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Op is the kind of change to a file.
+type Op int
+
+const (
+	// Changed files have been created, written, or renamed to their current name.
+	Changed Op = iota
+	// Removed files have been deleted, or renamed to another name.
+	Removed
+)
+
+func (op Op) String() string {
+	if op == Removed {
+		return "removed"
+	}
+	return "changed"
+}
+
+// Event is a change to a file.
+type Event struct {
+	FileName string
+	Op       Op
+}
+
+// Options configure a Watcher.
+type Options struct {
+	// Debounce is how long to wait for further changes before a batch of events is sent.
+	// Defaults to 100ms.
+	Debounce time.Duration
+	// Poll the tree for changes, instead of using inotify.
+	Poll bool
+	// PollInterval is the time between walks of the tree when polling. Defaults to 500ms.
+	PollInterval time.Duration
+	// SkipDir returns true for directories that shouldn't be watched.
+	SkipDir func(dir string) bool
+	// Match returns true for the files to watch. Defaults to templ files.
+	Match func(fileName string) bool
+	// Warnf is called with problems that don't stop the watcher, e.g. a new directory that
+	// can't be watched.
+	Warnf func(format string, args ...any)
+}
+
+// source sends the events of a directory tree.
+type source interface {
+	run(ctx context.Context, events chan<- Event) error
+	close() error
+}
+
+// Watcher watches a directory tree for changes to files.
+type Watcher struct {
+	opts    Options
+	src     source
+	polling bool
+}
+
+// errNotSupported is returned by newNotify on platforms without inotify.
+var errNotSupported = errors.New("inotify isn't supported on this platform")
+
+// New starts watching the files within root. Changes made after New returns are sent by
+// Run. If inotify can't be used, the tree is polled, and Polling returns true.
+func New(root string, opts Options) (w *Watcher, err error) {
+	if opts.Debounce == 0 {
+		opts.Debounce = 100 * time.Millisecond
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = 500 * time.Millisecond
+	}
+	if opts.SkipDir == nil {
+		opts.SkipDir = func(string) bool { return false }
+	}
+	if opts.Match == nil {
+		opts.Match = IsTemplFile
+	}
+	if opts.Warnf == nil {
+		opts.Warnf = func(string, ...any) {}
+	}
+	w = &Watcher{opts: opts}
+	if !opts.Poll {
+		w.src, err = newNotify(root, opts)
+		if err == nil {
+			return w, nil
+		}
+		if !errors.Is(err, errNotSupported) {
+			opts.Warnf("Failed to watch %q with inotify, polling instead: %v\n", root, err)
+		}
+	}
+	w.polling = true
+	if w.src, err = newPoller(root, opts); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Polling returns true if the tree is polled for changes.
+func (w *Watcher) Polling() bool {
+	return w.polling
+}
+
+// Run calls f with each batch of changes, until the context is cancelled. Changes that are
+// made within the debounce interval of each other are sent in the same batch, with one event
+// per file.
+func (w *Watcher) Run(ctx context.Context, f func(events []Event)) (err error) {
+	defer w.src.close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := make(chan Event)
+	srcErr := make(chan error, 1)
+	go func() {
+		srcErr <- w.src.run(ctx, events)
+	}()
+
+	pending := make(map[string]Op)
+	timer := time.NewTimer(w.opts.Debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-srcErr:
+			if err == nil {
+				err = ctx.Err()
+			}
+			return err
+		case e := <-events:
+			pending[e.FileName] = e.Op
+			timer.Reset(w.opts.Debounce)
+		case <-timer.C:
+			if batch := newBatch(pending); len(batch) > 0 {
+				f(batch)
+			}
+			pending = make(map[string]Op)
+		}
+	}
+}
+
+// newBatch sorts the pending events by file name. Files that no longer exist are reported as
+// removed, because a file can be created and deleted within the debounce interval.
+func newBatch(pending map[string]Op) (batch []Event) {
+	for fileName, op := range pending {
+		if op == Changed {
+			if _, err := os.Stat(fileName); errors.Is(err, os.ErrNotExist) {
+				op = Removed
+			}
+		}
+		batch = append(batch, Event{FileName: fileName, Op: op})
+	}
+	sort.Slice(batch, func(i, j int) bool {
+		return batch[i].FileName < batch[j].FileName
+	})
+	return batch
+}
+
+// IsTemplFile returns true for templ files.
+func IsTemplFile(fileName string) bool {
+	return strings.HasSuffix(fileName, ".templ")
+}
+
+// MatchGlob returns true if the file name, relative to the watched directory, matches the
+// pattern. Patterns without a slash match the base name of the file, e.g. "*.go", otherwise
+// the pattern matches the whole name, and "**" matches any number of directories, e.g.
+// "internal/**/*.go". Forward slashes separate directories on all platforms.
+func MatchGlob(pattern, name string) bool {
+	name = filepath.ToSlash(name)
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(name))
+		return matched
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// walk calls f with each directory, and each matching file, within root. Directories that are
+// skipped, and files that are removed during the walk, are ignored.
+func walk(root string, opts Options, f func(path string, d os.DirEntry) error) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() && path != root && opts.SkipDir(path) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || opts.Match(path) {
+			return f(path, d)
+		}
+		return nil
+	})
+}
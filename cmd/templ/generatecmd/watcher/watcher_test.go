@@ -0,0 +1,205 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWatcher(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		name := "inotify"
+		if poll {
+			name = "polling"
+		}
+		t.Run(name, func(t *testing.T) {
+			testWatcher(t, poll)
+		})
+	}
+}
+
+func testWatcher(t *testing.T, poll bool) {
+	dir := t.TempDir()
+	write := func(name, contents string) {
+		t.Helper()
+		fileName := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fileName, []byte(contents), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	write("a.templ", "package a")
+	write("sub/b.templ", "package sub")
+	write("_skipped/c.templ", "package skipped")
+
+	w, err := New(dir, Options{
+		Poll:         poll,
+		PollInterval: 20 * time.Millisecond,
+		Debounce:     50 * time.Millisecond,
+		SkipDir: func(dir string) bool {
+			return strings.HasPrefix(filepath.Base(dir), "_")
+		},
+		Warnf: t.Logf,
+	})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	if poll && !w.Polling() {
+		t.Fatal("expected the watcher to poll")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batches := make(chan []Event)
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx, func(events []Event) {
+			batches <- events
+		})
+	}()
+
+	// next waits for the next batch of events, with file names relative to the dir.
+	next := func(t *testing.T) (events []Event) {
+		t.Helper()
+		select {
+		case batch := <-batches:
+			for _, e := range batch {
+				rel, _ := filepath.Rel(dir, e.FileName)
+				events = append(events, Event{FileName: filepath.ToSlash(rel), Op: e.Op})
+			}
+			return events
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for events")
+			return nil
+		}
+	}
+	// The polling watcher compares modification times, so changes must be made after the
+	// initial scan.
+	time.Sleep(50 * time.Millisecond)
+
+	tests := []struct {
+		name     string
+		change   func()
+		expected []Event
+	}{
+		{
+			name: "files that are written are changed",
+			change: func() {
+				write("a.templ", "package a\n")
+				write("a.go", "package a\n")
+				write("_skipped/c.templ", "package skipped\n")
+			},
+			expected: []Event{{FileName: "a.templ", Op: Changed}},
+		},
+		{
+			name: "files that are created are changed",
+			change: func() {
+				write("d.templ", "package a")
+			},
+			expected: []Event{{FileName: "d.templ", Op: Changed}},
+		},
+		{
+			name: "files that are renamed are removed, and changed",
+			change: func() {
+				if err := os.Rename(filepath.Join(dir, "d.templ"), filepath.Join(dir, "e.templ")); err != nil {
+					t.Fatalf("failed to rename: %v", err)
+				}
+			},
+			expected: []Event{{FileName: "d.templ", Op: Removed}, {FileName: "e.templ", Op: Changed}},
+		},
+		{
+			name: "files that are deleted are removed",
+			change: func() {
+				if err := os.Remove(filepath.Join(dir, "e.templ")); err != nil {
+					t.Fatalf("failed to remove: %v", err)
+				}
+			},
+			expected: []Event{{FileName: "e.templ", Op: Removed}},
+		},
+		{
+			name: "files within new directories are changed",
+			change: func() {
+				write("new/deep/f.templ", "package deep")
+			},
+			expected: []Event{{FileName: "new/deep/f.templ", Op: Changed}},
+		},
+		{
+			name: "files within directories that are renamed are removed, and changed",
+			change: func() {
+				if err := os.Rename(filepath.Join(dir, "sub"), filepath.Join(dir, "moved")); err != nil {
+					t.Fatalf("failed to rename: %v", err)
+				}
+			},
+			expected: []Event{{FileName: "moved/b.templ", Op: Changed}, {FileName: "sub/b.templ", Op: Removed}},
+		},
+		{
+			name: "files within directories that are deleted are removed",
+			change: func() {
+				if err := os.RemoveAll(filepath.Join(dir, "new")); err != nil {
+					t.Fatalf("failed to remove: %v", err)
+				}
+			},
+			expected: []Event{{FileName: "new/deep/f.templ", Op: Removed}},
+		},
+		{
+			name: "files that are created, and deleted, within a batch are removed",
+			change: func() {
+				write("g.templ", "package a")
+				if err := os.Remove(filepath.Join(dir, "g.templ")); err != nil {
+					t.Fatalf("failed to remove: %v", err)
+				}
+				write("a.templ", "package a\n\n")
+			},
+			expected: []Event{{FileName: "a.templ", Op: Changed}, {FileName: "g.templ", Op: Removed}},
+		},
+	}
+	for _, test := range tests {
+		if poll && strings.Contains(test.name, "within a batch") {
+			// The polling watcher doesn't see files that exist between polls.
+			continue
+		}
+		t.Run(test.name, func(t *testing.T) {
+			test.change()
+			if diff := cmp.Diff(test.expected, next(t)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{pattern: "*.go", name: "main.go", expected: true},
+		{pattern: "*.go", name: "internal/db/db.go", expected: true},
+		{pattern: "*.go", name: "main.templ", expected: false},
+		{pattern: "go.mod", name: "go.mod", expected: true},
+		{pattern: "internal/*.go", name: "internal/db.go", expected: true},
+		{pattern: "internal/*.go", name: "internal/db/db.go", expected: false},
+		{pattern: "internal/**/*.go", name: "internal/db.go", expected: true},
+		{pattern: "internal/**/*.go", name: "internal/db/sql/db.go", expected: true},
+		{pattern: "internal/**/*.go", name: "cmd/main.go", expected: false},
+		{pattern: "**/static/*.css", name: "static/site.css", expected: true},
+		{pattern: "**/static/*.css", name: "web/static/site.css", expected: true},
+	}
+	for _, test := range tests {
+		if actual := MatchGlob(test.pattern, test.name); actual != test.expected {
+			t.Errorf("MatchGlob(%q, %q): expected %v, got %v", test.pattern, test.name, test.expected, actual)
+		}
+	}
+}
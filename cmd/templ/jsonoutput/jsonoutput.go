@@ -0,0 +1,307 @@
+// Package jsonoutput writes the progress of templ commands as newline-delimited JSON events,
+// for editor plugins, CI annotators and other tools.
+//
+// Each line is a JSON object with the version of the schema, the type of the event, the
+// command, and the time of the event. Fields aren't removed or changed within a version, but
+// fields and event types may be added, so readers should ignore the ones they don't know.
+package jsonoutput
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a-h/parse"
+	"github.com/a-h/templ/parser/v2"
+)
+
+// Version is the version of the schema of the events.
+const Version = 1
+
+type Type string
+
+const (
+	// TypeStart is the first event of a run of a command.
+	TypeStart Type = "start"
+	// TypeFile reports the result of processing a file.
+	TypeFile Type = "file"
+	// TypeError reports an error that isn't specific to a file.
+	TypeError Type = "error"
+	// TypeSummary reports the totals of the files that have been processed since the start
+	// event, or the previous summary event.
+	TypeSummary Type = "summary"
+)
+
+type Status string
+
+const (
+	// StatusGenerated is the status of a templ file that code has been generated for.
+	StatusGenerated Status = "generated"
+	// StatusCached is the status of a templ file whose generated code was up to date in the
+	// build cache.
+	StatusCached Status = "cached"
+	// StatusDeleted is the status of a generated file that's been deleted, because its templ
+	// file doesn't exist.
+	StatusDeleted Status = "deleted"
+	// StatusFormatted is the status of a templ file that's been reformatted.
+	StatusFormatted Status = "formatted"
+	// StatusUnchanged is the status of a templ file that was already formatted.
+	StatusUnchanged Status = "unchanged"
+	// StatusError is the status of a file that couldn't be processed.
+	StatusError Status = "error"
+)
+
+// Header is included in every event.
+type Header struct {
+	Version int    `json:"version"`
+	Type    Type   `json:"type"`
+	Command string `json:"command"`
+	// Time is formatted as RFC 3339.
+	Time time.Time `json:"time"`
+}
+
+type StartEvent struct {
+	Header
+	// Path is the directory that's processed. The file names of other events are relative to
+	// it.
+	Path string `json:"path"`
+}
+
+type FileEvent struct {
+	Header
+	File        string       `json:"file"`
+	Status      Status       `json:"status"`
+	DurationMS  float64      `json:"durationMs"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Errors      []Error      `json:"errors"`
+}
+
+type ErrorEvent struct {
+	Header
+	Message string `json:"message"`
+}
+
+type SummaryEvent struct {
+	Header
+	// Files is the number of files that have been processed.
+	Files int `json:"files"`
+	// Statuses is the number of files with each status.
+	Statuses map[Status]int `json:"statuses"`
+	// Errors is the number of errors, including the errors that aren't specific to a file.
+	Errors int `json:"errors"`
+	// Warnings is the number of diagnostics.
+	Warnings   int     `json:"warnings"`
+	DurationMS float64 `json:"durationMs"`
+}
+
+// Position is a position within a file. Lines and columns start at 1.
+type Position struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+type Range struct {
+	From Position `json:"from"`
+	To   Position `json:"to"`
+}
+
+// Diagnostic is a problem that doesn't prevent the file from being processed.
+type Diagnostic struct {
+	Message string `json:"message"`
+	// Code identifies the kind of problem, see `templ explain`.
+	Code     string `json:"code,omitempty"`
+	Severity string `json:"severity"`
+	Range    Range  `json:"range"`
+	// SuggestedFix describes how to fix the problem, if known.
+	SuggestedFix string `json:"suggestedFix,omitempty"`
+}
+
+// Error is a problem that prevents the file from being processed. The range is only set if the
+// position of the error is known.
+type Error struct {
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+	Range   *Range `json:"range,omitempty"`
+}
+
+// Writer writes events. It's safe for concurrent use. A nil Writer discards the events, so
+// that commands don't need to check whether JSON output is enabled.
+type Writer struct {
+	m       sync.Mutex
+	w       io.Writer
+	command string
+	path    string
+	now     func() time.Time
+	// summary is the totals since the last summary event.
+	summary SummaryEvent
+}
+
+// New creates a Writer for the events of a command, e.g. "generate".
+func New(w io.Writer, command string) *Writer {
+	jw := &Writer{
+		w:       w,
+		command: command,
+		now:     time.Now,
+	}
+	jw.resetSummary()
+	return jw
+}
+
+func (jw *Writer) header(t Type) Header {
+	return Header{Version: Version, Type: t, Command: jw.command, Time: jw.now().UTC()}
+}
+
+func (jw *Writer) write(v any) {
+	enc := json.NewEncoder(jw.w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+// Start writes the start event. File names are written relative to the path.
+func (jw *Writer) Start(path string) {
+	if jw == nil {
+		return
+	}
+	jw.m.Lock()
+	defer jw.m.Unlock()
+	jw.path = path
+	jw.write(StartEvent{Header: jw.header(TypeStart), Path: path})
+}
+
+// File writes the result of processing a file.
+func (jw *Writer) File(fileName string, status Status, d time.Duration, diagnostics []parser.Diagnostic, err error) {
+	if jw == nil {
+		return
+	}
+	jw.m.Lock()
+	defer jw.m.Unlock()
+	e := FileEvent{
+		Header:      jw.header(TypeFile),
+		File:        jw.relative(fileName),
+		Status:      status,
+		DurationMS:  milliseconds(d),
+		Diagnostics: make([]Diagnostic, 0, len(diagnostics)),
+		Errors:      Errors(err),
+	}
+	for _, d := range diagnostics {
+		e.Diagnostics = append(e.Diagnostics, Diagnostic{
+			Message:      d.Message,
+			Code:         string(d.Code),
+			Severity:     d.Severity.String(),
+			Range:        Range{From: position(d.Range.From.Line, d.Range.From.Col), To: position(d.Range.To.Line, d.Range.To.Col)},
+			SuggestedFix: d.SuggestedFix,
+		})
+	}
+	jw.summary.Files++
+	jw.summary.Statuses[status]++
+	jw.summary.Errors += len(e.Errors)
+	for _, d := range diagnostics {
+		if d.Severity == parser.DiagnosticSeverityWarning {
+			jw.summary.Warnings++
+		}
+	}
+	jw.write(e)
+}
+
+// Error writes an error that isn't specific to a file.
+func (jw *Writer) Error(err error) {
+	if jw == nil || err == nil {
+		return
+	}
+	jw.m.Lock()
+	defer jw.m.Unlock()
+	jw.summary.Errors++
+	jw.write(ErrorEvent{Header: jw.header(TypeError), Message: err.Error()})
+}
+
+// Summary writes the totals of the files that have been processed since the start event, or
+// the previous summary event, which took d.
+func (jw *Writer) Summary(d time.Duration) {
+	if jw == nil {
+		return
+	}
+	jw.m.Lock()
+	defer jw.m.Unlock()
+	e := jw.summary
+	e.Header = jw.header(TypeSummary)
+	e.DurationMS = milliseconds(d)
+	jw.write(e)
+	jw.resetSummary()
+}
+
+func (jw *Writer) resetSummary() {
+	jw.summary = SummaryEvent{Statuses: make(map[Status]int)}
+}
+
+func (jw *Writer) relative(fileName string) string {
+	if jw.path == "" {
+		return filepath.ToSlash(fileName)
+	}
+	rel, err := filepath.Rel(jw.path, fileName)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(fileName)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Errors splits err into the errors that it joins, and adds the position and code of parse
+// errors.
+func Errors(err error) []Error {
+	errs := []Error{}
+	if err == nil {
+		return errs
+	}
+	for _, e := range joinedErrors(err) {
+		var pe parse.ParseError
+		if !errors.As(e, &pe) {
+			errs = append(errs, Error{Message: e.Error()})
+			continue
+		}
+		pos := Position{Line: pe.Pos.Line + 1, Col: pe.Pos.Col + 1}
+		je := Error{Message: pe.Msg, Range: &Range{From: pos, To: pos}}
+		if info, ok := parser.ErrorCodeOf(e); ok {
+			je.Code = string(info.Code)
+		}
+		errs = append(errs, je)
+	}
+	return errs
+}
+
+// joinedErrors returns the errors that are joined within the chain of err, or err if there
+// aren't any. The messages of the errors that wrap the joined errors are added to each of them,
+// e.g. "failed to combine x: " is added to a and b of
+// fmt.Errorf("failed to combine x: %w", errors.Join(a, b)).
+func joinedErrors(err error) []error {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		joined, ok := e.(interface{ Unwrap() []error })
+		if !ok {
+			continue
+		}
+		prefix, ok := strings.CutSuffix(err.Error(), e.Error())
+		var errs []error
+		for _, member := range joined.Unwrap() {
+			for _, je := range joinedErrors(member) {
+				if ok && prefix != "" {
+					je = fmt.Errorf("%s%w", prefix, je)
+				}
+				errs = append(errs, je)
+			}
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+// position converts a zero-based line and column to a Position.
+func position(line, col uint32) Position {
+	return Position{Line: int(line) + 1, Col: int(col) + 1}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,168 @@
+package jsonoutput
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ/parser/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriter(t *testing.T) {
+	var b bytes.Buffer
+	jw := New(&b, "generate")
+	jw.now = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+
+	jw.Start("/src")
+	jw.File("/src/views/page.templ", StatusGenerated, 1500*time.Microsecond, []parser.Diagnostic{
+		{
+			Message: "<div>: invalid nesting, <div> cannot be within <p>",
+			Range: parser.Range{
+				From: parser.Position{Index: 30, Line: 3, Col: 4},
+				To:   parser.Position{Index: 33, Line: 3, Col: 7},
+			},
+			Code: "W0002",
+		},
+	}, nil)
+	_, err := parser.ParseString("package views\n\ntempl Broken() {\n\t<div>\n}\n")
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+	jw.File("/src/views/broken.templ", StatusError, time.Millisecond, nil, fmt.Errorf("views/broken.templ parsing error: %w", parser.WithErrorCodes(err)))
+	jw.Error(errors.New("failed to walk path"))
+	jw.Summary(5 * time.Millisecond)
+
+	expected := `{"version":1,"type":"start","command":"generate","time":"2024-01-02T03:04:05Z","path":"/src"}
+{"version":1,"type":"file","command":"generate","time":"2024-01-02T03:04:05Z","file":"views/page.templ","status":"generated","durationMs":1.5,"diagnostics":[{"message":"<div>: invalid nesting, <div> cannot be within <p>","code":"W0002","severity":"warning","range":{"from":{"line":4,"col":5},"to":{"line":4,"col":8}}}],"errors":[]}
+{"version":1,"type":"file","command":"generate","time":"2024-01-02T03:04:05Z","file":"views/broken.templ","status":"error","durationMs":1,"diagnostics":[],"errors":[{"message":"<div>: expected end tag not present or invalid tag contents","code":"T2001","range":{"from":{"line":5,"col":1},"to":{"line":5,"col":1}}}]}
+{"version":1,"type":"error","command":"generate","time":"2024-01-02T03:04:05Z","message":"failed to walk path"}
+{"version":1,"type":"summary","command":"generate","time":"2024-01-02T03:04:05Z","files":2,"statuses":{"error":1,"generated":1},"errors":2,"warnings":1,"durationMs":5}
+`
+	if diff := cmp.Diff(expected, b.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("the summary is reset after it's written", func(t *testing.T) {
+		b.Reset()
+		jw.File("/src/views/page.templ", StatusCached, 0, nil, nil)
+		jw.Summary(time.Millisecond)
+		lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+		expected := `{"version":1,"type":"summary","command":"generate","time":"2024-01-02T03:04:05Z","files":1,"statuses":{"cached":1},"errors":0,"warnings":0,"durationMs":1}`
+		if diff := cmp.Diff(expected, lines[len(lines)-1]); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Error
+	}{
+		{
+			name:  "missing closing brace",
+			input: "package views\n\ntempl Broken() {\n\t<div></div>\n",
+			expected: []Error{
+				{
+					Message: "template closing brace not found",
+					Code:    "T1002",
+					Range:   &Range{From: Position{Line: 5, Col: 1}, To: Position{Line: 5, Col: 1}},
+				},
+			},
+		},
+		{
+			name:  "errors in multiple templates",
+			input: "package views\n\ntempl A() {\n\t<p><b></p></b>\n}\n\ntempl B() {\n\t<!-- TODO\n}\n",
+			expected: []Error{
+				{
+					Message: "<b>: mismatched end tag, expected '</b>', got '</p>'",
+					Code:    "T2002",
+					Range:   &Range{From: Position{Line: 4, Col: 8}, To: Position{Line: 4, Col: 8}},
+				},
+				{
+					Message: "expected end comment literal '-->' not found",
+					Code:    "T2006",
+					Range:   &Range{From: Position{Line: 10, Col: 1}, To: Position{Line: 10, Col: 1}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.ParseString(tt.input)
+			if err == nil {
+				t.Fatal("expected a parse error")
+			}
+			actual := Errors(fmt.Errorf("views/broken.templ parsing error: %w", parser.WithErrorCodes(err)))
+			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestErrorsKeepsTheMessagesOfWrappingErrors(t *testing.T) {
+	err := fmt.Errorf("failed to combine views/templates_templ.go: %w", errors.Join(errors.New("a.templ: invalid package"), errors.New("b.templ: invalid package")))
+	expected := []Error{
+		{Message: "failed to combine views/templates_templ.go: a.templ: invalid package"},
+		{Message: "failed to combine views/templates_templ.go: b.templ: invalid package"},
+	}
+	if diff := cmp.Diff(expected, Errors(err)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestSummaryCountsDiagnosticsBySeverity(t *testing.T) {
+	var b bytes.Buffer
+	jw := New(&b, "generate")
+	tf, err := parser.ParseString("package views\n\ntempl A() {\n\t<p><div></div></p>\n}\n\ntempl B() {\n\t<!-- TODO\n}\n")
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+	jw.File("a.templ", StatusError, 0, tf.Diagnostics, err)
+	jw.Summary(0)
+
+	var summary SummaryEvent
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+		t.Fatalf("failed to read summary: %v", err)
+	}
+	if summary.Errors != 1 || summary.Warnings != 1 {
+		t.Errorf("expected 1 error and 1 warning, got %d errors and %d warnings", summary.Errors, summary.Warnings)
+	}
+}
+
+func TestWriterConcurrency(t *testing.T) {
+	var b bytes.Buffer
+	jw := New(&b, "fmt")
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			jw.File(fmt.Sprintf("file%d.templ", i), StatusUnchanged, 0, nil, nil)
+		}(i)
+	}
+	wg.Wait()
+	if lines := strings.Count(b.String(), "\n"); lines != 100 {
+		t.Errorf("expected 100 lines, got %d", lines)
+	}
+}
+
+func TestNilWriter(t *testing.T) {
+	var jw *Writer
+	jw.Start(".")
+	jw.File("a.templ", StatusGenerated, 0, nil, nil)
+	jw.Error(errors.New("error"))
+	jw.Summary(0)
+}
@@ -0,0 +1,282 @@
+// Package layout maps templ files to the files that templ generate writes for them, e.g. the Go
+// code for views/page.templ is written to views/page_templ.go by default.
+package layout
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// ConfigFileName is the name of the configuration file, which is found in the directory that's
+// generated, or a parent directory, up to the directory that contains the go.mod file.
+const ConfigFileName = ".templ-layout.json"
+
+// DefaultSuffix is added to the names of generated files, unless another suffix is configured.
+const DefaultSuffix = "_templ"
+
+// Header is the first line of the Go code that templ generates.
+const Header = "// Code generated by templ - DO NOT EDIT."
+
+// CombinedName is the name of the file, before the suffix, that the code generated from the
+// templ files of a directory is written to when Combine is set.
+const CombinedName = "templates"
+
+// Config configures where generated code is written, e.g.
+//
+//	{
+//	  "outputDir": "gen",
+//	  "suffix": "_gen",
+//	  "combine": true
+//	}
+type Config struct {
+	// OutputDir mirrors the directories of templ files within a directory, which is relative to
+	// the directory of the configuration file, e.g. with "gen", the code generated from
+	// views/page.templ is written to gen/views/page_templ.go.
+	OutputDir string `json:"outputDir,omitempty"`
+	// Package writes the code generated from the templ files of each directory to a
+	// subdirectory, as a separate package with that name, e.g. with "templates", the code
+	// generated from views/page.templ is written to views/templates/page_templ.go.
+	Package string `json:"package,omitempty"`
+	// Suffix is added to the names of generated files. Defaults to "_templ".
+	Suffix string `json:"suffix,omitempty"`
+	// Combine writes the code generated from the templ files of each directory to a single
+	// file, e.g. views/templates_templ.go.
+	Combine bool `json:"combine,omitempty"`
+}
+
+// Layout is a validated configuration, and the directory that it's relative to.
+type Layout struct {
+	// Root is the directory that OutputDir is relative to.
+	Root string
+	Config
+}
+
+// New returns the layout of a configuration, relative to root.
+func New(root string, c Config) (l Layout, err error) {
+	if l.Root, err = filepath.Abs(root); err != nil {
+		return l, err
+	}
+	l.Config = c
+	if l.Suffix == "" {
+		l.Suffix = DefaultSuffix
+	}
+	return l, l.validate()
+}
+
+// Default returns the default layout, where generated files are written next to templ files.
+func Default(root string) Layout {
+	l, _ := New(root, Config{})
+	return l
+}
+
+func (l Layout) validate() error {
+	if l.OutputDir != "" && l.Package != "" {
+		return fmt.Errorf("the output directory and the output package can't both be set")
+	}
+	if l.OutputDir != "" && !filepath.IsLocal(l.OutputDir) {
+		return fmt.Errorf("output directory %q must be a relative path within %s", l.OutputDir, l.Root)
+	}
+	if l.Package != "" && (!token.IsIdentifier(l.Package) || l.Package == "_") {
+		return fmt.Errorf("invalid output package name %q", l.Package)
+	}
+	for _, r := range l.Suffix {
+		if !isSuffixRune(r) {
+			return fmt.Errorf("invalid output suffix %q, only letters, numbers, '.', '-' and '_' are allowed", l.Suffix)
+		}
+	}
+	// The Go tool treats files that end with _test.go as tests.
+	if strings.HasSuffix(l.Suffix, "_test") {
+		return fmt.Errorf("invalid output suffix %q, files that end with _test.go are tests", l.Suffix)
+	}
+	return nil
+}
+
+func isSuffixRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_'
+}
+
+// IsDefault returns true if generated files are written next to templ files, one for each
+// templ file, with the default suffix.
+func (l Layout) IsDefault() bool {
+	return l.Config == Config{Suffix: DefaultSuffix}
+}
+
+// ReadConfig reads a configuration file.
+func ReadConfig(fileName string) (c Config, err error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return c, err
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("failed to parse %s: %w", fileName, err)
+	}
+	return c, nil
+}
+
+// Find reads the configuration file within dir, or the nearest parent directory, up to the
+// directory that contains the go.mod file, and returns the layout relative to the directory of
+// the configuration file. If there's no configuration file, the default layout is returned.
+func Find(dir string) (l Layout, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return l, err
+	}
+	for d := dir; ; {
+		fileName := filepath.Join(d, ConfigFileName)
+		if _, err = os.Stat(fileName); err == nil {
+			c, err := ReadConfig(fileName)
+			if err != nil {
+				return l, err
+			}
+			if l, err = New(d, c); err != nil {
+				return l, fmt.Errorf("%s: %w", fileName, err)
+			}
+			return l, nil
+		}
+		if _, err = os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			break
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	return Default(dir), nil
+}
+
+// Dir returns the directory that the code generated from the templ files within dir is written
+// to.
+func (l Layout) Dir(dir string) string {
+	switch {
+	case l.OutputDir != "":
+		rel, err := filepath.Rel(l.Root, dir)
+		// Templ files outside of the root are generated next to the templ file.
+		if err != nil || !filepath.IsLocal(rel) {
+			return dir
+		}
+		return filepath.Join(l.Root, l.OutputDir, rel)
+	case l.Package != "":
+		return filepath.Join(dir, l.Package)
+	}
+	return dir
+}
+
+// sourceDir is the inverse of Dir. It returns false if dir doesn't contain generated code.
+func (l Layout) sourceDir(dir string) (string, bool) {
+	switch {
+	case l.OutputDir != "":
+		rel, err := filepath.Rel(filepath.Join(l.Root, l.OutputDir), dir)
+		if err != nil || !filepath.IsLocal(rel) {
+			return "", false
+		}
+		return filepath.Join(l.Root, rel), true
+	case l.Package != "":
+		if filepath.Base(dir) != l.Package {
+			return "", false
+		}
+		return filepath.Dir(dir), true
+	}
+	return dir, true
+}
+
+func (l Layout) fileName(templFileName, extension string) string {
+	name := strings.TrimSuffix(filepath.Base(templFileName), ".templ")
+	return filepath.Join(l.Dir(filepath.Dir(templFileName)), name+l.Suffix+extension)
+}
+
+// GoFileName returns the name of the Go file that the code generated from a templ file is
+// written to. When the code is combined, it's the same file for all of the templ files in a
+// directory.
+func (l Layout) GoFileName(templFileName string) string {
+	if l.Combine {
+		return filepath.Join(l.Dir(filepath.Dir(templFileName)), CombinedName+l.Suffix+".go")
+	}
+	return l.fileName(templFileName, ".go")
+}
+
+// TextFileName returns the name of the file that the strings of the generated code are written
+// to in watch mode, which is read by the generated Go file.
+func (l Layout) TextFileName(templFileName string) string {
+	return strings.TrimSuffix(l.GoFileName(templFileName), ".go") + ".txt"
+}
+
+// SourceMapFileName returns the name of the source map of a templ file.
+func (l Layout) SourceMapFileName(templFileName string) string {
+	return l.fileName(templFileName, ".map.json")
+}
+
+// VisualisationFileName returns the name of the source map visualisation of a templ file.
+func (l Layout) VisualisationFileName(templFileName string) string {
+	return l.fileName(templFileName, "_sourcemap.html")
+}
+
+// Source returns the templ file that a generated Go file or source map was generated from, or
+// for a combined Go file, the directory of the templ files. It returns false if the name of the
+// file isn't the name of a generated file in this layout. Hand-written files can have the same
+// suffix, so use IsGenerated before changing or deleting the file.
+func (l Layout) Source(fileName string) (source string, combined, ok bool) {
+	name := filepath.Base(fileName)
+	var extension string
+	for _, ext := range []string{".go", ".map.json"} {
+		if strings.HasSuffix(name, l.Suffix+ext) {
+			extension = ext
+			break
+		}
+	}
+	if extension == "" {
+		return "", false, false
+	}
+	name = strings.TrimSuffix(name, l.Suffix+extension)
+	if name == "" {
+		return "", false, false
+	}
+	dir, ok := l.sourceDir(filepath.Dir(fileName))
+	if !ok {
+		return "", false, false
+	}
+	if l.Combine && extension == ".go" {
+		if name != CombinedName {
+			return "", false, false
+		}
+		return dir, true, true
+	}
+	return filepath.Join(dir, name+".templ"), false, true
+}
+
+// IsGenerated returns true if the contents of a file show that it was written by templ
+// generate. Go files start with Header, source maps are templ source maps, and the strings of
+// a Go file are generated if the Go file is.
+func IsGenerated(fileName string) bool {
+	switch {
+	case strings.HasSuffix(fileName, ".go"):
+		f, err := os.Open(fileName)
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		header := make([]byte, len(Header))
+		if _, err = io.ReadFull(f, header); err != nil {
+			return false
+		}
+		return bytes.Equal(header, []byte(Header))
+	case strings.HasSuffix(fileName, ".map.json"):
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return false
+		}
+		var sm parser.SourceMap
+		return json.Unmarshal(data, &sm) == nil
+	case strings.HasSuffix(fileName, ".txt"):
+		return IsGenerated(strings.TrimSuffix(fileName, ".txt") + ".go")
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	root := filepath.FromSlash("/src")
+	templFileName := filepath.FromSlash("/src/views/page.templ")
+	tests := []struct {
+		name          string
+		config        Config
+		goFileName    string
+		txtFileName   string
+		mapFileName   string
+		combinedGo    bool
+		visualisation string
+	}{
+		{
+			name:          "default",
+			goFileName:    "/src/views/page_templ.go",
+			txtFileName:   "/src/views/page_templ.txt",
+			mapFileName:   "/src/views/page_templ.map.json",
+			visualisation: "/src/views/page_templ_sourcemap.html",
+		},
+		{
+			name:          "output directory",
+			config:        Config{OutputDir: "gen"},
+			goFileName:    "/src/gen/views/page_templ.go",
+			txtFileName:   "/src/gen/views/page_templ.txt",
+			mapFileName:   "/src/gen/views/page_templ.map.json",
+			visualisation: "/src/gen/views/page_templ_sourcemap.html",
+		},
+		{
+			name:          "package",
+			config:        Config{Package: "templates"},
+			goFileName:    "/src/views/templates/page_templ.go",
+			txtFileName:   "/src/views/templates/page_templ.txt",
+			mapFileName:   "/src/views/templates/page_templ.map.json",
+			visualisation: "/src/views/templates/page_templ_sourcemap.html",
+		},
+		{
+			name:          "suffix",
+			config:        Config{Suffix: ".gen"},
+			goFileName:    "/src/views/page.gen.go",
+			txtFileName:   "/src/views/page.gen.txt",
+			mapFileName:   "/src/views/page.gen.map.json",
+			visualisation: "/src/views/page.gen_sourcemap.html",
+		},
+		{
+			name:          "combined",
+			config:        Config{Combine: true},
+			goFileName:    "/src/views/templates_templ.go",
+			txtFileName:   "/src/views/templates_templ.txt",
+			mapFileName:   "/src/views/page_templ.map.json",
+			combinedGo:    true,
+			visualisation: "/src/views/page_templ_sourcemap.html",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := New(root, tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, f := range []struct {
+				name, expected, actual string
+			}{
+				{"go", tt.goFileName, l.GoFileName(templFileName)},
+				{"txt", tt.txtFileName, l.TextFileName(templFileName)},
+				{"map", tt.mapFileName, l.SourceMapFileName(templFileName)},
+				{"visualisation", tt.visualisation, l.VisualisationFileName(templFileName)},
+			} {
+				if expected := filepath.FromSlash(f.expected); f.actual != expected {
+					t.Errorf("%s: expected %q, got %q", f.name, expected, f.actual)
+				}
+			}
+
+			source, combined, ok := l.Source(l.GoFileName(templFileName))
+			expectedSource := templFileName
+			if tt.combinedGo {
+				expectedSource = filepath.Dir(templFileName)
+			}
+			if !ok || source != expectedSource || combined != tt.combinedGo {
+				t.Errorf("expected the source of the Go file to be %q (combined: %v), got %q (combined: %v, ok: %v)", expectedSource, tt.combinedGo, source, combined, ok)
+			}
+			if source, _, ok = l.Source(l.SourceMapFileName(templFileName)); !ok || source != templFileName {
+				t.Errorf("expected the source of the source map to be %q, got %q (ok: %v)", templFileName, source, ok)
+			}
+			if _, _, ok = l.Source(filepath.FromSlash("/src/views/main.go")); ok {
+				t.Error("expected other Go files not to have a source")
+			}
+		})
+	}
+}
+
+func TestLayoutSourceOutsideOutput(t *testing.T) {
+	l, err := New(filepath.FromSlash("/src"), Config{OutputDir: "gen"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, ok := l.Source(filepath.FromSlash("/src/views/page_templ.go")); ok {
+		t.Error("expected files outside of the output directory not to have a source")
+	}
+	l, err = New(filepath.FromSlash("/src"), Config{Package: "templates"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, ok := l.Source(filepath.FromSlash("/src/views/page_templ.go")); ok {
+		t.Error("expected files outside of the output package not to have a source")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "output directory and package", config: Config{OutputDir: "gen", Package: "templates"}},
+		{name: "absolute output directory", config: Config{OutputDir: "/gen"}},
+		{name: "output directory outside the root", config: Config{OutputDir: "../gen"}},
+		{name: "invalid package", config: Config{Package: "my-templates"}},
+		{name: "blank package", config: Config{Package: "_"}},
+		{name: "suffix with a path separator", config: Config{Suffix: "/gen"}},
+		{name: "test suffix", config: Config{Suffix: "_test"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New("/src", tt.config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "views", "pages")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("the default layout is used without a configuration file", func(t *testing.T) {
+		l, err := Find(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !l.IsDefault() || l.Root != dir {
+			t.Errorf("expected the default layout relative to %q, got %+v", dir, l)
+		}
+	})
+	t.Run("the nearest configuration file is used", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(root, ConfigFileName), []byte(`{"outputDir":"gen","suffix":"_gen"}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		l, err := Find(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := Layout{Root: root, Config: Config{OutputDir: "gen", Suffix: "_gen"}}
+		if l != expected {
+			t.Errorf("expected %+v, got %+v", expected, l)
+		}
+	})
+	t.Run("invalid configuration files are an error", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(root, ConfigFileName), []byte(`{"outputDir":"gen","package":"templates"}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Find(dir); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestIsGenerated(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"page_templ.go":        Header + "\n\npackage views\n",
+		"page_templ.txt":       "<div>\n",
+		"page_templ.map.json":  `{"version":1,"mappings":[]}`,
+		"settings.go":          "package views\n",
+		"settings.txt":         "hand-written\n",
+		"errors.map.json":      `{"errors":[]}`,
+		"short.go":             "package",
+		"comment_templ.go":     "// Code generated by hand.\n\npackage views\n",
+		"requirements.txt":     "templ\n",
+		"lonely_templ.map.txt": "",
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name := range files {
+		expected := strings.HasPrefix(name, "page_templ.")
+		if actual := IsGenerated(filepath.Join(dir, name)); actual != expected {
+			t.Errorf("%s: expected %v, got %v", name, expected, actual)
+		}
+	}
+	if IsGenerated(filepath.Join(dir, "missing_templ.go")) {
+		t.Error("expected files that don't exist not to be generated")
+	}
+}
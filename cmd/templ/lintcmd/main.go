@@ -0,0 +1,156 @@
+package lintcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/a-h/templ/lint"
+	"github.com/a-h/templ/parser/v2"
+)
+
+type Arguments struct {
+	// Path to lint the templ files within.
+	Path string
+	// ConfigFileName of the configuration file. If empty, the configuration file is found
+	// within the directory of each templ file, or its parents.
+	ConfigFileName string
+	// JSON writes each finding as a JSON object on its own line.
+	JSON bool
+	// ListRules writes the names and descriptions of the rules, instead of linting.
+	ListRules bool
+	// Rules are run in addition to the default rules.
+	Rules []*lint.Rule
+}
+
+// Finding is the JSON representation of a lint finding.
+type Finding struct {
+	FileName string `json:"file"`
+	// Line is the one-based line of the finding.
+	Line int `json:"line"`
+	// Col is the one-based column of the finding, in bytes.
+	Col      int    `json:"col"`
+	Message  string `json:"message"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+}
+
+// ErrProblemsFound is returned by Run if any findings have the error severity.
+var ErrProblemsFound = errors.New("problems found")
+
+// Run lints the templ files within the path, and writes the findings.
+func Run(w io.Writer, args Arguments) (err error) {
+	if args.ListRules {
+		return listRules(w, append(append([]*lint.Rule{}, lint.DefaultRules...), args.Rules...))
+	}
+
+	// A configuration file set by the arguments applies to all templ files, otherwise each
+	// directory can have its own.
+	linters := make(map[string]lint.Linter)
+	var config *lint.Config
+	if args.ConfigFileName != "" {
+		c, err := lint.ReadConfig(args.ConfigFileName)
+		if err != nil {
+			return err
+		}
+		config = &c
+	}
+	getLinter := func(dir string) (l lint.Linter, err error) {
+		if l, ok := linters[dir]; ok {
+			return l, nil
+		}
+		c := config
+		if c == nil {
+			found, err := lint.FindConfig(dir)
+			if err != nil {
+				return l, err
+			}
+			c = &found
+		}
+		if l, err = lint.New(*c, args.Rules...); err != nil {
+			return l, fmt.Errorf("invalid configuration: %w", err)
+		}
+		linters[dir] = l
+		return l, nil
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	var errorCount int
+	err = filepath.WalkDir(args.Path, func(fileName string, info os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && fileName != args.Path && shouldSkipDir(info.Name()) {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || !strings.HasSuffix(fileName, ".templ") {
+			return nil
+		}
+		contents, err := os.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		tf, err := parser.ParseString(string(contents))
+		if err != nil {
+			return fmt.Errorf("%s parsing error: %w", fileName, err)
+		}
+		l, err := getLinter(filepath.Dir(fileName))
+		if err != nil {
+			return err
+		}
+		for _, f := range l.Lint(fileName, string(contents), tf) {
+			if f.Severity == parser.DiagnosticSeverityError {
+				errorCount++
+			}
+			if args.JSON {
+				err = enc.Encode(Finding{
+					FileName: f.FileName,
+					Line:     int(f.Range.From.Line) + 1,
+					Col:      int(f.Range.From.Col) + 1,
+					Message:  f.Message,
+					Rule:     f.Rule,
+					Severity: severity(f.Severity),
+				})
+			} else {
+				_, err = fmt.Fprintln(w, f.String())
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%w: %d", ErrProblemsFound, errorCount)
+	}
+	return nil
+}
+
+func listRules(w io.Writer, rules []*lint.Rule) error {
+	for _, r := range rules {
+		if _, err := fmt.Fprintf(w, "%s\n  %s\n\n", r.Name, r.Doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func severity(s parser.DiagnosticSeverity) string {
+	if s == parser.DiagnosticSeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// shouldSkipDir returns true for directories that are ignored by the Go tool.
+func shouldSkipDir(name string) bool {
+	return name == "vendor" || name == "node_modules" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
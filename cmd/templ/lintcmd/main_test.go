@@ -0,0 +1,70 @@
+package lintcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun(t *testing.T) {
+	fileName := filepath.Join("testdata", "page", "page.templ")
+	t.Run("findings are written as text", func(t *testing.T) {
+		var w bytes.Buffer
+		err := Run(&w, Arguments{Path: "testdata"})
+		if !errors.Is(err, ErrProblemsFound) {
+			t.Fatalf("expected ErrProblemsFound, got %v", err)
+		}
+		expected := fileName + `:6:5: <img>: missing alt attribute, use alt="" if the image is decorative (img-alt)` + "\n" +
+			fileName + `:9:34: <a>: target="_blank" without rel="noopener" (target-blank)` + "\n"
+		if diff := cmp.Diff(expected, w.String()); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("findings are written as JSON", func(t *testing.T) {
+		var w bytes.Buffer
+		err := Run(&w, Arguments{Path: "testdata", JSON: true})
+		if !errors.Is(err, ErrProblemsFound) {
+			t.Fatalf("expected ErrProblemsFound, got %v", err)
+		}
+		var actual []Finding
+		dec := json.NewDecoder(&w)
+		for dec.More() {
+			var f Finding
+			if err := dec.Decode(&f); err != nil {
+				t.Fatalf("failed to decode finding: %v", err)
+			}
+			actual = append(actual, f)
+		}
+		expected := []Finding{
+			{FileName: fileName, Line: 6, Col: 5, Message: `<img>: missing alt attribute, use alt="" if the image is decorative`, Rule: "img-alt", Severity: "error"},
+			{FileName: fileName, Line: 9, Col: 34, Message: `<a>: target="_blank" without rel="noopener"`, Rule: "target-blank", Severity: "warning"},
+		}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("warnings aren't errors", func(t *testing.T) {
+		var w bytes.Buffer
+		err := Run(&w, Arguments{Path: "testdata", ConfigFileName: filepath.Join("testdata", "warnings.json")})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if lines := strings.Count(w.String(), "\n"); lines != 3 {
+			t.Errorf("expected 3 findings, got:\n%s", w.String())
+		}
+	})
+	t.Run("rules can be listed", func(t *testing.T) {
+		var w bytes.Buffer
+		if err := Run(&w, Arguments{ListRules: true}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(w.String(), "img-alt\n  <img> elements must have an alt attribute") {
+			t.Errorf("unexpected output:\n%s", w.String())
+		}
+	})
+}
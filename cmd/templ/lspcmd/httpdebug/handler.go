@@ -33,7 +33,7 @@ func NewHandler(l *zap.Logger, s *proxy.Server) http.Handler {
 			Error(w, "uri not found", http.StatusNotFound)
 			return
 		}
-		JSON(w, sm.SourceLinesToTarget)
+		JSON(w, sm)
 	})
 	m.HandleFunc("/go", func(w http.ResponseWriter, r *http.Request) {
 		uri := r.URL.Query().Get("uri")
@@ -12,6 +12,7 @@ import (
 	"github.com/a-h/templ/cmd/templ/lspcmd/httpdebug"
 	"github.com/a-h/templ/cmd/templ/lspcmd/pls"
 	"github.com/a-h/templ/cmd/templ/lspcmd/proxy"
+	"github.com/a-h/templ/parser/v2"
 	"go.lsp.dev/jsonrpc2"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -27,6 +28,9 @@ type Arguments struct {
 	PPROF bool
 	// HTTPDebug sets the HTTP endpoint to listen on. Leave empty for no web debug.
 	HTTPDebug string
+	// WhitespaceSensitiveElements are the names of elements, in addition to <pre>, <textarea>
+	// and <code>, whose whitespace is preserved.
+	WhitespaceSensitiveElements []string
 }
 
 func Run(w io.Writer, args Arguments) error {
@@ -100,6 +104,7 @@ func run(ctx context.Context, w io.Writer, args Arguments) (err error) {
 	log.Info("creating proxy")
 	// Create the proxy to sit between.
 	serverProxy, serverInit := proxy.NewServer(log, goplsServer, cache, diagnosticCache)
+	serverProxy.ParseOpts = []parser.ParseOpt{parser.WithWhitespaceSensitiveElements(args.WhitespaceSensitiveElements...)}
 
 	// Create templ server.
 	log.Info("creating templ server")
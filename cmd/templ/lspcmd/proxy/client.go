@@ -53,8 +53,14 @@ func (p Client) PublishDiagnostics(ctx context.Context, params *lsp.PublishDiagn
 	for i, diagnostic := range params.Diagnostics {
 		p.Log.Info(fmt.Sprintf("client <- server: PublishDiagnostics: [%d]", i), zap.Any("diagnostic", diagnostic))
 	}
+	// The code of the templ files that aren't open is replaced, so the diagnostics of the file
+	// that it's combined into don't apply to the file on disk.
+	if isCombinedGoURI(params.URI) {
+		return nil
+	}
 	// Get the sourcemap from the cache.
-	uri := strings.TrimSuffix(string(params.URI), "_templ.go") + ".templ"
+	_, templURI := convertTemplGoToTemplURI(params.URI)
+	uri := string(templURI)
 	sourceMap, ok := p.SourceMapCache.Get(uri)
 	if !ok {
 		return fmt.Errorf("unable to complete because the sourcemap for %q doesn't exist in the cache, has the didOpen notification been sent yet?", uri)
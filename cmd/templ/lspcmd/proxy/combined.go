@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"go/format"
+	"path"
+	"path/filepath"
+	"strings"
+
+	lsp "github.com/a-h/protocol"
+	"github.com/a-h/templ/generator"
+	"github.com/a-h/templ/parser/v2"
+	"go.uber.org/zap"
+)
+
+// generateOpts returns the options that the Go code of a templ file is generated with, to
+// match the code that templ generate writes.
+func generateOpts(templURI lsp.DocumentURI) (opts []generator.GenerateOpt) {
+	templFileName, ok := fileNameOf(templURI)
+	if !ok {
+		return nil
+	}
+	return generateOptsOf(filepath.Dir(templFileName))
+}
+
+func generateOptsOf(dir string) (opts []generator.GenerateOpt) {
+	if l := layoutOf(dir); l.Package != "" {
+		opts = append(opts, generator.WithPackageName(l.Package))
+	}
+	return opts
+}
+
+// updateCombinedDocument replaces the file that the code of the templ files in the directory
+// of a templ file is combined into, when the templ file is opened or closed.
+//
+// Each open templ file is a separate document in gopls, so the combined file is replaced by
+// the code of the templ files that aren't open, to prevent the declarations of the open files
+// being duplicated. Once none of the templ files are open, gopls reads the combined file from
+// disk again.
+func (p *Server) updateCombinedDocument(ctx context.Context, templURI lsp.DocumentURI, packageName string) error {
+	templFileName, ok := fileNameOf(templURI)
+	if !ok {
+		return nil
+	}
+	dir := filepath.Dir(templFileName)
+	l := layoutOf(dir)
+	if !l.Combine {
+		return nil
+	}
+	base, _ := path.Split(string(templURI))
+	rel, err := filepath.Rel(dir, l.GoFileName(templFileName))
+	if err != nil {
+		return err
+	}
+	goURI := relativeURI(base, filepath.ToSlash(rel))
+
+	open := make(map[string]bool)
+	for _, u := range p.TemplSource.URIs() {
+		if fileName, ok := fileNameOf(lsp.DocumentURI(u)); ok {
+			open[fileName] = true
+		}
+	}
+	templFileNames, err := filepath.Glob(filepath.Join(dir, "*.templ"))
+	if err != nil {
+		return err
+	}
+	var parts []generator.CombineFile
+	var anyOpen bool
+	for _, fileName := range templFileNames {
+		if open[fileName] {
+			anyOpen = true
+			continue
+		}
+		code, err := generateGoCode(fileName, p.ParseOpts, generateOptsOf(dir))
+		if err != nil {
+			p.Log.Warn("failed to generate code of templ file that isn't open", zap.String("fileName", fileName), zap.Error(err))
+			continue
+		}
+		parts = append(parts, generator.CombineFile{Name: fileName, Code: code})
+	}
+
+	version, isOpen := p.combinedVersions[goURI]
+	if !anyOpen {
+		if !isOpen {
+			return nil
+		}
+		delete(p.combinedVersions, goURI)
+		return p.Target.DidClose(ctx, &lsp.DidCloseTextDocumentParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: goURI},
+		})
+	}
+	text := "package " + packageName + "\n"
+	if l.Package != "" {
+		text = "package " + l.Package + "\n"
+	}
+	if len(parts) > 0 {
+		code, _, _, err := generator.Combine(parts)
+		if err != nil {
+			return err
+		}
+		text = string(code)
+	}
+	if !isOpen {
+		p.combinedVersions[goURI] = 1
+		return p.Target.DidOpen(ctx, &lsp.DidOpenTextDocumentParams{
+			TextDocument: lsp.TextDocumentItem{URI: goURI, LanguageID: "go", Version: 1, Text: text},
+		})
+	}
+	version++
+	p.combinedVersions[goURI] = version
+	return p.Target.DidChange(ctx, &lsp.DidChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: goURI},
+			Version:                version,
+		},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: text}},
+	})
+}
+
+// generateGoCode generates the formatted Go code of a templ file on disk.
+func generateGoCode(fileName string, parseOpts []parser.ParseOpt, opts []generator.GenerateOpt) ([]byte, error) {
+	t, err := parser.Parse(fileName, parseOpts...)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if _, _, err = generator.Generate(t, &b, opts...); err != nil {
+		return nil, err
+	}
+	return format.Source(b.Bytes())
+}
+
+// packageNameOf returns the name of the package of a templ file.
+func packageNameOf(t parser.TemplateFile) string {
+	return strings.TrimSpace(strings.TrimPrefix(t.Package.Expression.Value, "package"))
+}
@@ -1,24 +1,129 @@
 package proxy
 
 import (
+	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	lsp "github.com/a-h/protocol"
+	"github.com/a-h/templ/cmd/templ/layout"
+	"go.lsp.dev/uri"
 )
 
+// layouts caches the layout of the generated code of each directory. Changes to the
+// .templ-layout.json file take effect when the LSP is restarted.
+var layouts sync.Map
+
+func layoutOf(dir string) layout.Layout {
+	if l, ok := layouts.Load(dir); ok {
+		return l.(layout.Layout)
+	}
+	l, err := layout.Find(dir)
+	if err != nil {
+		l = layout.Default(dir)
+	}
+	layouts.Store(dir, l)
+	return l
+}
+
+// documentLayout returns the layout that gopls is given the Go code of templ files in. When the
+// generated code is combined, the code of each templ file is still a separate document, and
+// the combined file is replaced by the code of the templ files that aren't open, see
+// Server.updateCombinedDocument.
+func documentLayout(dir string) layout.Layout {
+	l := layoutOf(dir)
+	l.Combine = false
+	return l
+}
+
+// fileNameOf returns the file name of a file URI. The layout of other URIs, e.g. of unsaved
+// documents, is the default layout.
+func fileNameOf(u lsp.DocumentURI) (fileName string, ok bool) {
+	parsed, err := url.ParseRequestURI(string(u))
+	if err != nil || parsed.Scheme != uri.FileScheme {
+		return "", false
+	}
+	return uri.URI(u).Filename(), true
+}
+
+// relativeURI replaces the file name of a URI with a relative path, e.g. ../gen/page_templ.go.
+func relativeURI(base, rel string) lsp.DocumentURI {
+	scheme, p, ok := strings.Cut(base, "://")
+	if !ok {
+		return lsp.DocumentURI(base + rel)
+	}
+	return lsp.DocumentURI(scheme + "://" + path.Join(p, rel))
+}
+
 func convertTemplToGoURI(templURI lsp.DocumentURI) (isTemplFile bool, goURI lsp.DocumentURI) {
 	base, fileName := path.Split(string(templURI))
 	if !strings.HasSuffix(fileName, ".templ") {
 		return
 	}
-	return true, lsp.DocumentURI(base + (strings.TrimSuffix(fileName, ".templ") + "_templ.go"))
+	templFileName, ok := fileNameOf(templURI)
+	if !ok || documentLayout(filepath.Dir(templFileName)).IsDefault() {
+		return true, lsp.DocumentURI(base + (strings.TrimSuffix(fileName, ".templ") + "_templ.go"))
+	}
+	l := documentLayout(filepath.Dir(templFileName))
+	rel, err := filepath.Rel(filepath.Dir(templFileName), l.GoFileName(templFileName))
+	if err != nil {
+		return
+	}
+	return true, relativeURI(base, filepath.ToSlash(rel))
 }
 
 func convertTemplGoToTemplURI(goURI lsp.DocumentURI) (isTemplGoFile bool, templURI lsp.DocumentURI) {
 	base, fileName := path.Split(string(goURI))
-	if !strings.HasSuffix(fileName, "_templ.go") {
+	if !strings.HasSuffix(fileName, ".go") {
+		return
+	}
+	goFileName, ok := fileNameOf(goURI)
+	if !ok || documentLayout(filepath.Dir(goFileName)).IsDefault() {
+		if !strings.HasSuffix(fileName, "_templ.go") {
+			return
+		}
+		return true, lsp.DocumentURI(base + (strings.TrimSuffix(fileName, "_templ.go") + ".templ"))
+	}
+	l := documentLayout(filepath.Dir(goFileName))
+	templFileName, _, ok := l.Source(goFileName)
+	if !ok || !isTemplSource(templFileName, goFileName) {
 		return
 	}
-	return true, lsp.DocumentURI(base + (strings.TrimSuffix(fileName, "_templ.go") + ".templ"))
+	rel, err := filepath.Rel(filepath.Dir(goFileName), templFileName)
+	if err != nil {
+		return
+	}
+	return true, relativeURI(base, filepath.ToSlash(rel))
+}
+
+// isTemplSource returns true if the Go file is the code of the templ file. Hand-written Go files
+// can have the suffix of generated files, so the templ file must exist, and the Go file must
+// have been generated by templ, unless it's only open in gopls, e.g. because the templ file
+// hasn't been generated yet.
+func isTemplSource(templFileName, goFileName string) bool {
+	if _, err := os.Stat(templFileName); err != nil {
+		return false
+	}
+	if _, err := os.Stat(goFileName); err != nil {
+		return true
+	}
+	return layout.IsGenerated(goFileName)
+}
+
+// isCombinedGoURI returns true if the URI is a file that the code of the templ files of a
+// directory is combined into.
+func isCombinedGoURI(goURI lsp.DocumentURI) bool {
+	goFileName, ok := fileNameOf(goURI)
+	if !ok {
+		return false
+	}
+	l := layoutOf(filepath.Dir(goFileName))
+	if !l.Combine {
+		return false
+	}
+	_, combined, ok := l.Source(goFileName)
+	return ok && combined
 }
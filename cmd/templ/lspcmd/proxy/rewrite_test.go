@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	lsp "github.com/a-h/protocol"
+	"github.com/a-h/templ/cmd/templ/layout"
+	"go.lsp.dev/uri"
+)
+
+func TestConvertURIs(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{name: "default", expected: "views/page_templ.go"},
+		{name: "output directory", config: `{"outputDir":"gen"}`, expected: "gen/views/page_templ.go"},
+		{name: "package", config: `{"package":"templates","suffix":"_gen"}`, expected: "views/templates/page_gen.go"},
+		{name: "combined files are opened as separate documents", config: `{"combine":true}`, expected: "views/page_templ.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(dir, "views"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.config != "" {
+				if err := os.WriteFile(filepath.Join(dir, layout.ConfigFileName), []byte(tt.config), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(dir, "views", "page.templ"), []byte("package views\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			templURI := lsp.DocumentURI(uri.File(filepath.Join(dir, "views", "page.templ")))
+			expectedGoURI := lsp.DocumentURI(uri.File(filepath.Join(dir, filepath.FromSlash(tt.expected))))
+
+			isTemplFile, goURI := convertTemplToGoURI(templURI)
+			if !isTemplFile || goURI != expectedGoURI {
+				t.Errorf("expected %q, got %q (isTemplFile: %v)", expectedGoURI, goURI, isTemplFile)
+			}
+			isTemplGoFile, actualTemplURI := convertTemplGoToTemplURI(goURI)
+			if !isTemplGoFile || actualTemplURI != templURI {
+				t.Errorf("expected %q, got %q (isTemplGoFile: %v)", templURI, actualTemplURI, isTemplGoFile)
+			}
+		})
+	}
+}
+
+func TestConvertHandWrittenGoURIs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, layout.ConfigFileName), []byte(`{"suffix":"s"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"settings.go":   "package app\n",
+		"setting.templ": "package app\n",
+		"options.go":    "package app\n",
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"settings.go", "options.go"} {
+		goURI := lsp.DocumentURI(uri.File(filepath.Join(dir, name)))
+		if isTemplGoFile, templURI := convertTemplGoToTemplURI(goURI); isTemplGoFile {
+			t.Errorf("%s: expected hand-written Go files not to be converted, got %q", name, templURI)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "settings.go"), []byte(layout.Header+"\n\npackage app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	goURI := lsp.DocumentURI(uri.File(filepath.Join(dir, "settings.go")))
+	if isTemplGoFile, _ := convertTemplGoToTemplURI(goURI); !isTemplGoFile {
+		t.Error("expected generated Go files to be converted")
+	}
+}
+
+func TestIsCombinedGoURI(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, layout.ConfigFileName), []byte(`{"combine":true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !isCombinedGoURI(lsp.DocumentURI(uri.File(filepath.Join(dir, "templates_templ.go")))) {
+		t.Error("expected the combined file to be detected")
+	}
+	if isCombinedGoURI(lsp.DocumentURI(uri.File(filepath.Join(dir, "page_templ.go")))) {
+		t.Error("expected other files not to be combined files")
+	}
+}
@@ -2,7 +2,9 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -10,6 +12,7 @@ import (
 	lsp "github.com/a-h/protocol"
 	"github.com/a-h/templ"
 	"github.com/a-h/templ/generator"
+	"github.com/a-h/templ/lint"
 	"github.com/a-h/templ/parser/v2"
 	"go.lsp.dev/uri"
 	"go.uber.org/zap"
@@ -37,6 +40,11 @@ type Server struct {
 	DiagnosticCache *DiagnosticCache
 	TemplSource     *DocumentContents
 	GoSource        map[string]string
+	// ParseOpts are the options used to parse templ files.
+	ParseOpts []parser.ParseOpt
+	// combinedVersions are the versions of the combined files that have been replaced, see
+	// updateCombinedDocument.
+	combinedVersions map[lsp.DocumentURI]int32
 }
 
 func NewServer(log *zap.Logger, target lsp.Server, cache *SourceMapCache, diagnosticCache *DiagnosticCache) (s *Server, init func(lsp.Client)) {
@@ -47,6 +55,8 @@ func NewServer(log *zap.Logger, target lsp.Server, cache *SourceMapCache, diagno
 		DiagnosticCache: diagnosticCache,
 		TemplSource:     newDocumentContents(log),
 		GoSource:        make(map[string]string),
+
+		combinedVersions: make(map[lsp.DocumentURI]int32),
 	}
 	return s, func(client lsp.Client) {
 		s.Client = client
@@ -120,8 +130,8 @@ func (p *Server) convertGoRangeToTemplRange(templURI lsp.DocumentURI, input lsp.
 
 // parseTemplate parses the templ file content, and notifies the end user via the LSP about how it went.
 func (p *Server) parseTemplate(ctx context.Context, uri uri.URI, templateText string) (template parser.TemplateFile, ok bool, err error) {
-	template, err = parser.ParseString(templateText)
-	if err != nil {
+	template, err = parser.ParseString(templateText, p.ParseOpts...)
+	if err != nil && !template.HasErrors() {
 		msg := &lsp.PublishDiagnosticsParams{
 			URI: uri,
 			Diagnostics: []lsp.Diagnostic{
@@ -133,7 +143,11 @@ func (p *Server) parseTemplate(ctx context.Context, uri uri.URI, templateText st
 				},
 			},
 		}
-		if pe, isParserError := err.(parse.ParseError); isParserError {
+		if info, ok := parser.ErrorCodeOf(err); ok {
+			msg.Diagnostics[0].Code, msg.Diagnostics[0].CodeDescription = diagnosticCode(info.Code)
+		}
+		var pe parse.ParseError
+		if errors.As(err, &pe) {
 			msg.Diagnostics[0].Range = lsp.Range{
 				Start: lsp.Position{
 					Line:      uint32(pe.Pos.Line),
@@ -152,30 +166,31 @@ func (p *Server) parseTemplate(ctx context.Context, uri uri.URI, templateText st
 		}
 		return
 	}
+	// The parser recovers from errors within templates, so a partial template can still be
+	// used for source maps, while the errors are reported as diagnostics.
 	ok = true
-	if len(template.Diagnostics) > 0 {
+	err = nil
+	var diagnostics []lsp.Diagnostic
+	for _, d := range template.Diagnostics {
+		code, codeDescription := diagnosticCode(d.Code)
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Severity:        diagnosticSeverity(d.Severity),
+			Code:            code,
+			CodeDescription: codeDescription,
+			Source:          "templ",
+			Message:         d.Message,
+			Range:           diagnosticRange(d.Range),
+		})
+	}
+	// Lint findings in a template that has errors would be confusing.
+	if !template.HasErrors() {
+		diagnostics = append(diagnostics, p.lintDiagnostics(uri, templateText, template)...)
+	}
+	if len(diagnostics) > 0 {
 		msg := &lsp.PublishDiagnosticsParams{
-			URI: uri,
+			URI:         uri,
+			Diagnostics: p.DiagnosticCache.AddGoDiagnostics(string(uri), diagnostics),
 		}
-		for _, d := range template.Diagnostics {
-			msg.Diagnostics = append(msg.Diagnostics, lsp.Diagnostic{
-				Severity: lsp.DiagnosticSeverityWarning,
-				Code:     "",
-				Source:   "templ",
-				Message:  d.Message,
-				Range: lsp.Range{
-					Start: lsp.Position{
-						Line:      uint32(d.Range.From.Line),
-						Character: uint32(d.Range.From.Col),
-					},
-					End: lsp.Position{
-						Line:      uint32(d.Range.To.Line),
-						Character: uint32(d.Range.To.Col),
-					},
-				},
-			})
-		}
-		msg.Diagnostics = p.DiagnosticCache.AddGoDiagnostics(string(uri), msg.Diagnostics)
 		err = p.Client.PublishDiagnostics(ctx, msg)
 		if err != nil {
 			p.Log.Error("failed to publish error diagnostics", zap.Error(err))
@@ -196,6 +211,61 @@ func (p *Server) parseTemplate(ctx context.Context, uri uri.URI, templateText st
 	return
 }
 
+// lintDiagnostics returns the findings of templ lint as diagnostics, configured by the .templ-lint.json
+// file of the template's directory.
+func (p *Server) lintDiagnostics(uri uri.URI, templateText string, template parser.TemplateFile) (diagnostics []lsp.Diagnostic) {
+	fileName := uri.Filename()
+	config, err := lint.FindConfig(filepath.Dir(fileName))
+	if err != nil {
+		p.Log.Error("failed to read lint configuration", zap.String("uri", string(uri)), zap.Error(err))
+		return nil
+	}
+	l, err := lint.New(config)
+	if err != nil {
+		p.Log.Error("invalid lint configuration", zap.String("uri", string(uri)), zap.Error(err))
+		return nil
+	}
+	for _, f := range l.Lint(fileName, templateText, template) {
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Severity: diagnosticSeverity(f.Severity),
+			Code:     f.Rule,
+			Source:   "templ-lint",
+			Message:  f.Message,
+			Range:    diagnosticRange(f.Range),
+		})
+	}
+	return diagnostics
+}
+
+func diagnosticSeverity(s parser.DiagnosticSeverity) lsp.DiagnosticSeverity {
+	if s == parser.DiagnosticSeverityError {
+		return lsp.DiagnosticSeverityError
+	}
+	return lsp.DiagnosticSeverityWarning
+}
+
+func diagnosticRange(r parser.Range) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{
+			Line:      uint32(r.From.Line),
+			Character: uint32(r.From.Col),
+		},
+		End: lsp.Position{
+			Line:      uint32(r.To.Line),
+			Character: uint32(r.To.Col),
+		},
+	}
+}
+
+// diagnosticCode returns the LSP code of a templ error code, and a link to its documentation.
+func diagnosticCode(code parser.ErrorCode) (c string, description *lsp.CodeDescription) {
+	info, ok := parser.LookupErrorCode(string(code))
+	if !ok {
+		return "", nil
+	}
+	return string(info.Code), &lsp.CodeDescription{Href: lsp.URI(info.URL())}
+}
+
 func (p *Server) Initialize(ctx context.Context, params *lsp.InitializeParams) (result *lsp.InitializeResult, err error) {
 	p.Log.Info("client -> server: Initialize")
 	defer p.Log.Info("client -> server: Initialize end")
@@ -542,7 +612,7 @@ func (p *Server) DidChange(ctx context.Context, params *lsp.DidChangeTextDocumen
 		return
 	}
 	w := new(strings.Builder)
-	sm, _, err := generator.Generate(template, w)
+	sm, _, err := generator.Generate(template, w, generateOpts(params.TextDocument.URI)...)
 	if err != nil {
 		p.Log.Error("generate failure", zap.Error(err))
 		return
@@ -590,8 +660,12 @@ func (p *Server) DidClose(ctx context.Context, params *lsp.DidCloseTextDocumentP
 	p.TemplSource.Delete(string(params.TextDocument.URI))
 	p.SourceMapCache.Delete(string(params.TextDocument.URI))
 	// Get gopls to delete the Go file from its cache.
+	templURI := params.TextDocument.URI
 	params.TextDocument.URI = goURI
-	return p.Target.DidClose(ctx, params)
+	if err = p.Target.DidClose(ctx, params); err != nil {
+		return err
+	}
+	return p.updateCombinedDocument(ctx, templURI, "")
 }
 
 func (p *Server) DidOpen(ctx context.Context, params *lsp.DidOpenTextDocumentParams) (err error) {
@@ -615,7 +689,7 @@ func (p *Server) DidOpen(ctx context.Context, params *lsp.DidOpenTextDocumentPar
 	// Generate the output code and cache the source map and Go contents to use during completion
 	// requests.
 	w := new(strings.Builder)
-	sm, _, err := generator.Generate(template, w)
+	sm, _, err := generator.Generate(template, w, generateOpts(params.TextDocument.URI)...)
 	if err != nil {
 		return
 	}
@@ -624,6 +698,11 @@ func (p *Server) DidOpen(ctx context.Context, params *lsp.DidOpenTextDocumentPar
 	// Set the Go contents.
 	params.TextDocument.Text = w.String()
 	p.GoSource[string(params.TextDocument.URI)] = params.TextDocument.Text
+	// Replace the combined file before the code of the templ file is opened, so that its
+	// declarations aren't duplicated.
+	if err = p.updateCombinedDocument(ctx, params.TextDocument.URI, packageNameOf(template)); err != nil {
+		p.Log.Error("failed to update combined file", zap.Error(err))
+	}
 	// Change the path.
 	params.TextDocument.URI = goURI
 	return p.Target.DidOpen(ctx, params)
@@ -726,7 +805,8 @@ func (p *Server) Formatting(ctx context.Context, params *lsp.DocumentFormattingP
 	if err != nil {
 		p.Log.Error("parseTemplate failure", zap.Error(err))
 	}
-	if !ok {
+	// Don't format a partial template, because the contents that couldn't be parsed would be lost.
+	if !ok || template.HasErrors() {
 		return
 	}
 	w := new(strings.Builder)
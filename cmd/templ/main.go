@@ -2,16 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/a-h/templ"
+	"github.com/a-h/templ/cmd/templ/checkcmd"
+	"github.com/a-h/templ/cmd/templ/coveragecmd"
+	"github.com/a-h/templ/cmd/templ/explaincmd"
 	"github.com/a-h/templ/cmd/templ/fmtcmd"
 	"github.com/a-h/templ/cmd/templ/generatecmd"
+	"github.com/a-h/templ/cmd/templ/generatecmd/buildcache"
+	"github.com/a-h/templ/cmd/templ/generatecmd/proxy"
+	"github.com/a-h/templ/cmd/templ/lintcmd"
 	"github.com/a-h/templ/cmd/templ/lspcmd"
 	"github.com/a-h/templ/cmd/templ/migratecmd"
 	"github.com/fatih/color"
@@ -33,7 +43,11 @@ See docs at https://templ.guide
 commands:
   generate   Generates Go code from templ files
   fmt        Formats templ files
+  check      Type-checks and vets templ files
+  lint       Lints templ files
   lsp        Starts a language server for templ files
+  explain    Explains an error code
+  coverage   Maps a Go cover profile to templ files
   migrate    Migrates v1 templ files to v2 format
   version    Prints the version
 `
@@ -50,8 +64,16 @@ func run(w io.Writer, args []string) (code int) {
 		return migrateCmd(w, args[2:])
 	case "fmt":
 		return fmtCmd(w, args[2:])
+	case "check":
+		return checkCmd(w, args[2:])
+	case "lint":
+		return lintCmd(w, args[2:])
 	case "lsp":
 		return lspCmd(w, args[2:])
+	case "explain":
+		return explainCmd(w, args[2:])
+	case "coverage":
+		return coverageCmd(w, args[2:])
 	case "version":
 		fmt.Fprintln(w, templ.Version())
 		return 0
@@ -74,27 +96,62 @@ Args:
     Optionally generates code for a single file, e.g. -f header.templ
   -sourceMapVisualisations
     Set to true to generate HTML files to visualise the templ code and its corresponding Go code.
+  -source-maps
+    Set to true to write a JSON source map next to each generated file, e.g. header_templ.map.json.
+  -line-directives
+    Set to true to add //line directives to the generated code, so that Go tools report positions within templ files.
   -include-version
     Set to false to skip inclusion of the templ version in the generated code. (default true)
   -include-timestamp
     Set to true to include the current time in the generated code.
   -watch
     Set to true to watch the path for changes and regenerate code.
+  -check
+    Set to true to check that the generated code is up to date, without writing any files. Prints a diff of each file that's out of date, and exits with a non-zero exit code.
+  -json
+    Set to true to write newline-delimited JSON events to stdout, instead of log messages, which are written to stderr.
   -cmd <cmd>
-    Set the command to run after generating code.
+    Set the command to run after generating code. In watch mode, the command is stopped and started again after code is generated, and restarted if it crashes.
+  -cmd-watch <patterns>
+    Comma separated list of glob patterns of files that restart the command when they change in watch mode, e.g. *.go,internal/**/*.sql (default *.go)
+  -cmd-stop-timeout <duration>
+    How long to wait for the command to exit after it's sent SIGTERM, before it's killed. (default 5s)
   -proxy
     Set the URL to proxy after generating code and executing the command.
   -proxyport
     The port the proxy will listen on. (default 7331)
+  -proxybind
+    The address the proxy will listen on, e.g. 0.0.0.0 to accept connections from other machines. (default 127.0.0.1)
+  -proxy-https
+    Set to true to serve the proxy over HTTPS, with certificates issued by a local certificate authority.
+  -proxy-route <routes>
+    Comma separated list of path prefixes to proxy to other URLs, e.g. /api/=http://localhost:8081
+  -proxy-config <file>
+    The proxy configuration file. Arguments take precedence over the file. (default <path>/.templ-proxy.json, if it exists)
   -w
     Number of workers to use when generating code. (default runtime.NumCPUs)
   -pprof
     Port to run the pprof server on.
   -keep-orphaned-files
     Keeps orphaned generated templ files. (default false)
+  -cache-dir <dir>
+    The directory of the build cache, which is used to skip templ files that haven't changed since code was last generated. Set to an empty string to disable the cache. (default $XDG_CACHE_HOME/templ)
+  -whitespace-sensitive-elements <names>
+    Comma separated list of elements, in addition to pre, textarea and code, whose whitespace is preserved.
+  -output-dir <dir>
+    Generates code into a directory that mirrors the directories of the templ files, relative to the .templ-layout.json file, or the path.
+  -output-package <name>
+    Generates the code for the templ files of each directory into a subdirectory, as a separate package with the name.
+  -output-suffix <suffix>
+    The suffix of the names of generated files. (default _templ)
+  -output-combine
+    Generates the code for the templ files of each directory into a single file, templates_templ.go. (default false)
   -help
     Print help and exit.
 
+The -output flags take precedence over the .templ-layout.json file within the path, or a parent
+directory, up to the directory that contains the go.mod file.
+
 Examples:
 
   Generate code for all files in the current directory and subdirectories:
@@ -108,6 +165,14 @@ Examples:
   Watch the current directory and subdirectories for changes and regenerate code:
 
     templ generate -watch
+
+  Check that the generated code is up to date, e.g. in CI:
+
+    templ generate -check
+
+  Generate code into a gen directory, with a file for each package:
+
+    templ generate -output-dir gen -output-combine
 `
 
 func generateCmd(w io.Writer, args []string) (code int) {
@@ -116,16 +181,33 @@ func generateCmd(w io.Writer, args []string) (code int) {
 	fileNameFlag := cmd.String("f", "", "")
 	pathFlag := cmd.String("path", ".", "")
 	sourceMapVisualisationsFlag := cmd.Bool("source-map-visualisations", false, "")
+	sourceMapsFlag := cmd.Bool("source-maps", false, "")
+	lineDirectivesFlag := cmd.Bool("line-directives", false, "")
 	includeVersionFlag := cmd.Bool("include-version", true, "")
 	includeTimestampFlag := cmd.Bool("include-timestamp", false, "")
 	watchFlag := cmd.Bool("watch", false, "")
+	checkFlag := cmd.Bool("check", false, "")
+	jsonFlag := cmd.Bool("json", false, "")
 	openBrowserFlag := cmd.Bool("open-browser", true, "")
 	cmdFlag := cmd.String("cmd", "", "")
+	cmdWatchFlag := cmd.String("cmd-watch", "*.go", "")
+	cmdStopTimeoutFlag := cmd.Duration("cmd-stop-timeout", 5*time.Second, "")
 	proxyFlag := cmd.String("proxy", "", "")
 	proxyPortFlag := cmd.Int("proxyport", 7331, "")
+	proxyBindFlag := cmd.String("proxybind", "127.0.0.1", "")
+	proxyHTTPSFlag := cmd.Bool("proxy-https", false, "")
+	proxyRouteFlag := cmd.String("proxy-route", "", "")
+	proxyConfigFlag := cmd.String("proxy-config", "", "")
 	workerCountFlag := cmd.Int("w", runtime.NumCPU(), "")
 	pprofPortFlag := cmd.Int("pprof", 0, "")
 	keepOrphanedFilesFlag := cmd.Bool("keep-orphaned-files", false, "")
+	defaultCacheDir, _ := buildcache.DefaultDir()
+	cacheDirFlag := cmd.String("cache-dir", defaultCacheDir, "")
+	whitespaceSensitiveElementsFlag := cmd.String("whitespace-sensitive-elements", "", "")
+	outputDirFlag := cmd.String("output-dir", "", "")
+	outputPackageFlag := cmd.String("output-package", "", "")
+	outputSuffixFlag := cmd.String("output-suffix", "", "")
+	outputCombineFlag := cmd.Bool("output-combine", false, "")
 	helpFlag := cmd.Bool("help", false, "")
 	err := cmd.Parse(args)
 	if err != nil || *helpFlag {
@@ -133,6 +215,41 @@ func generateCmd(w io.Writer, args []string) (code int) {
 		return
 	}
 
+	proxyRoutes, err := parseProxyRoutes(*proxyRouteFlag)
+	if err != nil {
+		color.New(color.FgRed).Fprint(w, "(✗) ")
+		fmt.Fprintln(w, err.Error())
+		return 1
+	}
+	proxyConfig, err := readProxyConfig(*pathFlag, *proxyConfigFlag)
+	if err != nil {
+		color.New(color.FgRed).Fprint(w, "(✗) ")
+		fmt.Fprintln(w, err.Error())
+		return 1
+	}
+	// Arguments take precedence over the configuration file.
+	set := make(map[string]bool)
+	cmd.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if !set["proxy"] && proxyConfig.Target != "" {
+		*proxyFlag = proxyConfig.Target
+	}
+	if !set["proxyport"] && proxyConfig.Port != 0 {
+		*proxyPortFlag = proxyConfig.Port
+	}
+	if !set["proxybind"] && proxyConfig.Bind != "" {
+		*proxyBindFlag = proxyConfig.Bind
+	}
+	if !set["proxy-https"] && proxyConfig.HTTPS {
+		*proxyHTTPSFlag = true
+	}
+	for prefix, target := range proxyConfig.Routes {
+		if _, ok := proxyRoutes[prefix]; !ok {
+			proxyRoutes[prefix] = target
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt)
@@ -145,18 +262,37 @@ func generateCmd(w io.Writer, args []string) (code int) {
 		FileName:                        *fileNameFlag,
 		Path:                            *pathFlag,
 		Watch:                           *watchFlag,
+		Check:                           *checkFlag,
+		JSON:                            *jsonFlag,
 		OpenBrowser:                     *openBrowserFlag,
 		Command:                         *cmdFlag,
+		CommandWatch:                    splitList(*cmdWatchFlag),
+		CommandStopTimeout:              *cmdStopTimeoutFlag,
 		Proxy:                           *proxyFlag,
 		ProxyPort:                       *proxyPortFlag,
+		ProxyBind:                       *proxyBindFlag,
+		ProxyHTTPS:                      *proxyHTTPSFlag,
+		ProxyRoutes:                     proxyRoutes,
+		CacheDir:                        *cacheDirFlag,
 		WorkerCount:                     *workerCountFlag,
 		GenerateSourceMapVisualisations: *sourceMapVisualisationsFlag,
+		GenerateSourceMaps:              *sourceMapsFlag,
+		LineDirectives:                  *lineDirectivesFlag,
 		IncludeVersion:                  *includeVersionFlag,
 		IncludeTimestamp:                *includeTimestampFlag,
 		PPROFPort:                       *pprofPortFlag,
 		KeepOrphanedFiles:               *keepOrphanedFilesFlag,
+		WhitespaceSensitiveElements:     splitList(*whitespaceSensitiveElementsFlag),
+		OutputDir:                       *outputDirFlag,
+		OutputPackage:                   *outputPackageFlag,
+		OutputSuffix:                    *outputSuffixFlag,
+		OutputCombine:                   *outputCombineFlag,
 	})
 	if err != nil {
+		// The error has already been written as an event in JSON mode.
+		if *jsonFlag {
+			return 1
+		}
 		color.New(color.FgRed).Fprint(w, "(✗) ")
 		fmt.Fprintln(w, err.Error())
 		return 1
@@ -213,6 +349,10 @@ Format stdin to stdout:
   templ fmt < header.templ
 
 Args:
+  -whitespace-sensitive-elements <names>
+    Comma separated list of elements, in addition to pre, textarea and code, whose contents are not reformatted.
+  -json
+    Set to true to write newline-delimited JSON events to stdout, instead of log messages. Requires a path.
   -help
     Print help and exit.
 `
@@ -223,14 +363,140 @@ func fmtCmd(w io.Writer, args []string) (code int) {
 	cmd.Usage = func() {
 		fmt.Fprint(w, fmtUsageText)
 	}
+	whitespaceSensitiveElementsFlag := cmd.String("whitespace-sensitive-elements", "", "")
+	jsonFlag := cmd.Bool("json", false, "")
 	helpFlag := cmd.Bool("help", false, "")
 	err := cmd.Parse(args)
 	if err != nil || *helpFlag {
 		cmd.Usage()
 		return
 	}
-	err = fmtcmd.Run(w, args)
+	err = fmtcmd.Run(w, fmtcmd.Arguments{
+		Path:                        cmd.Arg(0),
+		WhitespaceSensitiveElements: splitList(*whitespaceSensitiveElementsFlag),
+		JSON:                        *jsonFlag,
+	})
+	if err != nil {
+		// The errors in the files have already been written as events in JSON mode.
+		if *jsonFlag && cmd.Arg(0) != "" {
+			return 1
+		}
+		fmt.Fprintln(w, err.Error())
+		return 1
+	}
+	return 0
+}
+
+const checkUsageText = `usage: templ check [<args>...]
+
+Generates Go code from templ files in memory, type-checks the packages that contain them, and
+reports the problems at their positions within the templ files.
+
+Args:
+  -path <path>
+    Checks the templ files in path. (default .)
+  -vet
+    Set to true to also run the go vet analyzers on the generated code.
+  -json
+    Set to true to write each problem as a JSON object on its own line.
+  -help
+    Print help and exit.
+
+Examples:
+
+  Check the templ files in the current directory and subdirectories:
+
+    templ check
+
+  Check and vet the templ files, and write the problems as JSON:
+
+    templ check -vet -json
+`
+
+func checkCmd(w io.Writer, args []string) (code int) {
+	cmd := flag.NewFlagSet("check", flag.ExitOnError)
+	cmd.SetOutput(w)
+	pathFlag := cmd.String("path", ".", "")
+	vetFlag := cmd.Bool("vet", false, "")
+	jsonFlag := cmd.Bool("json", false, "")
+	helpFlag := cmd.Bool("help", false, "")
+	err := cmd.Parse(args)
+	if err != nil || *helpFlag {
+		fmt.Fprint(w, checkUsageText)
+		return
+	}
+	err = checkcmd.Run(context.Background(), w, checkcmd.Arguments{
+		Path: *pathFlag,
+		Vet:  *vetFlag,
+		JSON: *jsonFlag,
+	})
+	if err != nil {
+		// The problems are the only output in JSON mode.
+		if *jsonFlag && errors.Is(err, checkcmd.ErrProblemsFound) {
+			return 1
+		}
+		color.New(color.FgRed).Fprint(w, "(✗) ")
+		fmt.Fprintln(w, err.Error())
+		return 1
+	}
+	return 0
+}
+
+const lintUsageText = `usage: templ lint [<args>...]
+
+Checks templ files for accessibility, security and maintenance problems.
+
+Rules are configured by a .templ-lint.json file within the directory of the templ file, or a
+parent directory, and findings can be suppressed with a templ-lint:ignore comment.
+
+Args:
+  -path <path>
+    Lints the templ files in path. (default .)
+  -config <file>
+    The configuration file to use for all templ files, instead of finding one for each.
+  -json
+    Set to true to write each finding as a JSON object on its own line.
+  -rules
+    Print the rules and exit.
+  -help
+    Print help and exit.
+
+Examples:
+
+  Lint the templ files in the current directory and subdirectories:
+
+    templ lint
+
+  List the rules:
+
+    templ lint -rules
+`
+
+func lintCmd(w io.Writer, args []string) (code int) {
+	cmd := flag.NewFlagSet("lint", flag.ExitOnError)
+	cmd.SetOutput(w)
+	pathFlag := cmd.String("path", ".", "")
+	configFlag := cmd.String("config", "", "")
+	jsonFlag := cmd.Bool("json", false, "")
+	rulesFlag := cmd.Bool("rules", false, "")
+	helpFlag := cmd.Bool("help", false, "")
+	err := cmd.Parse(args)
+	if err != nil || *helpFlag {
+		fmt.Fprint(w, lintUsageText)
+		return
+	}
+	err = lintcmd.Run(w, lintcmd.Arguments{
+		Path:           *pathFlag,
+		ConfigFileName: *configFlag,
+		JSON:           *jsonFlag,
+		ListRules:      *rulesFlag,
+	})
 	if err != nil {
+		// The findings are the only output in JSON mode.
+		if *jsonFlag && errors.Is(err, lintcmd.ErrProblemsFound) {
+			return 1
+		}
+		color.New(color.FgRed).Fprint(w, "(✗) ")
 		fmt.Fprintln(w, err.Error())
 		return 1
 	}
@@ -254,6 +520,8 @@ Args:
     Enable pprof web server (default address is localhost:9999)
   -http string
     Enable http debug server by setting a listen address (e.g. localhost:7474)
+  -whitespace-sensitive-elements string
+    Comma separated list of elements, in addition to pre, textarea and code, whose whitespace is preserved.
 `
 
 func lspCmd(w io.Writer, args []string) (code int) {
@@ -265,17 +533,57 @@ func lspCmd(w io.Writer, args []string) (code int) {
 	helpFlag := cmd.Bool("help", false, "")
 	pprofFlag := cmd.Bool("pprof", false, "")
 	httpDebugFlag := cmd.String("http", "", "")
+	whitespaceSensitiveElementsFlag := cmd.String("whitespace-sensitive-elements", "", "")
 	err := cmd.Parse(args)
 	if err != nil || *helpFlag {
 		fmt.Fprint(w, lspUsageText)
 		return
 	}
 	err = lspcmd.Run(w, lspcmd.Arguments{
-		Log:           *log,
-		GoplsLog:      *goplsLog,
-		GoplsRPCTrace: *goplsRPCTrace,
-		PPROF:         *pprofFlag,
-		HTTPDebug:     *httpDebugFlag,
+		Log:                         *log,
+		GoplsLog:                    *goplsLog,
+		GoplsRPCTrace:               *goplsRPCTrace,
+		PPROF:                       *pprofFlag,
+		HTTPDebug:                   *httpDebugFlag,
+		WhitespaceSensitiveElements: splitList(*whitespaceSensitiveElementsFlag),
+	})
+	if err != nil {
+		fmt.Fprintln(w, err.Error())
+		return 1
+	}
+	return 0
+}
+
+const explainUsageText = `usage: templ explain [<code>]
+
+Explains an error code, e.g. T1002. If no code is provided, all error codes are listed.
+
+Args:
+  -help
+    Print help and exit.
+
+Examples:
+
+  List all error codes:
+
+    templ explain
+
+  Explain an error code:
+
+    templ explain T1002
+`
+
+func explainCmd(w io.Writer, args []string) (code int) {
+	cmd := flag.NewFlagSet("explain", flag.ExitOnError)
+	cmd.SetOutput(w)
+	helpFlag := cmd.Bool("help", false, "")
+	err := cmd.Parse(args)
+	if err != nil || *helpFlag {
+		fmt.Fprint(w, explainUsageText)
+		return
+	}
+	err = explaincmd.Run(w, explaincmd.Arguments{
+		Code: cmd.Arg(0),
 	})
 	if err != nil {
 		fmt.Fprintln(w, err.Error())
@@ -283,3 +591,98 @@ func lspCmd(w io.Writer, args []string) (code int) {
 	}
 	return 0
 }
+
+const coverageUsageText = `usage: templ coverage [<args>...] <profile>
+
+Reads a Go cover profile, e.g. created by go test -coverprofile=cover.out, and writes the
+coverage of the templ files that the _templ.go files in the profile were generated from.
+
+Args:
+  -path <path>
+    A path within the Go module that was tested. (default .)
+  -format <format>
+    The format of the report, lcov or html. (default lcov)
+  -o <file>
+    The file to write the report to. (default templ.lcov, or templ-coverage.html)
+  -help
+    Print help and exit.
+
+Examples:
+
+  Write an lcov report:
+
+    go test -coverprofile=cover.out ./...
+    templ coverage cover.out
+
+  Write an HTML report:
+
+    templ coverage -format html -o coverage.html cover.out
+`
+
+func coverageCmd(w io.Writer, args []string) (code int) {
+	cmd := flag.NewFlagSet("coverage", flag.ExitOnError)
+	cmd.SetOutput(w)
+	pathFlag := cmd.String("path", ".", "")
+	formatFlag := cmd.String("format", "lcov", "")
+	outputFlag := cmd.String("o", "", "")
+	helpFlag := cmd.Bool("help", false, "")
+	err := cmd.Parse(args)
+	if err != nil || *helpFlag || cmd.Arg(0) == "" {
+		fmt.Fprint(w, coverageUsageText)
+		return
+	}
+	output := *outputFlag
+	if output == "" {
+		output = "templ.lcov"
+		if *formatFlag == "html" {
+			output = "templ-coverage.html"
+		}
+	}
+	err = coveragecmd.Run(w, coveragecmd.Arguments{
+		ProfileFileName: cmd.Arg(0),
+		Path:            *pathFlag,
+		Format:          *formatFlag,
+		Output:          output,
+	})
+	if err != nil {
+		color.New(color.FgRed).Fprint(w, "(✗) ")
+		fmt.Fprintln(w, err.Error())
+		return 1
+	}
+	return 0
+}
+
+// parseProxyRoutes parses a comma separated list of routes, e.g. "/api/=http://localhost:8081".
+func parseProxyRoutes(s string) (routes map[string]string, err error) {
+	routes = make(map[string]string)
+	for _, route := range splitList(s) {
+		prefix, target, ok := strings.Cut(route, "=")
+		if !ok || prefix == "" || target == "" {
+			return nil, fmt.Errorf("invalid proxy route %q, expected <prefix>=<url>, e.g. /api/=http://localhost:8081", route)
+		}
+		routes[prefix] = target
+	}
+	return routes, nil
+}
+
+// readProxyConfig reads the proxy configuration file. If no file name is given, the
+// configuration file within the path is read, if it exists.
+func readProxyConfig(path, fileName string) (c proxy.Config, err error) {
+	if fileName == "" {
+		fileName = filepath.Join(path, proxy.ConfigFileName)
+		if _, err = os.Stat(fileName); errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+	}
+	return proxy.ReadConfig(fileName)
+}
+
+// splitList splits a comma separated flag value into its non-empty items.
+func splitList(s string) (items []string) {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
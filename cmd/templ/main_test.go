@@ -75,6 +75,48 @@ func TestMain(t *testing.T) {
 			expected:     lspUsageText,
 			expectedCode: 0,
 		},
+		{
+			name:         `"templ explain --help" prints usage`,
+			args:         []string{"templ", "explain", "--help"},
+			expected:     explainUsageText,
+			expectedCode: 0,
+		},
+		{
+			name:         `"templ explain" with an unknown code fails`,
+			args:         []string{"templ", "explain", "T9999"},
+			expected:     "unknown error code \"T9999\", run `templ explain` to list all error codes\n",
+			expectedCode: 1,
+		},
+		{
+			name:         `"templ check --help" prints usage`,
+			args:         []string{"templ", "check", "--help"},
+			expected:     checkUsageText,
+			expectedCode: 0,
+		},
+		{
+			name:         `"templ lint --help" prints usage`,
+			args:         []string{"templ", "lint", "--help"},
+			expected:     lintUsageText,
+			expectedCode: 0,
+		},
+		{
+			name:         `"templ generate" with an invalid proxy route prints an error`,
+			args:         []string{"templ", "generate", "-proxy-route", "/api/"},
+			expected:     "(✗) invalid proxy route \"/api/\", expected <prefix>=<url>, e.g. /api/=http://localhost:8081\n",
+			expectedCode: 1,
+		},
+		{
+			name:         `"templ coverage" prints usage`,
+			args:         []string{"templ", "coverage"},
+			expected:     coverageUsageText,
+			expectedCode: 0,
+		},
+		{
+			name:         `"templ coverage --help" prints usage`,
+			args:         []string{"templ", "coverage", "--help"},
+			expected:     coverageUsageText,
+			expectedCode: 0,
+		},
 	}
 
 	for _, test := range tests {
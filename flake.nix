@@ -34,7 +34,7 @@
           name = "templ";
           src = gitignore.lib.gitignoreSource ./.;
           subPackages = [ "cmd/templ" ];
-          vendorHash = "sha256-4tHofTnSNI/MBmrGdGsLNoXjxUC0+Gwp3PzzUwfUkQU=";
+          vendorHash = "sha256-c6FG1kvz15mr3lIvEqwtGxrnbCDysbo0c5cUf/YXtDI=";
           CGO_ENABLED = 0;
           flags = [
             "-trimpath"
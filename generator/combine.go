@@ -0,0 +1,211 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// CombineFile is the formatted Go code that's been generated from a templ file.
+type CombineFile struct {
+	// Name of the templ file, used in error messages.
+	Name string
+	Code []byte
+	// SourceMap of the code, which may be nil.
+	SourceMap *parser.SourceMap
+	// Literals are the strings that were extracted with WithExtractStrings.
+	Literals string
+}
+
+// Combine combines the code that's been generated from the templ files of a package into a
+// single Go file. The comments before the package clause are taken from the first file, and
+// the imports of the files are merged into a single import declaration.
+//
+// A source map is returned for each file that has one, with the target positions moved to the
+// position of the code within the combined file. Mappings within the package clause and the
+// imports are removed. The extracted strings are concatenated, and the indices of the strings
+// in the generated code are updated to match.
+func Combine(files []CombineFile) (code []byte, sourceMaps []*parser.SourceMap, literals string, err error) {
+	if len(files) == 0 {
+		return nil, nil, "", fmt.Errorf("no files to combine")
+	}
+	parts := make([]combinePart, len(files))
+	for i, f := range files {
+		if parts[i], err = splitGeneratedCode(f.Name, f.Code); err != nil {
+			return nil, nil, "", err
+		}
+		if parts[i].pkg != parts[0].pkg {
+			return nil, nil, "", fmt.Errorf("%s: package %s doesn't match package %s of %s", f.Name, parts[i].pkg, parts[0].pkg, files[0].Name)
+		}
+	}
+	imports, err := mergeImports(files, parts)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	var b bytes.Buffer
+	b.Write(parts[0].header)
+	b.WriteString("package " + parts[0].pkg + "\n\n")
+	b.WriteString("//lint:file-ignore SA4006 This context is only used if a nested component is present.\n\n")
+	b.WriteString("import (\n")
+	for _, imp := range imports {
+		b.WriteString("\t" + imp.String() + "\n")
+	}
+	b.WriteString(")\n")
+
+	// lineShifts are the number of lines that the body of each file has moved by.
+	lineShifts := make([]int, len(files))
+	var literalCount int
+	var lb strings.Builder
+	for i, f := range files {
+		b.WriteString("\n")
+		lineShifts[i] = bytes.Count(b.Bytes(), []byte("\n")) - parts[i].bodyLine
+		b.Write(renumberLiterals(parts[i].body, literalCount))
+		literalCount += strings.Count(f.Literals, "\n")
+		lb.WriteString(f.Literals)
+	}
+	code = b.Bytes()
+
+	lineStarts := []int64{0}
+	for i, c := range code {
+		if c == '\n' {
+			lineStarts = append(lineStarts, int64(i+1))
+		}
+	}
+	sourceMaps = make([]*parser.SourceMap, len(files))
+	for i, f := range files {
+		if f.SourceMap == nil {
+			continue
+		}
+		sourceMaps[i] = parser.NewSourceMap()
+		for _, m := range f.SourceMap.Mappings() {
+			if int(m.Target.Line) < parts[i].bodyLine {
+				continue
+			}
+			// The lines of the body are copied as they are, except for the indices of the
+			// extracted strings, which aren't mapped, so only the start of the line moves.
+			line := uint32(int(m.Target.Line) + lineShifts[i])
+			m.Target = parser.NewPosition(lineStarts[line]+int64(m.Target.Col), line, m.Target.Col)
+			sourceMaps[i].AddMapping(m)
+		}
+	}
+	return code, sourceMaps, lb.String(), nil
+}
+
+// combinePart is the generated code of a templ file, split into the parts that are combined.
+type combinePart struct {
+	// header is the comments before the package clause.
+	header []byte
+	pkg    string
+	// imports are the import specs of the file, in order.
+	imports []importSpec
+	// body is the code after the imports, and bodyLine is the zero-based line that it starts on.
+	body     []byte
+	bodyLine int
+}
+
+type importSpec struct {
+	name string
+	path string
+}
+
+func (is importSpec) String() string {
+	if is.name == "" {
+		return is.path
+	}
+	return is.name + " " + is.path
+}
+
+func splitGeneratedCode(name string, code []byte) (p combinePart, err error) {
+	fset := token.NewFileSet()
+	f, err := goparser.ParseFile(fset, name, code, goparser.ImportsOnly)
+	if err != nil {
+		return p, fmt.Errorf("%s: failed to parse generated code: %w", name, err)
+	}
+	file := fset.File(f.Package)
+	p.header = code[:file.Offset(f.Package)]
+	p.pkg = f.Name.Name
+	end := file.Offset(f.Name.End())
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		end = file.Offset(gd.End())
+		for _, spec := range gd.Specs {
+			is := spec.(*ast.ImportSpec)
+			var imp importSpec
+			if is.Name != nil {
+				imp.name = is.Name.Name
+			}
+			imp.path = is.Path.Value
+			p.imports = append(p.imports, imp)
+		}
+	}
+	// The body starts on the line after the package clause, or the last import, and the blank
+	// lines before it are removed.
+	if i := bytes.IndexByte(code[end:], '\n'); i >= 0 {
+		end += i + 1
+	} else {
+		end = len(code)
+	}
+	for end < len(code) && code[end] == '\n' {
+		end++
+	}
+	p.body = code[end:]
+	p.bodyLine = bytes.Count(code[:end], []byte("\n"))
+	return p, nil
+}
+
+// mergeImports returns the imports of all of the parts, without duplicates, sorted by path.
+func mergeImports(files []CombineFile, parts []combinePart) (imports []importSpec, err error) {
+	seen := make(map[importSpec]bool)
+	// pathOfName is the path that each explicitly named import refers to.
+	pathOfName := make(map[string]string)
+	fileOfName := make(map[string]string)
+	for i, p := range parts {
+		for _, imp := range p.imports {
+			if seen[imp] {
+				continue
+			}
+			seen[imp] = true
+			if imp.name != "" && imp.name != "_" && imp.name != "." {
+				if path, ok := pathOfName[imp.name]; ok && path != imp.path {
+					return nil, fmt.Errorf("%s: import %s %s conflicts with import %s %s of %s", files[i].Name, imp.name, imp.path, imp.name, path, fileOfName[imp.name])
+				}
+				pathOfName[imp.name] = imp.path
+				fileOfName[imp.name] = files[i].Name
+			}
+			imports = append(imports, imp)
+		}
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		if imports[i].path != imports[j].path {
+			return imports[i].path < imports[j].path
+		}
+		return imports[i].name < imports[j].name
+	})
+	return imports, nil
+}
+
+var watchModeStringCall = regexp.MustCompile(`templ\.WriteWatchModeString\(templ_7745c5c3_Buffer, (\d+)\)`)
+
+// renumberLiterals adds offset to the indices of the strings that are written in watch mode.
+func renumberLiterals(body []byte, offset int) []byte {
+	if offset == 0 {
+		return body
+	}
+	return watchModeStringCall.ReplaceAllFunc(body, func(call []byte) []byte {
+		m := watchModeStringCall.FindSubmatch(call)
+		index, _ := strconv.Atoi(string(m[1]))
+		return []byte("templ.WriteWatchModeString(templ_7745c5c3_Buffer, " + strconv.Itoa(index+offset) + ")")
+	})
+}
@@ -0,0 +1,160 @@
+package generator
+
+import (
+	"bytes"
+	"go/format"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ/parser/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCombine(t *testing.T) {
+	sources := []string{`package views
+
+import "fmt"
+
+// Title of the page.
+templ Title(n int) {
+	<h1>{ fmt.Sprint(n) }</h1>
+}
+`, `package views
+
+import (
+	"fmt"
+	"strings"
+)
+
+templ Upper(s string) {
+	<p>Upper: { strings.ToUpper(s) }</p>
+	<p>{ fmt.Sprint(len(s)) }</p>
+}
+
+css red() {
+	color: red;
+}
+`}
+	files := make([]CombineFile, len(sources))
+	for i, src := range sources {
+		tf, err := parser.ParseString(src)
+		if err != nil {
+			t.Fatalf("failed to parse template: %v", err)
+		}
+		w := new(bytes.Buffer)
+		sm, literals, err := Generate(tf, w, WithExtractStrings())
+		if err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		formatted, formattedSourceMap, err := Format(w.Bytes(), sm)
+		if err != nil {
+			t.Fatalf("failed to format: %v", err)
+		}
+		files[i] = CombineFile{Name: "file" + string(rune('a'+i)) + ".templ", Code: formatted, SourceMap: formattedSourceMap, Literals: literals}
+	}
+
+	code, sourceMaps, literals, err := Combine(files)
+	if err != nil {
+		t.Fatalf("failed to combine: %v", err)
+	}
+	formatted, err := format.Source(code)
+	if err != nil {
+		t.Fatalf("combined code is not valid Go: %v\n%s", err, code)
+	}
+	if diff := cmp.Diff(string(formatted), string(code)); diff != "" {
+		t.Errorf("combined code is not formatted:\n%s", diff)
+	}
+	if strings.Count(string(code), "\npackage views\n") != 1 {
+		t.Errorf("expected a single package clause, got:\n%s", code)
+	}
+	if !strings.Contains(string(code), "import (\n\t\"bytes\"\n\t\"context\"\n\t\"fmt\"\n\t\"github.com/a-h/templ\"\n\t\"io\"\n\t\"strings\"\n)\n") {
+		t.Errorf("expected the imports to be merged, got:\n%s", code)
+	}
+	if !strings.Contains(string(code), "// Title of the page.\nfunc Title(") {
+		t.Errorf("expected comments to be kept, got:\n%s", code)
+	}
+
+	t.Run("source maps point to the combined code", func(t *testing.T) {
+		for i, sm := range sourceMaps {
+			if len(sm.Mappings()) == 0 {
+				t.Fatalf("file %d: expected mappings", i)
+			}
+			for _, m := range sm.Mappings() {
+				expected := sources[i][m.Source.Index : m.Source.Index+int64(m.Length)]
+				// The last column of a mapping can be the end of the line.
+				actual := string(code[m.Target.Index : m.Target.Index+int64(m.Length)])
+				if strings.TrimSuffix(actual, "\n") != strings.TrimSuffix(expected, "\n") {
+					t.Errorf("file %d: %+v: expected target %q, got %q", i, m, expected, actual)
+				}
+			}
+		}
+		tgt, ok := sourceMaps[1].TargetPositionFromSource(8, 13)
+		if !ok {
+			t.Fatal("expected the expression to be mapped")
+		}
+		if actual := string(code[tgt.Index : tgt.Index+int64(len("strings.ToUpper"))]); actual != "strings.ToUpper" {
+			t.Errorf("expected %q, got %q", "strings.ToUpper", actual)
+		}
+	})
+	t.Run("the indices of extracted strings are updated", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSuffix(literals, "\n"), "\n")
+		for _, m := range watchModeStringCall.FindAllSubmatch(code, -1) {
+			index, err := strconv.Atoi(string(m[1]))
+			if err != nil {
+				t.Fatalf("invalid index: %v", err)
+			}
+			if index < 1 || index > len(lines) {
+				t.Fatalf("index %d is out of range of %d strings", index, len(lines))
+			}
+		}
+		var upper bool
+		for _, line := range lines {
+			if strings.Contains(line, "Upper: ") {
+				upper = true
+			}
+		}
+		if !upper {
+			t.Errorf("expected the strings of the second file, got %q", literals)
+		}
+		if strings.Count(literals, "\n") != strings.Count(files[0].Literals, "\n")+strings.Count(files[1].Literals, "\n") {
+			t.Errorf("expected the strings to be concatenated, got %q", literals)
+		}
+	})
+}
+
+func TestCombineErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []CombineFile
+		expected string
+	}{
+		{
+			name: "packages must match",
+			files: []CombineFile{
+				{Name: "a.templ", Code: []byte("package a\n\nimport \"fmt\"\n\nvar A = fmt.Sprint\n")},
+				{Name: "b.templ", Code: []byte("package b\n\nvar B = 1\n")},
+			},
+			expected: "b.templ: package b doesn't match package a of a.templ",
+		},
+		{
+			name: "imports with the same name must have the same path",
+			files: []CombineFile{
+				{Name: "a.templ", Code: []byte("package a\n\nimport x \"fmt\"\n\nvar A = x.Sprint\n")},
+				{Name: "b.templ", Code: []byte("package a\n\nimport x \"strings\"\n\nvar B = x.ToUpper\n")},
+			},
+			expected: `b.templ: import x "strings" conflicts with import x "fmt" of a.templ`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := Combine(tt.files)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"fmt"
+	"go/format"
+	"go/scanner"
+	"go/token"
+	"sort"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// Format formats generated Go code with gofmt, and returns a source map whose target
+// positions are within the formatted code.
+func Format(src []byte, sm *parser.SourceMap) (formatted []byte, formattedSourceMap *parser.SourceMap, err error) {
+	if formatted, err = format.Source(src); err != nil {
+		return nil, nil, err
+	}
+	if formattedSourceMap, err = RemapSourceMap(src, formatted, sm); err != nil {
+		return nil, nil, err
+	}
+	return formatted, formattedSourceMap, nil
+}
+
+// RemapSourceMap returns a source map whose target positions are within dst, where sm is the
+// source map returned by Generate for src, and dst contains the same Go tokens as src, e.g.
+// because dst is src after formatting, or dst was generated from the same templ file with
+// different options.
+//
+// Each mapping is split into the tokens that it covers, and each token is mapped to the same
+// token within dst. Comments, and the whitespace between tokens, aren't mapped.
+func RemapSourceMap(src, dst []byte, sm *parser.SourceMap) (dstSourceMap *parser.SourceMap, err error) {
+	before, after := scanTokens(src), scanTokens(dst)
+	if len(before) != len(after) {
+		return nil, fmt.Errorf("the number of tokens changed from %d to %d", len(before), len(after))
+	}
+	lineStarts := newLineStarts(dst)
+
+	dstSourceMap = parser.NewSourceMap()
+	for _, m := range sm.Mappings() {
+		// The last column of each mapping is the end of the line, which is only mapped for LSPs.
+		from, to := int(m.Target.Index), int(m.Target.Index)+int(m.Length)-1
+		// Find the first token that ends after the start of the mapping.
+		i := sort.Search(len(before), func(i int) bool {
+			return before[i].offset+before[i].length > from
+		})
+		for ; i < len(before) && before[i].offset < to; i++ {
+			// The file name within error messages can differ.
+			if before[i].length != after[i].length {
+				continue
+			}
+			start, end := before[i].offset, before[i].offset+before[i].length
+			if start < from {
+				start = from
+			}
+			if end > to {
+				end = to
+			}
+			tokenOffset := start - before[i].offset
+			dstSourceMap.AddMapping(parser.SourceMapping{
+				Source: parser.NewPosition(m.Source.Index+int64(start-from), m.Source.Line, m.Source.Col+uint32(start-from)),
+				Target: lineStarts.position(after[i].offset + tokenOffset),
+				Length: uint32(end - start),
+			})
+		}
+	}
+	return dstSourceMap, nil
+}
+
+type scannedToken struct {
+	offset int
+	length int
+}
+
+// scanTokens returns the offset and length of each token within src.
+func scanTokens(src []byte) (tokens []scannedToken) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return tokens
+		}
+		// Skip semicolons that are inserted automatically at the end of lines.
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		length := len(lit)
+		if length == 0 {
+			length = len(tok.String())
+		}
+		tokens = append(tokens, scannedToken{offset: file.Offset(pos), length: length})
+	}
+}
+
+type lineStarts []int
+
+func newLineStarts(src []byte) lineStarts {
+	starts := lineStarts{0}
+	for i, b := range src {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// position returns the zero-based line and column of the byte offset.
+func (ls lineStarts) position(offset int) parser.Position {
+	line := sort.Search(len(ls), func(i int) bool { return ls[i] > offset }) - 1
+	return parser.NewPosition(int64(offset), uint32(line), uint32(offset-ls[line]))
+}
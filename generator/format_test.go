@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+func TestFormat(t *testing.T) {
+	src := `package main
+
+templ page(items []string, class string) {
+	<ul class={ class }>
+		for _, item := range items {
+			<li>{ item+"!" }</li>
+		}
+	</ul>
+}
+`
+	tf, err := parser.ParseString(src)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	w := new(bytes.Buffer)
+	sm, _, err := Generate(tf, w)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	formatted, formattedSourceMap, err := Format(w.Bytes(), sm)
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	if len(formattedSourceMap.Mappings()) == 0 {
+		t.Fatal("expected mappings")
+	}
+	lines := bytes.Split(formatted, []byte("\n"))
+	for _, m := range formattedSourceMap.Mappings() {
+		expected := src[m.Source.Index : m.Source.Index+int64(m.Length)]
+		if actual := string(formatted[m.Target.Index : m.Target.Index+int64(m.Length)]); actual != expected {
+			t.Errorf("%+v: expected target %q, got %q", m, expected, actual)
+		}
+		if actual := string(lines[m.Target.Line][m.Target.Col : m.Target.Col+m.Length]); actual != expected {
+			t.Errorf("%+v: expected target line and col to contain %q, got %q", m, expected, actual)
+		}
+	}
+	// gofmt adds spaces around the + operator, so the expression is split into tokens.
+	tgt, ok := formattedSourceMap.TargetPositionFromSource(5, 14)
+	if !ok {
+		t.Fatal("expected the string to be mapped")
+	}
+	if actual := string(formatted[tgt.Index : tgt.Index+3]); actual != `"!"` {
+		t.Errorf("expected %q, got %q", `"!"`, actual)
+	}
+}
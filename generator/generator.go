@@ -4,6 +4,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"go/scanner"
+	"go/token"
 	"html"
 	"io"
 	"path/filepath"
@@ -57,6 +59,38 @@ func WithExtractStrings() GenerateOpt {
 	}
 }
 
+// WithLineDirectives adds //line directives around the Go expressions from the templ file, so
+// that compiler errors, panics, test failures and debuggers report positions within the templ
+// file instead of the generated Go code. The templ file name must be set with WithFileName.
+func WithLineDirectives() GenerateOpt {
+	return func(g *generator) error {
+		g.lineDirectives = true
+		return nil
+	}
+}
+
+// WithPackageName sets the name of the package of the generated code, instead of the package of
+// the templ file, e.g. when the code is generated into a separate package.
+func WithPackageName(name string) GenerateOpt {
+	return func(g *generator) error {
+		if !token.IsIdentifier(name) {
+			return fmt.Errorf("invalid package name %q", name)
+		}
+		g.packageName = name
+		return nil
+	}
+}
+
+// WithGeneratedFileName sets the name of the generated Go file, which is used by the //line
+// directives that restore positions within it. Defaults to the name of the templ file, with
+// the .templ extension replaced by _templ.go.
+func WithGeneratedFileName(name string) GenerateOpt {
+	return func(g *generator) error {
+		g.generatedFileName = filepath.Base(name)
+		return nil
+	}
+}
+
 // Generate generates Go code from the input template file to w, and returns a map of the location of Go expressions in the template
 // to the location of the generated Go code in the output.
 func Generate(template parser.TemplateFile, w io.Writer, opts ...GenerateOpt) (sm *parser.SourceMap, literals string, err error) {
@@ -89,9 +123,22 @@ type generator struct {
 	generatedDate string
 	// fileName to include in error messages if string expressions return an error.
 	fileName string
+	// xml is true while an XML template is being written.
+	xml bool
+	// text is true while a text template is being written.
+	text bool
+	// lineDirectives is true if //line directives are written around mapped expressions.
+	lineDirectives bool
+	// packageName overrides the package of the templ file.
+	packageName string
+	// generatedFileName is the name of the generated Go file in //line directives.
+	generatedFileName string
 }
 
 func (g *generator) generate() (err error) {
+	if g.lineDirectives && g.fileName == "" {
+		return fmt.Errorf("line directives require the templ file name, use WithFileName")
+	}
 	if err = g.writeCodeGeneratedComment(); err != nil {
 		return
 	}
@@ -136,20 +183,108 @@ func (g *generator) writePackage() error {
 	var r parser.Range
 	var err error
 	// package ...
-	if r, err = g.w.Write(g.tf.Package.Expression.Value + "\n\n"); err != nil {
-		return err
+	if g.packageName != "" {
+		// The package clause isn't mapped, because it doesn't match the templ file.
+		if _, err = g.w.Write("package " + g.packageName + "\n\n"); err != nil {
+			return err
+		}
+	} else {
+		if r, err = g.w.Write(g.tf.Package.Expression.Value + "\n\n"); err != nil {
+			return err
+		}
+		g.sourceMap.Add(g.tf.Package.Expression, r)
 	}
-	g.sourceMap.Add(g.tf.Package.Expression, r)
 	if _, err = g.w.Write("//lint:file-ignore SA4006 This context is only used if a nested component is present.\n\n"); err != nil {
 		return err
 	}
 	return nil
 }
 
+// writeMapped writes a Go expression from the templ file, and adds it to the source map.
+func (g *generator) writeMapped(expr parser.Expression) (err error) {
+	if err = g.writeTemplLineDirective(expr.Range.From); err != nil {
+		return err
+	}
+	r, err := g.w.Write(expr.Value)
+	if err != nil {
+		return err
+	}
+	g.sourceMap.Add(expr, r)
+	return g.writeGeneratedLineDirective(expr.Value)
+}
+
+func (g *generator) writeUnmapped(expr parser.Expression) (err error) {
+	_, err = g.w.Write(expr.Value)
+	return err
+}
+
+// writeTemplLineDirective sets the position of the next character to pos within the templ file.
+func (g *generator) writeTemplLineDirective(pos parser.Position) (err error) {
+	if !g.lineDirectives {
+		return nil
+	}
+	// Close any open string literal, so that the directive is written where the expression starts.
+	if _, err = g.w.Write(""); err != nil {
+		return err
+	}
+	// Line directives are 1-based.
+	fileName, line, col := filepath.Base(g.fileName), pos.Line+1, pos.Col+1
+	// A //line directive sets the position of the start of the next line.
+	if g.w.Current.Col == 0 {
+		_, err = g.w.Write(fmt.Sprintf("//line %s:%d:%d\n", fileName, line, col))
+		return err
+	}
+	// A /*line*/ directive sets the position of the character that follows it. gofmt adds a
+	// space after the comment, so the directive is written for the space before the expression.
+	if col == 1 {
+		_, err = g.w.Write(fmt.Sprintf("/*line %s:%d*/ ", fileName, line))
+		return err
+	}
+	_, err = g.w.Write(fmt.Sprintf("/*line %s:%d:%d*/ ", fileName, line, col-1))
+	return err
+}
+
+// writeGeneratedLineDirective restores the position within the generated Go file, after the
+// expression has been written. The column isn't set, because gofmt may move the code.
+func (g *generator) writeGeneratedLineDirective(expr string) (err error) {
+	if !g.lineDirectives {
+		return nil
+	}
+	// A /*line*/ directive after a line comment would be part of the comment.
+	if endsWithLineComment(expr) {
+		if _, err = g.w.Write("\n"); err != nil {
+			return err
+		}
+	}
+	fileName := g.generatedFileName
+	if fileName == "" {
+		fileName = strings.TrimSuffix(filepath.Base(g.fileName), ".templ") + "_templ.go"
+	}
+	_, err = g.w.Write(fmt.Sprintf("/*line %s:%d*/", fileName, g.w.Current.Line+1))
+	return err
+}
+
+func endsWithLineComment(expr string) bool {
+	lines := strings.Split(expr, "\n")
+	lastLine := []byte(lines[len(lines)-1])
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", fset.Base(), len(lastLine)), lastLine, nil, scanner.ScanComments)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return false
+		}
+		if tok == token.COMMENT && strings.HasPrefix(lit, "//") {
+			return true
+		}
+	}
+}
+
 func (g *generator) templateNodeInfo() (hasTemplates bool, hasCSS bool) {
 	for _, n := range g.tf.Nodes {
 		switch n.(type) {
-		case parser.HTMLTemplate:
+		case parser.HTMLTemplate, parser.XMLTemplate, parser.TextTemplate:
 			hasTemplates = true
 		case parser.CSSTemplate:
 			hasCSS = true
@@ -205,6 +340,14 @@ func (g *generator) writeTemplateNodes() error {
 			if err := g.writeTemplate(i, n); err != nil {
 				return err
 			}
+		case parser.XMLTemplate:
+			if err := g.writeXMLTemplate(i, n); err != nil {
+				return err
+			}
+		case parser.TextTemplate:
+			if err := g.writeTextTemplate(i, n); err != nil {
+				return err
+			}
 		case parser.CSSTemplate:
 			if err := g.writeCSS(n); err != nil {
 				return err
@@ -221,7 +364,6 @@ func (g *generator) writeTemplateNodes() error {
 }
 
 func (g *generator) writeCSS(n parser.CSSTemplate) error {
-	var r parser.Range
 	var err error
 	var indentLevel int
 
@@ -229,10 +371,9 @@ func (g *generator) writeCSS(n parser.CSSTemplate) error {
 	if _, err = g.w.Write("func "); err != nil {
 		return err
 	}
-	if r, err = g.w.Write(n.Name.Value); err != nil {
+	if err = g.writeMapped(n.Name); err != nil {
 		return err
 	}
-	g.sourceMap.Add(n.Name, r)
 	// () templ.CSSClass {
 	if _, err = g.w.Write("() templ.CSSClass {\n"); err != nil {
 		return err
@@ -255,10 +396,9 @@ func (g *generator) writeCSS(n parser.CSSTemplate) error {
 				if _, err = g.w.WriteIndent(indentLevel, fmt.Sprintf("templ_7745c5c3_CSSBuilder.WriteString(string(templ.SanitizeCSS(`%s`, ", p.Name)); err != nil {
 					return err
 				}
-				if r, err = g.w.Write(p.Value.Expression.Value); err != nil {
+				if err = g.writeMapped(p.Value.Expression); err != nil {
 					return err
 				}
-				g.sourceMap.Add(p.Value.Expression, r)
 				if _, err = g.w.Write(")))\n"); err != nil {
 					return err
 				}
@@ -298,11 +438,9 @@ func (g *generator) writeCSS(n parser.CSSTemplate) error {
 }
 
 func (g *generator) writeGoExpression(n parser.TemplateFileGoExpression) (err error) {
-	r, err := g.w.Write(n.Expression.Value)
-	if err != nil {
+	if err = g.writeMapped(n.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(n.Expression, r)
 	v := n.Expression.Value
 	lineSlice := strings.Split(v, "\n")
 	lastLine := lineSlice[len(lineSlice)-1]
@@ -344,25 +482,68 @@ func (g *generator) writeTemplBuffer(indentLevel int) (err error) {
 	return
 }
 
+// writeXMLTemplate writes an XML template. XML templates are written in the same way as HTML
+// templates, but the output follows XML rules.
+func (g *generator) writeXMLTemplate(nodeIdx int, t parser.XMLTemplate) error {
+	g.xml = true
+	defer func() { g.xml = false }()
+	return g.writeTemplate(nodeIdx, parser.HTMLTemplate{
+		Diagnostics: t.Diagnostics,
+		Expression:  t.Expression,
+		Children:    t.Children,
+	})
+}
+
+// writeTextTemplate writes a text template. The output of string expressions is not escaped.
+func (g *generator) writeTextTemplate(nodeIdx int, t parser.TextTemplate) error {
+	g.text = true
+	defer func() { g.text = false }()
+	return g.writeTemplate(nodeIdx, parser.HTMLTemplate{
+		Expression: t.Expression,
+		Children:   t.Children,
+	})
+}
+
 func (g *generator) writeTemplate(nodeIdx int, t parser.HTMLTemplate) error {
-	var r parser.Range
 	var err error
 	var indentLevel int
 
+	sig, err := parseTemplateSignature(t.Expression)
+	hasOptionalParameters := err == nil
+	if err != nil && err != errNoDefaultParameters {
+		return err
+	}
+	if hasOptionalParameters {
+		if err = g.writeTemplateOptions(sig); err != nil {
+			return err
+		}
+	}
+
 	// func
 	if _, err = g.w.Write("func "); err != nil {
 		return err
 	}
-	// (r *Receiver) Name(params []string)
-	if r, err = g.w.Write(t.Expression.Value); err != nil {
-		return err
+	if hasOptionalParameters {
+		// Name(params []string, templ_7745c5c3_Opts ...NameOpt)
+		if err = g.writeTemplateSignature(sig); err != nil {
+			return err
+		}
+	} else {
+		// (r *Receiver) Name(params []string)
+		if err = g.writeMapped(t.Expression); err != nil {
+			return err
+		}
 	}
-	g.sourceMap.Add(t.Expression, r)
 	// templ.Component {
 	if _, err = g.w.Write(" templ.Component {\n"); err != nil {
 		return err
 	}
 	indentLevel++
+	if hasOptionalParameters {
+		if err = g.writeTemplateOptionsResolution(indentLevel, sig); err != nil {
+			return err
+		}
+	}
 	// return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
 	if _, err = g.w.WriteIndent(indentLevel, "return templ.ComponentFunc(func(ctx context.Context, templ_7745c5c3_W io.Writer) (templ_7745c5c3_Err error) {\n"); err != nil {
 		return err
@@ -446,6 +627,134 @@ func (g *generator) writeTemplate(nodeIdx int, t parser.HTMLTemplate) error {
 	return nil
 }
 
+// writeTemplateOptions writes the types and functions used to set the optional parameters of a template.
+func (g *generator) writeTemplateOptions(sig templateSignature) (err error) {
+	optsType, optType := sig.OptionsTypeName(), sig.OptionTypeName()
+	// type NameOpts struct {
+	if _, err = g.w.Write(fmt.Sprintf("// %s contains the optional parameters of %s.\n", optsType, sig.Name)); err != nil {
+		return err
+	}
+	if _, err = g.w.Write("type " + optsType + " struct {\n"); err != nil {
+		return err
+	}
+	for _, p := range sig.Optional {
+		// Variant string
+		if _, err = g.w.WriteIndent(1, p.FieldName()+" "); err != nil {
+			return err
+		}
+		if err = g.writeMapped(p.Type); err != nil {
+			return err
+		}
+		if _, err = g.w.Write("\n"); err != nil {
+			return err
+		}
+	}
+	if _, err = g.w.Write("}\n\n"); err != nil {
+		return err
+	}
+	// type NameOpt func(*NameOpts)
+	if _, err = g.w.Write(fmt.Sprintf("// %s sets an optional parameter of %s.\n", optType, sig.Name)); err != nil {
+		return err
+	}
+	if _, err = g.w.Write(fmt.Sprintf("type %s func(*%s)\n\n", optType, optsType)); err != nil {
+		return err
+	}
+	for _, p := range sig.Optional {
+		// func NameVariant(v string) NameOpt {
+		fn := sig.OptionFuncName(p)
+		if _, err = g.w.Write(fmt.Sprintf("// %s sets the %s parameter of %s.\n", fn, p.Name.Value, sig.Name)); err != nil {
+			return err
+		}
+		if _, err = g.w.Write(fmt.Sprintf("func %s(v %s) %s {\n", fn, p.Type.Value, optType)); err != nil {
+			return err
+		}
+		if _, err = g.w.WriteIndent(1, fmt.Sprintf("return func(o *%s) {\n", optsType)); err != nil {
+			return err
+		}
+		if _, err = g.w.WriteIndent(2, fmt.Sprintf("o.%s = v\n", p.FieldName())); err != nil {
+			return err
+		}
+		if _, err = g.w.WriteIndent(1, "}\n"); err != nil {
+			return err
+		}
+		if _, err = g.w.Write("}\n\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTemplateSignature writes the function signature of a template with optional parameters.
+func (g *generator) writeTemplateSignature(sig templateSignature) (err error) {
+	// Name(params []string
+	if err = g.writeMapped(sig.Prefix); err != nil {
+		return err
+	}
+	// , templ_7745c5c3_Opts ...NameOpt)
+	separator := ""
+	if sig.HasRequired {
+		separator = ", "
+	}
+	if _, err = g.w.Write(separator + "templ_7745c5c3_Opts ..." + sig.OptionTypeName() + ")"); err != nil {
+		return err
+	}
+	if err = g.writeMapped(sig.Suffix); err != nil {
+		return err
+	}
+	return nil
+}
+
+// writeTemplateOptionsResolution applies the default values and any options passed by the caller,
+// and declares each optional parameter as a local variable.
+func (g *generator) writeTemplateOptionsResolution(indentLevel int, sig templateSignature) (err error) {
+	// templ_7745c5c3_Params := NameOpts{
+	if _, err = g.w.WriteIndent(indentLevel, "templ_7745c5c3_Params := "+sig.OptionsTypeName()+"{\n"); err != nil {
+		return err
+	}
+	for _, p := range sig.Optional {
+		// Variant: "primary",
+		if _, err = g.w.WriteIndent(indentLevel+1, p.FieldName()+": "); err != nil {
+			return err
+		}
+		if err = g.writeMapped(p.Value); err != nil {
+			return err
+		}
+		if _, err = g.w.Write(",\n"); err != nil {
+			return err
+		}
+	}
+	if _, err = g.w.WriteIndent(indentLevel, "}\n"); err != nil {
+		return err
+	}
+	// for _, templ_7745c5c3_Opt := range templ_7745c5c3_Opts {
+	if _, err = g.w.WriteIndent(indentLevel, "for _, templ_7745c5c3_Opt := range templ_7745c5c3_Opts {\n"); err != nil {
+		return err
+	}
+	if _, err = g.w.WriteIndent(indentLevel+1, "templ_7745c5c3_Opt(&templ_7745c5c3_Params)\n"); err != nil {
+		return err
+	}
+	if _, err = g.w.WriteIndent(indentLevel, "}\n"); err != nil {
+		return err
+	}
+	for _, p := range sig.Optional {
+		// variant := templ_7745c5c3_Params.Variant
+		if _, err = g.w.WriteIndent(indentLevel, ""); err != nil {
+			return err
+		}
+		if err = g.writeMapped(p.Name); err != nil {
+			return err
+		}
+		if _, err = g.w.Write(" := templ_7745c5c3_Params." + p.FieldName() + "\n"); err != nil {
+			return err
+		}
+		// Optional parameters don't have to be used by the template.
+		if _, err = g.w.WriteIndent(indentLevel, "_ = "+p.Name.Value+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func stripWhitespace(input []parser.Node) (output []parser.Node) {
 	for i, n := range input {
 		if _, isWhiteSpace := n.(parser.Whitespace); !isWhiteSpace {
@@ -503,10 +812,16 @@ func (g *generator) writeNode(indentLevel int, current parser.Node, next parser.
 		err = g.writeElement(indentLevel, n)
 	case parser.HTMLComment:
 		err = g.writeComment(indentLevel, n)
+	case parser.ProcessingInstruction:
+		err = g.writeProcessingInstruction(indentLevel, n)
+	case parser.CDATA:
+		err = g.writeCDATA(indentLevel, n)
 	case parser.ChildrenExpression:
 		err = g.writeChildrenExpression(indentLevel)
 	case parser.RawElement:
 		err = g.writeRawElement(indentLevel, n)
+	case parser.Markdown:
+		err = g.writeMarkdown(indentLevel, n)
 	case parser.ForExpression:
 		err = g.writeForExpression(indentLevel, n, next)
 	case parser.CallTemplateExpression:
@@ -580,23 +895,26 @@ func (g *generator) writeWhitespaceTrailer(indentLevel int, n parser.TrailingSpa
 }
 
 func (g *generator) writeDocType(indentLevel int, n parser.DocType) (err error) {
-	if _, err = g.w.WriteStringLiteral(indentLevel, fmt.Sprintf("<!doctype %s>", n.Value)); err != nil {
+	format := "<!doctype %s>"
+	if g.xml {
+		// XML is case-sensitive.
+		format = "<!DOCTYPE %s>"
+	}
+	if _, err = g.w.WriteStringLiteral(indentLevel, fmt.Sprintf(format, n.Value)); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (g *generator) writeIfExpression(indentLevel int, n parser.IfExpression, nextNode parser.Node) (err error) {
-	var r parser.Range
 	// if
 	if _, err = g.w.WriteIndent(indentLevel, `if `); err != nil {
 		return err
 	}
 	// x == y {
-	if r, err = g.w.Write(n.Expression.Value); err != nil {
+	if err = g.writeMapped(n.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(n.Expression, r)
 	// {
 	if _, err = g.w.Write(` {` + "\n"); err != nil {
 		return err
@@ -614,10 +932,9 @@ func (g *generator) writeIfExpression(indentLevel int, n parser.IfExpression, ne
 			return err
 		}
 		// x == y {
-		if r, err = g.w.Write(elseIf.Expression.Value); err != nil {
+		if err = g.writeMapped(elseIf.Expression); err != nil {
 			return err
 		}
-		g.sourceMap.Add(elseIf.Expression, r)
 		// {
 		if _, err = g.w.Write(` {` + "\n"); err != nil {
 			return err
@@ -651,16 +968,14 @@ func (g *generator) writeIfExpression(indentLevel int, n parser.IfExpression, ne
 }
 
 func (g *generator) writeSwitchExpression(indentLevel int, n parser.SwitchExpression, next parser.Node) (err error) {
-	var r parser.Range
 	// switch
 	if _, err = g.w.WriteIndent(indentLevel, `switch `); err != nil {
 		return err
 	}
 	// val
-	if r, err = g.w.Write(n.Expression.Value); err != nil {
+	if err = g.writeMapped(n.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(n.Expression, r)
 	// {
 	if _, err = g.w.Write(` {` + "\n"); err != nil {
 		return err
@@ -670,10 +985,12 @@ func (g *generator) writeSwitchExpression(indentLevel int, n parser.SwitchExpres
 		for _, c := range n.Cases {
 			// case x:
 			// default:
-			if r, err = g.w.WriteIndent(indentLevel, c.Expression.Value); err != nil {
+			if _, err = g.w.WriteIndent(indentLevel, ""); err != nil {
+				return err
+			}
+			if err = g.writeMapped(c.Expression); err != nil {
 				return err
 			}
-			g.sourceMap.Add(c.Expression, r)
 			indentLevel++
 			if err = g.writeNodes(indentLevel, stripLeadingAndTrailingWhitespace(c.Children), next); err != nil {
 				return err
@@ -706,7 +1023,6 @@ func (g *generator) writeTemplElementExpression(indentLevel int, n parser.TemplE
 }
 
 func (g *generator) writeBlockTemplElementExpression(indentLevel int, n parser.TemplElementExpression) (err error) {
-	var r parser.Range
 	childrenName := g.createVariableName()
 	if _, err = g.w.WriteIndent(indentLevel, childrenName+" := templ.ComponentFunc(func(ctx context.Context, templ_7745c5c3_W io.Writer) (templ_7745c5c3_Err error) {\n"); err != nil {
 		return err
@@ -744,10 +1060,9 @@ func (g *generator) writeBlockTemplElementExpression(indentLevel int, n parser.T
 	if _, err = g.w.WriteIndent(indentLevel, `templ_7745c5c3_Err = `); err != nil {
 		return err
 	}
-	if r, err = g.w.Write(n.Expression.Value); err != nil {
+	if err = g.writeMapped(n.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(n.Expression, r)
 	// .Render(templ.WithChildren(ctx, children), templ_7745c5c3_Buffer)
 	if _, err = g.w.Write(".Render(templ.WithChildren(ctx, " + childrenName + "), templ_7745c5c3_Buffer)\n"); err != nil {
 		return err
@@ -763,11 +1078,9 @@ func (g *generator) writeSelfClosingTemplElementExpression(indentLevel int, n pa
 		return err
 	}
 	// Template expression.
-	var r parser.Range
-	if r, err = g.w.Write(n.Expression.Value); err != nil {
+	if err = g.writeMapped(n.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(n.Expression, r)
 	// .Render(ctx, templ_7745c5c3_Buffer)
 	if _, err = g.w.Write(".Render(ctx, templ_7745c5c3_Buffer)\n"); err != nil {
 		return err
@@ -783,11 +1096,9 @@ func (g *generator) writeCallTemplateExpression(indentLevel int, n parser.CallTe
 		return err
 	}
 	// Template expression.
-	var r parser.Range
-	if r, err = g.w.Write(n.Expression.Value); err != nil {
+	if err = g.writeMapped(n.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(n.Expression, r)
 	// .Render(ctx, templ_7745c5c3_Buffer)
 	if _, err = g.w.Write(".Render(ctx, templ_7745c5c3_Buffer)\n"); err != nil {
 		return err
@@ -799,16 +1110,14 @@ func (g *generator) writeCallTemplateExpression(indentLevel int, n parser.CallTe
 }
 
 func (g *generator) writeForExpression(indentLevel int, n parser.ForExpression, next parser.Node) (err error) {
-	var r parser.Range
 	// for
 	if _, err = g.w.WriteIndent(indentLevel, `for `); err != nil {
 		return err
 	}
 	// i, v := range p.Stuff
-	if r, err = g.w.Write(n.Expression.Value); err != nil {
+	if err = g.writeMapped(n.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(n.Expression, r)
 	// {
 	if _, err = g.w.Write(` {` + "\n"); err != nil {
 		return err
@@ -848,9 +1157,44 @@ func (g *generator) writeElement(indentLevel int, n parser.Element) (err error)
 	if n.IsVoidElement() {
 		return g.writeVoidElement(indentLevel, n)
 	}
+	if n.IsSelfClosing() {
+		return g.writeSelfClosingForeignElement(indentLevel, n)
+	}
 	return g.writeStandardElement(indentLevel, n)
 }
 
+// writeSelfClosingForeignElement writes an SVG or MathML element that has no children, e.g. <path d="..."/>.
+// https://html.spec.whatwg.org/multipage/syntax.html#start-tags
+func (g *generator) writeSelfClosingForeignElement(indentLevel int, n parser.Element) (err error) {
+	if len(n.Attributes) == 0 {
+		// <path/>
+		if _, err = g.w.WriteStringLiteral(indentLevel, fmt.Sprintf(`<%s/>`, html.EscapeString(n.Name))); err != nil {
+			return err
+		}
+		return nil
+	}
+	// <style type="text/css"></style>
+	if err = g.writeElementCSS(indentLevel, n); err != nil {
+		return err
+	}
+	// <script type="text/javascript"></script>
+	if err = g.writeElementScript(indentLevel, n); err != nil {
+		return err
+	}
+	// <path
+	if _, err = g.w.WriteStringLiteral(indentLevel, fmt.Sprintf(`<%s`, html.EscapeString(n.Name))); err != nil {
+		return err
+	}
+	if err = g.writeElementAttributes(indentLevel, n.Name, n.Attributes); err != nil {
+		return err
+	}
+	// />
+	if _, err = g.w.WriteStringLiteral(indentLevel, `/>`); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (g *generator) writeVoidElement(indentLevel int, n parser.Element) (err error) {
 	if len(n.Children) > 0 {
 		return fmt.Errorf("writeVoidElement: void element %q must not have child elements", n.Name)
@@ -923,7 +1267,6 @@ func (g *generator) writeStandardElement(indentLevel int, n parser.Element) (err
 }
 
 func (g *generator) writeAttributeCSS(indentLevel int, attr parser.ExpressionAttribute) (result parser.ExpressionAttribute, ok bool, err error) {
-	var r parser.Range
 	name := html.EscapeString(attr.Name)
 	if name != "class" {
 		ok = false
@@ -937,10 +1280,9 @@ func (g *generator) writeAttributeCSS(indentLevel int, attr parser.ExpressionAtt
 		return
 	}
 	// p.Name()
-	if r, err = g.w.Write(attr.Expression.Value); err != nil {
+	if err = g.writeMapped(attr.Expression); err != nil {
 		return
 	}
-	g.sourceMap.Add(attr.Expression, r)
 	// }\n
 	if _, err = g.w.Write("}\n"); err != nil {
 		return
@@ -990,6 +1332,13 @@ func (g *generator) writeElementCSS(indentLevel int, n parser.Element) (err erro
 	return g.writeAttributesCSS(indentLevel, n.Attributes)
 }
 
+// isURLAttribute returns true if the attribute value must be a templ.SafeURL.
+// SVG <a> elements can use the legacy xlink:href attribute.
+func isURLAttribute(elementName, attrName string) bool {
+	return (elementName == "a" && (attrName == "href" || attrName == "xlink:href")) ||
+		(elementName == "form" && attrName == "action")
+}
+
 func isScriptAttribute(name string) bool {
 	for _, prefix := range []string{"on", "hx-on:"} {
 		if strings.HasPrefix(name, prefix) {
@@ -1037,13 +1386,22 @@ func getAttributeScripts(attr parser.Attribute) (scripts []string) {
 }
 
 func (g *generator) writeBoolConstantAttribute(indentLevel int, attr parser.BoolConstantAttribute) (err error) {
-	name := html.EscapeString(attr.Name)
-	if _, err = g.w.WriteStringLiteral(indentLevel, fmt.Sprintf(` %s`, name)); err != nil {
+	if _, err = g.w.WriteStringLiteral(indentLevel, g.boolAttribute(attr.Name)); err != nil {
 		return err
 	}
 	return nil
 }
 
+// boolAttribute returns the attribute to write when a boolean attribute is true. XML attributes
+// must have a value, so the name is used.
+func (g *generator) boolAttribute(name string) string {
+	name = html.EscapeString(name)
+	if g.xml {
+		return fmt.Sprintf(` %s=\"%s\"`, name, name)
+	}
+	return fmt.Sprintf(` %s`, name)
+}
+
 func (g *generator) writeConstantAttribute(indentLevel int, attr parser.ConstantAttribute) (err error) {
 	name := html.EscapeString(attr.Name)
 	value := html.EscapeString(attr.Value)
@@ -1055,24 +1413,21 @@ func (g *generator) writeConstantAttribute(indentLevel int, attr parser.Constant
 }
 
 func (g *generator) writeBoolExpressionAttribute(indentLevel int, attr parser.BoolExpressionAttribute) (err error) {
-	name := html.EscapeString(attr.Name)
 	// if
 	if _, err = g.w.WriteIndent(indentLevel, `if `); err != nil {
 		return err
 	}
 	// x == y
-	var r parser.Range
-	if r, err = g.w.Write(attr.Expression.Value); err != nil {
+	if err = g.writeMapped(attr.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(attr.Expression, r)
 	// {
 	if _, err = g.w.Write(` {` + "\n"); err != nil {
 		return err
 	}
 	{
 		indentLevel++
-		if _, err = g.w.WriteStringLiteral(indentLevel, fmt.Sprintf(` %s`, name)); err != nil {
+		if _, err = g.w.WriteStringLiteral(indentLevel, g.boolAttribute(attr.Name)); err != nil {
 			return err
 		}
 		indentLevel--
@@ -1095,18 +1450,16 @@ func (g *generator) writeExpressionAttribute(indentLevel int, elementName string
 	if _, err = g.w.WriteStringLiteral(indentLevel, `\"`); err != nil {
 		return err
 	}
-	if (elementName == "a" && attr.Name == "href") || (elementName == "form" && attr.Name == "action") {
+	if isURLAttribute(elementName, attr.Name) {
 		vn := g.createVariableName()
 		// var vn templ.SafeURL =
 		if _, err = g.w.WriteIndent(indentLevel, "var "+vn+" templ.SafeURL = "); err != nil {
 			return err
 		}
 		// p.Name()
-		var r parser.Range
-		if r, err = g.w.Write(attr.Expression.Value); err != nil {
+		if err = g.writeMapped(attr.Expression); err != nil {
 			return err
 		}
-		g.sourceMap.Add(attr.Expression, r)
 		if _, err = g.w.Write("\n"); err != nil {
 			return err
 		}
@@ -1125,11 +1478,9 @@ func (g *generator) writeExpressionAttribute(indentLevel int, elementName string
 				return err
 			}
 			// p.Name()
-			var r parser.Range
-			if r, err = g.w.Write(attr.Expression.Value); err != nil {
+			if err = g.writeMapped(attr.Expression); err != nil {
 				return err
 			}
-			g.sourceMap.Add(attr.Expression, r)
 			if _, err = g.w.Write("\n"); err != nil {
 				return err
 			}
@@ -1145,11 +1496,14 @@ func (g *generator) writeExpressionAttribute(indentLevel int, elementName string
 				return err
 			}
 			// p.Name()
-			var r parser.Range
-			if r, err = g.w.Write(attr.Expression.Value); err != nil {
+			write := g.writeMapped
+			if attr.Expression.Range == (parser.Range{}) {
+				// The CSS classes expression is created by writeAttributeCSS, so it's not within the templ file.
+				write = g.writeUnmapped
+			}
+			if err = write(attr.Expression); err != nil {
 				return err
 			}
-			g.sourceMap.Add(attr.Expression, r)
 			// ))
 			if _, err = g.w.Write("))\n"); err != nil {
 				return err
@@ -1172,11 +1526,9 @@ func (g *generator) writeSpreadAttributes(indentLevel int, attr parser.SpreadAtt
 		return err
 	}
 	// spreadAttrs
-	var r parser.Range
-	if r, err = g.w.Write(attr.Expression.Value); err != nil {
+	if err = g.writeMapped(attr.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(attr.Expression, r)
 	// )
 	if _, err = g.w.Write(")\n"); err != nil {
 		return err
@@ -1193,11 +1545,9 @@ func (g *generator) writeConditionalAttribute(indentLevel int, elementName strin
 		return err
 	}
 	// x == y
-	var r parser.Range
-	if r, err = g.w.Write(attr.Expression.Value); err != nil {
+	if err = g.writeMapped(attr.Expression); err != nil {
 		return err
 	}
-	g.sourceMap.Add(attr.Expression, r)
 	// {
 	if _, err = g.w.Write(` {` + "\n"); err != nil {
 		return err
@@ -1281,6 +1631,42 @@ func (g *generator) writeRawElement(indentLevel int, n parser.RawElement) (err e
 	return err
 }
 
+func (g *generator) writeMarkdown(indentLevel int, n parser.Markdown) (err error) {
+	// Replace expressions with placeholders, and convert the Markdown to HTML.
+	var src strings.Builder
+	var exprs []parser.Expression
+	for _, c := range n.Children {
+		switch c := c.(type) {
+		case parser.Text:
+			src.WriteString(c.Value)
+		case parser.StringExpression:
+			src.WriteString(markdownPlaceholder(len(exprs)))
+			exprs = append(exprs, c.Expression)
+		}
+	}
+	output, err := markdownToHTML(src.String())
+	if err != nil {
+		return err
+	}
+	output = strings.TrimSuffix(output, "\n")
+	// Write the HTML as string literals, and the placeholders as string expressions.
+	var last int
+	for _, m := range markdownPlaceholderRegexp.FindAllStringSubmatchIndex(output, -1) {
+		if err = g.writeText(indentLevel, parser.Text{Value: output[last:m[0]]}); err != nil {
+			return err
+		}
+		i, _ := strconv.Atoi(output[m[2]:m[3]])
+		if i >= len(exprs) {
+			return fmt.Errorf("markdown: unexpected placeholder %q", output[m[0]:m[1]])
+		}
+		if err = g.writeStringExpression(indentLevel, exprs[i]); err != nil {
+			return err
+		}
+		last = m[1]
+	}
+	return g.writeText(indentLevel, parser.Text{Value: output[last:]})
+}
+
 func (g *generator) writeComment(indentLevel int, c parser.HTMLComment) (err error) {
 	// <!--
 	if _, err = g.w.WriteStringLiteral(indentLevel, "<!--"); err != nil {
@@ -1297,6 +1683,36 @@ func (g *generator) writeComment(indentLevel int, c parser.HTMLComment) (err err
 	return err
 }
 
+func (g *generator) writeProcessingInstruction(indentLevel int, n parser.ProcessingInstruction) (err error) {
+	// <?xml version="1.0"?>
+	if _, err = g.w.WriteStringLiteral(indentLevel, "<?"); err != nil {
+		return err
+	}
+	if err = g.writeText(indentLevel, parser.Text{Value: n.Contents}); err != nil {
+		return err
+	}
+	if _, err = g.w.WriteStringLiteral(indentLevel, "?>"); err != nil {
+		return err
+	}
+	return err
+}
+
+func (g *generator) writeCDATA(indentLevel int, n parser.CDATA) (err error) {
+	// <![CDATA[
+	if _, err = g.w.WriteStringLiteral(indentLevel, "<![CDATA["); err != nil {
+		return err
+	}
+	// Contents.
+	if err = g.writeText(indentLevel, parser.Text{Value: n.Contents}); err != nil {
+		return err
+	}
+	// ]]>
+	if _, err = g.w.WriteStringLiteral(indentLevel, "]]>"); err != nil {
+		return err
+	}
+	return err
+}
+
 func (g *generator) createVariableName() string {
 	g.variableID++
 	return "templ_7745c5c3_Var" + strconv.Itoa(g.variableID)
@@ -1306,7 +1722,6 @@ func (g *generator) writeStringExpression(indentLevel int, e parser.Expression)
 	if strings.TrimSpace(e.Value) == "" {
 		return
 	}
-	var r parser.Range
 	vn := g.createVariableName()
 	// var vn string
 	if _, err = g.w.WriteIndent(indentLevel, "var "+vn+" string\n"); err != nil {
@@ -1317,10 +1732,9 @@ func (g *generator) writeStringExpression(indentLevel int, e parser.Expression)
 		return err
 	}
 	// p.Name()
-	if r, err = g.w.Write(e.Value); err != nil {
+	if err = g.writeMapped(e); err != nil {
 		return err
 	}
-	g.sourceMap.Add(e, r)
 	// )
 	if _, err = g.w.Write(")\n"); err != nil {
 		return err
@@ -1342,8 +1756,13 @@ func (g *generator) writeStringExpression(indentLevel int, e parser.Expression)
 		return err
 	}
 
-	// _, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(vn)
-	if _, err = g.w.WriteIndent(indentLevel, "_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString("+vn+"))\n"); err != nil {
+	// _, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(vn))
+	value := "templ.EscapeString(" + vn + ")"
+	if g.text {
+		// Text templates are not HTML, so the value is written as-is.
+		value = vn
+	}
+	if _, err = g.w.WriteIndent(indentLevel, "_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("+value+")\n"); err != nil {
 		return err
 	}
 	if err = g.writeErrorHandler(indentLevel); err != nil {
@@ -1384,7 +1803,6 @@ func createGoString(s string) string {
 }
 
 func (g *generator) writeScript(t parser.ScriptTemplate) error {
-	var r parser.Range
 	var err error
 	var indentLevel int
 
@@ -1392,19 +1810,17 @@ func (g *generator) writeScript(t parser.ScriptTemplate) error {
 	if _, err = g.w.Write("func "); err != nil {
 		return err
 	}
-	if r, err = g.w.Write(t.Name.Value); err != nil {
+	if err = g.writeMapped(t.Name); err != nil {
 		return err
 	}
-	g.sourceMap.Add(t.Name, r)
 	// (
 	if _, err = g.w.Write("("); err != nil {
 		return err
 	}
 	// Write parameters.
-	if r, err = g.w.Write(t.Parameters.Value); err != nil {
+	if err = g.writeMapped(t.Parameters); err != nil {
 		return err
 	}
-	g.sourceMap.Add(t.Parameters, r)
 	// ) templ.ComponentScript {
 	if _, err = g.w.Write(") templ.ComponentScript {\n"); err != nil {
 		return err
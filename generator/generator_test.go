@@ -2,6 +2,9 @@ package generator
 
 import (
 	"bytes"
+	"go/format"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/a-h/templ/parser/v2"
@@ -47,3 +50,90 @@ templ h1() {
 		t.Fatalf("failed to write Go expression: %v", err)
 	}
 }
+
+func TestGeneratorLineDirectives(t *testing.T) {
+	tf, err := parser.ParseString(`package main
+
+templ page(name string) {
+	if name != "" {
+		<p>{ name }</p>
+	}
+}
+`)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	w := new(bytes.Buffer)
+	sm, _, err := Generate(tf, w, WithFileName("/src/page.templ"), WithLineDirectives())
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if _, err = format.Source(w.Bytes()); err != nil {
+		t.Fatalf("generated code is not valid Go: %v", err)
+	}
+	lines := strings.Split(w.String(), "\n")
+	for _, expected := range []string{
+		"func /*line page.templ:3:6*/ page(name string)/*line page_templ.go:",
+		"if /*line page.templ:4:4*/ name != \"\"/*line page_templ.go:",
+		"templ.JoinStringErrs(/*line page.templ:5:7*/ name/*line page_templ.go:",
+	} {
+		var found bool
+		for _, line := range lines {
+			if strings.Contains(line, expected) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, w.String())
+		}
+	}
+	// The directives restore the line number of the generated code.
+	for i, line := range lines {
+		if idx := strings.LastIndex(line, "/*line page_templ.go:"); idx >= 0 {
+			expected := "/*line page_templ.go:" + strconv.Itoa(i+1) + "*/"
+			if !strings.HasPrefix(line[idx:], expected) {
+				t.Errorf("line %d: expected %q, got %q", i+1, expected, line[idx:])
+			}
+		}
+	}
+	// The source map includes the positions of the expressions after the directives.
+	tgt, ok := sm.TargetPositionFromSource(4, 7)
+	if !ok {
+		t.Fatal("expected the name expression to be in the source map")
+	}
+	if actual := w.String()[tgt.Index : tgt.Index+4]; actual != "name" {
+		t.Errorf("expected source map to point to %q, got %q", "name", actual)
+	}
+}
+
+func TestGeneratorLineDirectivesRequireFileName(t *testing.T) {
+	tf, err := parser.ParseString("package main\n\ntempl page() {\n\t<p></p>\n}\n")
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	if _, _, err = Generate(tf, new(bytes.Buffer), WithLineDirectives()); err == nil {
+		t.Error("expected an error when the file name isn't set")
+	}
+}
+
+func TestGeneratorPackageName(t *testing.T) {
+	tf, err := parser.ParseString("package main\n\ntempl page() {\n\t<p></p>\n}\n")
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	w := new(bytes.Buffer)
+	sm, _, err := Generate(tf, w, WithPackageName("templates"))
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if !strings.Contains(w.String(), "\npackage templates\n") {
+		t.Errorf("expected the package to be templates, got:\n%s", w.String())
+	}
+	if _, ok := sm.TargetPositionFromSource(0, 8); ok {
+		t.Error("expected the package clause not to be mapped")
+	}
+	if _, _, err = Generate(tf, new(bytes.Buffer), WithPackageName("my-templates")); err == nil {
+		t.Error("expected an error for an invalid package name")
+	}
+}
@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Markdown.
+//
+// The contents of <templ:markdown> elements are converted to HTML at generate time by
+// goldmark, which follows the CommonMark spec. Raw HTML within the Markdown is omitted.
+//
+// { expr } expressions are replaced with placeholders before the conversion, so that the
+// placeholders can be replaced with the escaped result of the expression at runtime.
+
+const markdownPlaceholderPrefix = "TEMPL7745C5C3MARKDOWN"
+
+var markdownPlaceholderRegexp = regexp.MustCompile(markdownPlaceholderPrefix + `(\d+)X`)
+
+func markdownPlaceholder(i int) string {
+	return fmt.Sprintf("%s%dX", markdownPlaceholderPrefix, i)
+}
+
+var errMarkdownPlaceholderInURL = errors.New("markdown: expressions can't be used within link or image destinations")
+
+var markdown = goldmark.New()
+
+// markdownToHTML converts Markdown to HTML.
+func markdownToHTML(src string) (string, error) {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	source := []byte(strings.Join(dedentLines(strings.Split(src, "\n")), "\n"))
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	if err := sanitizeMarkdownURLs(doc, source); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// sanitizeMarkdownURLs sanitizes the destinations of links and images in the same way as
+// templ.URL. URL autolinks are replaced with links, so that they're sanitized too.
+func sanitizeMarkdownURLs(doc ast.Node, source []byte) error {
+	var autoLinks []*ast.AutoLink
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var err error
+		switch n := n.(type) {
+		case *ast.Link:
+			n.Destination, err = markdownURL(n.Destination)
+		case *ast.Image:
+			n.Destination, err = markdownURL(n.Destination)
+		case *ast.AutoLink:
+			if n.AutoLinkType == ast.AutoLinkURL {
+				autoLinks = append(autoLinks, n)
+			}
+		}
+		return ast.WalkContinue, err
+	})
+	if err != nil {
+		return err
+	}
+	for _, n := range autoLinks {
+		link := ast.NewLink()
+		if link.Destination, err = markdownURL(n.URL(source)); err != nil {
+			return err
+		}
+		link.AppendChild(link, ast.NewString(n.Label(source)))
+		n.Parent().ReplaceChild(n.Parent(), n, link)
+	}
+	return nil
+}
+
+// markdownURL returns the sanitized URL of a link or image.
+func markdownURL(destination []byte) ([]byte, error) {
+	if bytes.Contains(destination, []byte(markdownPlaceholderPrefix)) {
+		return nil, errMarkdownPlaceholderInURL
+	}
+	return []byte(templ.URL(string(destination))), nil
+}
+
+// dedentLines removes the indentation that is common to all non-blank lines, so that the
+// Markdown can be indented within the template.
+func dedentLines(lines []string) []string {
+	var prefix string
+	var prefixSet bool
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if !prefixSet {
+			prefix, prefixSet = indent, true
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	op := make([]string, len(lines))
+	for i, l := range lines {
+		op[i] = strings.TrimPrefix(l, prefix)
+	}
+	return op
+}
@@ -0,0 +1,150 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "paragraphs",
+			input:    "Hello\nworld.\n\nSecond paragraph.",
+			expected: "<p>Hello\nworld.</p>\n<p>Second paragraph.</p>\n",
+		},
+		{
+			name:     "indentation is removed",
+			input:    "\n\t\t# Title\n\n\t\tText.\n\t",
+			expected: "<h1>Title</h1>\n<p>Text.</p>\n",
+		},
+		{
+			name:     "atx headings",
+			input:    "# One\n## Two ##\n###### Six",
+			expected: "<h1>One</h1>\n<h2>Two</h2>\n<h6>Six</h6>\n",
+		},
+		{
+			name:     "setext headings",
+			input:    "One\n===\nTwo\n---",
+			expected: "<h1>One</h1>\n<h2>Two</h2>\n",
+		},
+		{
+			name:     "emphasis",
+			input:    "*em* _em_ **strong** __strong__ ***both*** snake_case_name 2 * 3 * 4",
+			expected: "<p><em>em</em> <em>em</em> <strong>strong</strong> <strong>strong</strong> <em><strong>both</strong></em> snake_case_name 2 * 3 * 4</p>\n",
+		},
+		{
+			name:     "nested emphasis",
+			input:    "**bold *and italic***",
+			expected: "<p><strong>bold <em>and italic</em></strong></p>\n",
+		},
+		{
+			name:     "code spans",
+			input:    "Use `fmt.Println(\"<b>\")` or `` a ` b ``.",
+			expected: "<p>Use <code>fmt.Println(&quot;&lt;b&gt;&quot;)</code> or <code>a ` b</code>.</p>\n",
+		},
+		{
+			name:     "html is omitted",
+			input:    "<script>alert(1)</script>\n\nText <b>bold</b>",
+			expected: "<!-- raw HTML omitted -->\n<p>Text <!-- raw HTML omitted -->bold<!-- raw HTML omitted --></p>\n",
+		},
+		{
+			name:     "ampersands are escaped",
+			input:    "&amp; & AT&T",
+			expected: "<p>&amp; &amp; AT&amp;T</p>\n",
+		},
+		{
+			name:     "backslash escapes",
+			input:    "\\*not em\\*",
+			expected: "<p>*not em*</p>\n",
+		},
+		{
+			name:     "hard line breaks",
+			input:    "one  \ntwo\\\nthree",
+			expected: "<p>one<br>\ntwo<br>\nthree</p>\n",
+		},
+		{
+			name:     "links",
+			input:    "[templ](https://templ.guide \"Docs\") and [*home*](/)",
+			expected: "<p><a href=\"https://templ.guide\" title=\"Docs\">templ</a> and <a href=\"/\"><em>home</em></a></p>\n",
+		},
+		{
+			name:     "unsafe links are sanitized",
+			input:    "[x](javascript:alert(1))",
+			expected: "<p><a href=\"about:invalid#TemplFailedSanitizationURL\">x</a></p>\n",
+		},
+		{
+			name:     "unsafe autolinks are sanitized",
+			input:    "<javascript:alert(1)>",
+			expected: "<p><a href=\"about:invalid#TemplFailedSanitizationURL\">javascript:alert(1)</a></p>\n",
+		},
+		{
+			name:     "images",
+			input:    "![a *cat*](cat.png)",
+			expected: "<p><img src=\"cat.png\" alt=\"a cat\"></p>\n",
+		},
+		{
+			name:     "autolinks",
+			input:    "<https://example.com> <a@example.com>",
+			expected: "<p><a href=\"https://example.com\">https://example.com</a> <a href=\"mailto:a@example.com\">a@example.com</a></p>\n",
+		},
+		{
+			name:     "thematic break",
+			input:    "a\n\n***\n\nb",
+			expected: "<p>a</p>\n<hr>\n<p>b</p>\n",
+		},
+		{
+			name:     "block quotes",
+			input:    "> # Quote\n> text",
+			expected: "<blockquote>\n<h1>Quote</h1>\n<p>text</p>\n</blockquote>\n",
+		},
+		{
+			name:     "fenced code blocks",
+			input:    "```go\nfunc main() {\n\tfmt.Println(\"<b>\")\n}\n```",
+			expected: "<pre><code class=\"language-go\">func main() {\n\tfmt.Println(&quot;&lt;b&gt;&quot;)\n}\n</code></pre>\n",
+		},
+		{
+			name:     "tight lists",
+			input:    "- one\n- two\n  - nested\n- three",
+			expected: "<ul>\n<li>one</li>\n<li>two\n<ul>\n<li>nested</li>\n</ul>\n</li>\n<li>three</li>\n</ul>\n",
+		},
+		{
+			name:     "loose lists",
+			input:    "1. one\n\n2. two",
+			expected: "<ol>\n<li>\n<p>one</p>\n</li>\n<li>\n<p>two</p>\n</li>\n</ol>\n",
+		},
+		{
+			name:     "ordered lists with a start number",
+			input:    "3) three\n4) four",
+			expected: "<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>\n",
+		},
+		{
+			name:     "paragraphs are interrupted by lists",
+			input:    "Items:\n* a\n* b",
+			expected: "<p>Items:</p>\n<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := markdownToHTML(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMarkdownToHTMLErrors(t *testing.T) {
+	_, err := markdownToHTML("[link](" + markdownPlaceholder(0) + ")")
+	if err != errMarkdownPlaceholderInURL {
+		t.Errorf("expected error %v, got %v", errMarkdownPlaceholderInURL, err)
+	}
+}
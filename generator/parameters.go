@@ -0,0 +1,301 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// templateSignature is the parsed form of a templ declaration that has parameters with default values.
+//
+//	templ Button(label string, variant string = "primary", size int = 2) {
+type templateSignature struct {
+	// Name of the template, e.g. Button.
+	Name string
+	// Prefix is the source up to and including the required parameters, e.g. `Button(label string`.
+	Prefix parser.Expression
+	// HasRequired is true if there are required parameters before the optional ones.
+	HasRequired bool
+	// Optional parameters, in declaration order.
+	Optional []optionalParameter
+	// Suffix is the source after the closing parenthesis of the parameter list.
+	Suffix parser.Expression
+}
+
+// optionalParameter is a parameter with a default value, e.g. `variant string = "primary"`.
+type optionalParameter struct {
+	Name  parser.Expression
+	Type  parser.Expression
+	Value parser.Expression
+}
+
+// FieldName is the exported name used for the parameter in the generated options type.
+func (p optionalParameter) FieldName() string {
+	r, size := utf8.DecodeRuneInString(p.Name.Value)
+	return string(unicode.ToUpper(r)) + p.Name.Value[size:]
+}
+
+// OptionsTypeName is the name of the struct that holds the optional parameters.
+func (s templateSignature) OptionsTypeName() string {
+	return s.Name + "Opts"
+}
+
+// OptionTypeName is the name of the function type used to set an optional parameter.
+func (s templateSignature) OptionTypeName() string {
+	return s.Name + "Opt"
+}
+
+// OptionFuncName is the name of the function that sets the given optional parameter.
+func (s templateSignature) OptionFuncName(p optionalParameter) string {
+	return s.Name + p.FieldName()
+}
+
+var errNoDefaultParameters = errors.New("no default parameters")
+
+// parseTemplateSignature parses the expression of a templ declaration. If the declaration doesn't
+// have any parameters with default values, errNoDefaultParameters is returned.
+func parseTemplateSignature(expr parser.Expression) (sig templateSignature, err error) {
+	s := expr.Value
+	if !strings.Contains(s, "=") {
+		return sig, errNoDefaultParameters
+	}
+	// Find the opening parenthesis of the parameter list, skipping any method receiver.
+	var isMethod bool
+	searchFrom := 0
+	if receiverStart := strings.IndexRune(s, '('); receiverStart >= 0 && strings.TrimSpace(s[:receiverStart]) == "" {
+		isMethod = true
+		if searchFrom = matchingParen(s, receiverStart) + 1; searchFrom <= 0 {
+			return sig, errNoDefaultParameters
+		}
+	}
+	paramsStart := strings.IndexRune(s[searchFrom:], '(')
+	if paramsStart < 0 {
+		return sig, errNoDefaultParameters
+	}
+	paramsStart += searchFrom
+	paramsEnd := matchingParen(s, paramsStart)
+	if paramsEnd < 0 {
+		return sig, errNoDefaultParameters
+	}
+	segments := splitTopLevel(s, paramsStart+1, paramsEnd, ',')
+	var firstOptional = -1
+	for i, seg := range segments {
+		eq := assignmentIndex(s[seg.from:seg.to])
+		if eq < 0 {
+			if firstOptional >= 0 {
+				return sig, fmt.Errorf("templ: parameter %q must have a default value, because it follows a parameter with a default value", strings.TrimSpace(s[seg.from:seg.to]))
+			}
+			continue
+		}
+		if firstOptional < 0 {
+			firstOptional = i
+		}
+		p, err := parseOptionalParameter(expr, seg.from, seg.from+eq, seg.to)
+		if err != nil {
+			return sig, err
+		}
+		sig.Optional = append(sig.Optional, p)
+	}
+	if firstOptional < 0 {
+		return sig, errNoDefaultParameters
+	}
+	// Methods would require the option types to be namespaced by receiver.
+	if isMethod {
+		return sig, fmt.Errorf("templ: default parameter values are not supported on templ methods")
+	}
+	sig.Name = strings.TrimSpace(s[:paramsStart])
+	if !isGoIdentifier(sig.Name) {
+		return sig, fmt.Errorf("templ: default parameter values are not supported on template %q", sig.Name)
+	}
+	// The prefix includes the required parameters, without the trailing comma.
+	prefixEnd := paramsStart + 1
+	if firstOptional > 0 {
+		sig.HasRequired = true
+		last := segments[firstOptional-1]
+		required := s[last.from:last.to]
+		if fields := strings.Fields(required); len(fields) < 2 {
+			return sig, fmt.Errorf("templ: parameter %q must have its own type to be followed by a parameter with a default value", strings.TrimSpace(required))
+		}
+		if strings.Contains(required, "...") {
+			return sig, fmt.Errorf("templ: variadic parameter %q cannot be followed by a parameter with a default value", strings.TrimSpace(required))
+		}
+		prefixEnd = last.from + len(strings.TrimRightFunc(required, unicode.IsSpace))
+	}
+	sig.Prefix = subExpression(expr, 0, prefixEnd)
+	sig.Suffix = subExpression(expr, paramsEnd+1, len(s))
+	return sig, nil
+}
+
+func parseOptionalParameter(expr parser.Expression, from, eq, to int) (p optionalParameter, err error) {
+	s := expr.Value
+	decl := s[from:eq]
+	nameFrom := from + len(decl) - len(strings.TrimLeftFunc(decl, unicode.IsSpace))
+	nameTo := nameFrom + strings.IndexFunc(s[nameFrom:eq], unicode.IsSpace)
+	if nameTo < nameFrom {
+		return p, fmt.Errorf("templ: parameter %q with a default value must have a type", strings.TrimSpace(decl))
+	}
+	p.Name = subExpression(expr, nameFrom, nameTo)
+	if !isGoIdentifier(p.Name.Value) || p.Name.Value == "_" {
+		return p, fmt.Errorf("templ: invalid parameter name %q", p.Name.Value)
+	}
+	p.Type = trimmedSubExpression(expr, nameTo, eq)
+	if p.Type.Value == "" {
+		return p, fmt.Errorf("templ: parameter %q with a default value must have a type", p.Name.Value)
+	}
+	if strings.HasPrefix(p.Type.Value, "...") {
+		return p, fmt.Errorf("templ: variadic parameter %q cannot have a default value", p.Name.Value)
+	}
+	p.Value = trimmedSubExpression(expr, eq+1, to)
+	if p.Value.Value == "" {
+		return p, fmt.Errorf("templ: parameter %q is missing a default value", p.Name.Value)
+	}
+	return p, nil
+}
+
+type segment struct {
+	from, to int
+}
+
+// splitTopLevel splits s[from:to] by sep, ignoring separators nested within brackets,
+// braces, parentheses, strings and runes.
+func splitTopLevel(s string, from, to int, sep byte) (segments []segment) {
+	var depth int
+	start := from
+	for i := from; i < to; i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '"', '\'', '`':
+			i = skipLiteral(s, i)
+		case sep:
+			if depth == 0 {
+				segments = append(segments, segment{from: start, to: i})
+				start = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(s[start:to]) != "" {
+		segments = append(segments, segment{from: start, to: to})
+	}
+	return segments
+}
+
+// matchingParen returns the index of the parenthesis that closes the one at s[open], or -1.
+func matchingParen(s string, open int) int {
+	var depth int
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '"', '\'', '`':
+			i = skipLiteral(s, i)
+		}
+	}
+	return -1
+}
+
+// skipLiteral returns the index of the closing quote of the literal that starts at s[i].
+func skipLiteral(s string, i int) int {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		if s[j] == '\\' && quote != '`' {
+			j++
+			continue
+		}
+		if s[j] == quote {
+			return j
+		}
+	}
+	return len(s) - 1
+}
+
+// assignmentIndex returns the index of the top-level `=` in a parameter declaration, or -1.
+// Comparison operators such as `==`, `!=`, `<=` and `>=` are not assignments.
+func assignmentIndex(s string) int {
+	var depth int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '"', '\'', '`':
+			i = skipLiteral(s, i)
+		case '=':
+			if depth != 0 {
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '=' {
+				i++
+				continue
+			}
+			if i > 0 && strings.ContainsRune("!<>=:", rune(s[i-1])) {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// subExpression returns the part of the expression between the from and to byte offsets, with
+// its range calculated relative to the parent expression.
+func subExpression(expr parser.Expression, from, to int) parser.Expression {
+	return parser.Expression{
+		Value: expr.Value[from:to],
+		Range: parser.Range{
+			From: offsetPosition(expr, from),
+			To:   offsetPosition(expr, to),
+		},
+	}
+}
+
+func trimmedSubExpression(expr parser.Expression, from, to int) parser.Expression {
+	s := expr.Value[from:to]
+	from += len(s) - len(strings.TrimLeftFunc(s, unicode.IsSpace))
+	to -= len(s) - len(strings.TrimRightFunc(s, unicode.IsSpace))
+	if to < from {
+		to = from
+	}
+	return subExpression(expr, from, to)
+}
+
+func offsetPosition(expr parser.Expression, offset int) parser.Position {
+	pos := expr.Range.From
+	pos.Index += int64(offset)
+	before := expr.Value[:offset]
+	if newLines := strings.Count(before, "\n"); newLines > 0 {
+		pos.Line += uint32(newLines)
+		pos.Col = uint32(len(before) - strings.LastIndex(before, "\n") - 1)
+		return pos
+	}
+	pos.Col += uint32(len(before))
+	return pos
+}
+
+func isGoIdentifier(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i, r := range s {
+		if unicode.IsLetter(r) || r == '_' {
+			continue
+		}
+		if i > 0 && unicode.IsDigit(r) {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/a-h/templ/parser/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTemplateSignature(t *testing.T) {
+	expr := parser.Expression{
+		Value: `Button(label string, variant string = "primary", attrs map[string]any = map[string]any{"a": 1, "b": 2})`,
+		Range: parser.Range{
+			From: parser.NewPosition(8, 1, 6),
+		},
+	}
+	sig, err := parseTemplateSignature(expr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sig.Name != "Button" {
+		t.Errorf("expected name %q, got %q", "Button", sig.Name)
+	}
+	if !sig.HasRequired {
+		t.Error("expected required parameters")
+	}
+	if diff := cmp.Diff("Button(label string", sig.Prefix.Value); diff != "" {
+		t.Error(diff)
+	}
+	if len(sig.Optional) != 2 {
+		t.Fatalf("expected 2 optional parameters, got %d", len(sig.Optional))
+	}
+	variant := sig.Optional[0]
+	expectedVariant := optionalParameter{
+		Name: parser.Expression{
+			Value: "variant",
+			Range: parser.Range{From: parser.NewPosition(29, 1, 27), To: parser.NewPosition(36, 1, 34)},
+		},
+		Type: parser.Expression{
+			Value: "string",
+			Range: parser.Range{From: parser.NewPosition(37, 1, 35), To: parser.NewPosition(43, 1, 41)},
+		},
+		Value: parser.Expression{
+			Value: `"primary"`,
+			Range: parser.Range{From: parser.NewPosition(46, 1, 44), To: parser.NewPosition(55, 1, 53)},
+		},
+	}
+	if diff := cmp.Diff(expectedVariant, variant); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(`map[string]any{"a": 1, "b": 2}`, sig.Optional[1].Value.Value); diff != "" {
+		t.Error(diff)
+	}
+	if sig.OptionFuncName(variant) != "ButtonVariant" {
+		t.Errorf("unexpected option func name %q", sig.OptionFuncName(variant))
+	}
+}
+
+func TestParseTemplateSignatureWithoutDefaults(t *testing.T) {
+	tests := []string{
+		`Button(label string)`,
+		`Button()`,
+		`(b Button) Render(ok bool)`,
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			_, err := parseTemplateSignature(parser.Expression{Value: test})
+			if err != errNoDefaultParameters {
+				t.Errorf("expected errNoDefaultParameters, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseTemplateSignatureErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "required parameters cannot follow optional parameters",
+			input: `Button(variant string = "primary", label string)`,
+		},
+		{
+			name:  "grouped parameters cannot precede optional parameters",
+			input: `Button(a, b string = "b")`,
+		},
+		{
+			name:  "variadic parameters cannot have defaults",
+			input: `Button(labels ...string = nil)`,
+		},
+		{
+			name:  "optional parameters must have a type",
+			input: `Button(variant = "primary")`,
+		},
+		{
+			name:  "methods are not supported",
+			input: `(b Button) Render(variant string = "primary")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTemplateSignature(parser.Expression{Value: tt.input})
+			if err == nil || err == errNoDefaultParameters {
+				t.Errorf("expected error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package testmarkdown
+
+import (
+	"context"
+	_ "embed"
+	"strings"
+	"testing"
+)
+
+//go:embed expected.html
+var expected string
+
+func Test(t *testing.T) {
+	var sb strings.Builder
+	if err := page("Ada & Bob").Render(context.Background(), &sb); err != nil {
+		t.Fatalf("failed to render page: %v", err)
+	}
+	sb.WriteString("\n")
+	if sb.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, sb.String())
+	}
+}
@@ -0,0 +1,26 @@
+package testsvg
+
+import (
+	"context"
+	_ "embed"
+	"strings"
+	"testing"
+)
+
+//go:embed expected.html
+var expected string
+
+func Test(t *testing.T) {
+	var sb strings.Builder
+	if err := icon("/home").Render(context.Background(), &sb); err != nil {
+		t.Fatalf("failed to render icon: %v", err)
+	}
+	sb.WriteString("\n")
+	if err := formula().Render(context.Background(), &sb); err != nil {
+		t.Fatalf("failed to render formula: %v", err)
+	}
+	sb.WriteString("\n")
+	if sb.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, sb.String())
+	}
+}
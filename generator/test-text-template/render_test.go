@@ -0,0 +1,26 @@
+package testtexttemplate
+
+import (
+	"context"
+	_ "embed"
+	"strings"
+	"testing"
+)
+
+//go:embed expected.txt
+var expected string
+
+func Test(t *testing.T) {
+	order := Order{
+		Name:  "Ada & <Bob>",
+		Items: []string{"Tea", "Cake"},
+		Total: "£5",
+	}
+	var sb strings.Builder
+	if err := email(order).Render(context.Background(), &sb); err != nil {
+		t.Fatalf("failed to render email: %v", err)
+	}
+	if sb.String() != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, sb.String())
+	}
+}
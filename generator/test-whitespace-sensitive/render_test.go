@@ -0,0 +1,21 @@
+package testwhitespacesensitive
+
+import (
+	"context"
+	_ "embed"
+	"strings"
+	"testing"
+)
+
+//go:embed expected.html
+var expected string
+
+func Test(t *testing.T) {
+	var sb strings.Builder
+	if err := render("<Ada>", []string{"a", "b"}).Render(context.Background(), &sb); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if sb.String() != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, sb.String())
+	}
+}
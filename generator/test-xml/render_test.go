@@ -0,0 +1,30 @@
+package testxml
+
+import (
+	"context"
+	_ "embed"
+	"strings"
+	"testing"
+)
+
+//go:embed expected.xml
+var expected string
+
+func Test(t *testing.T) {
+	items := []Item{
+		{Title: "First & best", Link: "https://example.com/1"},
+		{Title: "Second", Link: "https://example.com/2", Draft: true},
+	}
+	var sb strings.Builder
+	if err := feed("News", items).Render(context.Background(), &sb); err != nil {
+		t.Fatalf("failed to render feed: %v", err)
+	}
+	sb.WriteString("\n")
+	if err := config().Render(context.Background(), &sb); err != nil {
+		t.Fatalf("failed to render config: %v", err)
+	}
+	sb.WriteString("\n")
+	if sb.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, sb.String())
+	}
+}
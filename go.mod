@@ -9,12 +9,14 @@ require (
 	github.com/a-h/parse v0.0.0-20230402144745-e6c8bc86e846
 	github.com/a-h/pathvars v0.0.12
 	github.com/a-h/protocol v0.0.0-20230224160810-b4eec67c1c22
+	github.com/andybalholm/brotli v1.0.5
 	github.com/cenkalti/backoff/v4 v4.2.1
 	github.com/cli/browser v1.2.0
 	github.com/fatih/color v1.16.0
 	github.com/google/go-cmp v0.6.0
 	github.com/natefinch/atomic v1.0.1
 	github.com/rs/cors v1.8.3
+	github.com/yuin/goldmark v1.5.4
 	go.lsp.dev/jsonrpc2 v0.10.0
 	go.lsp.dev/uri v0.3.0
 	go.uber.org/zap v1.24.0
@@ -0,0 +1,146 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// ImgAlt reports images without alternative text.
+var ImgAlt = &Rule{
+	Name: "img-alt",
+	Doc:  `<img> elements must have an alt attribute that describes the image to screen readers, or alt="" if the image is decorative.`,
+	Run: func(pass *Pass) {
+		walkElements(pass.File, func(el parser.Element, _ []parser.Element) {
+			if strings.EqualFold(el.Name, "img") && !hasAttribute(el.Attributes, "alt") {
+				pass.Reportf(el.NameRange, `<img>: missing alt attribute, use alt="" if the image is decorative`)
+			}
+		})
+	},
+}
+
+// HTMLLang reports documents without a language.
+var HTMLLang = &Rule{
+	Name: "html-lang",
+	Doc:  `<html> elements must have a lang attribute, e.g. lang="en", so that screen readers use the right pronunciation.`,
+	Run: func(pass *Pass) {
+		walkElements(pass.File, func(el parser.Element, _ []parser.Element) {
+			if strings.EqualFold(el.Name, "html") && !hasAttribute(el.Attributes, "lang") {
+				pass.Reportf(el.NameRange, `<html>: missing lang attribute, e.g. lang="en"`)
+			}
+		})
+	},
+}
+
+// unlabelledInputTypes are the types of input that don't need a label.
+var unlabelledInputTypes = map[string]bool{
+	"hidden": true,
+	"submit": true,
+	"reset":  true,
+	"button": true,
+	"image":  true,
+}
+
+// FormLabel reports form controls without labels.
+var FormLabel = &Rule{
+	Name: "form-label",
+	Doc:  `<input>, <select> and <textarea> elements must have a label, either a <label> element that contains the control, or refers to its id with the for attribute, or an aria-label, aria-labelledby or title attribute.`,
+	Run: func(pass *Pass) {
+		// Find the ids that labels refer to.
+		labelled := make(map[string]bool)
+		var dynamicLabels bool
+		walkElements(pass.File, func(el parser.Element, _ []parser.Element) {
+			if !strings.EqualFold(el.Name, "label") {
+				return
+			}
+			if a, unknown := findAttribute(el.Attributes, "for"); a.constant {
+				labelled[a.value] = true
+			} else if a.found || unknown {
+				dynamicLabels = true
+			}
+		})
+
+		walkElements(pass.File, func(el parser.Element, ancestors []parser.Element) {
+			name := strings.ToLower(el.Name)
+			if name != "input" && name != "select" && name != "textarea" {
+				return
+			}
+			if name == "input" {
+				if t, unknown := findAttribute(el.Attributes, "type"); unknown || (t.found && (!t.constant || unlabelledInputTypes[strings.ToLower(t.value)])) {
+					return
+				}
+			}
+			if hasAttribute(el.Attributes, "aria-label", "aria-labelledby", "title") {
+				return
+			}
+			for _, a := range ancestors {
+				if strings.EqualFold(a.Name, "label") {
+					return
+				}
+			}
+			id, unknown := findAttribute(el.Attributes, "id")
+			if unknown || (id.found && (!id.constant || labelled[id.value] || dynamicLabels)) {
+				return
+			}
+			pass.Reportf(el.NameRange, "<%s>: missing label, add a <label> element, or an aria-label attribute", el.Name)
+		})
+	},
+}
+
+// EmptyLink reports links without text.
+var EmptyLink = &Rule{
+	Name: "empty-link",
+	Doc:  `<a> elements must have text, or an aria-label, aria-labelledby or title attribute, so that screen readers can describe the link.`,
+	Run: func(pass *Pass) {
+		reportEmpty(pass, "a")
+	},
+}
+
+// EmptyButton reports buttons without text.
+var EmptyButton = &Rule{
+	Name: "empty-button",
+	Doc:  `<button> elements must have text, or an aria-label, aria-labelledby or title attribute, so that screen readers can describe the button.`,
+	Run: func(pass *Pass) {
+		reportEmpty(pass, "button")
+	},
+}
+
+func reportEmpty(pass *Pass, name string) {
+	walkElements(pass.File, func(el parser.Element, _ []parser.Element) {
+		if !strings.EqualFold(el.Name, name) || hasContent(el.Children) {
+			return
+		}
+		if hasAttribute(el.Attributes, "aria-label", "aria-labelledby", "title") {
+			return
+		}
+		pass.Reportf(el.NameRange, "<%s>: no text, add text, or an aria-label attribute", el.Name)
+	})
+}
+
+// hasContent returns true if the nodes contain text that's read by screen readers, or might do.
+func hasContent(nodes []parser.Node) bool {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case parser.Whitespace, parser.GoComment, parser.HTMLComment:
+			continue
+		case parser.Text:
+			if strings.TrimSpace(n.Value) != "" {
+				return true
+			}
+		case parser.Element:
+			if strings.EqualFold(n.Name, "img") {
+				if alt, unknown := findAttribute(n.Attributes, "alt"); unknown || (alt.found && (!alt.constant || strings.TrimSpace(alt.value) != "")) {
+					return true
+				}
+				continue
+			}
+			if hasAttribute(n.Attributes, "aria-label", "aria-labelledby", "title") || hasContent(n.Children) {
+				return true
+			}
+		default:
+			// Expressions, and components, might output text.
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// ConfigFileName is the name of the configuration file, which is found in the directory of the
+// templ file, or a parent directory, up to the directory that contains the go.mod file.
+const ConfigFileName = ".templ-lint.json"
+
+// Config configures the rules, e.g.
+//
+//	{
+//	  "rules": {
+//	    "img-alt": { "severity": "error" },
+//	    "raw-html": { "disabled": true },
+//	    "unused-template": { "options": { "exported": "true" } }
+//	  }
+//	}
+type Config struct {
+	// Rules configures each rule by name. Rules that aren't configured are enabled, and report
+	// warnings.
+	Rules map[string]RuleConfig `json:"rules"`
+}
+
+// RuleConfig configures a rule.
+type RuleConfig struct {
+	// Disabled turns the rule off.
+	Disabled bool `json:"disabled,omitempty"`
+	// Severity of the findings of the rule, "warning" (the default) or "error".
+	Severity string `json:"severity,omitempty"`
+	// Options are specific to each rule.
+	Options map[string]string `json:"options,omitempty"`
+}
+
+func (rc RuleConfig) severity() parser.DiagnosticSeverity {
+	if rc.Severity == "error" {
+		return parser.DiagnosticSeverityError
+	}
+	return parser.DiagnosticSeverityWarning
+}
+
+func (c Config) validate(rules []*Rule) error {
+	names := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		names[r.Name] = true
+	}
+	for name, rc := range c.Rules {
+		if !names[name] {
+			return fmt.Errorf("unknown rule %q", name)
+		}
+		if rc.Severity != "" && rc.Severity != "warning" && rc.Severity != "error" {
+			return fmt.Errorf("rule %q: unknown severity %q, expected warning or error", name, rc.Severity)
+		}
+	}
+	return nil
+}
+
+// ReadConfig reads a configuration file.
+func ReadConfig(fileName string) (c Config, err error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return c, err
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("failed to parse %s: %w", fileName, err)
+	}
+	return c, nil
+}
+
+// FindConfig reads the configuration file within dir, or the nearest parent directory, up to
+// the directory that contains the go.mod file. If there's no configuration file, the default
+// configuration is returned.
+func FindConfig(dir string) (c Config, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return c, err
+	}
+	for {
+		fileName := filepath.Join(dir, ConfigFileName)
+		if _, err = os.Stat(fileName); err == nil {
+			return ReadConfig(fileName)
+		}
+		if _, err = os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return c, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return c, nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,100 @@
+package lint
+
+import (
+	"github.com/a-h/templ/parser/v2"
+)
+
+// DuplicateID reports elements with the same id within a template.
+var DuplicateID = &Rule{
+	Name: "duplicate-id",
+	Doc:  `Element ids must be unique within a page. The rule reports constant ids that are used more than once within a template, or within a for loop. The branches of if and switch statements are checked separately.`,
+	Run: func(pass *Pass) {
+		for _, n := range pass.File.Nodes {
+			if t, ok := n.(parser.HTMLTemplate); ok {
+				checkDuplicateIDs(pass, t)
+			}
+		}
+	},
+}
+
+// checkDuplicateIDs reports the duplicate ids within a template.
+func checkDuplicateIDs(pass *Pass, t parser.HTMLTemplate) {
+	frames := []*idFrame{{seen: make(map[string]parser.Range)}}
+	parser.Inspect(t, func(n any) bool {
+		if n == nil {
+			top := frames[len(frames)-1]
+			frames = frames[:len(frames)-1]
+			top.mergeBranches()
+			return false
+		}
+		parent := frames[len(frames)-1]
+		seen, inLoop := parent.childSeen(), parent.inLoop
+		frame := &idFrame{seen: seen, inLoop: inLoop}
+		switch n := n.(type) {
+		case parser.Element:
+			if id, _ := findAttribute(n.Attributes, "id"); id.constant && id.value != "" {
+				if first, ok := seen[id.value]; ok {
+					pass.Reportf(id.r, "<%s>: duplicate id %q, also used on line %d", n.Name, id.value, first.From.Line+1)
+				} else if inLoop {
+					pass.Reportf(id.r, "<%s>: duplicate id %q, the element is within a for loop", n.Name, id.value)
+				}
+				if _, ok := seen[id.value]; !ok {
+					seen[id.value] = id.r
+				}
+			}
+		case parser.IfExpression:
+			// The then nodes, each else if statement, and the else nodes are branches.
+			elseFrom := len(n.Then) + len(n.ElseIfs)
+			frame.isBranch = func(i int) bool {
+				return i == 0 || i >= len(n.Then) && i <= elseFrom
+			}
+		case parser.SwitchExpression:
+			// Each case is a branch.
+			frame.isBranch = func(int) bool { return true }
+		case parser.ForExpression:
+			frame.inLoop = true
+		}
+		frames = append(frames, frame)
+		return true
+	})
+}
+
+// idFrame is the state of a node while its children are checked for duplicate ids.
+type idFrame struct {
+	// seen is the ids seen before the children of the node.
+	seen   map[string]parser.Range
+	inLoop bool
+	// isBranch returns true if the child at the index starts a branch of an if or switch
+	// statement. Each branch is checked with a copy of seen, and then the ids of all of the
+	// branches are added to seen.
+	isBranch func(i int) bool
+	children int
+	branches []map[string]parser.Range
+}
+
+// childSeen returns the ids seen before the next child of the node.
+func (f *idFrame) childSeen() map[string]parser.Range {
+	if f.isBranch == nil {
+		return f.seen
+	}
+	if f.isBranch(f.children) {
+		branchSeen := make(map[string]parser.Range, len(f.seen))
+		for id, r := range f.seen {
+			branchSeen[id] = r
+		}
+		f.branches = append(f.branches, branchSeen)
+	}
+	f.children++
+	return f.branches[len(f.branches)-1]
+}
+
+// mergeBranches adds the ids of the branches to seen, keeping the first use of each id.
+func (f *idFrame) mergeBranches() {
+	for _, branchSeen := range f.branches {
+		for id, r := range branchSeen {
+			if _, ok := f.seen[id]; !ok {
+				f.seen[id] = r
+			}
+		}
+	}
+}
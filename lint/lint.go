@@ -0,0 +1,226 @@
+// Package lint checks templ files for accessibility, security and maintenance problems.
+//
+// Each check is a Rule. The built-in rules are in DefaultRules, and custom rules can be run
+// alongside them with a Linter.
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// Rule checks templ files for a kind of problem.
+type Rule struct {
+	// Name of the rule, used in configuration and suppression comments, e.g. img-alt.
+	Name string
+	// Doc describes the problem that the rule finds, and how to fix it.
+	Doc string
+	// Run reports the problems within the file of the pass.
+	Run func(pass *Pass)
+}
+
+// DefaultRules are the built-in rules.
+var DefaultRules = []*Rule{
+	ImgAlt,
+	HTMLLang,
+	FormLabel,
+	EmptyLink,
+	EmptyButton,
+	RawHTML,
+	TargetBlank,
+	UnusedTemplate,
+	DuplicateID,
+}
+
+// Pass provides a rule with a templ file to check, and collects its findings.
+type Pass struct {
+	Rule *Rule
+	// FileName of the templ file.
+	FileName string
+	// File is the parsed templ file.
+	File parser.TemplateFile
+	// Options of the rule, from the configuration.
+	Options map[string]string
+
+	pkg      func() (*Package, error)
+	severity parser.DiagnosticSeverity
+	findings []Finding
+}
+
+// Reportf reports a problem within the range of the file.
+func (p *Pass) Reportf(r parser.Range, format string, args ...any) {
+	p.findings = append(p.findings, Finding{
+		FileName: p.FileName,
+		Rule:     p.Rule.Name,
+		Message:  fmt.Sprintf(format, args...),
+		Range:    r,
+		Severity: p.severity,
+	})
+}
+
+// Package returns the other files within the package of the templ file. The files are read
+// from disk the first time that Package is called.
+func (p *Pass) Package() (*Package, error) {
+	return p.pkg()
+}
+
+// Package is the templ and Go files within a directory, other than the file that's being
+// linted.
+type Package struct {
+	// TemplFiles that could be parsed, by file name.
+	TemplFiles map[string]parser.TemplateFile
+	// GoFiles are the contents of the Go files, by file name. Generated _templ.go files are
+	// excluded.
+	GoFiles map[string]string
+}
+
+// Finding is a problem found by a rule.
+type Finding struct {
+	FileName string
+	// Rule is the name of the rule that found the problem.
+	Rule     string
+	Message  string
+	Range    parser.Range
+	Severity parser.DiagnosticSeverity
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", f.FileName, f.Range.From.Line+1, f.Range.From.Col+1, f.Message, f.Rule)
+}
+
+// Linter runs rules on templ files.
+type Linter struct {
+	Rules  []*Rule
+	Config Config
+}
+
+// New creates a Linter that runs the default rules, and any additional rules, configured by
+// config.
+func New(config Config, additionalRules ...*Rule) (l Linter, err error) {
+	l = Linter{
+		Rules:  append(append([]*Rule{}, DefaultRules...), additionalRules...),
+		Config: config,
+	}
+	return l, config.validate(l.Rules)
+}
+
+// Lint runs the rules on a templ file, and returns the findings that aren't suppressed,
+// ordered by position.
+func (l Linter) Lint(fileName, contents string, tf parser.TemplateFile) (findings []Finding) {
+	var pkg *Package
+	var pkgErr error
+	var pkgLoaded bool
+	loadPackage := func() (*Package, error) {
+		if !pkgLoaded {
+			pkg, pkgErr = readPackage(fileName)
+			pkgLoaded = true
+		}
+		return pkg, pkgErr
+	}
+
+	suppressed := parseSuppressions(contents)
+	for _, rule := range l.Rules {
+		rc := l.Config.Rules[rule.Name]
+		if rc.Disabled {
+			continue
+		}
+		pass := &Pass{
+			Rule:     rule,
+			FileName: fileName,
+			File:     tf,
+			Options:  rc.Options,
+			pkg:      loadPackage,
+			severity: rc.severity(),
+		}
+		rule.Run(pass)
+		for _, f := range pass.findings {
+			if !suppressed.has(rule.Name, int(f.Range.From.Line)) {
+				findings = append(findings, f)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Range.From, findings[j].Range.From
+		return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+	})
+	return findings
+}
+
+// readPackage reads the other templ and Go files within the directory of fileName.
+func readPackage(fileName string) (pkg *Package, err error) {
+	dir := filepath.Dir(fileName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkg = &Package{
+		TemplFiles: make(map[string]parser.TemplateFile),
+		GoFiles:    make(map[string]string),
+	}
+	for _, entry := range entries {
+		name := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || filepath.Base(name) == filepath.Base(fileName) {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, ".templ"):
+			tf, err := parser.Parse(name)
+			if err != nil {
+				continue
+			}
+			pkg.TemplFiles[name] = tf
+		case strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_templ.go"):
+			contents, err := os.ReadFile(name)
+			if err != nil {
+				return nil, err
+			}
+			// Generated code may have a different suffix, see templ generate -output-suffix.
+			if strings.HasPrefix(string(contents), "// Code generated by templ - DO NOT EDIT.") {
+				continue
+			}
+			pkg.GoFiles[name] = string(contents)
+		}
+	}
+	return pkg, nil
+}
+
+// suppressionRegexp matches comments that suppress findings, e.g.
+// "// templ-lint:ignore img-alt,raw-html the image is decorative".
+var suppressionRegexp = regexp.MustCompile(`templ-lint:(ignore|file-ignore)\s+([a-z0-9,-]+)`)
+
+// suppressions are the rules that are suppressed by line, or for the whole file, at line -1.
+type suppressions map[int]map[string]bool
+
+// parseSuppressions finds the suppression comments within a templ file. An ignore comment
+// suppresses findings on the same line and the next line, and a file-ignore comment
+// suppresses findings in the whole file.
+func parseSuppressions(contents string) suppressions {
+	s := suppressions{}
+	for i, line := range strings.Split(contents, "\n") {
+		for _, m := range suppressionRegexp.FindAllStringSubmatch(line, -1) {
+			lines := []int{i, i + 1}
+			if m[1] == "file-ignore" {
+				lines = []int{-1}
+			}
+			for _, l := range lines {
+				if s[l] == nil {
+					s[l] = make(map[string]bool)
+				}
+				for _, rule := range strings.Split(m[2], ",") {
+					s[l][rule] = true
+				}
+			}
+		}
+	}
+	return s
+}
+
+func (s suppressions) has(rule string, line int) bool {
+	return s[-1][rule] || s[-1]["all"] || s[line][rule] || s[line]["all"]
+}
@@ -0,0 +1,363 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/a-h/templ/parser/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+// lintFile writes the templ file, and any other files of the package, to a temporary
+// directory, and returns the findings as "line: message (rule)".
+func lintFile(t *testing.T, config Config, contents string, otherFiles map[string]string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, c := range otherFiles {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(c), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	fileName := filepath.Join(dir, "test.templ")
+	if err := os.WriteFile(fileName, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write templ file: %v", err)
+	}
+	tf, err := parser.ParseString(contents)
+	if err != nil {
+		t.Fatalf("failed to parse templ file: %v", err)
+	}
+	l, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create linter: %v", err)
+	}
+	var actual []string
+	for _, f := range l.Lint(fileName, contents, tf) {
+		if f.FileName != fileName {
+			t.Errorf("expected file name %q, got %q", fileName, f.FileName)
+		}
+		actual = append(actual, fmt.Sprintf("%d: %s (%s)", f.Range.From.Line+1, f.Message, f.Rule))
+	}
+	return actual
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		otherFiles map[string]string
+		expected   []string
+	}{
+		{
+			name: "img-alt: images without alt are reported",
+			input: `package test
+
+templ Images(src string) {
+	<img src="a.png" alt="A"/>
+	<img src="b.png" alt=""/>
+	<img src={ src } alt?={ true }/>
+	<img src="c.png"/>
+	<img { attrs... }/>
+}`,
+			expected: []string{
+				`7: <img>: missing alt attribute, use alt="" if the image is decorative (img-alt)`,
+			},
+		},
+		{
+			name: "html-lang: html elements without lang are reported",
+			input: `package test
+
+templ Page() {
+	<html>
+		<body></body>
+	</html>
+}
+
+templ LangPage() {
+	<html lang="en"></html>
+}`,
+			expected: []string{
+				`4: <html>: missing lang attribute, e.g. lang="en" (html-lang)`,
+			},
+		},
+		{
+			name: "form-label: controls without labels are reported",
+			input: `package test
+
+templ Form() {
+	<form>
+		<label>Name <input type="text" name="name"/></label>
+		<label for="email">Email</label>
+		<input type="email" id="email"/>
+		<input type="hidden" name="csrf"/>
+		<input type="submit"/>
+		<input type="text" aria-label="Search"/>
+		<input type="text" name="unlabelled"/>
+		<select name="colour"></select>
+		<textarea id="notes"></textarea>
+	</form>
+}`,
+			expected: []string{
+				`11: <input>: missing label, add a <label> element, or an aria-label attribute (form-label)`,
+				`12: <select>: missing label, add a <label> element, or an aria-label attribute (form-label)`,
+				`13: <textarea>: missing label, add a <label> element, or an aria-label attribute (form-label)`,
+			},
+		},
+		{
+			name: "form-label: ids might be labelled by dynamic for attributes",
+			input: `package test
+
+templ Form(id string) {
+	<label for={ id }>Name</label>
+	<input type="text" id="name"/>
+}`,
+		},
+		{
+			name: "empty-link and empty-button: elements without text are reported",
+			input: `package test
+
+templ Links(name string) {
+	<a href="/"></a>
+	<a href="/"><img src="home.png" alt=""/></a>
+	<a href="/"><img src="home.png" alt="Home"/></a>
+	<a href="/">{ name }</a>
+	<a href="/" aria-label="Home"><svg></svg></a>
+	<button>
+	</button>
+	<button>Save</button>
+}`,
+			expected: []string{
+				`4: <a>: no text, add text, or an aria-label attribute (empty-link)`,
+				`5: <a>: no text, add text, or an aria-label attribute (empty-link)`,
+				`9: <button>: no text, add text, or an aria-label attribute (empty-button)`,
+			},
+		},
+		{
+			name: "raw-html: templ.Raw is reported",
+			input: `package test
+
+templ Raw(html string) {
+	<div>
+		@templ.Raw(html)
+	</div>
+}`,
+			expected: []string{
+				`5: templ.Raw: the HTML isn't escaped, make sure that it's trusted (raw-html)`,
+			},
+		},
+		{
+			name: "target-blank: links that open a new window without rel are reported",
+			input: `package test
+
+templ Links() {
+	<a href="/a" target="_blank">A</a>
+	<a href="/b" target="_blank" rel="noopener">B</a>
+	<a href="/c" target="_blank" rel="external noreferrer">C</a>
+	<a href="/d" target="_self">D</a>
+	<form target="_blank"></form>
+}`,
+			expected: []string{
+				`4: <a>: target="_blank" without rel="noopener" (target-blank)`,
+				`8: <form>: target="_blank" without rel="noopener" (target-blank)`,
+			},
+		},
+		{
+			name: "unused-template: unused css and script templates are reported",
+			input: `package test
+
+css used() {
+	color: red;
+}
+
+css unused() {
+	color: blue;
+}
+
+css Exported() {
+	color: green;
+}
+
+script usedByGo() {
+	alert("hello");
+}
+
+script unusedScript() {
+	alert("hello");
+}
+
+templ Page() {
+	<div class={ used() }></div>
+}`,
+			otherFiles: map[string]string{
+				"test.go":       "package test\n\nvar _ = usedByGo()\n",
+				"test_templ.go": "package test\n\nvar _ = unused()\n",
+			},
+			expected: []string{
+				`7: css template unused is not used (unused-template)`,
+				`19: script template unusedScript is not used (unused-template)`,
+			},
+		},
+		{
+			name: "duplicate-id: repeated constant ids are reported",
+			input: `package test
+
+templ Page(ok bool, items []string) {
+	<div id="a"></div>
+	<div id="a"></div>
+	if ok {
+		<div id="b"></div>
+	} else {
+		<div id="b"></div>
+	}
+	<div id="b"></div>
+	for _, item := range items {
+		<div id="c">{ item }</div>
+	}
+	switch len(items) {
+	case 0:
+		<div id="d"></div>
+	default:
+		<div id="d"></div>
+	}
+}
+
+templ Other() {
+	<div id="a"></div>
+}`,
+			expected: []string{
+				`5: <div>: duplicate id "a", also used on line 4 (duplicate-id)`,
+				`11: <div>: duplicate id "b", also used on line 7 (duplicate-id)`,
+				`13: <div>: duplicate id "c", the element is within a for loop (duplicate-id)`,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := lintFile(t, Config{}, test.input, test.otherFiles)
+			if diff := cmp.Diff(test.expected, actual); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestSuppressions(t *testing.T) {
+	input := `package test
+
+// templ-lint:file-ignore html-lang
+
+templ Page() {
+	<html>
+		// templ-lint:ignore img-alt the image is decorative
+		<img src="a.png"/>
+		<img src="b.png"/>
+		<img src="c.png"/> // templ-lint:ignore empty-link,img-alt
+	</html>
+}`
+	expected := []string{
+		`9: <img>: missing alt attribute, use alt="" if the image is decorative (img-alt)`,
+	}
+	actual := lintFile(t, Config{}, input, nil)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	input := `package test
+
+templ Page() {
+	<html>
+		<img src="a.png"/>
+	</html>
+}`
+	t.Run("rules can be disabled", func(t *testing.T) {
+		config := Config{Rules: map[string]RuleConfig{"html-lang": {Disabled: true}}}
+		expected := []string{
+			`5: <img>: missing alt attribute, use alt="" if the image is decorative (img-alt)`,
+		}
+		if diff := cmp.Diff(expected, lintFile(t, config, input, nil)); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("the severity of rules can be set", func(t *testing.T) {
+		config := Config{Rules: map[string]RuleConfig{"img-alt": {Severity: "error"}}}
+		l, err := New(config)
+		if err != nil {
+			t.Fatalf("failed to create linter: %v", err)
+		}
+		tf, err := parser.ParseString(input)
+		if err != nil {
+			t.Fatalf("failed to parse templ file: %v", err)
+		}
+		severities := map[string]parser.DiagnosticSeverity{}
+		for _, f := range l.Lint(filepath.Join(t.TempDir(), "test.templ"), input, tf) {
+			severities[f.Rule] = f.Severity
+		}
+		expected := map[string]parser.DiagnosticSeverity{
+			"html-lang": parser.DiagnosticSeverityWarning,
+			"img-alt":   parser.DiagnosticSeverityError,
+		}
+		if diff := cmp.Diff(expected, severities); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("unknown rules are rejected", func(t *testing.T) {
+		_, err := New(Config{Rules: map[string]RuleConfig{"img-alts": {}}})
+		if err == nil || err.Error() != `unknown rule "img-alts"` {
+			t.Errorf("expected unknown rule error, got %v", err)
+		}
+	})
+	t.Run("unknown severities are rejected", func(t *testing.T) {
+		_, err := New(Config{Rules: map[string]RuleConfig{"img-alt": {Severity: "info"}}})
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+	t.Run("custom rules can be configured", func(t *testing.T) {
+		custom := &Rule{
+			Name: "no-div",
+			Run: func(pass *Pass) {
+				walkElements(pass.File, func(el parser.Element, _ []parser.Element) {
+					if el.Name == "div" {
+						pass.Reportf(el.NameRange, "<div>: use a semantic element")
+					}
+				})
+			},
+		}
+		if _, err := New(Config{Rules: map[string]RuleConfig{"no-div": {Severity: "error"}}}, custom); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestFindConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/lint\n"), 0o644); err != nil {
+		t.Fatalf("failed to write go.mod: %v", err)
+	}
+	sub := filepath.Join(dir, "components")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	c, err := FindConfig(sub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(c.Rules) != 0 {
+		t.Errorf("expected the default config, got %v", c)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(`{"rules":{"raw-html":{"disabled":true}}}`), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	c, err = FindConfig(sub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !c.Rules["raw-html"].Disabled {
+		t.Errorf("expected raw-html to be disabled, got %v", c)
+	}
+}
@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"go/token"
+	"strings"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// RawHTML reports uses of templ.Raw.
+var RawHTML = &Rule{
+	Name: "raw-html",
+	Doc:  `templ.Raw outputs HTML without escaping it, so that any HTML or scripts within user input are run by the browser. Only use templ.Raw with HTML that's trusted, and suppress the finding.`,
+	Run: func(pass *Pass) {
+		for _, expr := range goExpressions(pass.File) {
+			toks, lits := goTokens(expr.Value)
+			for i := 0; i+2 < len(toks); i++ {
+				if toks[i] == token.IDENT && lits[i] == "templ" && toks[i+1] == token.PERIOD && toks[i+2] == token.IDENT && lits[i+2] == "Raw" {
+					pass.Reportf(expr.Range, "templ.Raw: the HTML isn't escaped, make sure that it's trusted")
+					break
+				}
+			}
+		}
+	},
+}
+
+// TargetBlank reports links that open a new window, and give it access to the page.
+var TargetBlank = &Rule{
+	Name: "target-blank",
+	Doc:  `Pages opened with target="_blank" can navigate the page that opened them with window.opener in older browsers. Add rel="noopener", or rel="noreferrer".`,
+	Run: func(pass *Pass) {
+		walkElements(pass.File, func(el parser.Element, _ []parser.Element) {
+			name := strings.ToLower(el.Name)
+			if name != "a" && name != "area" && name != "form" {
+				return
+			}
+			target, _ := findAttribute(el.Attributes, "target")
+			if !target.constant || !strings.EqualFold(target.value, "_blank") {
+				return
+			}
+			rel, unknown := findAttribute(el.Attributes, "rel")
+			if unknown || (rel.found && !rel.constant) {
+				return
+			}
+			for _, value := range strings.Fields(strings.ToLower(rel.value)) {
+				if value == "noopener" || value == "noreferrer" {
+					return
+				}
+			}
+			pass.Reportf(target.r, `<%s>: target="_blank" without rel="noopener"`, el.Name)
+		})
+	},
+}
@@ -0,0 +1,67 @@
+package lint
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// UnusedTemplate reports css and script templates that aren't used within their package.
+var UnusedTemplate = &Rule{
+	Name: "unused-template",
+	Doc:  `css and script templates that aren't used within their package can be removed. Exported templates might be used by other packages, and are only reported if the "exported" option is "true".`,
+	Run: func(pass *Pass) {
+		type definition struct {
+			kind string
+			name parser.Expression
+		}
+		var definitions []definition
+		for _, n := range pass.File.Nodes {
+			switch n := n.(type) {
+			case parser.CSSTemplate:
+				definitions = append(definitions, definition{kind: "css", name: n.Name})
+			case parser.ScriptTemplate:
+				definitions = append(definitions, definition{kind: "script", name: n.Name})
+			}
+		}
+		if len(definitions) == 0 {
+			return
+		}
+		pkg, err := pass.Package()
+		if err != nil {
+			return
+		}
+
+		// Count the identifiers within the Go code of the package.
+		used := make(map[string]bool)
+		addIdents := func(src string) {
+			toks, lits := goTokens(src)
+			for i, tok := range toks {
+				if tok == token.IDENT {
+					used[lits[i]] = true
+				}
+			}
+		}
+		for _, expr := range goExpressions(pass.File) {
+			addIdents(expr.Value)
+		}
+		for _, tf := range pkg.TemplFiles {
+			for _, expr := range goExpressions(tf) {
+				addIdents(expr.Value)
+			}
+		}
+		for _, src := range pkg.GoFiles {
+			addIdents(src)
+		}
+
+		for _, d := range definitions {
+			if ast.IsExported(d.name.Value) && pass.Options["exported"] != "true" {
+				continue
+			}
+			if !used[d.name.Value] {
+				pass.Reportf(d.name.Range, "%s template %s is not used", d.kind, d.name.Value)
+			}
+		}
+	},
+}
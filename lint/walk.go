@@ -0,0 +1,148 @@
+package lint
+
+import (
+	"go/scanner"
+	"go/token"
+	"strings"
+
+	"github.com/a-h/templ/parser/v2"
+)
+
+// walkElements calls f for each element within the HTML templates of a file, with the
+// elements that contain it, outermost first.
+func walkElements(tf parser.TemplateFile, f func(el parser.Element, ancestors []parser.Element)) {
+	for _, n := range tf.Nodes {
+		t, ok := n.(parser.HTMLTemplate)
+		if !ok {
+			continue
+		}
+		var ancestors []parser.Element
+		// isElement records whether each node being visited is an element, so that it can be
+		// removed from the ancestors after its children.
+		var isElement []bool
+		parser.Inspect(t, func(n any) bool {
+			if n == nil {
+				if isElement[len(isElement)-1] {
+					ancestors = ancestors[:len(ancestors)-1]
+				}
+				isElement = isElement[:len(isElement)-1]
+				return false
+			}
+			el, ok := n.(parser.Element)
+			if ok {
+				f(el, ancestors)
+				ancestors = append(ancestors[:len(ancestors):len(ancestors)], el)
+			}
+			isElement = append(isElement, ok)
+			return true
+		})
+	}
+}
+
+// attribute describes an attribute of an element.
+type attribute struct {
+	// found is true if the element has the attribute.
+	found bool
+	// constant is true if the value of the attribute is known.
+	constant bool
+	value    string
+	r        parser.Range
+}
+
+// findAttribute finds an attribute by name. If the element has spread attributes, which could
+// include the attribute, unknown is true.
+func findAttribute(attrs []parser.Attribute, name string) (a attribute, unknown bool) {
+	for _, attr := range attrs {
+		switch attr := attr.(type) {
+		case parser.ConstantAttribute:
+			if strings.EqualFold(attr.Name, name) {
+				return attribute{found: true, constant: true, value: attr.Value, r: attr.Range}, unknown
+			}
+		case parser.BoolConstantAttribute:
+			if strings.EqualFold(attr.Name, name) {
+				return attribute{found: true, constant: true, r: attr.Range}, unknown
+			}
+		case parser.ExpressionAttribute:
+			if strings.EqualFold(attr.Name, name) {
+				return attribute{found: true, r: attr.Range}, unknown
+			}
+		case parser.BoolExpressionAttribute:
+			if strings.EqualFold(attr.Name, name) {
+				return attribute{found: true, r: attr.Range}, unknown
+			}
+		case parser.SpreadAttributes:
+			unknown = true
+		case parser.ConditionalAttribute:
+			then, thenUnknown := findAttribute(attr.Then, name)
+			els, elseUnknown := findAttribute(attr.Else, name)
+			unknown = unknown || thenUnknown || elseUnknown
+			if then.found || els.found {
+				// The value depends on the condition.
+				return attribute{found: true, r: attr.Range}, unknown
+			}
+		}
+	}
+	return attribute{}, unknown
+}
+
+// hasAttribute returns true if the element has, or might have, any of the attributes.
+func hasAttribute(attrs []parser.Attribute, names ...string) bool {
+	for _, name := range names {
+		if a, unknown := findAttribute(attrs, name); a.found || unknown {
+			return true
+		}
+	}
+	return false
+}
+
+// goExpressions returns the Go expressions within the templates and Go code of a file.
+func goExpressions(tf parser.TemplateFile) (expressions []parser.Expression) {
+	parser.Inspect(tf, func(n any) bool {
+		switch n := n.(type) {
+		case parser.TemplateFileGoExpression:
+			expressions = append(expressions, n.Expression)
+		case parser.StringExpression:
+			expressions = append(expressions, n.Expression)
+		case parser.TemplElementExpression:
+			expressions = append(expressions, n.Expression)
+		case parser.CallTemplateExpression:
+			expressions = append(expressions, n.Expression)
+		case parser.IfExpression:
+			expressions = append(expressions, n.Expression)
+		case parser.ElseIfExpression:
+			expressions = append(expressions, n.Expression)
+		case parser.ForExpression:
+			expressions = append(expressions, n.Expression)
+		case parser.SwitchExpression:
+			expressions = append(expressions, n.Expression)
+		case parser.CaseExpression:
+			expressions = append(expressions, n.Expression)
+		case parser.ExpressionAttribute:
+			expressions = append(expressions, n.Expression)
+		case parser.BoolExpressionAttribute:
+			expressions = append(expressions, n.Expression)
+		case parser.SpreadAttributes:
+			expressions = append(expressions, n.Expression)
+		case parser.ConditionalAttribute:
+			expressions = append(expressions, n.Expression)
+		}
+		return true
+	})
+	return expressions
+}
+
+// goTokens returns the tokens of Go code, and their literal values. Code that can't be
+// scanned is skipped.
+func goTokens(src string) (toks []token.Token, lits []string) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, []byte(src), func(token.Position, string) {}, 0)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return toks, lits
+		}
+		toks, lits = append(toks, tok), append(lits, lit)
+	}
+}
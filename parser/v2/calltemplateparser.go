@@ -12,6 +12,7 @@ type callTemplateExpressionParser struct{}
 
 func (p callTemplateExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// Check the prefix first.
+	from := pi.Position()
 	if _, ok, err = callTemplateExpressionStart.Parse(pi); err != nil || !ok {
 		return
 	}
@@ -24,9 +25,10 @@ func (p callTemplateExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, e
 
 	// Eat the final brace.
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("call template expression: missing closing brace", pi.Position())
+		err = newParseError(codeMissingCloseBrace, "call template expression: missing closing brace", pi.Position())
 		return
 	}
+	r.Range = NewRange(from, pi.Position())
 
 	return r, true, nil
 }
@@ -13,9 +13,10 @@ var childrenExpressionParser = parse.StringFrom(
 )
 
 var childrenExpression = parse.Func(func(in *parse.Input) (n Node, ok bool, err error) {
+	from := in.Position()
 	_, ok, err = childrenExpressionParser.Parse(in)
 	if err != nil || !ok {
 		return
 	}
-	return ChildrenExpression{}, true, nil
+	return ChildrenExpression{Range: NewRange(from, in.Position())}, true, nil
 })
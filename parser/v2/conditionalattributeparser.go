@@ -20,25 +20,25 @@ func (_ conditionalAttributeParser) Parse(pi *parse.Input) (r ConditionalAttribu
 	// Once we've got a prefix, read until {\n.
 	until := parse.All(openBraceWithOptionalPadding, parse.NewLine)
 	if r.Expression, ok, err = ExpressionOf(parse.StringUntil(until)).Parse(pi); err != nil || !ok {
-		err = parse.Error("attribute if: unterminated (missing closing '{\n')", pi.Position())
+		err = newParseError(codeIncompleteStatement, "attribute if: unterminated (missing closing '{\n')", pi.Position())
 		return
 	}
 
 	// Eat " {\n".
 	if _, ok, err = until.Parse(pi); err != nil || !ok {
-		err = parse.Error("attribute if: unterminated (missing closing '{\n')", pi.Position())
+		err = newParseError(codeIncompleteStatement, "attribute if: unterminated (missing closing '{\n')", pi.Position())
 		return
 	}
 
 	// Read the 'Then' attributes.
 	// If there's no match, there's a problem reading the attributes.
 	if r.Then, ok, err = (attributesParser{}).Parse(pi); err != nil || !ok {
-		err = parse.Error("attribute if: expected attributes in block, but none were found", pi.Position())
+		err = newParseError(codeInvalidBlock, "attribute if: expected attributes in block, but none were found", pi.Position())
 		return
 	}
 
 	if len(r.Then) == 0 {
-		err = parse.Error("attribute if: invalid content or no attributes were found in the if block", pi.Position())
+		err = newParseError(codeInvalidBlock, "attribute if: invalid content or no attributes were found in the if block", pi.Position())
 		return
 	}
 
@@ -47,7 +47,7 @@ func (_ conditionalAttributeParser) Parse(pi *parse.Input) (r ConditionalAttribu
 		return
 	}
 	if ok && len(r.Else) == 0 {
-		err = parse.Error("attribute if: invalid content or no attributes were found in the else block", pi.Position())
+		err = newParseError(codeInvalidBlock, "attribute if: invalid content or no attributes were found in the else block", pi.Position())
 		return
 	}
 
@@ -56,9 +56,11 @@ func (_ conditionalAttributeParser) Parse(pi *parse.Input) (r ConditionalAttribu
 
 	// Read the required closing brace.
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("attribute if: missing end (expected '}')", pi.Position())
+		err = newParseError(codeMissingCloseBrace, "attribute if: missing end (expected '}')", pi.Position())
 		return
 	}
+	// The range starts at `if `, before the expression.
+	r.Range = NewRange(pi.PositionAt(int(r.Expression.Range.From.Index)-len("if ")), pi.Position())
 
 	return r, true, nil
 }
@@ -87,7 +89,7 @@ func (attributeElseExpressionParser) Parse(in *parse.Input) (r []Attribute, ok b
 
 	// Else contents
 	if r, ok, err = (attributesParser{}).Parse(in); err != nil || !ok {
-		err = parse.Error("attribute if: expected attributes in else block, but none were found", in.Position())
+		err = newParseError(codeInvalidBlock, "attribute if: expected attributes in else block, but none were found", in.Position())
 		return
 	}
 
@@ -16,6 +16,7 @@ var cssParser = parse.Func(func(pi *parse.Input) (r CSSTemplate, ok bool, err er
 	}
 
 	// Parse the name.
+	from := pi.Position()
 	var exp cssExpression
 	if exp, ok, err = cssExpressionParser.Parse(pi); err != nil || !ok {
 		return
@@ -54,9 +55,10 @@ var cssParser = parse.Func(func(pi *parse.Input) (r CSSTemplate, ok bool, err er
 
 		// Try for }
 		if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-			err = parse.Error("css property expression: missing closing brace", pi.Position())
+			err = newParseError(codeMissingCloseBrace, "css property expression: missing closing brace", pi.Position())
 			return
 		}
+		r.Range = NewRange(from, pi.Position())
 
 		return r, true, nil
 	}
@@ -91,14 +93,14 @@ var cssExpressionParser = parse.Func(func(pi *parse.Input) (r cssExpression, ok
 	// If there's no match, the name wasn't correctly terminated.
 	var name string
 	if name, ok, err = cssExpressionNameParser.Parse(pi); err != nil || !ok {
-		err = parse.Error("css expression: invalid name", pi.Position())
+		err = newParseError(codeMalformedTemplate, "css expression: invalid name", pi.Position())
 		return
 	}
 	r.Name = NewExpression(name, from, pi.Position())
 
 	// Eat the open bracket.
 	if _, ok, err = openBracket.Parse(pi); err != nil || !ok {
-		err = parse.Error("css expression: parameters missing open bracket", pi.Position())
+		err = newParseError(codeMalformedTemplate, "css expression: parameters missing open bracket", pi.Position())
 		return
 	}
 
@@ -109,21 +111,21 @@ var cssExpressionParser = parse.Func(func(pi *parse.Input) (r cssExpression, ok
 	}
 	// If there's no match, the name wasn't correctly terminated.
 	if !ok {
-		return r, ok, parse.Error("css expression: parameters missing close bracket", pi.Position())
+		return r, ok, newParseError(codeMalformedTemplate, "css expression: parameters missing close bracket", pi.Position())
 	}
 	if pi.Index()-int(from.Index) > 0 {
-		return r, ok, parse.Error("css expression: found unexpected parameters", pi.Position())
+		return r, ok, newParseError(codeMalformedTemplate, "css expression: found unexpected parameters", pi.Position())
 	}
 
 	// Eat ") {".
 	if _, ok, err = expressionFuncEnd.Parse(pi); err != nil || !ok {
-		err = parse.Error("css expression: unterminated (missing ') {')", pi.Position())
+		err = newParseError(codeMalformedTemplate, "css expression: unterminated (missing ') {')", pi.Position())
 		return
 	}
 
 	// Expect a newline.
 	if _, ok, err = parse.NewLine.Parse(pi); err != nil || !ok {
-		err = parse.Error("css expression: missing terminating newline", pi.Position())
+		err = newParseError(codeMalformedTemplate, "css expression: missing terminating newline", pi.Position())
 		return
 	}
 
@@ -145,7 +147,7 @@ var cssPropertyNameParser = parse.Func(func(in *parse.Input) (name string, ok bo
 	}
 	if len(suffix)+1 > 128 {
 		ok = false
-		err = parse.Error("css property names must be < 128 characters long", in.Position())
+		err = newParseError(codeInvalidCSSProperty, "css property names must be < 128 characters long", in.Position())
 		return
 	}
 	return prefix + suffix, true, nil
@@ -160,6 +162,7 @@ var expressionCSSPropertyParser = parse.Func(func(pi *parse.Input) (r Expression
 		return
 	}
 	// Property name.
+	from := pi.Position()
 	if r.Name, ok, err = cssPropertyNameParser.Parse(pi); err != nil || !ok {
 		pi.Seek(start)
 		return
@@ -180,12 +183,13 @@ var expressionCSSPropertyParser = parse.Func(func(pi *parse.Input) (r Expression
 
 	// ;
 	if _, ok, err = parse.String(";").Parse(pi); err != nil || !ok {
-		err = parse.Error("missing expected semicolon (;)", pi.Position())
+		err = newParseError(codeInvalidCSSProperty, "missing expected semicolon (;)", pi.Position())
 		return
 	}
+	r.Range = NewRange(from, pi.Position())
 	// \n
 	if _, ok, err = parse.NewLine.Parse(pi); err != nil || !ok {
-		err = parse.Error("missing expected linebreak", pi.Position())
+		err = newParseError(codeInvalidCSSProperty, "missing expected linebreak", pi.Position())
 		return
 	}
 
@@ -201,6 +205,7 @@ var constantCSSPropertyParser = parse.Func(func(pi *parse.Input) (r ConstantCSSP
 		return
 	}
 	// Property name.
+	from := pi.Position()
 	if r.Name, ok, err = cssPropertyNameParser.Parse(pi); err != nil || !ok {
 		pi.Seek(start)
 		return
@@ -218,13 +223,16 @@ var constantCSSPropertyParser = parse.Func(func(pi *parse.Input) (r ConstantCSSP
 		parse.NewLine,
 	)
 	if r.Value, ok, err = parse.StringUntil(untilEnd).Parse(pi); err != nil || !ok {
-		err = parse.Error("missing expected semicolon and linebreak (;\\n", pi.Position())
+		err = newParseError(codeInvalidCSSProperty, "missing expected semicolon and linebreak (;\\n", pi.Position())
 		return
 	}
 
 	// Chomp the ;\n
-	if _, ok, err = untilEnd.Parse(pi); err != nil || !ok {
-		err = parse.Error("failed to chomp semicolon and linebreak (;\\n)", pi.Position())
+	_, _, _ = parse.OptionalWhitespace.Parse(pi)
+	_, _, _ = parse.Rune(';').Parse(pi)
+	r.Range = NewRange(from, pi.Position())
+	if _, ok, err = parse.NewLine.Parse(pi); err != nil || !ok {
+		err = newParseError(codeInvalidCSSProperty, "failed to chomp semicolon and linebreak (;\\n)", pi.Position())
 		return
 	}
 
@@ -73,7 +73,7 @@ func TestExpressionCSSPropertyParser(t *testing.T) {
 			if !ok {
 				t.Fatalf("failed to parse at %d", input.Index())
 			}
-			if diff := cmp.Diff(tt.expected, result); diff != "" {
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
 				t.Errorf(diff)
 			}
 		})
@@ -114,7 +114,7 @@ func TestConstantCSSPropertyParser(t *testing.T) {
 			if !ok {
 				t.Fatalf("failed to parse at %d", input.Index())
 			}
-			if diff := cmp.Diff(tt.expected, result); diff != "" {
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
 				t.Errorf(diff)
 			}
 		})
@@ -259,7 +259,7 @@ background-color: { constants.BackgroundColor };
 			if !ok {
 				t.Fatalf("failed to parse at %d", input.Index())
 			}
-			if diff := cmp.Diff(tt.expected, result); diff != "" {
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
 				t.Errorf(diff)
 			}
 		})
@@ -11,21 +11,23 @@ var stringUntilLtOrGt = parse.StringUntil(untilLtOrGt)
 
 var docType = parse.Func(func(pi *parse.Input) (n Node, ok bool, err error) {
 	var r DocType
+	from := pi.Position()
 	if _, ok, err = doctypeStartParser.Parse(pi); err != nil || !ok {
 		return
 	}
 
 	// Once a doctype has started, take everything until the end.
 	if r.Value, ok, err = stringUntilLtOrGt.Parse(pi); err != nil || !ok {
-		err = parse.Error("unclosed DOCTYPE", pi.Position())
+		err = newParseError(codeUnterminatedDeclaration, "unclosed DOCTYPE", pi.Position())
 		return
 	}
 
 	// Clear the final '>'.
 	if _, ok, err = gt.Parse(pi); err != nil || !ok {
-		err = parse.Error("unclosed DOCTYPE", pi.Position())
+		err = newParseError(codeUnterminatedDeclaration, "unclosed DOCTYPE", pi.Position())
 		return
 	}
+	r.Range = NewRange(from, pi.Position())
 
 	return r, true, nil
 })
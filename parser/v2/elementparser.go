@@ -13,6 +13,7 @@ import (
 // Element open tag.
 type elementOpenTag struct {
 	Name        string
+	NameRange   Range
 	Attributes  []Attribute
 	IndentAttrs bool
 }
@@ -26,11 +27,13 @@ var elementOpenTagParser = parse.Func(func(pi *parse.Input) (e elementOpenTag, o
 	}
 
 	// Element name.
-	l := pi.Position().Line
+	from := pi.Position()
+	l := from.Line
 	if e.Name, ok, err = elementNameParser.Parse(pi); err != nil || !ok {
 		pi.Seek(start)
 		return
 	}
+	e.NameRange = NewRange(from, pi.Position())
 
 	if e.Attributes, ok, err = (attributesParser{}).Parse(pi); err != nil || !ok {
 		pi.Seek(start)
@@ -53,7 +56,7 @@ var elementOpenTagParser = parse.Func(func(pi *parse.Input) (e elementOpenTag, o
 		return
 	}
 	if !ok {
-		err = parse.Error(fmt.Sprintf("<%s>: malformed open element", e.Name), pi.Position())
+		err = newParseError(codeMalformedElement, fmt.Sprintf("<%s>: malformed open element", e.Name), pi.Position())
 		return e, false, err
 	}
 
@@ -94,7 +97,7 @@ var (
 		}
 		if len(suffix)+1 > 128 {
 			ok = false
-			err = parse.Error("attribute names must be < 128 characters long", in.Position())
+			err = newParseError(codeMalformedElement, "attribute names must be < 128 characters long", in.Position())
 			return
 		}
 		return prefix + suffix, true, nil
@@ -114,10 +117,12 @@ var (
 		}
 
 		// Attribute name.
+		from := pi.Position()
 		if attr.Name, ok, err = attributeNameParser.Parse(pi); err != nil || !ok {
 			pi.Seek(start)
 			return
 		}
+		attr.NameRange = NewRange(from, pi.Position())
 
 		// ="
 		result, ok, err := parse.Or(parse.String(`="`), parse.String(`='`)).Parse(pi)
@@ -135,10 +140,12 @@ var (
 		}
 
 		// Attribute value.
+		valueFrom := pi.Position()
 		if attr.Value, ok, err = valueParser.Parse(pi); err != nil || !ok {
 			pi.Seek(start)
 			return
 		}
+		attr.ValueRange = NewRange(valueFrom, pi.Position())
 
 		attr.Value = html.UnescapeString(attr.Value)
 		// Only use single quotes if actually required, due to double quote in the value (prefer double quotes).
@@ -148,9 +155,10 @@ var (
 
 		// " - closing quote.
 		if _, ok, err = closeParser.Parse(pi); err != nil || !ok {
-			err = parse.Error(fmt.Sprintf("missing closing quote on attribute %q", attr.Name), pi.Position())
+			err = newParseError(codeMalformedElement, fmt.Sprintf("missing closing quote on attribute %q", attr.Name), pi.Position())
 			return
 		}
+		attr.Range = NewRange(from, pi.Position())
 
 		return attr, true, nil
 	})
@@ -166,15 +174,17 @@ var boolConstantAttributeParser = parse.Func(func(pi *parse.Input) (attr BoolCon
 	}
 
 	// Attribute name.
+	from := pi.Position()
 	if attr.Name, ok, err = attributeNameParser.Parse(pi); err != nil || !ok {
 		pi.Seek(start)
 		return
 	}
+	attr.NameRange = NewRange(from, pi.Position())
 
 	// We have a name, but if we have an equals sign, it's not a constant boolean attribute.
 	next, ok := pi.Peek(1)
 	if !ok {
-		err = parse.Error("boolConstantAttributeParser: unexpected EOF after attribute name", pi.Position())
+		err = newParseError(codeMalformedElement, "boolConstantAttributeParser: unexpected EOF after attribute name", pi.Position())
 		return
 	}
 	if next == "=" || next == "?" {
@@ -183,9 +193,10 @@ var boolConstantAttributeParser = parse.Func(func(pi *parse.Input) (attr BoolCon
 		return attr, false, nil
 	}
 	if !(next == " " || next == "\t" || next == "\r" || next == "\n" || next == "/" || next == ">") {
-		err = parse.Error(fmt.Sprintf("boolConstantAttributeParser: expected attribute name to end with space, newline, '/>' or '>', but got %q", next), pi.Position())
+		err = newParseError(codeMalformedElement, fmt.Sprintf("boolConstantAttributeParser: expected attribute name to end with space, newline, '/>' or '>', but got %q", next), pi.Position())
 		return attr, false, err
 	}
+	attr.Range = attr.NameRange
 
 	return attr, true, nil
 })
@@ -203,10 +214,12 @@ var boolExpressionAttributeParser = parse.Func(func(pi *parse.Input) (r BoolExpr
 	}
 
 	// Attribute name.
+	from := pi.Position()
 	if r.Name, ok, err = attributeNameParser.Parse(pi); err != nil || !ok {
 		pi.Seek(start)
 		return
 	}
+	r.NameRange = NewRange(from, pi.Position())
 
 	// Check whether this is a boolean expression attribute.
 	if _, ok, err = boolExpressionStart.Parse(pi); err != nil || !ok {
@@ -216,16 +229,17 @@ var boolExpressionAttributeParser = parse.Func(func(pi *parse.Input) (r BoolExpr
 
 	// Once we have a prefix, we must have an expression that returns a template.
 	if r.Expression, ok, err = exp.Parse(pi); err != nil || !ok {
-		err = parse.Error("boolean expression: expected Go expression not found", pi.Position())
+		err = newParseError(codeInvalidExpression, "boolean expression: expected Go expression not found", pi.Position())
 		return
 	}
 
 	// Eat the Final brace.
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("boolean expression: missing closing brace", pi.Position())
+		err = newParseError(codeMissingCloseBrace, "boolean expression: missing closing brace", pi.Position())
 		pi.Seek(start)
 		return
 	}
+	r.Range = NewRange(from, pi.Position())
 
 	return r, true, nil
 })
@@ -239,10 +253,12 @@ var expressionAttributeParser = parse.Func(func(pi *parse.Input) (attr Expressio
 	}
 
 	// Attribute name.
+	from := pi.Position()
 	if attr.Name, ok, err = attributeNameParser.Parse(pi); err != nil || !ok {
 		pi.Seek(start)
 		return
 	}
+	attr.NameRange = NewRange(from, pi.Position())
 
 	// ={
 	if _, ok, err = parse.Or(parse.String("={ "), parse.String("={")).Parse(pi); err != nil || !ok {
@@ -258,9 +274,10 @@ var expressionAttributeParser = parse.Func(func(pi *parse.Input) (attr Expressio
 
 	// Eat the final brace.
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("boolean expression: missing closing brace", pi.Position())
+		err = newParseError(codeMissingCloseBrace, "boolean expression: missing closing brace", pi.Position())
 		return
 	}
+	attr.Range = NewRange(from, pi.Position())
 
 	return attr, true, nil
 })
@@ -274,6 +291,7 @@ var spreadAttributesParser = parse.Func(func(pi *parse.Input) (attr SpreadAttrib
 	}
 
 	// Eat the first brace.
+	from := pi.Position()
 	if _, ok, err = openBraceWithOptionalPadding.Parse(pi); err != nil ||
 		!ok {
 		pi.Seek(start)
@@ -300,9 +318,10 @@ var spreadAttributesParser = parse.Func(func(pi *parse.Input) (attr SpreadAttrib
 
 	// Eat the final brace.
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("attribute spread expression: missing closing brace", pi.Position())
+		err = newParseError(codeMissingCloseBrace, "attribute spread expression: missing closing brace", pi.Position())
 		return
 	}
+	attr.Range = NewRange(from, pi.Position())
 
 	return attr, true, nil
 })
@@ -355,19 +374,27 @@ func (attributesParser) Parse(in *parse.Input) (attributes []Attribute, ok bool,
 var (
 	elementNameFirst      = "abcdefghijklmnopqrstuvwxyz"
 	elementNameSubsequent = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-"
-	elementNameParser     = parse.Func(func(in *parse.Input) (name string, ok bool, err error) {
+	// XML element names within XML templates can start with an uppercase letter, and contain
+	// _, . and :, e.g. <dc:creator>.
+	xmlElementNameFirst      = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	xmlElementNameSubsequent = xmlElementNameFirst + "0123456789-_.:"
+	elementNameParser        = parse.Func(func(in *parse.Input) (name string, ok bool, err error) {
 		start := in.Index()
+		first, subsequent := elementNameFirst, elementNameSubsequent
+		if stateOf(in).xml {
+			first, subsequent = xmlElementNameFirst, xmlElementNameSubsequent
+		}
 		var prefix, suffix string
-		if prefix, ok, err = parse.RuneIn(elementNameFirst).Parse(in); err != nil || !ok {
+		if prefix, ok, err = parse.RuneIn(first).Parse(in); err != nil || !ok {
 			return
 		}
-		if suffix, ok, err = parse.StringUntil(parse.RuneNotIn(elementNameSubsequent)).Parse(in); err != nil || !ok {
+		if suffix, ok, err = parse.StringUntil(parse.RuneNotIn(subsequent)).Parse(in); err != nil || !ok {
 			in.Seek(start)
 			return
 		}
 		if len(suffix)+1 > 128 {
 			ok = false
-			err = parse.Error("element names must be < 128 characters long", in.Position())
+			err = newParseError(codeMalformedElement, "element names must be < 128 characters long", in.Position())
 			return
 		}
 		return prefix + suffix, true, nil
@@ -381,24 +408,38 @@ type elementOpenCloseParser struct{}
 
 func (elementOpenCloseParser) Parse(pi *parse.Input) (r Element, ok bool, err error) {
 	// Check the open tag.
+	from := pi.Position()
 	var ot elementOpenTag
 	if ot, ok, err = elementOpenTagParser.Parse(pi); err != nil || !ok {
 		return
 	}
 	r.Name = ot.Name
+	r.NameRange = ot.NameRange
 	r.Attributes = ot.Attributes
 	r.IndentAttrs = ot.IndentAttrs
 
+	// The whitespace within whitespace-sensitive elements, and their descendants, is preserved.
+	s := stateOf(pi)
+	defer beginParse(pi, s)()
+	if !s.preserveWhitespace && s.isWhitespaceSensitive(r.Name) {
+		s.preserveWhitespace = true
+		defer func() { s.preserveWhitespace = false }()
+	}
+	r.PreserveWhitespace = s.preserveWhitespace
+
 	// Once we've got an open tag, the rest must be present.
 	l := pi.Position().Line
 	var nodes Nodes
-	if nodes, ok, err = newTemplateNodeParser[any](nil, "").Parse(pi); err != nil || !ok {
+	s.openElements = append(s.openElements, r.Name)
+	nodes, ok, err = newTemplateNodeParser[any](nil, "").Parse(pi)
+	s.openElements = s.openElements[:len(s.openElements)-1]
+	if err != nil || !ok {
 		return
 	}
 	r.Children = nodes.Nodes
 	r.Diagnostics = nodes.Diagnostics
 	// If the children are not all on the same line, indent them
-	if l != pi.Position().Line {
+	if l != pi.Position().Line && !r.PreserveWhitespace {
 		r.IndentChildren = true
 	}
 
@@ -409,14 +450,34 @@ func (elementOpenCloseParser) Parse(pi *parse.Input) (r Element, ok bool, err er
 	if err != nil {
 		return
 	}
+	// If the end tag is missing or mismatched, recover by closing the element, so that the
+	// parent element or template can continue. The error is reported once, by the innermost
+	// element, and the other open elements are closed quietly.
 	if !ok {
-		err = parse.Error(fmt.Sprintf("<%s>: expected end tag not present or invalid tag contents", r.Name), pi.Position())
-		return
+		pi.Seek(int(pos.Index))
+		r.Range = NewRange(from, pos)
+		if s.endTagErrorIndex != pos.Index {
+			s.endTagErrorIndex = pos.Index
+			r.Diagnostics = append(r.Diagnostics, newErrorDiagnostic(codeMissingEndTag, fmt.Sprintf("<%s>: expected end tag not present or invalid tag contents", r.Name), pos))
+		}
+		return r, true, nil
 	}
 	if ct.Name != r.Name {
-		err = parse.Error(fmt.Sprintf("<%s>: mismatched end tag, expected '</%s>', got '</%s>'", r.Name, r.Name, ct.Name), pos)
-		return
+		if s.endTagErrorIndex != pos.Index {
+			s.endTagErrorIndex = pos.Index
+			r.Diagnostics = append(r.Diagnostics, newErrorDiagnostic(codeMismatchedEndTag, fmt.Sprintf("<%s>: mismatched end tag, expected '</%s>', got '</%s>'", r.Name, r.Name, ct.Name), pos))
+		}
+		if s.isOpen(ct.Name) {
+			// Leave the end tag for the open element that it belongs to.
+			s.implicitlyClosed = append(s.implicitlyClosed, r.Name)
+			pi.Seek(int(pos.Index))
+			r.Range = NewRange(from, pos)
+			return r, true, nil
+		}
+		// The end tag doesn't belong to an open element, so it's taken to be the end tag of
+		// this element.
 	}
+	r.Range = NewRange(from, pi.Position())
 
 	// Parse trailing whitespace.
 	ws, _, err := parse.Whitespace.Parse(pi)
@@ -441,11 +502,13 @@ var selfClosingElement = parse.Func(func(pi *parse.Input) (e Element, ok bool, e
 	}
 
 	// Element name.
-	l := pi.Position().Line
+	from := pi.Position()
+	l := from.Line
 	if e.Name, ok, err = elementNameParser.Parse(pi); err != nil || !ok {
 		pi.Seek(start)
 		return
 	}
+	e.NameRange = NewRange(from, pi.Position())
 
 	if e.Attributes, ok, err = (attributesParser{}).Parse(pi); err != nil || !ok {
 		pi.Seek(start)
@@ -467,6 +530,7 @@ var selfClosingElement = parse.Func(func(pi *parse.Input) (e Element, ok bool, e
 		pi.Seek(start)
 		return
 	}
+	e.Range = NewRange(pi.PositionAt(start), pi.Position())
 
 	// Parse trailing whitespace.
 	ws, _, err := parse.Whitespace.Parse(pi)
@@ -493,10 +557,85 @@ func (elementParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	if r, ok, err = parse.Any[Element](selfClosingElement, elementOpenClose).Parse(pi); err != nil || !ok {
 		return
 	}
-	var msgs []string
-	if msgs, ok = r.Validate(); !ok {
-		err = parse.Error(fmt.Sprintf("<%s>: %s", r.Name, strings.Join(msgs, ", ")), start)
+	return validatedElement(r, start)
+}
+
+func validatedElement(r Element, from parse.Position) (n Node, ok bool, err error) {
+	if ns, isForeign := foreignNamespace(r.Name); isForeign {
+		r = withNamespace(r, ns)
+	}
+	if problems := r.problems(); len(problems) > 0 {
+		msgs := make([]string, len(problems))
+		for i, p := range problems {
+			msgs[i] = p.msg
+		}
+		return r, false, newParseError(problems[0].code, fmt.Sprintf("<%s>: %s", r.Name, strings.Join(msgs, ", ")), from)
 	}
+	return r, true, nil
+}
 
-	return r, ok, err
+// Foreign elements.
+
+// foreignNamespace returns the namespace that an element with the given name starts. Only
+// <svg> and <math> start a foreign namespace, so elements within them are foreign elements,
+// while elements such as <a>, <title> and <text> are HTML elements everywhere else.
+func foreignNamespace(name string) (ns Namespace, ok bool) {
+	switch name {
+	case "svg":
+		return NamespaceSVG, true
+	case "math":
+		return NamespaceMathML, true
+	}
+	return NamespaceHTML, false
+}
+
+// withNamespace sets the namespace of the element and its descendants.
+// The children of <foreignObject> are HTML.
+func withNamespace(e Element, ns Namespace) Element {
+	e.Namespace = ns
+	childNamespace := ns
+	if ns == NamespaceSVG && e.Name == "foreignObject" {
+		childNamespace = NamespaceHTML
+	}
+	e.Children = nodesWithNamespace(e.Children, childNamespace)
+	return e
+}
+
+func nodesWithNamespace(nodes []Node, ns Namespace) []Node {
+	if len(nodes) == 0 {
+		return nodes
+	}
+	op := make([]Node, len(nodes))
+	for i, n := range nodes {
+		switch n := n.(type) {
+		case Element:
+			if ns == NamespaceHTML {
+				// HTML content can contain a new <svg> or <math> element.
+				op[i] = n
+				continue
+			}
+			op[i] = withNamespace(n, ns)
+		case IfExpression:
+			n.Then = nodesWithNamespace(n.Then, ns)
+			for j := range n.ElseIfs {
+				n.ElseIfs[j].Then = nodesWithNamespace(n.ElseIfs[j].Then, ns)
+			}
+			n.Else = nodesWithNamespace(n.Else, ns)
+			op[i] = n
+		case SwitchExpression:
+			for j := range n.Cases {
+				n.Cases[j].Children = nodesWithNamespace(n.Cases[j].Children, ns)
+			}
+			op[i] = n
+		case ForExpression:
+			n.Children = nodesWithNamespace(n.Children, ns)
+			op[i] = n
+		case TemplElementExpression:
+			n.Children = nodesWithNamespace(n.Children, ns)
+			op[i] = n
+		default:
+			op[i] = n
+		}
+	}
+	return op
 }
@@ -244,7 +244,7 @@ if test {
 			input:  ` { spread... }"`,
 			parser: StripType(spreadAttributesParser),
 			expected: SpreadAttributes{
-				Expression{
+				Expression: Expression{
 					Value: "spread",
 					Range: Range{
 						From: Position{
@@ -391,7 +391,7 @@ if test {
 			if !ok {
 				t.Errorf("failed to parse at %v", input.Position())
 			}
-			if diff := cmp.Diff(tt.expected, result); diff != "" {
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
 				t.Errorf(diff)
 			}
 		})
@@ -976,33 +976,267 @@ func TestElementParser(t *testing.T) {
 			if !ok {
 				t.Fatalf("failed to parse at %d", input.Index())
 			}
-			if diff := cmp.Diff(tt.expected, result); diff != "" {
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
 				t.Errorf(diff)
 			}
 		})
 	}
 }
 
-func TestElementParserErrors(t *testing.T) {
+func TestElementParserNamespaces(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
-		expected error
+		expected Element
 	}{
 		{
-			name:  "element: mismatched end tag",
+			name:  "element: svg children are in the svg namespace",
+			input: `<svg viewBox="0 0 24 24"><path d="M0 0"/><text>A</text></svg>`,
+			expected: Element{
+				Name:      "svg",
+				Namespace: NamespaceSVG,
+				Attributes: []Attribute{
+					ConstantAttribute{Name: "viewBox", Value: "0 0 24 24"},
+				},
+				Children: []Node{
+					Element{
+						Name:      "path",
+						Namespace: NamespaceSVG,
+						Attributes: []Attribute{
+							ConstantAttribute{Name: "d", Value: "M0 0"},
+						},
+					},
+					Element{
+						Name:      "text",
+						Namespace: NamespaceSVG,
+						Children:  []Node{Text{Value: "A"}},
+					},
+				},
+			},
+		},
+		{
+			name:  "element: foreignObject children are in the html namespace",
+			input: `<svg><foreignObject><div></div></foreignObject></svg>`,
+			expected: Element{
+				Name:      "svg",
+				Namespace: NamespaceSVG,
+				Children: []Node{
+					Element{
+						Name:      "foreignObject",
+						Namespace: NamespaceSVG,
+						Children: []Node{
+							Element{Name: "div"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "element: math children are in the mathml namespace",
+			input: `<math><mi>x</mi></math>`,
+			expected: Element{
+				Name:      "math",
+				Namespace: NamespaceMathML,
+				Children: []Node{
+					Element{
+						Name:      "mi",
+						Namespace: NamespaceMathML,
+						Children:  []Node{Text{Value: "x"}},
+					},
+				},
+			},
+		},
+		{
+			name:  "element: svg element names outside of an svg element are html",
+			input: `<div><text>A</text><a href="/">B</a></div>`,
+			expected: Element{
+				Name: "div",
+				Children: []Node{
+					Element{Name: "text", Children: []Node{Text{Value: "A"}}},
+					Element{Name: "a", Attributes: []Attribute{ConstantAttribute{Name: "href", Value: "/"}}, Children: []Node{Text{Value: "B"}}},
+				},
+			},
+		},
+		{
+			name:  "element: html elements are not foreign",
+			input: `<div><span></span></div>`,
+			expected: Element{
+				Name: "div",
+				Children: []Node{
+					Element{Name: "span"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			input := parse.NewInput(tt.input)
+			result, ok, err := element.Parse(input)
+			if err != nil {
+				t.Fatalf("parser error: %v", err)
+			}
+			if !ok {
+				t.Fatalf("failed to parse at %d", input.Index())
+			}
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestElementParserRecovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Element
+		rest     string
+	}{
+		{
+			name:  "element: mismatched end tag that doesn't belong to an open element closes the element",
 			input: `<a></b>`,
-			expected: parse.Error("<a>: mismatched end tag, expected '</a>', got '</b>'",
-				parse.Position{
-					Index: 3,
-					Line:  0,
-					Col:   3,
-				}),
+			expected: Element{
+				Name: "a",
+				Diagnostics: []Diagnostic{
+					{
+						Message:      "<a>: mismatched end tag, expected '</a>', got '</b>'",
+						Range:        Range{From: Position{Index: 3, Line: 0, Col: 3}, To: Position{Index: 3, Line: 0, Col: 3}},
+						Severity:     DiagnosticSeverityError,
+						Code:         "T2002",
+						SuggestedFix: "Close the most recently opened element first.",
+					},
+				},
+			},
+		},
+		{
+			name:  "element: missing end tag",
+			input: `<div><span>Text</div>`,
+			expected: Element{
+				Name: "div",
+				Children: []Node{
+					Element{
+						Name:     "span",
+						Children: []Node{Text{Value: "Text"}},
+						Diagnostics: []Diagnostic{
+							{
+								Message:      "<span>: mismatched end tag, expected '</span>', got '</div>'",
+								Range:        Range{From: Position{Index: 15, Line: 0, Col: 15}, To: Position{Index: 15, Line: 0, Col: 15}},
+								Severity:     DiagnosticSeverityError,
+								Code:         "T2002",
+								SuggestedFix: "Close the most recently opened element first.",
+							},
+						},
+					},
+				},
+				Diagnostics: []Diagnostic{
+					{
+						Message:      "<span>: mismatched end tag, expected '</span>', got '</div>'",
+						Range:        Range{From: Position{Index: 15, Line: 0, Col: 15}, To: Position{Index: 15, Line: 0, Col: 15}},
+						Severity:     DiagnosticSeverityError,
+						Code:         "T2002",
+						SuggestedFix: "Close the most recently opened element first.",
+					},
+				},
+			},
 		},
+		{
+			name:  "element: mismatched end tag is reported once, and the open elements are closed",
+			input: `<section><div><b>Text</span></div><p>Text</section>`,
+			expected: Element{
+				Name: "section",
+				Children: []Node{
+					Element{
+						Name: "div",
+						Children: []Node{
+							Element{
+								Name:     "b",
+								Children: []Node{Text{Value: "Text"}},
+								Diagnostics: []Diagnostic{
+									{
+										Message:      "<b>: mismatched end tag, expected '</b>', got '</span>'",
+										Range:        Range{From: Position{Index: 21, Line: 0, Col: 21}, To: Position{Index: 21, Line: 0, Col: 21}},
+										Severity:     DiagnosticSeverityError,
+										Code:         "T2002",
+										SuggestedFix: "Close the most recently opened element first.",
+									},
+								},
+							},
+						},
+						Diagnostics: []Diagnostic{
+							{
+								Message:      "<b>: mismatched end tag, expected '</b>', got '</span>'",
+								Range:        Range{From: Position{Index: 21, Line: 0, Col: 21}, To: Position{Index: 21, Line: 0, Col: 21}},
+								Severity:     DiagnosticSeverityError,
+								Code:         "T2002",
+								SuggestedFix: "Close the most recently opened element first.",
+							},
+						},
+					},
+					Element{
+						Name:     "p",
+						Children: []Node{Text{Value: "Text"}},
+						Diagnostics: []Diagnostic{
+							{
+								Message:      "<p>: mismatched end tag, expected '</p>', got '</section>'",
+								Range:        Range{From: Position{Index: 41, Line: 0, Col: 41}, To: Position{Index: 41, Line: 0, Col: 41}},
+								Severity:     DiagnosticSeverityError,
+								Code:         "T2002",
+								SuggestedFix: "Close the most recently opened element first.",
+							},
+						},
+					},
+				},
+				Diagnostics: []Diagnostic{
+					{
+						Message:      "<b>: mismatched end tag, expected '</b>', got '</span>'",
+						Range:        Range{From: Position{Index: 21, Line: 0, Col: 21}, To: Position{Index: 21, Line: 0, Col: 21}},
+						Severity:     DiagnosticSeverityError,
+						Code:         "T2002",
+						SuggestedFix: "Close the most recently opened element first.",
+					},
+					{
+						Message:      "<p>: mismatched end tag, expected '</p>', got '</section>'",
+						Range:        Range{From: Position{Index: 41, Line: 0, Col: 41}, To: Position{Index: 41, Line: 0, Col: 41}},
+						Severity:     DiagnosticSeverityError,
+						Code:         "T2002",
+						SuggestedFix: "Close the most recently opened element first.",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			input := parse.NewInput(tt.input)
+			result, ok, err := element.Parse(input)
+			if err != nil {
+				t.Fatalf("parser error: %v", err)
+			}
+			if !ok {
+				t.Fatalf("failed to parse at %d", input.Index())
+			}
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
+				t.Error(diff)
+			}
+			if rest, _ := input.Peek(-1); rest != tt.rest {
+				t.Errorf("expected remaining input %q, got %q", tt.rest, rest)
+			}
+		})
+	}
+}
+
+func TestElementParserErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected error
+	}{
 		{
 			name:  "element: style must only contain text",
 			input: `<style><button /></style>`,
-			expected: parse.Error("<style>: invalid node contents: script and style attributes must only contain text",
+			expected: newParseError(codeInvalidScriptContents, "<style>: invalid node contents: script and style attributes must only contain text",
 				parse.Position{
 					Index: 0,
 					Line:  0,
@@ -1012,7 +1246,7 @@ func TestElementParserErrors(t *testing.T) {
 		{
 			name:  "element: script must only contain text",
 			input: `<script><button /></script>`,
-			expected: parse.Error("<script>: invalid node contents: script and style attributes must only contain text",
+			expected: newParseError(codeInvalidScriptContents, "<script>: invalid node contents: script and style attributes must only contain text",
 				parse.Position{
 					Index: 0,
 					Line:  0,
@@ -1022,7 +1256,7 @@ func TestElementParserErrors(t *testing.T) {
 		{
 			name:  "element: attempted use of expression for style attribute (open/close)",
 			input: `<a style={ value }></a>`,
-			expected: parse.Error(`<a>: invalid style attribute: style attributes cannot be a templ expression`,
+			expected: newParseError(codeInvalidStyleAttribute, `<a>: invalid style attribute: style attributes cannot be a templ expression`,
 				parse.Position{
 					Index: 0,
 					Line:  0,
@@ -1032,7 +1266,7 @@ func TestElementParserErrors(t *testing.T) {
 		{
 			name:  "element: attempted use of expression for style attribute (self-closing)",
 			input: `<a style={ value }/>`,
-			expected: parse.Error(`<a>: invalid style attribute: style attributes cannot be a templ expression`,
+			expected: newParseError(codeInvalidStyleAttribute, `<a>: invalid style attribute: style attributes cannot be a templ expression`,
 				parse.Position{
 					Index: 0,
 					Line:  0,
@@ -1042,7 +1276,7 @@ func TestElementParserErrors(t *testing.T) {
 		{
 			name:  "element: script tags cannot contain non-text nodes",
 			input: `<script>{ "value" }</script>`,
-			expected: parse.Error("<script>: invalid node contents: script and style attributes must only contain text",
+			expected: newParseError(codeInvalidScriptContents, "<script>: invalid node contents: script and style attributes must only contain text",
 				parse.Position{
 					Index: 0,
 					Line:  0,
@@ -1052,7 +1286,7 @@ func TestElementParserErrors(t *testing.T) {
 		{
 			name:  "element: style tags cannot contain non-text nodes",
 			input: `<style>{ "value" }</style>`,
-			expected: parse.Error("<style>: invalid node contents: script and style attributes must only contain text",
+			expected: newParseError(codeInvalidScriptContents, "<style>: invalid node contents: script and style attributes must only contain text",
 				parse.Position{
 					Index: 0,
 					Line:  0,
@@ -1062,7 +1296,7 @@ func TestElementParserErrors(t *testing.T) {
 		{
 			name:  "element: names cannot be greater than 128 characters",
 			input: `<aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa></aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa>`,
-			expected: parse.Error("element names must be < 128 characters long",
+			expected: newParseError(codeMalformedElement, "element names must be < 128 characters long",
 				parse.Position{
 					Index: 130,
 					Line:  0,
@@ -1097,3 +1331,31 @@ func TestBigElement(t *testing.T) {
 		t.Errorf("unexpected failure to parse")
 	}
 }
+
+func TestElementNameParser(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{input: "div", expected: "div", ok: true},
+		{input: "my-element2", expected: "my-element2", ok: true},
+		{input: "linearGradient", expected: "linearGradient", ok: true},
+		// The XML names of XML templates aren't HTML element names.
+		{input: "Link", ok: false},
+		{input: "image:image", expected: "image", ok: true},
+		{input: "a_b", expected: "a", ok: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.input, func(t *testing.T) {
+			name, ok, err := elementNameParser.Parse(parse.NewInput(tt.input + ">"))
+			if err != nil {
+				t.Fatalf("parser error: %v", err)
+			}
+			if ok != tt.ok || name != tt.expected {
+				t.Errorf("expected %q, %v, got %q, %v", tt.expected, tt.ok, name, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/a-h/parse"
+)
+
+// ErrorCode identifies a kind of error or warning, e.g. T1002. Codes don't change between
+// releases, so they can be searched for, and explained with `templ explain <code>`.
+type ErrorCode string
+
+// The codes of the problems that the parser finds.
+const (
+	codeMalformedTemplate       ErrorCode = "T1001"
+	codeMissingCloseBrace       ErrorCode = "T1002"
+	codeIncompleteStatement     ErrorCode = "T1003"
+	codeInvalidBlock            ErrorCode = "T1004"
+	codeInvalidPackage          ErrorCode = "T1005"
+	codeMissingEndTag           ErrorCode = "T2001"
+	codeMismatchedEndTag        ErrorCode = "T2002"
+	codeMalformedElement        ErrorCode = "T2003"
+	codeInvalidStyleAttribute   ErrorCode = "T2004"
+	codeInvalidScriptContents   ErrorCode = "T2005"
+	codeUnterminatedComment     ErrorCode = "T2006"
+	codeUnterminatedDeclaration ErrorCode = "T2007"
+	codeUnterminatedCDATA       ErrorCode = "T2008"
+	codeInvalidExpression       ErrorCode = "T3001"
+	codeInvalidCSSProperty      ErrorCode = "T3002"
+	codeUnterminatedText        ErrorCode = "T3003"
+	codeDeprecatedCall          ErrorCode = "W0001"
+	codeInvalidNesting          ErrorCode = "W0002"
+	codeDuplicateAttribute      ErrorCode = "W0003"
+	codeUnknownAttribute        ErrorCode = "W0004"
+	codeMisusedBooleanAttribute ErrorCode = "W0005"
+)
+
+// ErrorCodeInfo describes an error code.
+type ErrorCodeInfo struct {
+	Code ErrorCode
+	// Title is a short summary of the problem.
+	Title string
+	// Fix is a short suggestion of how to fix the problem.
+	Fix string
+	// Explanation is a long-form description of the problem.
+	Explanation string
+	// Example is templ code that causes the problem.
+	Example string
+	// Fixed is the example, without the problem.
+	Fixed string
+}
+
+// URL of the documentation for the error code.
+func (info ErrorCodeInfo) URL() string {
+	return "https://templ.guide/commands-and-tools/error-codes#" + strings.ToLower(string(info.Code))
+}
+
+// ErrorCodes lists all error codes, in order.
+var ErrorCodes = []ErrorCodeInfo{
+	{
+		Code:  codeMalformedTemplate,
+		Title: "Malformed template declaration",
+		Fix:   "Declare templates with the form `templ name(parameters) {`, and end the line after the opening brace.",
+		Explanation: `A templ, xml, text, css or script template declaration must contain a name, parameters in brackets,
+and an opening brace at the end of the line. css templates don't take parameters.`,
+		Example: "templ hello(name string) { <div>{ name }</div> }",
+		Fixed:   "templ hello(name string) {\n\t<div>{ name }</div>\n}",
+	},
+	{
+		Code:  codeMissingCloseBrace,
+		Title: "Missing closing brace",
+		Fix:   "Add a closing brace `}` at the end of the block.",
+		Explanation: `Templates, statements, templ element blocks and expressions that start with an opening brace must
+be closed with a closing brace. Check that the braces of if, for and switch statements are balanced.`,
+		Example: "templ list(items []string) {\n\tfor _, item := range items {\n\t\t<li>{ item }</li>\n}",
+		Fixed:   "templ list(items []string) {\n\tfor _, item := range items {\n\t\t<li>{ item }</li>\n\t}\n}",
+	},
+	{
+		Code:  codeIncompleteStatement,
+		Title: "Incomplete statement",
+		Fix:   "End the statement with an opening brace `{` and a new line.",
+		Explanation: `if, else if, for and switch statements must end with an opening brace, followed by a new line.
+If the line is intended to be text, put it within an element, or use a string expression, e.g. { "if it rains" }.`,
+		Example: "templ weather() {\n\tif raining\n\t\t<p>Umbrella</p>\n\t}\n}",
+		Fixed:   "templ weather() {\n\tif raining {\n\t\t<p>Umbrella</p>\n\t}\n}",
+	},
+	{
+		Code:  codeInvalidBlock,
+		Title: "Empty or invalid block",
+		Fix:   "Check the contents of the block for syntax errors.",
+		Explanation: `The contents of a template, statement, or conditional attribute could not be parsed. This is
+usually caused by a syntax error within the block, such as an unclosed element or expression.`,
+		Example: "templ button(disabled bool) {\n\t<button\n\t\tif disabled {\n\t\t}\n\t>Click</button>\n}",
+		Fixed:   "templ button(disabled bool) {\n\t<button\n\t\tif disabled {\n\t\t\tdisabled\n\t\t}\n\t>Click</button>\n}",
+	},
+	{
+		Code:        codeInvalidPackage,
+		Title:       "Invalid package declaration",
+		Fix:         "Start the file with a package declaration, e.g. `package main`.",
+		Explanation: `A templ file must contain a Go package declaration before the first template.`,
+		Example:     "package",
+		Fixed:       "package main",
+	},
+	{
+		Code:  codeMissingEndTag,
+		Title: "Missing end tag",
+		Fix:   "Add the end tag, or use a self-closing tag, e.g. `<br/>`.",
+		Explanation: `Elements must be closed with an end tag, or written as self-closing elements. Unlike HTML, templ
+doesn't close elements automatically.`,
+		Example: "templ para() {\n\t<p>Hello\n}",
+		Fixed:   "templ para() {\n\t<p>Hello</p>\n}",
+	},
+	{
+		Code:  codeMismatchedEndTag,
+		Title: "Mismatched end tag",
+		Fix:   "Close the most recently opened element first.",
+		Explanation: `The end tag doesn't match the most recently opened element. Elements must be closed in the reverse
+order that they're opened.`,
+		Example: "templ para() {\n\t<p><b>Hello</p></b>\n}",
+		Fixed:   "templ para() {\n\t<p><b>Hello</b></p>\n}",
+	},
+	{
+		Code:  codeMalformedElement,
+		Title: "Malformed element",
+		Fix:   "Check the attributes of the element, and close the start tag with `>` or `/>`.",
+		Explanation: `The start tag of the element could not be parsed. Element and attribute names must start with a
+letter, be less than 128 characters long, and attribute values must be quoted.`,
+		Example: "templ link() {\n\t<a href=\"/>Home</a>\n}",
+		Fixed:   "templ link() {\n\t<a href=\"/\">Home</a>\n}",
+	},
+	{
+		Code:  codeInvalidStyleAttribute,
+		Title: "Invalid style attribute",
+		Fix:   "Use a constant style attribute, or a css template in the class attribute.",
+		Explanation: `style attributes can't be set with a Go expression, because the CSS can't be safely escaped. Use a
+css template, and pass it to the class attribute.`,
+		Example: "templ box(color string) {\n\t<div style={ \"color: \" + color }></div>\n}",
+		Fixed:   "css boxColor(color string) {\n\tcolor: { color };\n}\n\ntempl box(color string) {\n\t<div class={ boxColor(color) }></div>\n}",
+	},
+	{
+		Code:  codeInvalidScriptContents,
+		Title: "Invalid script or style contents",
+		Fix:   "Only use text within script and style elements. Use a script template to pass data to scripts.",
+		Explanation: `The contents of script and style elements are written as-is, so they can't contain elements or
+Go expressions. Use a script template to call JavaScript with Go values.`,
+		Example: "templ alert(msg string) {\n\t<script>alert({ msg })</script>\n}",
+		Fixed:   "script alert(msg string) {\n\talert(msg)\n}",
+	},
+	{
+		Code:  codeUnterminatedComment,
+		Title: "Unterminated comment",
+		Fix:   "Close the HTML comment with `-->`, or the Go comment with `*/`.",
+		Explanation: `HTML comments must be closed with -->, and can't contain the sequence "--". Go comments that start
+with /* must be closed with */.`,
+		Example: "templ page() {\n\t<!-- TODO\n}",
+		Fixed:   "templ page() {\n\t<!-- TODO -->\n}",
+	},
+	{
+		Code:  codeUnterminatedDeclaration,
+		Title: "Unterminated declaration",
+		Fix:   "Close the DOCTYPE declaration with `>`, or the processing instruction with `?>`.",
+		Explanation: `DOCTYPE declarations must be closed with >, and the processing instructions of xml templates must be
+closed with ?>.`,
+		Example: "xml feed() {\n\t<?xml version=\"1.0\"\n\t<feed></feed>\n}",
+		Fixed:   "xml feed() {\n\t<?xml version=\"1.0\"?>\n\t<feed></feed>\n}",
+	},
+	{
+		Code:        codeUnterminatedCDATA,
+		Title:       "Unterminated CDATA section",
+		Fix:         "Close the CDATA section with `]]>`.",
+		Explanation: `The CDATA sections of xml templates must be closed with ]]>.`,
+		Example:     "xml feed() {\n\t<summary><![CDATA[<p>Hello</p></summary>\n}",
+		Fixed:       "xml feed() {\n\t<summary><![CDATA[<p>Hello</p>]]></summary>\n}",
+	},
+	{
+		Code:  codeInvalidExpression,
+		Title: "Invalid Go expression",
+		Fix:   "Check that the braces and brackets of the Go expression are balanced.",
+		Explanation: `The Go expression could not be parsed. The braces and brackets within Go expressions must be
+balanced, and a templ element, e.g. @component(), must be a function call or a value.`,
+		Example: "templ hello(names []string) {\n\t<p>{ names[0 }</p>\n}",
+		Fixed:   "templ hello(names []string) {\n\t<p>{ names[0] }</p>\n}",
+	},
+	{
+		Code:        codeInvalidCSSProperty,
+		Title:       "Invalid CSS property",
+		Fix:         "End each CSS property with a semicolon and a new line.",
+		Explanation: `Each property within a css template must be on its own line, and end with a semicolon.`,
+		Example:     "css red() {\n\tcolor: red\n}",
+		Fixed:       "css red() {\n\tcolor: red;\n}",
+	},
+	{
+		Code:        codeUnterminatedText,
+		Title:       "Unterminated text",
+		Fix:         "Close the template, or the element that contains the text.",
+		Explanation: `The file ended within text. Check that all elements and templates are closed.`,
+		Example:     "templ hello() {\n\t<p>Hello",
+		Fixed:       "templ hello() {\n\t<p>Hello</p>\n}",
+	},
+	{
+		Code:        codeDeprecatedCall,
+		Title:       "Deprecated template call syntax",
+		Fix:         "Replace `{! component }` with `@component`, or run `templ fmt .`.",
+		Explanation: `The {! component } syntax for calling other components is deprecated. templ fmt rewrites it to the @ syntax.`,
+		Example:     "templ page() {\n\t{! header() }\n}",
+		Fixed:       "templ page() {\n\t@header()\n}",
+	},
+	{
+		Code:  codeInvalidNesting,
+		Title: "Invalid element nesting",
+		Fix:   "Move the element outside of its ancestor, or change the ancestor to an element that can contain it.",
+		Explanation: `Some elements can't contain others. Browsers correct invalid nesting as they parse the page, so the
+page doesn't have the structure that was written. For example, a <p> is closed before a <div> starts, a link can't
+contain another link or a button, a <button> can only contain text-level elements, and a <li> must be within a list.`,
+		Example: "templ intro() {\n\t<p>\n\t\t<div>Hello</div>\n\t</p>\n}",
+		Fixed:   "templ intro() {\n\t<div>\n\t\t<div>Hello</div>\n\t</div>\n}",
+	},
+	{
+		Code:        codeDuplicateAttribute,
+		Title:       "Duplicate attribute",
+		Fix:         "Remove one of the attributes.",
+		Explanation: `An element has the same attribute more than once. Browsers ignore all but the first.`,
+		Example:     "templ link() {\n\t<a class=\"button\" href=\"/\" class=\"primary\">Home</a>\n}",
+		Fixed:       "templ link() {\n\t<a class=\"button primary\" href=\"/\">Home</a>\n}",
+	},
+	{
+		Code:  codeUnknownAttribute,
+		Title: "Unknown attribute",
+		Fix:   "Check the spelling of the attribute, or use a data-* attribute for custom data.",
+		Explanation: `The attribute isn't a global attribute, or an attribute of the element. Event handlers, and attributes
+that contain -, :, . or @, such as data-*, aria-*, hx-get and @click, aren't checked.`,
+		Example: "templ link() {\n\t<a hrf=\"/\">Home</a>\n}",
+		Fixed:   "templ link() {\n\t<a href=\"/\">Home</a>\n}",
+	},
+	{
+		Code:  codeMisusedBooleanAttribute,
+		Title: "Misused boolean attribute",
+		Fix:   "Use `name?={ value }` to set a boolean attribute from a Go expression.",
+		Explanation: `Boolean attributes, such as disabled, are true when they're present, whatever their value, so
+disabled="false" disables the element. Use a boolean expression attribute, e.g. disabled?={ isDisabled }, to
+include the attribute only when it's true. Boolean expression attributes can't be used for attributes that aren't
+boolean.`,
+		Example: "templ submit() {\n\t<button disabled=\"false\">Submit</button>\n}",
+		Fixed:   "templ submit(disabled bool) {\n\t<button disabled?={ disabled }>Submit</button>\n}",
+	},
+}
+
+// LookupErrorCode returns information about an error code. The code is case-insensitive.
+func LookupErrorCode(code string) (info ErrorCodeInfo, ok bool) {
+	for _, info := range ErrorCodes {
+		if strings.EqualFold(string(info.Code), code) {
+			return info, true
+		}
+	}
+	return info, false
+}
+
+// ParseError is a parse error, and the code of the problem. It unwraps to the parse.ParseError,
+// so its message and position can be retrieved with errors.As.
+type ParseError struct {
+	parse.ParseError
+	Code ErrorCode
+}
+
+func newParseError(code ErrorCode, msg string, pos parse.Position) ParseError {
+	return ParseError{ParseError: parse.Error(msg, pos), Code: code}
+}
+
+func (e ParseError) Unwrap() error {
+	return e.ParseError
+}
+
+// ErrorCodeOf returns information about the error code of a parse error. If err contains
+// multiple errors, the code of the first parse error is returned.
+func ErrorCodeOf(err error) (info ErrorCodeInfo, ok bool) {
+	var pe ParseError
+	if !errors.As(err, &pe) {
+		return info, false
+	}
+	return LookupErrorCode(string(pe.Code))
+}
+
+// WithErrorCodes adds the error code to the message of err, and to each of the errors that it
+// joins, e.g. "<p>: mismatched end tag ... [T2002]". The original errors can still be retrieved
+// with errors.As.
+func WithErrorCodes(err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		coded := make([]error, len(errs))
+		for i, e := range errs {
+			coded[i] = WithErrorCodes(e)
+		}
+		return errors.Join(coded...)
+	}
+	info, ok := ErrorCodeOf(err)
+	if !ok {
+		return err
+	}
+	return codedError{err: err, code: info.Code}
+}
+
+type codedError struct {
+	err  error
+	code ErrorCode
+}
+
+func (e codedError) Error() string {
+	return fmt.Sprintf("%v [%s]", e.err, e.code)
+}
+
+func (e codedError) Unwrap() error {
+	return e.err
+}
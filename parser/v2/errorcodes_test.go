@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/a-h/parse"
+)
+
+func TestErrorCodes(t *testing.T) {
+	seen := map[ErrorCode]bool{}
+	for _, info := range ErrorCodes {
+		if seen[info.Code] {
+			t.Errorf("duplicate error code %q", info.Code)
+		}
+		seen[info.Code] = true
+		if info.Title == "" || info.Fix == "" || info.Explanation == "" {
+			t.Errorf("%s: expected title, fix and explanation", info.Code)
+		}
+		if got, ok := LookupErrorCode(string(info.Code)); !ok || got.Code != info.Code {
+			t.Errorf("%s: lookup failed", info.Code)
+		}
+	}
+	if _, ok := LookupErrorCode("t1002"); !ok {
+		t.Error("expected lookup to be case-insensitive")
+	}
+	if _, ok := LookupErrorCode("T9999"); ok {
+		t.Error("expected unknown code not to be found")
+	}
+}
+
+func TestErrorCodeOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected ErrorCode
+	}{
+		{
+			name:     "malformed template declaration",
+			input:    "package main\n\ntempl hello() { <div></div> }\n",
+			expected: "T1001",
+		},
+		{
+			name:     "missing closing brace",
+			input:    "package main\n\ntempl hello() {\n\t<div></div>\n",
+			expected: "T1002",
+		},
+		{
+			name:     "incomplete statement",
+			input:    "package main\n\ntempl hello() {\n\tfor _, x := range xs\n\t}\n}\n",
+			expected: "T1003",
+		},
+		{
+			name:     "missing end tag",
+			input:    "package main\n\ntempl hello() {\n\t<style>\n}\n",
+			expected: "T2001",
+		},
+		{
+			name:     "mismatched end tag",
+			input:    "package main\n\ntempl hello() {\n\t<p><b>Hello</p></b>\n}\n",
+			expected: "T2002",
+		},
+		{
+			name:     "invalid style attribute",
+			input:    "package main\n\ntempl hello() {\n\t<div style={ s }></div>\n}\n",
+			expected: "T2004",
+		},
+		{
+			name:     "unterminated comment",
+			input:    "package main\n\ntempl hello() {\n\t<!-- TODO\n}\n",
+			expected: "T2006",
+		},
+		{
+			name:     "unterminated doctype",
+			input:    "package main\n\ntempl hello() {\n\t<!DOCTYPE html\n}\n",
+			expected: "T2007",
+		},
+		{
+			name:     "unterminated processing instruction",
+			input:    "package main\n\nxml feed() {\n\t<?xml version=\"1.0\"\n\t<feed></feed>\n}\n",
+			expected: "T2007",
+		},
+		{
+			name:     "unterminated cdata section",
+			input:    "package main\n\nxml feed() {\n\t<summary><![CDATA[<p>Hello</p></summary>\n}\n",
+			expected: "T2008",
+		},
+		{
+			name:     "invalid css property",
+			input:    "package main\n\ncss red() {\n\tcolor: red\n}\n",
+			expected: "T3002",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := ParseString(tt.input)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			info, ok := ErrorCodeOf(err)
+			if !ok {
+				t.Fatalf("no error code for %v", err)
+			}
+			if info.Code != tt.expected {
+				t.Errorf("expected code %s, got %s for %v", tt.expected, info.Code, err)
+			}
+			if len(tf.Diagnostics) == 0 {
+				t.Fatal("expected diagnostics")
+			}
+			if tf.Diagnostics[0].Code != tt.expected {
+				t.Errorf("expected diagnostic code %s, got %q", tt.expected, tf.Diagnostics[0].Code)
+			}
+		})
+	}
+}
+
+func TestDiagnosticCodes(t *testing.T) {
+	tf, err := ParseString("package main\n\ntempl hello() {\n\t<p><div>mismatched end tag</div></p>\n\t{! header() }\n}\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var codes []ErrorCode
+	for _, d := range tf.Diagnostics {
+		codes = append(codes, d.Code)
+	}
+	expected := []ErrorCode{"W0001", "W0002"}
+	if len(codes) != len(expected) || codes[0] != expected[0] || codes[1] != expected[1] {
+		t.Errorf("expected codes %v, got %v", expected, codes)
+	}
+}
+
+func TestWithErrorCodes(t *testing.T) {
+	_, err := ParseString("package main\n\ntempl a() {\n\t<p><b></p></b>\n}\n\ntempl b() {\n\t<!-- TODO\n}\n")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = WithErrorCodes(err)
+	expected := "<b>: mismatched end tag, expected '</b>', got '</p>': line 3, col 7 [T2002]\n" +
+		"expected end comment literal '-->' not found: line 9, col 0 [T2006]"
+	if err.Error() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, err.Error())
+	}
+	var pe parse.ParseError
+	if !errors.As(err, &pe) {
+		t.Error("expected the parse error to be available")
+	}
+}
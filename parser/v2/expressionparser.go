@@ -120,7 +120,7 @@ loop:
 		if ok {
 			braceCount--
 			if braceCount < 0 {
-				err = parse.Error("expression: too many closing braces", pi.Position())
+				err = newParseError(codeInvalidExpression, "expression: too many closing braces", pi.Position())
 				return
 			}
 			if braceCount == 0 {
@@ -143,7 +143,7 @@ loop:
 		sb.WriteString(c)
 	}
 	if braceCount != 0 {
-		err = parse.Error("expression: unexpected brace count", pi.Position())
+		err = newParseError(codeInvalidExpression, "expression: unexpected brace count", pi.Position())
 		return
 	}
 
@@ -197,7 +197,7 @@ loop:
 		if ok {
 			bracketCount--
 			if bracketCount < 0 {
-				err = parse.Error("expression: too many closing brackets", pi.Position())
+				err = newParseError(codeInvalidExpression, "expression: too many closing brackets", pi.Position())
 				return
 			}
 			if bracketCount == 0 {
@@ -220,7 +220,7 @@ loop:
 		sb.WriteString(c)
 	}
 	if bracketCount != 0 {
-		err = parse.Error("expression: unexpected bracket count", pi.Position())
+		err = newParseError(codeInvalidExpression, "expression: unexpected bracket count", pi.Position())
 		return
 	}
 
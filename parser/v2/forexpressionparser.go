@@ -10,6 +10,7 @@ type forExpressionParser struct{}
 
 func (_ forExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// Check the prefix first.
+	start := pi.Position()
 	if _, ok, err = parse.String("for ").Parse(pi); err != nil || !ok {
 		return
 	}
@@ -21,14 +22,14 @@ func (_ forExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error)
 	until := parse.All(openBraceWithOptionalPadding, parse.NewLine)
 	var fexp string
 	if fexp, ok, err = parse.StringUntil(until).Parse(pi); err != nil || !ok {
-		err = parse.Error("for: "+unterminatedMissingCurly, pi.Position())
+		err = newParseError(codeIncompleteStatement, "for: "+unterminatedMissingCurly, pi.Position())
 		return
 	}
 	r.Expression = NewExpression(fexp, from, pi.Position())
 
 	// Eat " {".
 	if _, ok, err = until.Parse(pi); err != nil || !ok {
-		err = parse.Error("for: "+unterminatedMissingCurly, pi.Position())
+		err = newParseError(codeIncompleteStatement, "for: "+unterminatedMissingCurly, pi.Position())
 		return
 	}
 
@@ -36,7 +37,7 @@ func (_ forExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error)
 	tnp := newTemplateNodeParser(closeBraceWithOptionalPadding, "for expression closing brace")
 	var nodes Nodes
 	if nodes, ok, err = tnp.Parse(pi); err != nil || !ok {
-		err = parse.Error("for: expected nodes, but none were found", pi.Position())
+		err = newParseError(codeInvalidBlock, "for: expected nodes, but none were found", pi.Position())
 		return
 	}
 	r.Children = nodes.Nodes
@@ -44,9 +45,10 @@ func (_ forExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error)
 
 	// Read the required closing brace.
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("for: "+unterminatedMissingEnd, pi.Position())
+		err = newParseError(codeMissingCloseBrace, "for: "+unterminatedMissingEnd, pi.Position())
 		return
 	}
+	r.Range = NewRange(start, pi.Position())
 
 	return r, true, nil
 }
@@ -15,15 +15,17 @@ var goSingleLineComment = goSingleLineCommentParser{}
 func (p goSingleLineCommentParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// Comment start.
 	var c GoComment
+	from := pi.Position()
 	if _, ok, err = goSingleLineCommentStart.Parse(pi); err != nil || !ok {
 		return
 	}
 	// Once we've got the comment start sequence, parse anything until the end
 	// sequence as the comment contents.
 	if c.Contents, ok, err = parse.StringUntil(goSingleLineCommentEnd).Parse(pi); err != nil || !ok {
-		err = parse.Error("expected end comment literal '\n' not found", pi.Position())
+		err = newParseError(codeUnterminatedComment, "expected end comment literal '\n' not found", pi.Position())
 		return
 	}
+	c.Range = NewRange(from, pi.Position())
 	// Move past the end element.
 	_, _, _ = goSingleLineCommentEnd.Parse(pi)
 	// Return the comment.
@@ -42,6 +44,7 @@ var goMultiLineComment = goMultiLineCommentParser{}
 func (p goMultiLineCommentParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// Comment start.
 	var c GoComment
+	from := pi.Position()
 	if _, ok, err = goMultiLineCommentStart.Parse(pi); err != nil || !ok {
 		return
 	}
@@ -49,11 +52,12 @@ func (p goMultiLineCommentParser) Parse(pi *parse.Input) (n Node, ok bool, err e
 	// Once we've got the comment start sequence, parse anything until the end
 	// sequence as the comment contents.
 	if c.Contents, ok, err = parse.StringUntil(goMultiLineCommentEnd).Parse(pi); err != nil || !ok {
-		err = parse.Error("expected end comment literal '*/' not found", pi.Position())
+		err = newParseError(codeUnterminatedComment, "expected end comment literal '*/' not found", pi.Position())
 		return
 	}
 	// Move past the end element.
 	_, _, _ = goMultiLineCommentEnd.Parse(pi)
+	c.Range = NewRange(from, pi.Position())
 	// Return the comment.
 	c.Multiline = true
 	return c, true, nil
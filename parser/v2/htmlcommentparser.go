@@ -15,6 +15,7 @@ var htmlComment = htmlCommentParser{}
 func (p htmlCommentParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// Comment start.
 	var c HTMLComment
+	from := pi.Position()
 	if _, ok, err = htmlCommentStart.Parse(pi); err != nil || !ok {
 		return
 	}
@@ -22,7 +23,7 @@ func (p htmlCommentParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// Once we've got the comment start sequence, parse anything until the end
 	// sequence as the comment contents.
 	if c.Contents, ok, err = parse.StringUntil(htmlCommentEnd).Parse(pi); err != nil || !ok {
-		err = parse.Error("expected end comment literal '-->' not found", pi.Position())
+		err = newParseError(codeUnterminatedComment, "expected end comment literal '-->' not found", pi.Position())
 		return
 	}
 	// Cut the end element.
@@ -30,9 +31,10 @@ func (p htmlCommentParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 
 	// Cut the gt.
 	if _, ok, err = gt.Parse(pi); err != nil || !ok {
-		err = parse.Error("comment contains invalid sequence '--'", pi.Position())
+		err = newParseError(codeUnterminatedComment, "comment contains invalid sequence '--'", pi.Position())
 		return
 	}
+	c.Range = NewRange(from, pi.Position())
 
 	return c, true, nil
 }
@@ -0,0 +1,347 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateHTML returns warnings for HTML that is well-formed, but not valid, such as a <div>
+// within a <p>, or an element with the same attribute twice. Browsers correct invalid HTML
+// as they parse it, so the page may not have the structure that was written.
+func validateHTML(nodes []Node) []Diagnostic {
+	v := &htmlValidator{}
+	for _, n := range nodes {
+		Walk(v, n)
+	}
+	return v.diagnostics
+}
+
+type htmlAncestor struct {
+	// name of the element, or empty if the ancestor is a statement or expression.
+	name string
+	// boundary is true if the parent element isn't known, e.g. for the children of a templ
+	// element, which are rendered wherever the component places them.
+	boundary bool
+}
+
+type htmlValidator struct {
+	ancestors   []htmlAncestor
+	diagnostics []Diagnostic
+}
+
+func (v *htmlValidator) Visit(node any) Visitor {
+	switch n := node.(type) {
+	case nil:
+		v.ancestors = v.ancestors[:len(v.ancestors)-1]
+		return nil
+	case Element:
+		// SVG and MathML elements follow different rules.
+		if n.IsForeignElement() {
+			return nil
+		}
+		name := strings.ToLower(n.Name)
+		v.validateNesting(name, n.NameRange)
+		v.validateAttributes(name, n.Attributes, map[string]struct{}{})
+		v.ancestors = append(v.ancestors, htmlAncestor{name: name})
+	case RawElement:
+		v.validateAttributes(strings.ToLower(n.Name), n.Attributes, map[string]struct{}{})
+		return nil
+	case BoolConstantAttribute, ConstantAttribute, BoolExpressionAttribute, ExpressionAttribute, SpreadAttributes, ConditionalAttribute:
+		// Attributes are validated with their element.
+		return nil
+	case TemplElementExpression:
+		v.ancestors = append(v.ancestors, htmlAncestor{boundary: true})
+	default:
+		v.ancestors = append(v.ancestors, htmlAncestor{})
+	}
+	return v
+}
+
+func (v *htmlValidator) warn(code ErrorCode, msg string, r Range) {
+	v.diagnostics = append(v.diagnostics, newDiagnostic(code, msg, r, DiagnosticSeverityWarning))
+}
+
+// parent returns the name of the nearest ancestor element, if it's known.
+func (v *htmlValidator) parent() (name string, ok bool) {
+	for i := len(v.ancestors) - 1; i >= 0; i-- {
+		a := v.ancestors[i]
+		if a.boundary {
+			return "", false
+		}
+		if a.name != "" {
+			return a.name, true
+		}
+	}
+	return "", false
+}
+
+func (v *htmlValidator) validateNesting(name string, r Range) {
+	if name == "li" {
+		// Custom elements and templates may be used to build lists.
+		if parent, ok := v.parent(); ok && !isListElement(parent) && parent != "template" && !strings.Contains(parent, "-") {
+			v.warn(codeInvalidNesting, fmt.Sprintf("<li>: invalid nesting, <li> must be within <ul>, <ol> or <menu>, not <%s>", parent), r)
+		}
+	}
+	for i := len(v.ancestors) - 1; i >= 0; i-- {
+		a := v.ancestors[i]
+		if a.boundary {
+			return
+		}
+		var invalid bool
+		switch a.name {
+		case "p":
+			// The <p> is closed by the browser before the block element starts.
+			_, invalid = htmlBlockElements[name]
+		case "a":
+			_, invalid = htmlInteractiveElements[name]
+		case "button":
+			_, isBlock := htmlBlockElements[name]
+			_, isInteractive := htmlInteractiveElements[name]
+			invalid = isBlock || isInteractive
+		}
+		if invalid {
+			v.warn(codeInvalidNesting, fmt.Sprintf("<%s>: invalid nesting, <%s> cannot be within <%s>", name, name, a.name), r)
+			return
+		}
+	}
+}
+
+func isListElement(name string) bool {
+	return name == "ul" || name == "ol" || name == "menu"
+}
+
+// validateAttributes checks for duplicate, unknown and misused boolean attributes. The
+// branches of conditional attributes are each checked against the attributes that are
+// always present.
+func (v *htmlValidator) validateAttributes(element string, attrs []Attribute, seen map[string]struct{}) {
+	var conditionals []ConditionalAttribute
+	for _, attr := range attrs {
+		var name string
+		var r Range
+		switch attr := attr.(type) {
+		case ConditionalAttribute:
+			conditionals = append(conditionals, attr)
+			continue
+		case BoolConstantAttribute:
+			name, r = attr.Name, attr.Range
+		case ConstantAttribute:
+			name, r = attr.Name, attr.Range
+			if isBooleanAttribute(attr.Name) && !isBooleanAttributeValue(attr.Name, attr.Value) {
+				v.warn(codeMisusedBooleanAttribute, fmt.Sprintf("<%s>: misused boolean attribute %q, the attribute is true when present, whatever its value", element, attr.Name), r)
+			}
+		case BoolExpressionAttribute:
+			name, r = attr.Name, attr.Range
+			if isKnownAttribute(element, attr.Name) && !isBooleanAttribute(attr.Name) {
+				v.warn(codeMisusedBooleanAttribute, fmt.Sprintf("<%s>: misused boolean attribute, %q is not a boolean attribute", element, attr.Name), r)
+			}
+		case ExpressionAttribute:
+			name, r = attr.Name, attr.Range
+		default:
+			continue
+		}
+		key := strings.ToLower(name)
+		if _, duplicate := seen[key]; duplicate {
+			v.warn(codeDuplicateAttribute, fmt.Sprintf("<%s>: duplicate attribute %q", element, name), r)
+		}
+		seen[key] = struct{}{}
+		if _, isStandardElement := htmlElementAttributes[element]; isStandardElement && !isKnownAttribute(element, name) && !isCustomAttribute(name) {
+			v.warn(codeUnknownAttribute, fmt.Sprintf("<%s>: unknown attribute %q", element, name), r)
+		}
+	}
+	for _, ca := range conditionals {
+		v.validateAttributes(element, ca.Then, copyNameSet(seen))
+		v.validateAttributes(element, ca.Else, copyNameSet(seen))
+	}
+}
+
+func copyNameSet(m map[string]struct{}) map[string]struct{} {
+	c := make(map[string]struct{}, len(m))
+	for k := range m {
+		c[k] = struct{}{}
+	}
+	return c
+}
+
+func isKnownAttribute(element, name string) bool {
+	name = strings.ToLower(name)
+	if _, ok := htmlGlobalAttributes[name]; ok {
+		return true
+	}
+	if _, ok := rdfaAttributes[name]; ok {
+		return true
+	}
+	_, ok := htmlElementAttributes[element][name]
+	return ok
+}
+
+// isCustomAttribute returns true for event handlers, data-* and aria-* attributes, and the
+// attributes used by libraries such as htmx and Alpine.js, which aren't checked.
+func isCustomAttribute(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "on") || strings.ContainsAny(name, "-:.@")
+}
+
+func isBooleanAttribute(name string) bool {
+	_, ok := htmlBooleanAttributes[strings.ToLower(name)]
+	return ok
+}
+
+func isBooleanAttributeValue(name, value string) bool {
+	if value == "" || strings.EqualFold(value, name) {
+		return true
+	}
+	return strings.EqualFold(name, "hidden") && strings.EqualFold(value, "until-found")
+}
+
+func wordSet(s string) map[string]struct{} {
+	m := map[string]struct{}{}
+	for _, w := range strings.Fields(s) {
+		m[w] = struct{}{}
+	}
+	return m
+}
+
+// Elements that close an open <p>.
+// https://html.spec.whatwg.org/multipage/grouping-content.html#the-p-element
+var htmlBlockElements = wordSet(`address article aside blockquote details dialog div dl fieldset figcaption figure footer form
+h1 h2 h3 h4 h5 h6 header hgroup hr main menu nav ol p pre search section table ul`)
+
+// https://html.spec.whatwg.org/multipage/dom.html#interactive-content
+var htmlInteractiveElements = wordSet(`a button details embed iframe label select textarea`)
+
+// https://html.spec.whatwg.org/multipage/indices.html#attributes-3
+var htmlBooleanAttributes = wordSet(`allowfullscreen async autofocus autoplay checked controls default defer disabled
+formnovalidate hidden inert ismap itemscope loop multiple muted nomodule novalidate open playsinline readonly required
+reversed selected shadowrootclonable shadowrootdelegatesfocus shadowrootserializable`)
+
+// https://html.spec.whatwg.org/multipage/dom.html#global-attributes
+var htmlGlobalAttributes = wordSet(`accesskey autocapitalize autocorrect autofocus class contenteditable dir draggable
+enterkeyhint exportparts hidden id inert inputmode is itemid itemprop itemref itemscope itemtype lang nonce part popover
+role slot spellcheck style tabindex title translate writingsuggestions`)
+
+// RDFa attributes may be used on any element, e.g. the Open Graph <meta property="og:title">.
+// https://www.w3.org/TR/rdfa-core/#s_syntax
+var rdfaAttributes = wordSet(`about content datatype href inlist prefix property rel resource rev src typeof vocab`)
+
+// htmlElementAttributes contains the attributes of standard HTML elements, in addition to the
+// global attributes. Elements that aren't listed, such as custom elements, aren't checked.
+var htmlElementAttributes = func() map[string]map[string]struct{} {
+	attrs := map[string]string{
+		"a":          "href target download ping rel hreflang type referrerpolicy",
+		"abbr":       "",
+		"address":    "",
+		"area":       "alt coords shape href target download ping rel referrerpolicy",
+		"article":    "",
+		"aside":      "",
+		"audio":      "src crossorigin preload autoplay loop muted controls",
+		"b":          "",
+		"base":       "href target",
+		"bdi":        "",
+		"bdo":        "",
+		"blockquote": "cite",
+		"body":       "",
+		"br":         "",
+		"button":     "command commandfor disabled form formaction formenctype formmethod formnovalidate formtarget name popovertarget popovertargetaction type value",
+		"canvas":     "width height",
+		"caption":    "",
+		"cite":       "",
+		"code":       "",
+		"col":        "span",
+		"colgroup":   "span",
+		"data":       "value",
+		"datalist":   "",
+		"dd":         "",
+		"del":        "cite datetime",
+		"details":    "name open",
+		"dfn":        "",
+		"dialog":     "closedby open",
+		"div":        "",
+		"dl":         "",
+		"dt":         "",
+		"em":         "",
+		"embed":      "src type width height",
+		"fieldset":   "disabled form name",
+		"figcaption": "",
+		"figure":     "",
+		"footer":     "",
+		"form":       "accept-charset action autocomplete enctype method name novalidate rel target",
+		"h1":         "",
+		"h2":         "",
+		"h3":         "",
+		"h4":         "",
+		"h5":         "",
+		"h6":         "",
+		"head":       "",
+		"header":     "",
+		"hgroup":     "",
+		"hr":         "",
+		"html":       "manifest xmlns",
+		"i":          "",
+		"iframe":     "src srcdoc name sandbox allow allowfullscreen width height referrerpolicy loading",
+		"img":        "alt src srcset sizes crossorigin usemap ismap width height referrerpolicy decoding loading fetchpriority",
+		"input":      "accept alpha alt autocomplete capture checked colorspace dirname disabled form formaction formenctype formmethod formnovalidate formtarget height list max maxlength min minlength multiple name pattern placeholder popovertarget popovertargetaction readonly required size src step type value width",
+		"ins":        "cite datetime",
+		"kbd":        "",
+		"label":      "for",
+		"legend":     "",
+		"li":         "value",
+		"link":       "href crossorigin rel as media integrity hreflang type referrerpolicy sizes imagesrcset imagesizes blocking color disabled fetchpriority",
+		"main":       "",
+		"map":        "name",
+		"mark":       "",
+		"menu":       "",
+		"meta":       "name http-equiv content charset media",
+		"meter":      "value min max low high optimum",
+		"nav":        "",
+		"noscript":   "",
+		"object":     "data type name form width height",
+		"ol":         "reversed start type",
+		"optgroup":   "disabled label",
+		"option":     "disabled label selected value",
+		"output":     "for form name",
+		"p":          "",
+		"picture":    "",
+		"pre":        "",
+		"progress":   "value max",
+		"q":          "cite",
+		"rp":         "",
+		"rt":         "",
+		"ruby":       "",
+		"s":          "",
+		"samp":       "",
+		"script":     "src type nomodule async defer crossorigin integrity referrerpolicy blocking fetchpriority",
+		"search":     "",
+		"section":    "",
+		"select":     "autocomplete disabled form multiple name required size",
+		"slot":       "name",
+		"small":      "",
+		"source":     "type media src srcset sizes width height",
+		"span":       "",
+		"strong":     "",
+		"style":      "media blocking type",
+		"sub":        "",
+		"summary":    "",
+		"sup":        "",
+		"table":      "",
+		"tbody":      "",
+		"td":         "colspan rowspan headers",
+		"template":   "shadowrootmode shadowrootdelegatesfocus shadowrootclonable shadowrootserializable",
+		"textarea":   "autocomplete cols dirname disabled form maxlength minlength name placeholder readonly required rows wrap",
+		"tfoot":      "",
+		"th":         "colspan rowspan headers scope abbr",
+		"thead":      "",
+		"time":       "datetime",
+		"title":      "",
+		"tr":         "",
+		"track":      "default kind label src srclang",
+		"u":          "",
+		"ul":         "",
+		"var":        "",
+		"video":      "src crossorigin poster preload autoplay playsinline loop muted controls width height",
+		"wbr":        "",
+	}
+	m := make(map[string]map[string]struct{}, len(attrs))
+	for element, names := range attrs {
+		m[element] = wordSet(names)
+	}
+	return m
+}()
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHTMLValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "valid HTML has no warnings",
+			input:    `<ul class="list" data-id="1" hx-get="/" @click="open = true"><li><a href="/" onclick="go()">Home</a></li></ul>`,
+			expected: nil,
+		},
+		{
+			name:     "block element within p",
+			input:    `<p><span><div>Hello</div></span></p>`,
+			expected: []string{`W0002 <div>: invalid nesting, <div> cannot be within <p>`},
+		},
+		{
+			name:     "link within a link",
+			input:    `<a href="/"><a href="/about">About</a></a>`,
+			expected: []string{`W0002 <a>: invalid nesting, <a> cannot be within <a>`},
+		},
+		{
+			name:     "block content within a button",
+			input:    `<button><div>Click</div></button>`,
+			expected: []string{`W0002 <div>: invalid nesting, <div> cannot be within <button>`},
+		},
+		{
+			name:     "li outside of a list",
+			input:    `<div><li>Item</li></div>`,
+			expected: []string{`W0002 <li>: invalid nesting, <li> must be within <ul>, <ol> or <menu>, not <div>`},
+		},
+		{
+			name:     "li within statements within a list",
+			input:    "<ol>\n\t\tfor _, item := range items {\n\t\t\t<li>{ item }</li>\n\t\t}\n\t</ol>",
+			expected: nil,
+		},
+		{
+			name:     "the parent of the children of a templ element is unknown",
+			input:    "<p>\n\t\t@list() {\n\t\t\t<li><div>Item</div></li>\n\t\t}\n\t</p>",
+			expected: nil,
+		},
+		{
+			name:     "svg elements are not checked",
+			input:    `<p><svg viewBox="0 0 10 10"><a href="/"><a href="/"></a></a></svg></p>`,
+			expected: nil,
+		},
+		{
+			name:  "duplicate attributes",
+			input: `<div class="a" CLASS="b" id={ id } id="c"></div>`,
+			expected: []string{
+				`W0003 <div>: duplicate attribute "CLASS"`,
+				`W0003 <div>: duplicate attribute "id"`,
+			},
+		},
+		{
+			name:     "attributes in separate branches of a conditional are not duplicates",
+			input:    "<div\n\t\tif a {\n\t\t\tclass=\"a\"\n\t\t} else {\n\t\t\tclass=\"b\"\n\t\t}\n\t></div>",
+			expected: nil,
+		},
+		{
+			name:     "conditional attributes that duplicate attributes",
+			input:    "<div\n\t\tclass=\"a\"\n\t\tif a {\n\t\t\tclass=\"b\"\n\t\t}\n\t></div>",
+			expected: []string{`W0003 <div>: duplicate attribute "class"`},
+		},
+		{
+			name:     "unknown attribute",
+			input:    `<a hrf="/">Home</a>`,
+			expected: []string{`W0004 <a>: unknown attribute "hrf"`},
+		},
+		{
+			name:     "rdfa attributes are known",
+			input:    `<meta property="og:title" content="templ"/><div vocab="https://schema.org/" typeof="Person"><span property="name">Ada</span></div>`,
+			expected: nil,
+		},
+		{
+			name:     "attributes of custom elements are not checked",
+			input:    `<my-element size="large"></my-element>`,
+			expected: nil,
+		},
+		{
+			name:  "misused boolean attributes",
+			input: `<input type="checkbox" checked="false" disabled="disabled" hidden="until-found" value?={ v }/>`,
+			expected: []string{
+				`W0005 <input>: misused boolean attribute "checked", the attribute is true when present, whatever its value`,
+				`W0005 <input>: misused boolean attribute, "value" is not a boolean attribute`,
+			},
+		},
+		{
+			name:     "script attributes",
+			input:    `<script src="/app.js" defer="true"></script>`,
+			expected: []string{`W0005 <script>: misused boolean attribute "defer", the attribute is true when present, whatever its value`},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := ParseString("package main\n\ntempl test() {\n\t" + tt.input + "\n}\n")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var actual []string
+			for _, d := range tf.Diagnostics {
+				if d.Severity != DiagnosticSeverityWarning {
+					t.Errorf("unexpected severity: %v", d)
+				}
+				actual = append(actual, string(d.Code)+" "+d.Message)
+			}
+			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestHTMLValidationRanges(t *testing.T) {
+	input := "package main\n\ntempl test() {\n\t<p><div class=\"a\" class=\"b\"></div></p>\n}\n"
+	tf, err := ParseString(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var actual []string
+	for _, d := range tf.Diagnostics {
+		actual = append(actual, input[d.Range.From.Index:d.Range.To.Index])
+	}
+	if diff := cmp.Diff([]string{`div`, `class="b"`}, actual); diff != "" {
+		t.Error(diff)
+	}
+}
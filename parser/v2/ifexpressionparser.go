@@ -12,6 +12,7 @@ type ifExpressionParser struct{}
 
 func (ifExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// Check the prefix first.
+	from := pi.Position()
 	if _, ok, err = parse.String("if ").Parse(pi); err != nil || !ok {
 		return
 	}
@@ -21,13 +22,13 @@ func (ifExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	var r IfExpression
 	until := parse.All(openBraceWithOptionalPadding, parse.NewLine)
 	if r.Expression, ok, err = ExpressionOf(parse.StringUntil(until)).Parse(pi); err != nil || !ok {
-		err = parse.Error("if: "+unterminatedMissingCurly, pi.Position())
+		err = newParseError(codeIncompleteStatement, "if: "+unterminatedMissingCurly, pi.Position())
 		return
 	}
 
 	// Eat " {\n".
 	if _, ok, err = until.Parse(pi); err != nil || !ok {
-		err = parse.Error("if: "+unterminatedMissingCurly, pi.Position())
+		err = newParseError(codeIncompleteStatement, "if: "+unterminatedMissingCurly, pi.Position())
 		return
 	}
 
@@ -38,7 +39,7 @@ func (ifExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	np := newTemplateNodeParser(untilElseIfElseOrEnd, "else expression or closing brace")
 	var thenNodes Nodes
 	if thenNodes, ok, err = np.Parse(pi); err != nil || !ok {
-		err = parse.Error("if: expected nodes, but none were found", pi.Position())
+		err = newParseError(codeInvalidBlock, "if: expected nodes, but none were found", pi.Position())
 		return
 	}
 	r.Then = thenNodes.Nodes
@@ -59,9 +60,10 @@ func (ifExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 
 	// Read the required closing brace.
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("if: "+unterminatedMissingEnd, pi.Position())
+		err = newParseError(codeMissingCloseBrace, "if: "+unterminatedMissingEnd, pi.Position())
 		return
 	}
+	r.Range = NewRange(from, pi.Position())
 
 	return r, true, nil
 }
@@ -72,12 +74,18 @@ type elseIfExpressionParser struct{}
 
 func (elseIfExpressionParser) Parse(pi *parse.Input) (r ElseIfExpression, ok bool, err error) {
 	// Check the prefix first.
+	start := pi.Index()
 	if _, ok, err = parse.All(
 		parse.OptionalWhitespace,
 		closeBrace,
-		parse.OptionalWhitespace,
+		parse.OptionalWhitespace).Parse(pi); err != nil || !ok {
+		return
+	}
+	from := pi.Position()
+	if _, ok, err = parse.All(
 		parse.String("else if"),
 		parse.Whitespace).Parse(pi); err != nil || !ok {
+		pi.Seek(start)
 		return
 	}
 
@@ -85,13 +93,13 @@ func (elseIfExpressionParser) Parse(pi *parse.Input) (r ElseIfExpression, ok boo
 	// If there's no match, there's no {\n, which is an error.
 	until := parse.All(openBraceWithOptionalPadding, parse.NewLine)
 	if r.Expression, ok, err = ExpressionOf(parse.StringUntil(until)).Parse(pi); err != nil || !ok {
-		err = parse.Error("if: unterminated else if (missing closing '{\n')", pi.Position())
+		err = newParseError(codeIncompleteStatement, "if: unterminated else if (missing closing '{\n')", pi.Position())
 		return
 	}
 
 	// Eat " {\n".
 	if _, ok, err = until.Parse(pi); err != nil || !ok {
-		err = parse.Error("if: unterminated (missing closing '{')", pi.Position())
+		err = newParseError(codeIncompleteStatement, "if: unterminated (missing closing '{')", pi.Position())
 		return
 	}
 
@@ -102,22 +110,23 @@ func (elseIfExpressionParser) Parse(pi *parse.Input) (r ElseIfExpression, ok boo
 	np := newTemplateNodeParser(untilElseIfElseOrEnd, "else expression or closing brace")
 	var thenNodes Nodes
 	if thenNodes, ok, err = np.Parse(pi); err != nil || !ok {
-		err = parse.Error("if: expected nodes, but none were found", pi.Position())
+		err = newParseError(codeInvalidBlock, "if: expected nodes, but none were found", pi.Position())
 		return
 	}
 	r.Then = thenNodes.Nodes
 	r.Diagnostics = append(r.Diagnostics, thenNodes.Diagnostics...)
+	r.Range = NewRange(from, pi.Position())
 
 	return r, true, nil
 }
 
 var endElseParser = parse.All(
+	parse.OptionalWhitespace,
 	parse.Rune('}'),
 	parse.OptionalWhitespace,
 	parse.String("else"),
 	parse.OptionalWhitespace,
-	parse.Rune('{'),
-	parse.OptionalWhitespace)
+	parse.Rune('{'))
 
 var elseExpression parse.Parser[Nodes] = elseExpressionParser{}
 
@@ -131,6 +140,15 @@ func (elseExpressionParser) Parse(in *parse.Input) (r Nodes, ok bool, err error)
 		in.Seek(start)
 		return
 	}
+	// Within whitespace-sensitive elements, only the line break after the brace is syntax.
+	afterBrace := parse.OptionalWhitespace
+	if stateOf(in).preserveWhitespace {
+		afterBrace = parse.StringFrom(parse.Optional(parse.NewLine))
+	}
+	if _, _, err = afterBrace.Parse(in); err != nil {
+		in.Seek(start)
+		return
+	}
 
 	// Else contents
 	if r, ok, err = newTemplateNodeParser(closeBraceWithOptionalPadding, "else expression closing brace").Parse(in); err != nil || !ok {
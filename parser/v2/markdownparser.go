@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/a-h/parse"
+)
+
+// Markdown.
+//
+// The contents of a <templ:markdown> element are converted to HTML by the generator. { expr }
+// expressions are allowed, but everything else, including elements, is Markdown text.
+var markdownOpenTag = parse.All(parse.String("<templ:markdown"), parse.OptionalWhitespace, parse.String(">"))
+var markdownCloseTag = parse.String("</templ:markdown>")
+
+var markdownElement = parse.Func(func(pi *parse.Input) (n Node, ok bool, err error) {
+	start := pi.Position()
+	if _, ok, err = markdownOpenTag.Parse(pi); err != nil || !ok {
+		return
+	}
+	var r Markdown
+	var sb strings.Builder
+	var textFrom parse.Position
+	flush := func(to parse.Position) {
+		if sb.Len() > 0 {
+			r.Children = append(r.Children, Text{Value: sb.String(), Range: NewRange(textFrom, to)})
+			sb.Reset()
+		}
+	}
+	for {
+		end := pi.Position()
+		if _, ok, err = markdownCloseTag.Parse(pi); err != nil {
+			return
+		}
+		if ok {
+			flush(end)
+			break
+		}
+		next, hasNext := pi.Peek(1)
+		if !hasNext {
+			err = newParseError(codeMissingEndTag, "<templ:markdown>: expected end tag not present", start)
+			return
+		}
+		if next == "{" {
+			exprStart := pi.Index()
+			var se Node
+			if se, ok, err = stringExpression.Parse(pi); err != nil {
+				return
+			}
+			if ok {
+				// The whitespace after the expression is part of the Markdown.
+				unreadTrailingWhitespace(pi, exprStart)
+				e := se.(StringExpression)
+				e.TrailingSpace = SpaceNone
+				flush(end)
+				r.Children = append(r.Children, e)
+				continue
+			}
+		}
+		if sb.Len() == 0 {
+			textFrom = pi.Position()
+		}
+		sb.WriteString(next)
+		pi.Take(1)
+	}
+	r.Range = NewRange(start, pi.Position())
+	return r, true, nil
+})
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/a-h/parse"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMarkdownParser(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		expected Markdown
+	}{
+		{
+			name:     "markdown: empty",
+			input:    `<templ:markdown></templ:markdown>`,
+			expected: Markdown{},
+		},
+		{
+			name:  "markdown: elements are text",
+			input: "<templ:markdown>\n# { title }\n<b>not an element</b>\n</templ:markdown>",
+			expected: Markdown{
+				Children: []Node{
+					Text{Value: "\n# "},
+					StringExpression{
+						Expression: Expression{
+							Value: "title",
+							Range: Range{
+								From: Position{Index: 21, Line: 1, Col: 4},
+								To:   Position{Index: 26, Line: 1, Col: 9},
+							},
+						},
+					},
+					Text{Value: "\n<b>not an element</b>\n"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			input := parse.NewInput(tt.input)
+			result, ok, err := markdownElement.Parse(input)
+			if err != nil {
+				t.Fatalf("parser error: %v", err)
+			}
+			if !ok {
+				t.Fatalf("failed to parse at %d", input.Index())
+			}
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMarkdownParserErrors(t *testing.T) {
+	_, _, err := markdownElement.Parse(parse.NewInput("<templ:markdown>\n# Title\n"))
+	expected := "<templ:markdown>: expected end tag not present: line 0, col 0"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestMarkdownElementIsOptIn(t *testing.T) {
+	tf, err := ParseString("package main\n\ntempl f() {\n\t<markdown># Title</markdown>\n}\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elements := elementsOf(tf); len(elements) != 1 || elements[0].Name != "markdown" {
+		t.Errorf("expected <markdown> to be parsed as an element, got %#v", tf.Nodes[0])
+	}
+}
@@ -16,12 +16,12 @@ var pkg = parse.Func(func(pi *parse.Input) (pkg Package, ok bool, err error) {
 	// Once we have the prefix, it's an expression until the end of the line.
 	var exp string
 	if exp, ok, err = stringUntilNewLine.Parse(pi); err != nil || !ok {
-		err = parse.Error("package literal not terminated", pi.Position())
+		err = newParseError(codeInvalidPackage, "package literal not terminated", pi.Position())
 		return
 	}
 	if len(exp) == 0 {
 		ok = false
-		err = parse.Error("package literal not terminated", start)
+		err = newParseError(codeInvalidPackage, "package literal not terminated", start)
 		return
 	}
 
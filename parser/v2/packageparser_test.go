@@ -11,12 +11,12 @@ func TestPackageParserErrors(t *testing.T) {
 	var tests = []struct {
 		name     string
 		input    string
-		expected parse.ParseError
+		expected ParseError
 	}{
 		{
 			name:  "unterminated package",
 			input: "package ",
-			expected: parse.Error(
+			expected: newParseError(codeInvalidPackage,
 				"package literal not terminated",
 				parse.Position{
 					Index: 8,
@@ -28,7 +28,7 @@ func TestPackageParserErrors(t *testing.T) {
 		{
 			name:  "unterminated package, new line",
 			input: "package \n",
-			expected: parse.Error(
+			expected: newParseError(codeInvalidPackage,
 				"package literal not terminated",
 				parse.Position{
 					Index: 0,
@@ -92,7 +92,7 @@ func TestPackageParser(t *testing.T) {
 			if !ok {
 				t.Fatalf("unexpected failure for input %q", tt.input)
 			}
-			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+			if diff := cmp.Diff(tt.expected, actual, ignoreNodeRanges); diff != "" {
 				t.Error(diff)
 			}
 		})
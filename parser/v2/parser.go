@@ -11,25 +11,71 @@ var expressionFuncEnd = parse.All(parse.Rune(')'), openBraceWithOptionalPadding)
 
 var template = parse.Func(func(pi *parse.Input) (r HTMLTemplate, ok bool, err error) {
 	// templ FuncName(p Person, other Other) {
+	from := pi.Position()
 	var te templateExpression
 	if te, ok, err = templateExpressionParser.Parse(pi); err != nil || !ok {
 		return
 	}
 	r.Expression = te.Expression
 
+	// On error, the nodes that were parsed are returned, so that the partial template can be used.
+	nodes, err := parseTemplateBody(pi, "templ")
+	r.Range = NewRange(from, pi.Position())
+	r.Children = nodes.Nodes
+	r.Diagnostics = append(nodes.Diagnostics, validateHTML(nodes.Nodes)...)
+	if err != nil {
+		return r, false, err
+	}
+
+	return r, true, nil
+})
+
+// XML template
+
+var xmlTemplateExpressionParser = newTemplateExpressionParser("xml")
+
+var xmlTemplate = parse.Func(func(pi *parse.Input) (r XMLTemplate, ok bool, err error) {
+	// xml FuncName(p Person, other Other) {
+	from := pi.Position()
+	var te templateExpression
+	if te, ok, err = xmlTemplateExpressionParser.Parse(pi); err != nil || !ok {
+		return
+	}
+	r.Expression = te.Expression
+
+	s := stateOf(pi)
+	defer beginParse(pi, s)()
+	s.xml = true
+	nodes, err := parseTemplateBody(pi, "xml")
+	s.xml = false
+	r.Range = NewRange(from, pi.Position())
+	// All elements within an XML template are XML elements, regardless of their name.
+	r.Children = nodesWithNamespace(nodes.Nodes, NamespaceXML)
+	r.Diagnostics = nodes.Diagnostics
+	if err != nil {
+		return r, false, err
+	}
+
+	return r, true, nil
+})
+
+// parseTemplateBody parses the nodes of a template, and its closing brace.
+func parseTemplateBody(pi *parse.Input, keyword string) (nodes Nodes, err error) {
 	// Once we're in a template, we should expect some template whitespace, if/switch/for,
 	// or node string expressions etc.
-	var nodes Nodes
+	s := stateOf(pi)
+	defer beginParse(pi, s)()
+	// Elements can't be closed by the end tags of another template.
+	defer func() { s.implicitlyClosed = nil }()
+	var ok bool
 	nodes, ok, err = newTemplateNodeParser(closeBraceWithOptionalPadding, "template closing brace").Parse(pi)
 	if err != nil {
 		return
 	}
 	if !ok {
-		err = parse.Error("templ: expected nodes in templ body, but found none", pi.Position())
+		err = newParseError(codeInvalidBlock, keyword+": expected nodes in "+keyword+" body, but found none", pi.Position())
 		return
 	}
-	r.Children = nodes.Nodes
-	r.Diagnostics = nodes.Diagnostics
 
 	// Eat any whitespace.
 	_, _, err = parse.OptionalWhitespace.Parse(pi)
@@ -39,9 +85,9 @@ var template = parse.Func(func(pi *parse.Input) (r HTMLTemplate, ok bool, err er
 
 	// Try for }
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("template: missing closing brace", pi.Position())
+		err = newParseError(codeMissingCloseBrace, "template: missing closing brace", pi.Position())
 		return
 	}
 
-	return r, true, nil
-})
+	return nodes, nil
+}
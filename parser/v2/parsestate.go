@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"sync"
+
+	"github.com/a-h/parse"
+)
+
+// ParseOpt configures how templates are parsed.
+type ParseOpt func(s *parseState)
+
+// WithWhitespaceSensitiveElements adds elements whose whitespace is preserved, in addition to
+// <pre>, <textarea> and <code>.
+func WithWhitespaceSensitiveElements(names ...string) ParseOpt {
+	return func(s *parseState) {
+		if s.whitespaceSensitiveElements == nil {
+			s.whitespaceSensitiveElements = make(map[string]struct{}, len(names))
+		}
+		for _, name := range names {
+			s.whitespaceSensitiveElements[name] = struct{}{}
+		}
+	}
+}
+
+// parseState is the state of a parse that isn't part of its input, i.e. the options that the
+// parse was started with, and the context of the node that's being parsed.
+type parseState struct {
+	// whitespaceSensitiveElements are the elements whose whitespace is preserved, in addition
+	// to the default elements.
+	whitespaceSensitiveElements map[string]struct{}
+	// preserveWhitespace is true within whitespace-sensitive elements.
+	preserveWhitespace bool
+	// xml is true within XML templates, where element names are parsed with XML rules.
+	xml bool
+	// openElements are the names of the elements whose children are being parsed, outermost
+	// first.
+	openElements []string
+	// endTagErrorIndex is the index of the most recent missing or mismatched end tag that has
+	// been reported. The elements that are open at the same index are closed without another
+	// error. The index is never 0, because an element starts before its end tag.
+	endTagErrorIndex int
+	// implicitlyClosed are the names of the elements that were closed by a mismatched end
+	// tag. Their own end tags are skipped, e.g. the </b> of <p><b></p></b>.
+	implicitlyClosed []string
+}
+
+// isOpen returns true if the children of an element with the name are being parsed.
+func (s *parseState) isOpen(name string) bool {
+	for _, open := range s.openElements {
+		if open == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStates are the states of the inputs that are being parsed. The parsers are shared, so
+// the state of a parse is found by its input.
+var parseStates sync.Map
+
+// beginParse sets the state of the input until the returned func is called. If the input is
+// already being parsed, its state is kept.
+func beginParse(pi *parse.Input, s *parseState) (end func()) {
+	if _, loaded := parseStates.LoadOrStore(pi, s); loaded {
+		return func() {}
+	}
+	return func() { parseStates.Delete(pi) }
+}
+
+// stateOf returns the state of the parse of the input. Inputs that are parsed without
+// beginParse, e.g. by a single parser in tests, have the default state.
+func stateOf(pi *parse.Input) *parseState {
+	if s, ok := parseStates.Load(pi); ok {
+		return s.(*parseState)
+	}
+	return &parseState{}
+}
+
+// skipImplicitlyClosed returns true if the end tag belongs to an element that was closed by a
+// mismatched end tag, rather than to an open element. Each element's end tag is skipped once.
+func (s *parseState) skipImplicitlyClosed(name string) bool {
+	if s.isOpen(name) {
+		return false
+	}
+	for i, closed := range s.implicitlyClosed {
+		if closed == name {
+			s.implicitlyClosed = append(s.implicitlyClosed[:i], s.implicitlyClosed[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
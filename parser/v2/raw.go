@@ -28,10 +28,12 @@ func (p rawElementParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 
 	// Element name.
 	var e RawElement
+	from := pi.Position()
 	if e.Name, ok, err = parse.String(p.name).Parse(pi); err != nil || !ok {
 		pi.Seek(start)
 		return
 	}
+	e.NameRange = NewRange(from, pi.Position())
 
 	if e.Attributes, ok, err = (attributesParser{}).Parse(pi); err != nil || !ok {
 		pi.Seek(start)
@@ -54,11 +56,12 @@ func (p rawElementParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// It's going to be rendered out raw.
 	end := parse.All(parse.String("</"), parse.String(p.name), parse.String(">"))
 	if e.Contents, ok, err = parse.StringUntil(end).Parse(pi); err != nil || !ok {
-		err = parse.Error(fmt.Sprintf("<%s>: expected end tag not present", e.Name), pi.Position())
+		err = newParseError(codeMissingEndTag, fmt.Sprintf("<%s>: expected end tag not present", e.Name), pi.Position())
 		return
 	}
 	// Cut the end element.
 	_, _, _ = end.Parse(pi)
+	e.Range = NewRange(pi.PositionAt(start), pi.Position())
 
 	return e, true, nil
 }
@@ -26,9 +26,10 @@ var scriptTemplateParser = parse.Func(func(pi *parse.Input) (r ScriptTemplate, o
 
 	// Try for }
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("script template: missing closing brace", pi.Position())
+		err = newParseError(codeMissingCloseBrace, "script template: missing closing brace", pi.Position())
 		return
 	}
+	r.Range = NewRange(pi.PositionAt(start), pi.Position())
 
 	return r, true, nil
 })
@@ -53,32 +54,32 @@ var scriptExpressionParser = parse.Func(func(pi *parse.Input) (r scriptExpressio
 	// Once we have the prefix, we must have a name and parameters.
 	// Read the name of the function.
 	if r.Name, ok, err = scriptExpressionNameParser.Parse(pi); err != nil || !ok {
-		err = parse.Error("script expression: invalid name", pi.Position())
+		err = newParseError(codeMalformedTemplate, "script expression: invalid name", pi.Position())
 		return
 	}
 
 	// Eat the open bracket.
 	if _, ok, err = openBracket.Parse(pi); err != nil || !ok {
-		err = parse.Error("script expression: parameters missing open bracket", pi.Position())
+		err = newParseError(codeMalformedTemplate, "script expression: parameters missing open bracket", pi.Position())
 		return
 	}
 
 	// Read the parameters.
 	// p Person, other Other, t thing.Thing)
 	if r.Parameters, ok, err = ExpressionOf(parse.StringUntil(closeBracket)).Parse(pi); err != nil || !ok {
-		err = parse.Error("script expression: parameters missing close bracket", pi.Position())
+		err = newParseError(codeMalformedTemplate, "script expression: parameters missing close bracket", pi.Position())
 		return
 	}
 
 	// Eat ") {".
 	if _, ok, err = expressionFuncEnd.Parse(pi); err != nil || !ok {
-		err = parse.Error("script expression: unterminated (missing ') {')", pi.Position())
+		err = newParseError(codeMalformedTemplate, "script expression: unterminated (missing ') {')", pi.Position())
 		return
 	}
 
 	// Expect a newline.
 	if _, ok, err = parse.NewLine.Parse(pi); err != nil || !ok {
-		err = parse.Error("script expression: missing terminating newline", pi.Position())
+		err = newParseError(codeMalformedTemplate, "script expression: missing terminating newline", pi.Position())
 		return
 	}
 
@@ -1,21 +1,40 @@
 package parser
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // NewSourceMap creates a new lookup to map templ source code to items in the
 // parsed template.
 func NewSourceMap() *SourceMap {
-	return &SourceMap{
-		SourceLinesToTarget: make(map[uint32]map[uint32]Position),
-		TargetLinesToSource: make(map[uint32]map[uint32]Position),
-	}
+	return &SourceMap{}
 }
 
+// SourceMap maps positions within a templ file to positions within the generated Go code,
+// and back. Each mapping covers a run of characters within a single line, and mappings are
+// ordered by their start position, so that lookups are a binary search.
 type SourceMap struct {
-	SourceLinesToTarget map[uint32]map[uint32]Position
-	TargetLinesToSource map[uint32]map[uint32]Position
+	mappings []SourceMapping
+	// m guards the indices, which are sorted by the first lookup after mappings are added.
+	m sync.Mutex
+	// bySource and byTarget are the indices of the mappings, ordered by the start of the
+	// source and target ranges. Mappings that start at the same position are ordered by the
+	// order that they were added in.
+	bySource []int
+	byTarget []int
+}
+
+// SourceMapping maps Length columns of a single line of the templ file, starting at Source,
+// to the same number of columns of a single line of the generated Go code, starting at
+// Target.
+type SourceMapping struct {
+	Source Position
+	Target Position
+	Length uint32
 }
 
 // Add an item to the lookup.
@@ -35,57 +54,181 @@ func (sm *SourceMap) Add(src Expression, tgt Range) (updatedFrom Position) {
 			tgtCol += tgt.From.Col
 		}
 
-		// Process the cols.
-		for colIndex := 0; colIndex < len(line); colIndex++ {
-			if _, ok := sm.SourceLinesToTarget[srcLine]; !ok {
-				sm.SourceLinesToTarget[srcLine] = make(map[uint32]Position)
-			}
-			sm.SourceLinesToTarget[srcLine][srcCol] = NewPosition(tgtIndex, tgtLine, tgtCol)
-
-			if _, ok := sm.TargetLinesToSource[tgtLine]; !ok {
-				sm.TargetLinesToSource[tgtLine] = make(map[uint32]Position)
-			}
-			sm.TargetLinesToSource[tgtLine][tgtCol] = NewPosition(srcIndex, srcLine, srcCol)
-
-			srcCol++
-			tgtCol++
-			srcIndex++
-			tgtIndex++
-		}
+		// LSPs include the newline char as a col, so it's mapped too.
+		sm.AddMapping(SourceMapping{
+			Source: NewPosition(srcIndex, srcLine, srcCol),
+			Target: NewPosition(tgtIndex, tgtLine, tgtCol),
+			Length: uint32(len(line)) + 1,
+		})
+		srcIndex += int64(len(line)) + 1
+		tgtIndex += int64(len(line)) + 1
+	}
+	return src.Range.From
+}
 
-		// LSPs include the newline char as a col.
-		if _, ok := sm.SourceLinesToTarget[srcLine]; !ok {
-			sm.SourceLinesToTarget[srcLine] = make(map[uint32]Position)
-		}
-		sm.SourceLinesToTarget[srcLine][srcCol] = NewPosition(tgtIndex, tgtLine, tgtCol)
+// AddMapping adds a mapping to the lookup. If mappings overlap, the most recently added
+// mapping is used.
+func (sm *SourceMap) AddMapping(m SourceMapping) {
+	if m.Length == 0 {
+		return
+	}
+	sm.mappings = append(sm.mappings, m)
+}
 
-		if _, ok := sm.TargetLinesToSource[tgtLine]; !ok {
-			sm.TargetLinesToSource[tgtLine] = make(map[uint32]Position)
-		}
-		sm.TargetLinesToSource[tgtLine][tgtCol] = NewPosition(srcIndex, srcLine, srcCol)
+// indices returns the indices of the mappings, ordered by source and target position. The
+// indices are sorted once all of the mappings have been added, rather than as each mapping is
+// added.
+func (sm *SourceMap) indices() (bySource, byTarget []int) {
+	sm.m.Lock()
+	defer sm.m.Unlock()
+	if len(sm.bySource) != len(sm.mappings) {
+		sm.bySource = sortedIndices(len(sm.mappings), func(i int) Position { return sm.mappings[i].Source })
+		sm.byTarget = sortedIndices(len(sm.mappings), func(i int) Position { return sm.mappings[i].Target })
+	}
+	return sm.bySource, sm.byTarget
+}
 
-		srcIndex++
-		tgtIndex++
+// sortedIndices returns the indices 0 to n-1, ordered by position. Indices with the same
+// position stay in order.
+func sortedIndices(n int, pos func(i int) Position) []int {
+	indices := make([]int, n)
+	for i := range indices {
+		indices[i] = i
 	}
-	return src.Range.From
+	sort.SliceStable(indices, func(a, b int) bool {
+		return isBefore(pos(indices[a]), pos(indices[b]))
+	})
+	return indices
+}
+
+func isBefore(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+}
+
+// Mappings returns the mappings, in the order that they were added.
+func (sm *SourceMap) Mappings() []SourceMapping {
+	return sm.mappings
+}
+
+// SourceLinesToTarget returns the target position of each source line and column.
+//
+// Deprecated: SourceLinesToTarget was a field that held a position for every column. Use
+// TargetPositionFromSource, or Mappings.
+func (sm *SourceMap) SourceLinesToTarget() map[uint32]map[uint32]Position {
+	return linesTo(sm.mappings, func(m SourceMapping) (from, to Position) { return m.Source, m.Target })
+}
+
+// TargetLinesToSource returns the source position of each target line and column.
+//
+// Deprecated: TargetLinesToSource was a field that held a position for every column. Use
+// SourcePositionFromTarget, or Mappings.
+func (sm *SourceMap) TargetLinesToSource() map[uint32]map[uint32]Position {
+	return linesTo(sm.mappings, func(m SourceMapping) (from, to Position) { return m.Target, m.Source })
+}
+
+func linesTo(mappings []SourceMapping, fromTo func(m SourceMapping) (from, to Position)) map[uint32]map[uint32]Position {
+	lines := make(map[uint32]map[uint32]Position)
+	for _, m := range mappings {
+		from, to := fromTo(m)
+		if _, ok := lines[from.Line]; !ok {
+			lines[from.Line] = make(map[uint32]Position)
+		}
+		for offset := uint32(0); offset < m.Length; offset++ {
+			lines[from.Line][from.Col+offset] = NewPosition(to.Index+int64(offset), to.Line, to.Col+offset)
+		}
+	}
+	return lines
 }
 
 // TargetPositionFromSource looks up the target position using the source position.
 func (sm *SourceMap) TargetPositionFromSource(line, col uint32) (tgt Position, ok bool) {
-	lm, ok := sm.SourceLinesToTarget[line]
+	bySource, _ := sm.indices()
+	m, offset, ok := sm.lookup(bySource, func(m SourceMapping) Position { return m.Source }, line, col)
 	if !ok {
 		return
 	}
-	tgt, ok = lm[col]
-	return
+	return NewPosition(m.Target.Index+int64(offset), m.Target.Line, m.Target.Col+offset), true
 }
 
 // SourcePositionFromTarget looks the source position using the target position.
 func (sm *SourceMap) SourcePositionFromTarget(line, col uint32) (src Position, ok bool) {
-	lm, ok := sm.TargetLinesToSource[line]
+	_, byTarget := sm.indices()
+	m, offset, ok := sm.lookup(byTarget, func(m SourceMapping) Position { return m.Target }, line, col)
 	if !ok {
 		return
 	}
-	src, ok = lm[col]
-	return
+	return NewPosition(m.Source.Index+int64(offset), m.Source.Line, m.Source.Col+offset), true
+}
+
+// lookup finds the most recently added mapping that contains the position, and the offset of
+// the position from the start of the mapping.
+func (sm *SourceMap) lookup(indices []int, start func(m SourceMapping) Position, line, col uint32) (m SourceMapping, offset uint32, ok bool) {
+	p := NewPosition(0, line, col)
+	// Find the mappings that start after the position.
+	end := sort.Search(len(indices), func(k int) bool {
+		return isBefore(p, start(sm.mappings[indices[k]]))
+	})
+	best := -1
+	for k := end - 1; k >= 0; k-- {
+		i := indices[k]
+		s := start(sm.mappings[i])
+		if s.Line != line {
+			break
+		}
+		if col < s.Col+sm.mappings[i].Length && i > best {
+			best = i
+		}
+	}
+	if best < 0 {
+		return m, 0, false
+	}
+	m = sm.mappings[best]
+	return m, col - start(m).Col, true
+}
+
+// sourceMapVersion is the version of the JSON format of source maps.
+const sourceMapVersion = 1
+
+type sourceMapJSON struct {
+	Version  int        `json:"version"`
+	Mappings [][7]int64 `json:"mappings"`
+}
+
+// MarshalJSON writes the source map as JSON. Each mapping is an array of the zero-based
+// source line, column and byte index, the target line, column and byte index, and the length,
+// e.g. {"version":1,"mappings":[[2,6,20,12,5,240,12]]}. Mappings are in the order that they
+// were added, because later mappings take precedence.
+func (sm *SourceMap) MarshalJSON() ([]byte, error) {
+	v := sourceMapJSON{
+		Version:  sourceMapVersion,
+		Mappings: make([][7]int64, len(sm.mappings)),
+	}
+	for i, m := range sm.mappings {
+		v.Mappings[i] = [7]int64{
+			int64(m.Source.Line), int64(m.Source.Col), m.Source.Index,
+			int64(m.Target.Line), int64(m.Target.Col), m.Target.Index,
+			int64(m.Length),
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON reads a source map that was written by MarshalJSON.
+func (sm *SourceMap) UnmarshalJSON(data []byte) error {
+	var v sourceMapJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Version != sourceMapVersion {
+		return fmt.Errorf("unsupported source map version %d, expected %d", v.Version, sourceMapVersion)
+	}
+	*sm = SourceMap{}
+	for _, m := range v.Mappings {
+		sm.AddMapping(SourceMapping{
+			Source: NewPosition(m[2], uint32(m[0]), uint32(m[1])),
+			Target: NewPosition(m[5], uint32(m[3]), uint32(m[4])),
+			Length: uint32(m[6]),
+		})
+	}
+	return nil
 }
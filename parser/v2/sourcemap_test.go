@@ -1,7 +1,7 @@
 package parser
 
 import (
-	"sort"
+	"encoding/json"
 	"testing"
 
 	"github.com/a-h/parse"
@@ -124,17 +124,7 @@ func TestSourceMapPosition(t *testing.T) {
 				t.Errorf("TargetPositionFromSource: expected result from source %v, got no results", tt.source)
 			}
 			if diff := cmp.Diff(tt.target, actualTarget); diff != "" {
-				lines := keys(sm.SourceLinesToTarget)
-				sort.Slice(lines, func(i, j int) bool {
-					return lines[i] < lines[j]
-				})
-				for _, lineIndex := range lines {
-					cols := keys(sm.SourceLinesToTarget[lineIndex])
-					sort.Slice(cols, func(i, j int) bool {
-						return cols[i] < cols[j]
-					})
-					t.Error(lineIndex, cols)
-				}
+				t.Error(sm.Mappings())
 				t.Error("TargetPositionFromSource\n\n" + diff)
 			}
 			actualSource, ok := sm.SourcePositionFromTarget(actualTarget.Line, actualTarget.Col)
@@ -148,9 +138,75 @@ func TestSourceMapPosition(t *testing.T) {
 	}
 }
 
-func keys[K comparable, V any](m map[K]V) (keys []K) {
-	for k := range m {
-		keys = append(keys, k)
+func TestSourceMapJSON(t *testing.T) {
+	sm := NewSourceMap()
+	sm.Add(NewExpression("rst", pos(4, 3, 3), pos(7, 3, 6)),
+		Range{From: NewPosition(10, 8, 6), To: NewPosition(13, 8, 9)})
+	sm.Add(NewExpression("s", pos(5, 3, 4), pos(6, 3, 5)),
+		Range{From: NewPosition(20, 9, 7), To: NewPosition(21, 9, 8)})
+	sm.Add(NewExpression("multi\nline", pos(30, 5, 2), pos(40, 6, 4)),
+		Range{From: NewPosition(50, 10, 1), To: NewPosition(60, 11, 4)})
+
+	data, err := json.Marshal(sm)
+	if err != nil {
+		t.Fatalf("failed to marshal source map: %v", err)
+	}
+	expected := `{"version":1,"mappings":[[3,3,4,8,6,10,4],[3,4,5,9,7,20,2],[5,2,30,10,1,50,6],[6,0,36,11,0,56,5]]}`
+	if diff := cmp.Diff(expected, string(data)); diff != "" {
+		t.Error(diff)
+	}
+
+	actual := NewSourceMap()
+	if err = json.Unmarshal(data, actual); err != nil {
+		t.Fatalf("failed to unmarshal source map: %v", err)
+	}
+	if diff := cmp.Diff(sm.Mappings(), actual.Mappings()); diff != "" {
+		t.Error(diff)
+	}
+	// The most recently added mapping takes precedence.
+	for _, m := range []*SourceMap{sm, actual} {
+		tgt, ok := m.TargetPositionFromSource(3, 4)
+		if !ok {
+			t.Fatal("expected a target position")
+		}
+		if diff := cmp.Diff(NewPosition(20, 9, 7), tgt); diff != "" {
+			t.Error(diff)
+		}
+		tgt, ok = m.TargetPositionFromSource(6, 3)
+		if !ok {
+			t.Fatal("expected a target position")
+		}
+		if diff := cmp.Diff(NewPosition(59, 11, 3), tgt); diff != "" {
+			t.Error(diff)
+		}
+	}
+
+	if err = json.Unmarshal([]byte(`{"version":2,"mappings":[]}`), actual); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
+
+func TestSourceMapLines(t *testing.T) {
+	sm := NewSourceMap()
+	sm.Add(Expression{Value: "ab", Range: NewRange(pos(2, 1, 2), pos(4, 1, 4))}, NewRange(pos(10, 3, 5), pos(12, 3, 7)))
+	expectedSourceLines := map[uint32]map[uint32]Position{
+		1: {
+			2: NewPosition(10, 3, 5),
+			3: NewPosition(11, 3, 6),
+			4: NewPosition(12, 3, 7),
+		},
+	}
+	if diff := cmp.Diff(expectedSourceLines, sm.SourceLinesToTarget()); diff != "" {
+		t.Error(diff)
+	}
+	expectedTargetLines := map[uint32]map[uint32]Position{
+		3: {
+			5: NewPosition(2, 1, 2),
+			6: NewPosition(3, 1, 3),
+			7: NewPosition(4, 1, 4),
+		},
+	}
+	if diff := cmp.Diff(expectedTargetLines, sm.TargetLinesToSource()); diff != "" {
+		t.Error(diff)
 	}
-	return
 }
@@ -6,6 +6,7 @@ import (
 
 var stringExpression = parse.Func(func(pi *parse.Input) (n Node, ok bool, err error) {
 	// Check the prefix first.
+	from := pi.Position()
 	if _, ok, err = parse.Or(parse.String("{ "), parse.String("{")).Parse(pi); err != nil || !ok {
 		return
 	}
@@ -18,9 +19,10 @@ var stringExpression = parse.Func(func(pi *parse.Input) (n Node, ok bool, err er
 
 	// }
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("string expression: missing close brace", pi.Position())
+		err = newParseError(codeMissingCloseBrace, "string expression: missing close brace", pi.Position())
 		return
 	}
+	r.Range = NewRange(from, pi.Position())
 
 	// Parse trailing whitespace.
 	ws, _, err := parse.Whitespace.Parse(pi)
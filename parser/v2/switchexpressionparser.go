@@ -12,6 +12,7 @@ type switchExpressionParser struct{}
 
 func (_ switchExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// Check the prefix first.
+	from := pi.Position()
 	if _, ok, err = parse.String("switch ").Parse(pi); err != nil || !ok {
 		return
 	}
@@ -21,13 +22,13 @@ func (_ switchExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err err
 	until := parse.All(openBraceWithOptionalPadding, parse.NewLine)
 	endOfStatementExpression := ExpressionOf(parse.StringUntil(until))
 	if r.Expression, ok, err = endOfStatementExpression.Parse(pi); err != nil || !ok {
-		err = parse.Error("switch: "+unterminatedMissingCurly, pi.Position())
+		err = newParseError(codeIncompleteStatement, "switch: "+unterminatedMissingCurly, pi.Position())
 		return
 	}
 
 	// Eat " {\n".
 	if _, ok, err = until.Parse(pi); err != nil || !ok {
-		err = parse.Error("switch: "+unterminatedMissingCurly, pi.Position())
+		err = newParseError(codeIncompleteStatement, "switch: "+unterminatedMissingCurly, pi.Position())
 		return
 	}
 
@@ -48,9 +49,10 @@ func (_ switchExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err err
 
 	// Read the required closing brace.
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("switch: "+unterminatedMissingEnd, pi.Position())
+		err = newParseError(codeMissingCloseBrace, "switch: "+unterminatedMissingEnd, pi.Position())
 		return
 	}
+	r.Range = NewRange(from, pi.Position())
 
 	return r, true, nil
 }
@@ -91,11 +93,12 @@ var caseExpressionParser = parse.Func(func(pi *parse.Input) (r CaseExpression, o
 	pr := newTemplateNodeParser(parse.Any(StripType(closeBraceWithOptionalPadding), StripType(caseExpressionStartParser)), "closing brace or case expression")
 	var nodes Nodes
 	if nodes, ok, err = pr.Parse(pi); err != nil || !ok {
-		err = parse.Error("case: expected nodes, but none were found", pi.Position())
+		err = newParseError(codeInvalidBlock, "case: expected nodes, but none were found", pi.Position())
 		return
 	}
 	r.Children = nodes.Nodes
 	r.Diagnostics = nodes.Diagnostics
+	r.Range = Range{From: r.Expression.Range.From, To: newPositionFromInput(pi.Position())}
 
 	// Optional whitespace.
 	if _, ok, err = parse.OptionalWhitespace.Parse(pi); err != nil || !ok {
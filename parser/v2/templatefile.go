@@ -10,12 +10,12 @@ import (
 	"github.com/a-h/parse"
 )
 
-func Parse(fileName string) (TemplateFile, error) {
+func Parse(fileName string, opts ...ParseOpt) (TemplateFile, error) {
 	fc, err := os.ReadFile(fileName)
 	if err != nil {
 		return TemplateFile{}, err
 	}
-	return ParseString(string(fc))
+	return ParseString(string(fc), opts...)
 }
 
 func getDefaultPackageName(fileName string) (pkg string) {
@@ -42,8 +42,8 @@ func isGoIdentifier(s string) bool {
 	return true
 }
 
-func ParseString(template string) (TemplateFile, error) {
-	tf, ok, err := NewTemplateFileParser("main").Parse(parse.NewInput(template))
+func ParseString(template string, opts ...ParseOpt) (TemplateFile, error) {
+	tf, ok, err := NewTemplateFileParser("main", opts...).Parse(parse.NewInput(template))
 	if err != nil {
 		return tf, err
 	}
@@ -54,9 +54,10 @@ func ParseString(template string) (TemplateFile, error) {
 }
 
 // NewTemplateFileParser creates a new TemplateFileParser.
-func NewTemplateFileParser(pkg string) TemplateFileParser {
+func NewTemplateFileParser(pkg string, opts ...ParseOpt) TemplateFileParser {
 	return TemplateFileParser{
 		DefaultPackage: pkg,
+		opts:           opts,
 	}
 }
 
@@ -65,11 +66,18 @@ var ErrTemplateNotFound = errors.New("template not found")
 
 type TemplateFileParser struct {
 	DefaultPackage string
+	opts           []ParseOpt
 }
 
 var legacyPackageParser = parse.String("{% package")
 
 func (p TemplateFileParser) Parse(pi *parse.Input) (tf TemplateFile, ok bool, err error) {
+	s := &parseState{}
+	for _, opt := range p.opts {
+		opt(s)
+	}
+	defer beginParse(pi, s)()
+
 	// If we're parsing a legacy file, complain that migration needs to happen.
 	_, ok, err = legacyPackageParser.Parse(pi)
 	if err != nil {
@@ -108,43 +116,65 @@ func (p TemplateFileParser) Parse(pi *parse.Input) (tf TemplateFile, ok bool, er
 	// Strip any whitespace between the template declaration and the first template.
 	_, _, _ = parse.OptionalWhitespace.Parse(pi)
 
+	// Errors within a template are recorded as diagnostics, and parsing continues with the next
+	// template, so that a partial template file is returned.
+	var errs []error
+	addTemplate := func(start int, n TemplateFileNode, expr Expression, diagnostics []Diagnostic, parseErr error) {
+		if expr.Value != "" {
+			tf.Nodes = append(tf.Nodes, n)
+		}
+		for _, d := range diagnostics {
+			tf.Diagnostics = append(tf.Diagnostics, d)
+			if d.Severity == DiagnosticSeverityError {
+				errs = append(errs, d.Err())
+			}
+		}
+		if parseErr != nil {
+			tf.Diagnostics = append(tf.Diagnostics, errorDiagnostic(parseErr, pi.Position()))
+			errs = append(errs, parseErr)
+			pi.Seek(start)
+			skipTemplate(pi)
+		}
+		_, _, _ = parse.OptionalWhitespace.Parse(pi)
+	}
+
 outer:
 	for {
-		// Optional templates, CSS, and script templates.
+		start := pi.Index()
+
+		// Optional templates, XML templates, text templates, CSS, and script templates.
 		// templ Name(p Parameter)
 		var tn HTMLTemplate
-		tn, ok, err = template.Parse(pi)
-		if err != nil {
-			return tf, false, err
+		if tn, ok, err = template.Parse(pi); ok || err != nil {
+			addTemplate(start, tn, tn.Expression, tn.Diagnostics, err)
+			continue
 		}
-		if ok {
-			tf.Nodes = append(tf.Nodes, tn)
-			tf.Diagnostics = append(tf.Diagnostics, tn.Diagnostics...)
-			_, _, _ = parse.OptionalWhitespace.Parse(pi)
+
+		// xml Name(p Parameter)
+		var xn XMLTemplate
+		if xn, ok, err = xmlTemplate.Parse(pi); ok || err != nil {
+			addTemplate(start, xn, xn.Expression, xn.Diagnostics, err)
+			continue
+		}
+
+		// text Name(p Parameter)
+		var txn TextTemplate
+		if txn, ok, err = textTemplate.Parse(pi); ok || err != nil {
+			addTemplate(start, txn, txn.Expression, nil, err)
 			continue
 		}
 
 		// css Name()
 		var cn CSSTemplate
-		cn, ok, err = cssParser.Parse(pi)
-		if err != nil {
-			return tf, false, err
-		}
-		if ok {
-			tf.Nodes = append(tf.Nodes, cn)
-			_, _, _ = parse.OptionalWhitespace.Parse(pi)
+		if cn, ok, err = cssParser.Parse(pi); ok || err != nil {
+			addTemplate(start, cn, Expression{Value: cn.Name.Value}, nil, err)
 			continue
 		}
 
 		// script Name()
 		var sn ScriptTemplate
-		sn, ok, err = scriptTemplateParser.Parse(pi)
-		if err != nil {
-			return tf, false, err
-		}
-		if ok {
-			tf.Nodes = append(tf.Nodes, sn)
-			_, _, _ = parse.OptionalWhitespace.Parse(pi)
+		if sn, ok, err = scriptTemplateParser.Parse(pi); ok || err != nil {
+			addTemplate(start, sn, Expression{Value: sn.Name.Value}, nil, err)
 			continue
 		}
 
@@ -159,8 +189,7 @@ outer:
 			if l, ok, err = stringUntilNewLineOrEOF.Parse(pi); err != nil {
 				return
 			}
-			hasTemplatePrefix := strings.HasPrefix(l, "templ ") || strings.HasPrefix(l, "css ") || strings.HasPrefix(l, "script ")
-			if hasTemplatePrefix && strings.HasSuffix(l, "{") {
+			if isTemplateDeclaration(l) {
 				// Unread the line.
 				pi.Seek(last)
 				// Take the code so far.
@@ -190,5 +219,59 @@ outer:
 		}
 	}
 
-	return tf, true, nil
+	return tf, true, joinErrors(errs)
+}
+
+// isTemplateDeclaration returns true if the line starts a template, e.g. `templ Name() {`.
+func isTemplateDeclaration(l string) bool {
+	hasTemplatePrefix := strings.HasPrefix(l, "templ ") || strings.HasPrefix(l, "xml ") || strings.HasPrefix(l, "text ") || strings.HasPrefix(l, "css ") || strings.HasPrefix(l, "script ")
+	return hasTemplatePrefix && strings.HasSuffix(l, "{")
+}
+
+// skipTemplate moves past the template that starts at the current position. The end of the
+// template is the first line that contains only a closing brace, or the line before the next
+// template declaration.
+func skipTemplate(pi *parse.Input) {
+	_, _, _ = stringUntilNewLineOrEOF.Parse(pi)
+	_, _, _ = parse.NewLine.Parse(pi)
+	for {
+		if _, ok := pi.Peek(1); !ok {
+			return
+		}
+		last := pi.Index()
+		l, _, _ := stringUntilNewLineOrEOF.Parse(pi)
+		if isTemplateDeclaration(l) {
+			pi.Seek(last)
+			return
+		}
+		_, _, _ = parse.NewLine.Parse(pi)
+		if strings.TrimRightFunc(l, unicode.IsSpace) == "}" {
+			return
+		}
+	}
+}
+
+// errorDiagnostic creates an error diagnostic from a parse error. If the error doesn't have
+// a position, the diagnostic is placed at pos.
+func errorDiagnostic(err error, pos parse.Position) Diagnostic {
+	var pe ParseError
+	if errors.As(err, &pe) {
+		return newErrorDiagnostic(pe.Code, pe.Msg, pe.Pos)
+	}
+	if pe, ok := err.(parse.ParseError); ok {
+		return newErrorDiagnostic("", pe.Msg, pe.Pos)
+	}
+	return newErrorDiagnostic("", err.Error(), pos)
+}
+
+// joinErrors returns nil if there are no errors, the error itself if there is one, and the
+// errors joined together otherwise.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errors.Join(errs...)
 }
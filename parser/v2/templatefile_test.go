@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -125,6 +126,54 @@ const y = ` + "`456`"
 			t.Errorf("2: unexpected expression: %q", expr.Expression.Value)
 		}
 	})
+	t.Run("errors are collected, and the rest of the file is parsed", func(t *testing.T) {
+		input := `package goof
+
+templ A() {
+	<div>
+		if true {
+	</div>
+}
+
+templ B() {
+	<span>Hello</div>
+}
+
+templ C() {
+	<p>OK</p>
+}
+`
+		tf, err := ParseString(input)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if !tf.HasErrors() {
+			t.Error("expected the template file to have errors")
+		}
+		var names []string
+		for _, n := range tf.Nodes {
+			if ht, ok := n.(HTMLTemplate); ok {
+				names = append(names, ht.Expression.Value)
+			}
+		}
+		if diff := cmp.Diff([]string{"A()", "B()", "C()"}, names); diff != "" {
+			t.Errorf("unexpected templates:\n%s", diff)
+		}
+		var messages []string
+		for _, d := range tf.Diagnostics {
+			messages = append(messages, fmt.Sprintf("%d:%d: %s: %s", d.Range.From.Line, d.Range.From.Col, d.Severity, d.Message))
+		}
+		expected := []string{
+			"5:1: error: if: expected nodes, but none were found",
+			"9:12: error: <span>: mismatched end tag, expected '</span>', got '</div>'",
+		}
+		if diff := cmp.Diff(expected, messages); diff != "" {
+			t.Errorf("unexpected diagnostics:\n%s", diff)
+		}
+		if !strings.Contains(err.Error(), "if: expected nodes, but none were found") || !strings.Contains(err.Error(), "<span>: mismatched end tag") {
+			t.Errorf("expected all errors to be returned, got %v", err)
+		}
+	})
 }
 
 func TestTemplateFileRoundTrip(t *testing.T) {
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/a-h/parse"
 )
@@ -16,36 +17,41 @@ type templateExpression struct {
 	Expression Expression
 }
 
-var templateExpressionStartParser = parse.String("templ ")
+var templateExpressionParser = newTemplateExpressionParser("templ")
 
-var templateExpressionParser = parse.Func(func(pi *parse.Input) (r templateExpression, ok bool, err error) {
-	// Check the prefix first.
-	if _, ok, err = templateExpressionStartParser.Parse(pi); err != nil || !ok {
-		return
-	}
+// newTemplateExpressionParser parses the declaration of a template that starts with the keyword,
+// e.g. templ or xml.
+func newTemplateExpressionParser(keyword string) parse.Parser[templateExpression] {
+	start := parse.String(keyword + " ")
+	return parse.Func(func(pi *parse.Input) (r templateExpression, ok bool, err error) {
+		// Check the prefix first.
+		if _, ok, err = start.Parse(pi); err != nil || !ok {
+			return
+		}
 
-	// Once we have the prefix, everything to the brace at the end of the line is Go.
-	// e.g.
-	// templ (x []string) Test() {
-	// becomes:
-	// func (x []string) Test() templ.Component {
-
-	// Once we've got a prefix, read until {\n.
-	until := parse.All(openBraceWithOptionalPadding, parse.NewLine)
-	msg := "templ: malformed templ expression, expected `templ functionName() {`"
-	if r.Expression, ok, err = ExpressionOf(parse.StringUntil(until)).Parse(pi); err != nil || !ok {
-		err = parse.Error(msg, pi.Position())
-		return
-	}
+		// Once we have the prefix, everything to the brace at the end of the line is Go.
+		// e.g.
+		// templ (x []string) Test() {
+		// becomes:
+		// func (x []string) Test() templ.Component {
+
+		// Once we've got a prefix, read until {\n.
+		until := parse.All(openBraceWithOptionalPadding, parse.NewLine)
+		msg := fmt.Sprintf("%[1]s: malformed %[1]s expression, expected `%[1]s functionName() {`", keyword)
+		if r.Expression, ok, err = ExpressionOf(parse.StringUntil(until)).Parse(pi); err != nil || !ok {
+			err = newParseError(codeMalformedTemplate, msg, pi.Position())
+			return
+		}
 
-	// Eat " {\n".
-	if _, ok, err = until.Parse(pi); err != nil || !ok {
-		err = parse.Error(msg, pi.Position())
-		return
-	}
+		// Eat " {\n".
+		if _, ok, err = until.Parse(pi); err != nil || !ok {
+			err = newParseError(codeMalformedTemplate, msg, pi.Position())
+			return
+		}
 
-	return r, true, nil
-})
+		return r, true, nil
+	})
+}
 
 const (
 	unterminatedMissingCurly = `unterminated (missing closing '{\n') - https://templ.guide/syntax-and-usage/statements#incomplete-statements`
@@ -70,8 +76,11 @@ var rawElements = parse.Any[Node](styleElement, scriptElement)
 var templateNodeParsers = []parse.Parser[Node]{
 	docType,                // <!DOCTYPE html>
 	htmlComment,            // <!--
+	processingInstruction,  // <?xml version="1.0"?>
+	cdata,                  // <![CDATA[
 	goComment,              // // or /*
 	rawElements,            // <text>, <>, or <style> element (special behaviour - contents are not parsed).
+	markdownElement,        // <templ:markdown> element (special behaviour - contents are converted to HTML).
 	element,                // <a>, <br/> etc.
 	ifExpression,           // if {}
 	forExpression,          // for {}
@@ -102,20 +111,28 @@ func (p templateNodeParser[T]) Parse(pi *parse.Input) (op Nodes, ok bool, err er
 		// Attempt to parse a node.
 		// Loop through the parsers and try to parse a node.
 		var matched bool
+		start := pi.Index()
 		for _, p := range templateNodeParsers {
 			var node Node
 			node, matched, err = p.Parse(pi)
 			if err != nil {
-				return Nodes{}, false, err
+				// Return the nodes parsed so far, so that a partial result can be used.
+				return op, false, err
+			}
+			if matched && stateOf(pi).preserveWhitespace {
+				node = preservingWhitespace(pi, start, node)
+			}
+			if n, ok := node.(StringExpression); ok && stateOf(pi).preserveWhitespace && strings.TrimSpace(n.Expression.Value) == "" {
+				msg := "empty expression renders nothing, use { \"{\" } and { \"}\" } to write braces in whitespace-sensitive elements"
+				op.Diagnostics = append(op.Diagnostics, newDiagnostic("", msg, n.Range, DiagnosticSeverityWarning))
 			}
 			if n, ok := node.(CallTemplateExpression); ok {
-				op.Diagnostics = append(op.Diagnostics, Diagnostic{
-					Message: "`{! foo }` syntax is deprecated. Use `@foo` syntax instead. Run `templ fmt .` to fix all instances.",
-					Range:   n.Expression.Range,
-				})
+				msg := "`{! foo }` syntax is deprecated. Use `@foo` syntax instead. Run `templ fmt .` to fix all instances."
+				op.Diagnostics = append(op.Diagnostics, newDiagnostic(codeDeprecatedCall, msg, n.Expression.Range, DiagnosticSeverityWarning))
 			}
 			if matched {
 				op.Nodes = append(op.Nodes, node)
+				op.Diagnostics = append(op.Diagnostics, nodeDiagnostics(node)...)
 				break
 			}
 		}
@@ -123,6 +140,19 @@ func (p templateNodeParser[T]) Parse(pi *parse.Input) (op Nodes, ok bool, err er
 			continue
 		}
 
+		// Skip the end tags of elements that have already been closed, because the mismatched
+		// end tag that closed them has been reported.
+		if s := stateOf(pi); len(s.implicitlyClosed) > 0 {
+			ct, ok, err := elementCloseTagParser.Parse(pi)
+			if err != nil {
+				return op, false, err
+			}
+			if ok && s.skipImplicitlyClosed(ct.Name) {
+				continue
+			}
+			pi.Seek(start)
+		}
+
 		if p.until == nil {
 			// In this case, we're just reading as many nodes as we can until we can't read any more.
 			// If we've reached here, we couldn't find a node.
@@ -130,9 +160,33 @@ func (p templateNodeParser[T]) Parse(pi *parse.Input) (op Nodes, ok bool, err er
 			break
 		}
 
-		err = fmt.Errorf("%v not found", p.untilName)
+		err = newParseError(codeMissingCloseBrace, fmt.Sprintf("%v not found", p.untilName), pi.Position())
 		return
 	}
 
 	return op, true, nil
 }
+
+// nodeDiagnostics returns the diagnostics of the node, and its children.
+func nodeDiagnostics(n Node) (diagnostics []Diagnostic) {
+	switch n := n.(type) {
+	case Element:
+		return n.Diagnostics
+	case IfExpression:
+		diagnostics = append(diagnostics, n.Diagnostics...)
+		for _, elseIf := range n.ElseIfs {
+			diagnostics = append(diagnostics, elseIf.Diagnostics...)
+		}
+		return diagnostics
+	case SwitchExpression:
+		for _, c := range n.Cases {
+			diagnostics = append(diagnostics, c.Diagnostics...)
+		}
+		return diagnostics
+	case ForExpression:
+		return n.Diagnostics
+	case TemplElementExpression:
+		return n.Diagnostics
+	}
+	return nil
+}
@@ -280,7 +280,7 @@ func TestTemplateParser(t *testing.T) {
 							Element{
 								Name: "span",
 								Children: []Node{
-									Whitespace{"\n\t\t\t"},
+									Whitespace{Value: "\n\t\t\t"},
 									StringExpression{
 										Expression: Expression{
 											Value: `"span content"`,
@@ -567,7 +567,7 @@ func TestTemplateParser(t *testing.T) {
 					Element{
 						Name: "span",
 						Attributes: []Attribute{SpreadAttributes{
-							Expression{
+							Expression: Expression{
 								Value: "children",
 								Range: Range{
 									From: Position{
@@ -584,7 +584,7 @@ func TestTemplateParser(t *testing.T) {
 							},
 						}},
 						Children: []Node{
-							Whitespace{"\n\t\t\t"},
+							Whitespace{Value: "\n\t\t\t"},
 							ChildrenExpression{},
 							Whitespace{Value: "\n\t\t"},
 						},
@@ -600,7 +600,7 @@ func TestTemplateParser(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			input := parse.NewInput(tt.input)
 			actual, ok, err := template.Parse(input)
-			diff := cmp.Diff(tt.expected, actual)
+			diff := cmp.Diff(tt.expected, actual, ignoreNodeRanges)
 			switch {
 			case tt.expectError && err == nil:
 				t.Errorf("expected an error got nil: %+v", actual)
@@ -28,6 +28,7 @@ type templElementExpressionParser struct{}
 
 func (p templElementExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, err error) {
 	// Check the prefix first.
+	from := pi.Position()
 	if _, ok, err = parse.Rune('@').Parse(pi); err != nil || !ok {
 		return
 	}
@@ -35,17 +36,19 @@ func (p templElementExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, e
 	// Parse the identifier.
 	var r TemplElementExpression
 	if r.Expression, ok, err = templElementStartExpression.Parse(pi); err != nil || !ok {
-		err = parse.Error("templ element: found start '@' but expression was not closed", pi.Position())
+		err = newParseError(codeInvalidExpression, "templ element: found start '@' but expression was not closed", pi.Position())
 		return
 	}
 
 	// Once we've got a start expression, check to see if there's an open brace for children. {\n.
 	var hasOpenBrace bool
+	to := pi.Position()
 	_, hasOpenBrace, err = openBraceWithOptionalPadding.Parse(pi)
 	if err != nil {
 		return
 	}
 	if !hasOpenBrace {
+		r.Range = NewRange(from, to)
 		return r, true, nil
 	}
 
@@ -55,7 +58,7 @@ func (p templElementExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, e
 	np := newTemplateNodeParser(closeBraceWithOptionalPadding, "templ element closing brace")
 	var nodes Nodes
 	if nodes, ok, err = np.Parse(pi); err != nil || !ok {
-		err = parse.Error("@"+r.Expression.Value+": expected nodes, but none were found", pi.Position())
+		err = newParseError(codeInvalidBlock, "@"+r.Expression.Value+": expected nodes, but none were found", pi.Position())
 		return
 	}
 	r.Children = nodes.Nodes
@@ -63,9 +66,10 @@ func (p templElementExpressionParser) Parse(pi *parse.Input) (n Node, ok bool, e
 
 	// Read the required closing brace.
 	if _, ok, err = closeBraceWithOptionalPadding.Parse(pi); err != nil || !ok {
-		err = parse.Error("@"+r.Expression.Value+": missing end (expected '}')", pi.Position())
+		err = newParseError(codeMissingCloseBrace, "@"+r.Expression.Value+": missing end (expected '}')", pi.Position())
 		return
 	}
+	r.Range = NewRange(from, pi.Position())
 
 	return r, true, nil
 }
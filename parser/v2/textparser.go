@@ -16,11 +16,12 @@ var textParser = parse.Func(func(pi *parse.Input) (n Node, ok bool, err error) {
 	if t.Value, ok, err = parse.StringUntil(tagTemplOrNewLine).Parse(pi); err != nil || !ok {
 		return
 	}
+	t.Range = NewRange(from, pi.Position())
 	if isWhitespace(t.Value) {
 		return t, false, nil
 	}
 	if _, ok = pi.Peek(1); !ok {
-		err = parse.Error("textParser: unterminated text, expected tag open, templ expression open, or newline", from)
+		err = newParseError(codeUnterminatedText, "textParser: unterminated text, expected tag open, templ expression open, or newline", from)
 		return
 	}
 
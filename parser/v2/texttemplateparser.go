@@ -0,0 +1,270 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/a-h/parse"
+)
+
+// Text templates.
+//
+// The body of a text template is read line by line. Lines that contain an if, for or switch
+// statement, a case, a closing brace, or an @ call, are statements. All other lines are text,
+// which is rendered exactly as written, including the newline, after the removal of one tab
+// of indentation for each level of nesting. { expr } expressions within text are not escaped.
+
+var textTemplateExpressionParser = newTemplateExpressionParser("text")
+
+var textTemplate = parse.Func(func(pi *parse.Input) (r TextTemplate, ok bool, err error) {
+	// text FuncName(p Person, other Other) {
+	from := pi.Position()
+	var te templateExpression
+	if te, ok, err = textTemplateExpressionParser.Parse(pi); err != nil || !ok {
+		return
+	}
+	r.Expression = te.Expression
+
+	if r.Children, err = parseTextNodes(pi, 1, false); err != nil {
+		return r, false, err
+	}
+	to, err := parseTextBlockEnd(pi, "text")
+	if err != nil {
+		return r, false, err
+	}
+	r.Range = NewRange(from, to)
+	return r, true, nil
+})
+
+// parseTextNodes reads lines until the end of the current block, i.e. a closing brace, an else,
+// or if the lines are within a switch statement, a case.
+func parseTextNodes(pi *parse.Input, depth int, inCase bool) (nodes []Node, err error) {
+	for {
+		if _, ok := pi.Peek(1); !ok {
+			return nodes, nil
+		}
+		line := strings.TrimSpace(peekTextLine(pi))
+		if line == "}" || strings.HasPrefix(line, "} else") || (inCase && isTextCase(line)) {
+			return nodes, nil
+		}
+		var n Node
+		switch {
+		case strings.HasPrefix(line, "if ") && strings.HasSuffix(line, "{"):
+			n, err = parseTextIf(pi, depth)
+		case strings.HasPrefix(line, "for ") && strings.HasSuffix(line, "{"):
+			n, err = parseTextFor(pi, depth)
+		case strings.HasPrefix(line, "switch ") && strings.HasSuffix(line, "{"):
+			n, err = parseTextSwitch(pi, depth)
+		case strings.HasPrefix(line, "@"):
+			n, err = parseTextCall(pi)
+		default:
+			var lineNodes []Node
+			lineNodes, err = parseTextLine(pi, depth)
+			nodes = append(nodes, lineNodes...)
+			if err != nil {
+				return nodes, err
+			}
+			continue
+		}
+		if err != nil {
+			return nodes, err
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+func peekTextLine(pi *parse.Input) string {
+	start := pi.Index()
+	defer pi.Seek(start)
+	line, _, _ := stringUntilNewLineOrEOF.Parse(pi)
+	return line
+}
+
+func isTextCase(line string) bool {
+	return (strings.HasPrefix(line, "case ") || strings.HasPrefix(line, "default")) && strings.HasSuffix(line, ":")
+}
+
+// skipTextLine moves past the rest of the current line, including the newline.
+func skipTextLine(pi *parse.Input) {
+	_, _, _ = stringUntilNewLineOrEOF.Parse(pi)
+	_, _, _ = parse.NewLine.Parse(pi)
+}
+
+// parseTextBlockStart parses a statement line, such as `for _, v := range values {`, and returns
+// the expression between the keyword and the opening brace.
+func parseTextBlockStart(pi *parse.Input, prefix string) (e Expression, err error) {
+	_, _, _ = parse.OptionalWhitespace.Parse(pi)
+	keyword := strings.TrimSpace(strings.TrimPrefix(prefix, "}"))
+	if _, ok, _ := parse.String(prefix).Parse(pi); !ok {
+		return e, newParseError(codeIncompleteStatement, keyword+": expected statement", pi.Position())
+	}
+	until := parse.All(openBraceWithOptionalPadding, parse.NewLine)
+	var ok bool
+	if e, ok, err = ExpressionOf(parse.StringUntil(until)).Parse(pi); err != nil || !ok {
+		return e, newParseError(codeIncompleteStatement, keyword+": "+unterminatedMissingCurly, pi.Position())
+	}
+	if _, ok, err = until.Parse(pi); err != nil || !ok {
+		return e, newParseError(codeIncompleteStatement, keyword+": "+unterminatedMissingCurly, pi.Position())
+	}
+	return e, nil
+}
+
+// parseTextBlockEnd parses a line that contains only a closing brace, and returns the position
+// after the brace.
+func parseTextBlockEnd(pi *parse.Input, keyword string) (to parse.Position, err error) {
+	line := peekTextLine(pi)
+	if strings.TrimSpace(line) != "}" {
+		return to, newParseError(codeMissingCloseBrace, keyword+": "+unterminatedMissingEnd, pi.Position())
+	}
+	to = pi.PositionAt(pi.Index() + strings.Index(line, "}") + 1)
+	skipTextLine(pi)
+	return to, nil
+}
+
+// statementStart returns the position of the keyword that precedes the expression of a statement.
+func statementStart(pi *parse.Input, e Expression, keyword string) parse.Position {
+	return pi.PositionAt(int(e.Range.From.Index) - len(keyword))
+}
+
+func parseTextIf(pi *parse.Input, depth int) (n Node, err error) {
+	var r IfExpression
+	if r.Expression, err = parseTextBlockStart(pi, "if "); err != nil {
+		return r, err
+	}
+	if r.Then, err = parseTextNodes(pi, depth+1, false); err != nil {
+		return r, err
+	}
+	for {
+		line := strings.TrimSpace(peekTextLine(pi))
+		if !strings.HasPrefix(line, "} else if ") {
+			break
+		}
+		var elseIf ElseIfExpression
+		if elseIf.Expression, err = parseTextBlockStart(pi, "} else if "); err != nil {
+			return r, err
+		}
+		if elseIf.Then, err = parseTextNodes(pi, depth+1, false); err != nil {
+			return r, err
+		}
+		elseIf.Range = NewRange(statementStart(pi, elseIf.Expression, "else if "), pi.Position())
+		r.ElseIfs = append(r.ElseIfs, elseIf)
+	}
+	if line := strings.TrimSpace(peekTextLine(pi)); strings.HasPrefix(line, "} else") {
+		if strings.TrimSpace(strings.TrimPrefix(line, "}")) != "else {" {
+			return r, newParseError(codeIncompleteStatement, "else: "+unterminatedMissingCurly, pi.Position())
+		}
+		skipTextLine(pi)
+		if r.Else, err = parseTextNodes(pi, depth+1, false); err != nil {
+			return r, err
+		}
+	}
+	to, err := parseTextBlockEnd(pi, "if")
+	r.Range = NewRange(statementStart(pi, r.Expression, "if "), to)
+	return r, err
+}
+
+func parseTextFor(pi *parse.Input, depth int) (n Node, err error) {
+	var r ForExpression
+	if r.Expression, err = parseTextBlockStart(pi, "for "); err != nil {
+		return r, err
+	}
+	if r.Children, err = parseTextNodes(pi, depth+1, false); err != nil {
+		return r, err
+	}
+	to, err := parseTextBlockEnd(pi, "for")
+	r.Range = NewRange(statementStart(pi, r.Expression, "for "), to)
+	return r, err
+}
+
+func parseTextSwitch(pi *parse.Input, depth int) (n Node, err error) {
+	var r SwitchExpression
+	if r.Expression, err = parseTextBlockStart(pi, "switch "); err != nil {
+		return r, err
+	}
+	for {
+		if line := strings.TrimSpace(peekTextLine(pi)); !isTextCase(line) {
+			break
+		}
+		var c CaseExpression
+		var ok bool
+		if c.Expression, ok, err = caseExpressionStartParser.Parse(pi); err != nil || !ok {
+			return r, newParseError(codeIncompleteStatement, "switch: malformed case", pi.Position())
+		}
+		if c.Children, err = parseTextNodes(pi, depth+2, true); err != nil {
+			return r, err
+		}
+		c.Range = Range{From: c.Expression.Range.From, To: newPositionFromInput(pi.Position())}
+		r.Cases = append(r.Cases, c)
+	}
+	to, err := parseTextBlockEnd(pi, "switch")
+	r.Range = NewRange(statementStart(pi, r.Expression, "switch "), to)
+	return r, err
+}
+
+// parseTextCall parses a line that renders another component, e.g. @footer(name).
+func parseTextCall(pi *parse.Input) (n Node, err error) {
+	var r TemplElementExpression
+	_, _, _ = parse.OptionalWhitespace.Parse(pi)
+	start := pi.Position()
+	_, _, _ = parse.Rune('@').Parse(pi)
+	from := pi.Position()
+	value := strings.TrimRightFunc(peekTextLine(pi), unicode.IsSpace)
+	if strings.TrimSpace(value) == "" {
+		return r, newParseError(codeInvalidExpression, "@: expected component expression", from)
+	}
+	pi.Take(len(value))
+	r.Expression = NewExpression(value, from, pi.Position())
+	r.Range = NewRange(start, pi.Position())
+	skipTextLine(pi)
+	return r, nil
+}
+
+// parseTextLine parses a line of text, removing one tab of indentation for each level of depth.
+func parseTextLine(pi *parse.Input, depth int) (nodes []Node, err error) {
+	for i := 0; i < depth; i++ {
+		if next, _ := pi.Peek(1); next != "\t" {
+			break
+		}
+		pi.Take(1)
+	}
+	var sb strings.Builder
+	var textFrom parse.Position
+	flush := func(to parse.Position) {
+		if sb.Len() > 0 {
+			nodes = append(nodes, Text{Value: sb.String(), Range: NewRange(textFrom, to)})
+			sb.Reset()
+		}
+	}
+	for {
+		next, ok := pi.Peek(1)
+		if !ok {
+			break
+		}
+		if next == "{" {
+			start := pi.Index()
+			var n Node
+			if n, ok, err = stringExpression.Parse(pi); err != nil {
+				return nodes, err
+			}
+			if ok {
+				// The whitespace after the expression is part of the text.
+				unreadTrailingWhitespace(pi, start)
+				se := n.(StringExpression)
+				se.TrailingSpace = SpaceNone
+				flush(pi.PositionAt(start))
+				nodes = append(nodes, se)
+				continue
+			}
+		}
+		if sb.Len() == 0 {
+			textFrom = pi.Position()
+		}
+		pi.Take(1)
+		sb.WriteString(next)
+		if next == "\n" {
+			break
+		}
+	}
+	flush(pi.Position())
+	return nodes, nil
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/a-h/parse"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTextTemplateParser(t *testing.T) {
+	input := parse.NewInput("text Name(items []string) {\n\tHi { name }!\n\tfor _, v := range items {\n\t\t- { v }\n\t}\n}")
+	expected := TextTemplate{
+		Expression: Expression{
+			Value: "Name(items []string)",
+			Range: Range{
+				From: Position{Index: 5, Line: 0, Col: 5},
+				To:   Position{Index: 25, Line: 0, Col: 25},
+			},
+		},
+		Children: []Node{
+			Text{Value: "Hi "},
+			StringExpression{
+				Expression: Expression{
+					Value: "name",
+					Range: Range{
+						From: Position{Index: 34, Line: 1, Col: 6},
+						To:   Position{Index: 38, Line: 1, Col: 10},
+					},
+				},
+			},
+			Text{Value: "!\n"},
+			ForExpression{
+				Expression: Expression{
+					Value: "_, v := range items",
+					Range: Range{
+						From: Position{Index: 47, Line: 2, Col: 5},
+						To:   Position{Index: 66, Line: 2, Col: 24},
+					},
+				},
+				Children: []Node{
+					Text{Value: "- "},
+					StringExpression{
+						Expression: Expression{
+							Value: "v",
+							Range: Range{
+								From: Position{Index: 75, Line: 3, Col: 6},
+								To:   Position{Index: 76, Line: 3, Col: 7},
+							},
+						},
+					},
+					Text{Value: "\n"},
+				},
+			},
+		},
+	}
+	actual, ok, err := textTemplate.Parse(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("unexpected failure for input %q", input)
+	}
+	if diff := cmp.Diff(expected, actual, ignoreNodeRanges); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestTextTemplateParserErrors(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "text: missing closing brace",
+			input:    "text Name() {\n\tHello\n",
+			expected: "text: " + unterminatedMissingEnd + ": line 2, col 0",
+		},
+		{
+			name:     "text: unclosed for",
+			input:    "text Name() {\n\tfor _, v := range x {\n\t\t{ v }\n}",
+			expected: "text: " + unterminatedMissingEnd + ": line 3, col 1",
+		},
+		{
+			name:     "text: empty call",
+			input:    "text Name() {\n\t@\n}",
+			expected: "@: expected component expression: line 1, col 2",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := textTemplate.Parse(parse.NewInput(tt.input))
+			if err == nil {
+				t.Fatalf("expected error %q, got nil", tt.expected)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("expected error %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
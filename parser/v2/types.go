@@ -71,21 +71,22 @@ func NewPosition(index int64, line, col uint32) Position {
 func NewExpression(value string, from, to parse.Position) Expression {
 	return Expression{
 		Value: value,
-		Range: Range{
-			From: Position{
-				Index: int64(from.Index),
-				Line:  uint32(from.Line),
-				Col:   uint32(from.Col),
-			},
-			To: Position{
-				Index: int64(to.Index),
-				Line:  uint32(to.Line),
-				Col:   uint32(to.Col),
-			},
-		},
+		Range: NewRange(from, to),
 	}
 }
 
+// NewRange creates a range of text from a start position up to, but not including, an end position.
+func NewRange(from, to parse.Position) Range {
+	return Range{
+		From: newPositionFromInput(from),
+		To:   newPositionFromInput(to),
+	}
+}
+
+func newPositionFromInput(p parse.Position) Position {
+	return NewPosition(int64(p.Index), uint32(p.Line), uint32(p.Col))
+}
+
 // Range of text within a file.
 type Range struct {
 	From Position
@@ -98,10 +99,61 @@ type Expression struct {
 	Range Range
 }
 
+// DiagnosticSeverity of a diagnostic. The zero value is a warning.
+type DiagnosticSeverity int
+
+const (
+	// DiagnosticSeverityWarning is used for problems that don't prevent code generation, such
+	// as the use of deprecated syntax.
+	DiagnosticSeverityWarning DiagnosticSeverity = iota
+	// DiagnosticSeverityError is used for parse errors. The parser recovers from errors to
+	// return a partial template file, but code is not generated.
+	DiagnosticSeverityError
+)
+
+func (s DiagnosticSeverity) String() string {
+	if s == DiagnosticSeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
 // Diagnostic for template file.
 type Diagnostic struct {
-	Message string
-	Range   Range
+	Message  string
+	Range    Range
+	Severity DiagnosticSeverity
+	// Code identifies the kind of problem, see `templ explain`.
+	Code ErrorCode
+	// SuggestedFix describes how to fix the problem, if known.
+	SuggestedFix string
+}
+
+// newErrorDiagnostic creates an error diagnostic at the position of a parse error.
+func newErrorDiagnostic(code ErrorCode, msg string, pos parse.Position) Diagnostic {
+	return newDiagnostic(code, msg, NewRange(pos, pos), DiagnosticSeverityError)
+}
+
+func newDiagnostic(code ErrorCode, msg string, r Range, severity DiagnosticSeverity) Diagnostic {
+	d := Diagnostic{
+		Message:  msg,
+		Range:    r,
+		Severity: severity,
+		Code:     code,
+	}
+	if info, ok := LookupErrorCode(string(code)); ok {
+		d.SuggestedFix = info.Fix
+	}
+	return d
+}
+
+// Err returns the diagnostic as a parse error.
+func (d Diagnostic) Err() error {
+	return newParseError(d.Code, d.Message, parse.Position{
+		Index: int(d.Range.From.Index),
+		Line:  int(d.Range.From.Line),
+		Col:   int(d.Range.From.Col),
+	})
 }
 
 type TemplateFile struct {
@@ -115,6 +167,17 @@ type TemplateFile struct {
 	Diagnostics []Diagnostic
 }
 
+// HasErrors returns true if any of the diagnostics are errors. If the template file has errors,
+// it's a partial result, and code must not be generated from it.
+func (tf TemplateFile) HasErrors() bool {
+	for _, d := range tf.Diagnostics {
+		if d.Severity == DiagnosticSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
 func (tf TemplateFile) Write(w io.Writer) error {
 	for _, n := range tf.Header {
 		if err := n.Write(w, 0); err != nil {
@@ -143,7 +206,8 @@ func getNodeWhitespace(nodes []TemplateFileNode, i int) string {
 	if i == len(nodes)-1 {
 		return "\n"
 	}
-	if _, nextIsTemplate := nodes[i+1].(HTMLTemplate); nextIsTemplate {
+	switch nodes[i+1].(type) {
+	case HTMLTemplate, XMLTemplate, TextTemplate:
 		if e, isGo := nodes[i].(TemplateFileGoExpression); isGo && endsWithComment(e.Expression.Value) {
 			return "\n"
 		}
@@ -216,6 +280,7 @@ func (p Package) Write(w io.Writer, indent int) error {
 // Whitespace.
 type Whitespace struct {
 	Value string
+	Range Range
 }
 
 func (ws Whitespace) IsNode() bool { return true }
@@ -249,6 +314,7 @@ func (ws Whitespace) Write(w io.Writer, indent int) error {
 type CSSTemplate struct {
 	Name       Expression
 	Properties []CSSProperty
+	Range      Range
 }
 
 func (css CSSTemplate) IsTemplateFileNode() bool { return true }
@@ -277,6 +343,7 @@ type CSSProperty interface {
 type ConstantCSSProperty struct {
 	Name  string
 	Value string
+	Range Range
 }
 
 func (c ConstantCSSProperty) IsCSSProperty() bool { return true }
@@ -307,6 +374,7 @@ func (c ConstantCSSProperty) String(minified bool) string {
 type ExpressionCSSProperty struct {
 	Name  string
 	Value StringExpression
+	Range Range
 }
 
 func (c ExpressionCSSProperty) IsCSSProperty() bool { return true }
@@ -326,6 +394,7 @@ func (c ExpressionCSSProperty) Write(w io.Writer, indent int) error {
 // <!DOCTYPE html>
 type DocType struct {
 	Value string
+	Range Range
 }
 
 func (dt DocType) IsNode() bool { return true }
@@ -344,6 +413,7 @@ type HTMLTemplate struct {
 	Diagnostics []Diagnostic
 	Expression  Expression
 	Children    []Node
+	Range       Range
 }
 
 func (t HTMLTemplate) IsTemplateFileNode() bool { return true }
@@ -361,6 +431,180 @@ func (t HTMLTemplate) Write(w io.Writer, indent int) error {
 	return nil
 }
 
+// XMLTemplate definition. The elements within an XML template are XML elements.
+//
+//	xml Name(p Parameter) {
+//	  <?xml version="1.0" encoding="UTF-8"?>
+//	  <Element/>
+//	}
+type XMLTemplate struct {
+	Diagnostics []Diagnostic
+	Expression  Expression
+	Children    []Node
+	Range       Range
+}
+
+func (t XMLTemplate) IsTemplateFileNode() bool { return true }
+
+func (t XMLTemplate) Write(w io.Writer, indent int) error {
+	if err := writeIndent(w, indent, "xml ", t.Expression.Value, " {\n"); err != nil {
+		return err
+	}
+	if err := writeNodesIndented(w, indent+1, t.Children); err != nil {
+		return err
+	}
+	if err := writeIndent(w, indent, "}"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TextTemplate definition. The contents are rendered as plain text, without escaping.
+//
+//	text Name(p Parameter) {
+//	  Hello, { p.Name }.
+//	  if ... {
+//	    Text.
+//	  }
+//	}
+type TextTemplate struct {
+	Expression Expression
+	Children   []Node
+	Range      Range
+}
+
+func (t TextTemplate) IsTemplateFileNode() bool { return true }
+
+func (t TextTemplate) Write(w io.Writer, indent int) error {
+	if err := writeIndent(w, indent, "text ", t.Expression.Value, " {\n"); err != nil {
+		return err
+	}
+	tw := &textWriter{w: w, lineStart: true}
+	if err := tw.writeNodes(indent+1, t.Children); err != nil {
+		return err
+	}
+	if err := writeIndent(w, indent, "}"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// textWriter writes the contents of a text template. Statements are indented to their depth,
+// and the text of each line is prefixed with the indentation of its depth.
+type textWriter struct {
+	w         io.Writer
+	lineStart bool
+}
+
+func (tw *textWriter) writeNodes(indent int, nodes []Node) (err error) {
+	for _, n := range nodes {
+		if err = tw.writeNode(indent, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tw *textWriter) writeLineIndent(indent int, next string) (err error) {
+	if tw.lineStart && next != "\n" {
+		_, err = io.WriteString(tw.w, strings.Repeat("\t", indent))
+	}
+	tw.lineStart = false
+	return err
+}
+
+func (tw *textWriter) writeNode(indent int, n Node) (err error) {
+	switch n := n.(type) {
+	case Text:
+		if err = tw.writeLineIndent(indent, n.Value); err != nil {
+			return err
+		}
+		if _, err = io.WriteString(tw.w, n.Value); err != nil {
+			return err
+		}
+		tw.lineStart = strings.HasSuffix(n.Value, "\n")
+		return nil
+	case StringExpression:
+		if err = tw.writeLineIndent(indent, "{"); err != nil {
+			return err
+		}
+		_, err = io.WriteString(tw.w, "{ "+n.Expression.Value+" }")
+		return err
+	}
+	// Statements start on a new line.
+	if !tw.lineStart {
+		if _, err = io.WriteString(tw.w, "\n"); err != nil {
+			return err
+		}
+	}
+	switch n := n.(type) {
+	case TemplElementExpression:
+		err = writeIndent(tw.w, indent, "@", n.Expression.Value, "\n")
+	case IfExpression:
+		if err = writeIndent(tw.w, indent, "if ", n.Expression.Value, " {\n"); err != nil {
+			return err
+		}
+		if err = tw.writeBlock(indent+1, n.Then); err != nil {
+			return err
+		}
+		for _, elseIf := range n.ElseIfs {
+			if err = writeIndent(tw.w, indent, "} else if ", elseIf.Expression.Value, " {\n"); err != nil {
+				return err
+			}
+			if err = tw.writeBlock(indent+1, elseIf.Then); err != nil {
+				return err
+			}
+		}
+		if len(n.Else) > 0 {
+			if err = writeIndent(tw.w, indent, "} else {\n"); err != nil {
+				return err
+			}
+			if err = tw.writeBlock(indent+1, n.Else); err != nil {
+				return err
+			}
+		}
+		err = writeIndent(tw.w, indent, "}\n")
+	case ForExpression:
+		if err = writeIndent(tw.w, indent, "for ", n.Expression.Value, " {\n"); err != nil {
+			return err
+		}
+		if err = tw.writeBlock(indent+1, n.Children); err != nil {
+			return err
+		}
+		err = writeIndent(tw.w, indent, "}\n")
+	case SwitchExpression:
+		if err = writeIndent(tw.w, indent, "switch ", n.Expression.Value, " {\n"); err != nil {
+			return err
+		}
+		for _, c := range n.Cases {
+			if err = writeIndent(tw.w, indent+1, strings.TrimSpace(c.Expression.Value), "\n"); err != nil {
+				return err
+			}
+			if err = tw.writeBlock(indent+2, c.Children); err != nil {
+				return err
+			}
+		}
+		err = writeIndent(tw.w, indent, "}\n")
+	default:
+		err = fmt.Errorf("text template: unsupported node %T", n)
+	}
+	tw.lineStart = true
+	return err
+}
+
+// writeBlock writes the nodes within a statement, ensuring that the block ends with a newline.
+func (tw *textWriter) writeBlock(indent int, nodes []Node) (err error) {
+	tw.lineStart = true
+	if err = tw.writeNodes(indent, nodes); err != nil {
+		return err
+	}
+	if !tw.lineStart {
+		_, err = io.WriteString(tw.w, "\n")
+		tw.lineStart = true
+	}
+	return err
+}
+
 // TrailingSpace defines the whitespace that may trail behind the close of an element, a
 // text node, or string expression.
 type TrailingSpace string
@@ -419,6 +663,7 @@ type Text struct {
 	Value string
 	// TrailingSpace lists what happens after the text.
 	TrailingSpace TrailingSpace
+	Range         Range
 }
 
 func (t Text) Trailing() TrailingSpace {
@@ -430,15 +675,35 @@ func (t Text) Write(w io.Writer, indent int) error {
 	return writeIndent(w, indent, t.Value)
 }
 
+// Namespace of an element. Elements within <svg> and <math> are foreign elements, and follow
+// XML rules: names are case-sensitive and any element can be self-closing. All elements within
+// an XML template are in the XML namespace.
+// https://html.spec.whatwg.org/multipage/syntax.html#foreign-elements
+type Namespace string
+
+const (
+	NamespaceHTML   Namespace = ""
+	NamespaceSVG    Namespace = "svg"
+	NamespaceMathML Namespace = "math"
+	NamespaceXML    Namespace = "xml"
+)
+
 // <a .../> or <div ...>...</div>
 type Element struct {
 	Name           string
+	Namespace      Namespace
 	Attributes     []Attribute
 	IndentAttrs    bool
 	Children       []Node
 	IndentChildren bool
-	TrailingSpace  TrailingSpace
-	Diagnostics    []Diagnostic
+	// PreserveWhitespace is true if the element is, or is within, a whitespace-sensitive
+	// element such as <pre>. The children are written exactly as they were parsed.
+	PreserveWhitespace bool
+	TrailingSpace      TrailingSpace
+	Diagnostics        []Diagnostic
+	// NameRange is the range of the element name within the start tag.
+	NameRange Range
+	Range     Range
 }
 
 func (e Element) Trailing() TrailingSpace {
@@ -451,10 +716,27 @@ var voidElements = map[string]struct{}{
 
 // https://www.w3.org/TR/2011/WD-html-markup-20110113/syntax.html#void-element
 func (e Element) IsVoidElement() bool {
+	if e.IsForeignElement() {
+		return false
+	}
 	_, ok := voidElements[e.Name]
 	return ok
 }
 
+// IsForeignElement returns true if the element is an SVG, MathML or XML element.
+func (e Element) IsForeignElement() bool {
+	return e.Namespace != NamespaceHTML
+}
+
+// IsSelfClosing returns true if the element is written as <name/>, because it's a void element,
+// or it's a foreign element without children.
+func (e Element) IsSelfClosing() bool {
+	if e.IsVoidElement() {
+		return true
+	}
+	return e.IsForeignElement() && !e.hasNonWhitespaceChildren()
+}
+
 func (e Element) hasNonWhitespaceChildren() bool {
 	for _, c := range e.Children {
 		if _, isWhitespace := c.(Whitespace); !isWhitespace {
@@ -470,28 +752,55 @@ var blockElements = map[string]struct{}{
 	"title": {}, "style": {}, "link": {}, "td": {}, "th": {}, "tr": {}, "br": {},
 }
 
+// SVG elements that contain text, and are laid out inline.
+var svgInlineElements = map[string]struct{}{
+	"svg": {}, "a": {}, "text": {}, "textPath": {}, "tspan": {},
+}
+
 func (e Element) IsBlockElement() bool {
+	switch e.Namespace {
+	case NamespaceSVG:
+		_, isInline := svgInlineElements[e.Name]
+		return !isInline
+	case NamespaceMathML:
+		return false
+	case NamespaceXML:
+		return true
+	}
 	_, ok := blockElements[e.Name]
 	return ok
 }
 
 // Validate that no invalid expressions have been used.
 func (e Element) Validate() (msgs []string, ok bool) {
+	for _, p := range e.problems() {
+		msgs = append(msgs, p.msg)
+	}
+	return msgs, len(msgs) == 0
+}
+
+// elementProblem is a reason that an element is invalid.
+type elementProblem struct {
+	code ErrorCode
+	msg  string
+}
+
+func (e Element) problems() (problems []elementProblem) {
 	// Validate that style attributes are constant.
 	for _, attr := range e.Attributes {
 		if exprAttr, isExprAttr := attr.(ExpressionAttribute); isExprAttr {
 			if strings.EqualFold(exprAttr.Name, "style") {
-				msgs = append(msgs, "invalid style attribute: style attributes cannot be a templ expression")
+				problems = append(problems, elementProblem{codeInvalidStyleAttribute, "invalid style attribute: style attributes cannot be a templ expression"})
 			}
 		}
 	}
 	// Validate that script and style tags don't contain expressions.
 	if strings.EqualFold(e.Name, "script") || strings.EqualFold(e.Name, "style") {
 		if containsNonTextNodes(e.Children) {
-			msgs = append(msgs, "invalid node contents: script and style attributes must only contain text")
+			problems = append(problems, elementProblem{codeInvalidScriptContents, "invalid node contents: script and style attributes must only contain text"})
 		}
 	}
-	return msgs, len(msgs) == 0
+	return problems
 }
 
 func containsNonTextNodes(nodes []Node) bool {
@@ -539,6 +848,18 @@ func (e Element) Write(w io.Writer, indent int) error {
 		}
 		closeAngleBracketIndent = indent
 	}
+	if e.PreserveWhitespace && len(e.Children) > 0 {
+		if err := writeIndent(w, closeAngleBracketIndent, ">"); err != nil {
+			return err
+		}
+		if err := writePreservingWhitespace(w, e.Children); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte("</" + e.Name + ">")); err != nil {
+			return err
+		}
+		return nil
+	}
 	if e.hasNonWhitespaceChildren() {
 		if e.IndentChildren {
 			if err := writeIndent(w, closeAngleBracketIndent, ">\n"); err != nil {
@@ -563,7 +884,7 @@ func (e Element) Write(w io.Writer, indent int) error {
 		}
 		return nil
 	}
-	if e.IsVoidElement() {
+	if e.IsSelfClosing() {
 		if err := writeIndent(w, closeAngleBracketIndent, "/>"); err != nil {
 			return err
 		}
@@ -652,6 +973,8 @@ type RawElement struct {
 	Name       string
 	Attributes []Attribute
 	Contents   string
+	NameRange  Range
+	Range      Range
 }
 
 func (e RawElement) IsNode() bool { return true }
@@ -691,7 +1014,9 @@ type Attribute interface {
 
 // <hr noshade/>
 type BoolConstantAttribute struct {
-	Name string
+	Name      string
+	NameRange Range
+	Range     Range
 }
 
 func (bca BoolConstantAttribute) String() string {
@@ -707,6 +1032,9 @@ type ConstantAttribute struct {
 	Name        string
 	Value       string
 	SingleQuote bool
+	NameRange   Range
+	ValueRange  Range
+	Range       Range
 }
 
 func (ca ConstantAttribute) String() string {
@@ -725,6 +1053,8 @@ func (ca ConstantAttribute) Write(w io.Writer, indent int) error {
 type BoolExpressionAttribute struct {
 	Name       string
 	Expression Expression
+	NameRange  Range
+	Range      Range
 }
 
 func (ea BoolExpressionAttribute) String() string {
@@ -739,6 +1069,8 @@ func (ea BoolExpressionAttribute) Write(w io.Writer, indent int) error {
 type ExpressionAttribute struct {
 	Name       string
 	Expression Expression
+	NameRange  Range
+	Range      Range
 }
 
 func (ea ExpressionAttribute) String() string {
@@ -796,6 +1128,7 @@ func (ea ExpressionAttribute) Write(w io.Writer, indent int) (err error) {
 // <a { spread... } />
 type SpreadAttributes struct {
 	Expression Expression
+	Range      Range
 }
 
 func (sa SpreadAttributes) String() string {
@@ -814,6 +1147,7 @@ type ConditionalAttribute struct {
 	Expression Expression
 	Then       []Attribute
 	Else       []Attribute
+	Range      Range
 }
 
 func (ca ConditionalAttribute) String() string {
@@ -876,6 +1210,7 @@ func (ca ConditionalAttribute) Write(w io.Writer, indent int) error {
 type GoComment struct {
 	Contents  string
 	Multiline bool
+	Range     Range
 }
 
 func (c GoComment) IsNode() bool { return true }
@@ -889,6 +1224,7 @@ func (c GoComment) Write(w io.Writer, indent int) error {
 // HTMLComment.
 type HTMLComment struct {
 	Contents string
+	Range    Range
 }
 
 func (c HTMLComment) IsNode() bool { return true }
@@ -896,6 +1232,65 @@ func (c HTMLComment) Write(w io.Writer, indent int) error {
 	return writeIndent(w, indent, "<!--", c.Contents, "-->")
 }
 
+// Markdown that is converted to HTML by the generator. The children are Text and
+// StringExpression nodes.
+// <templ:markdown># { title }</templ:markdown>
+type Markdown struct {
+	Children []Node
+	Range    Range
+}
+
+func (md Markdown) IsNode() bool { return true }
+func (md Markdown) Write(w io.Writer, indent int) error {
+	// The contents are written as they were parsed, because whitespace is significant in Markdown.
+	if err := writeIndent(w, indent, "<templ:markdown>"); err != nil {
+		return err
+	}
+	for _, c := range md.Children {
+		var err error
+		switch c := c.(type) {
+		case StringExpression:
+			_, err = io.WriteString(w, "{ "+c.Expression.Value+" }")
+		default:
+			err = c.Write(w, 0)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</templ:markdown>")
+	return err
+}
+
+// ProcessingInstruction, such as an XML declaration.
+// <?xml version="1.0" encoding="UTF-8"?>
+type ProcessingInstruction struct {
+	Contents string
+	Range    Range
+}
+
+func (pi ProcessingInstruction) IsNode() bool { return true }
+func (pi ProcessingInstruction) Write(w io.Writer, indent int) error {
+	return writeIndent(w, indent, "<?", pi.Contents, "?>")
+}
+
+// CDATA section. The contents are not escaped.
+// <![CDATA[ ... ]]>
+type CDATA struct {
+	Contents      string
+	TrailingSpace TrailingSpace
+	Range         Range
+}
+
+func (c CDATA) Trailing() TrailingSpace {
+	return c.TrailingSpace
+}
+
+func (c CDATA) IsNode() bool { return true }
+func (c CDATA) Write(w io.Writer, indent int) error {
+	return writeIndent(w, indent, "<![CDATA[", c.Contents, "]]>")
+}
+
 // Nodes.
 
 // CallTemplateExpression can be used to create and render a template using data.
@@ -905,6 +1300,7 @@ func (c HTMLComment) Write(w io.Writer, indent int) error {
 type CallTemplateExpression struct {
 	// Expression returns a template to execute.
 	Expression Expression
+	Range      Range
 }
 
 func (cte CallTemplateExpression) IsNode() bool { return true }
@@ -923,6 +1319,7 @@ type TemplElementExpression struct {
 	// Children returns the elements in a block element.
 	Children    []Node
 	Diagnostics []Diagnostic
+	Range       Range
 }
 
 func (tee TemplElementExpression) IsNode() bool { return true }
@@ -951,7 +1348,9 @@ func (tee TemplElementExpression) Write(w io.Writer, indent int) error {
 
 // ChildrenExpression can be used to rended the children of a templ element.
 // { children ... }
-type ChildrenExpression struct{}
+type ChildrenExpression struct {
+	Range Range
+}
 
 func (ChildrenExpression) IsNode() bool { return true }
 func (ChildrenExpression) Write(w io.Writer, indent int) error {
@@ -969,12 +1368,14 @@ type IfExpression struct {
 	ElseIfs     []ElseIfExpression
 	Else        []Node
 	Diagnostics []Diagnostic
+	Range       Range
 }
 
 type ElseIfExpression struct {
 	Expression  Expression
 	Then        []Node
 	Diagnostics []Diagnostic
+	Range       Range
 }
 
 func (n IfExpression) IsNode() bool { return true }
@@ -1017,6 +1418,7 @@ func (n IfExpression) Write(w io.Writer, indent int) error {
 type SwitchExpression struct {
 	Expression Expression
 	Cases      []CaseExpression
+	Range      Range
 }
 
 func (se SwitchExpression) IsNode() bool { return true }
@@ -1046,6 +1448,7 @@ type CaseExpression struct {
 	Expression  Expression
 	Children    []Node
 	Diagnostics []Diagnostic
+	Range       Range
 }
 
 //	for i, v := range p.Addresses {
@@ -1055,6 +1458,7 @@ type ForExpression struct {
 	Expression  Expression
 	Children    []Node
 	Diagnostics []Diagnostic
+	Range       Range
 }
 
 func (fe ForExpression) IsNode() bool { return true }
@@ -1077,6 +1481,7 @@ type StringExpression struct {
 	Expression Expression
 	// TrailingSpace lists what happens after the expression.
 	TrailingSpace TrailingSpace
+	Range         Range
 }
 
 func (se StringExpression) Trailing() TrailingSpace {
@@ -1094,6 +1499,7 @@ type ScriptTemplate struct {
 	Name       Expression
 	Parameters Expression
 	Value      string
+	Range      Range
 }
 
 func (s ScriptTemplate) IsTemplateFileNode() bool { return true }
@@ -0,0 +1,171 @@
+package parser
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node any) (w Visitor)
+}
+
+// Walk traverses a TemplateFile, TemplateFileNode, Node, Attribute or CSSProperty in
+// depth-first order. It starts by calling v.Visit(node). If the visitor returned by
+// v.Visit(node) is not nil, Walk is called recursively with that visitor for each of the
+// children of node, followed by a call of w.Visit(nil).
+//
+// The branches of if and switch statements are visited as ElseIfExpression and
+// CaseExpression values, and the attributes of an element are visited before its children.
+func Walk(v Visitor, node any) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case TemplateFile:
+		for _, c := range n.Nodes {
+			Walk(v, c)
+		}
+	case HTMLTemplate:
+		walkNodes(v, n.Children)
+	case XMLTemplate:
+		walkNodes(v, n.Children)
+	case TextTemplate:
+		walkNodes(v, n.Children)
+	case CSSTemplate:
+		for _, p := range n.Properties {
+			Walk(v, p)
+		}
+	case ExpressionCSSProperty:
+		Walk(v, n.Value)
+	case Element:
+		walkAttributes(v, n.Attributes)
+		walkNodes(v, n.Children)
+	case RawElement:
+		walkAttributes(v, n.Attributes)
+	case ConditionalAttribute:
+		walkAttributes(v, n.Then)
+		walkAttributes(v, n.Else)
+	case Markdown:
+		walkNodes(v, n.Children)
+	case TemplElementExpression:
+		walkNodes(v, n.Children)
+	case IfExpression:
+		walkNodes(v, n.Then)
+		for _, elseIf := range n.ElseIfs {
+			Walk(v, elseIf)
+		}
+		walkNodes(v, n.Else)
+	case ElseIfExpression:
+		walkNodes(v, n.Then)
+	case SwitchExpression:
+		for _, c := range n.Cases {
+			Walk(v, c)
+		}
+	case CaseExpression:
+		walkNodes(v, n.Children)
+	case ForExpression:
+		walkNodes(v, n.Children)
+	}
+
+	v.Visit(nil)
+}
+
+func walkNodes(v Visitor, nodes []Node) {
+	for _, n := range nodes {
+		Walk(v, n)
+	}
+}
+
+func walkAttributes(v Visitor, attributes []Attribute) {
+	for _, a := range attributes {
+		Walk(v, a)
+	}
+}
+
+type inspector func(any) bool
+
+func (f inspector) Visit(node any) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a TemplateFile, TemplateFileNode, Node, Attribute or CSSProperty in
+// depth-first order. It starts by calling f(node); node must not be nil. If f returns true,
+// Inspect invokes f recursively for each of the children of node, followed by a call of
+// f(nil).
+func Inspect(node any, f func(any) bool) {
+	Walk(inspector(f), node)
+}
+
+// RangeOf returns the source range of a TemplateFileNode, Node, Attribute or CSSProperty, or
+// false if it doesn't have one.
+func RangeOf(node any) (r Range, ok bool) {
+	switch n := node.(type) {
+	case HTMLTemplate:
+		return n.Range, true
+	case XMLTemplate:
+		return n.Range, true
+	case TextTemplate:
+		return n.Range, true
+	case CSSTemplate:
+		return n.Range, true
+	case ScriptTemplate:
+		return n.Range, true
+	case ConstantCSSProperty:
+		return n.Range, true
+	case ExpressionCSSProperty:
+		return n.Range, true
+	case Text:
+		return n.Range, true
+	case Whitespace:
+		return n.Range, true
+	case Element:
+		return n.Range, true
+	case RawElement:
+		return n.Range, true
+	case Markdown:
+		return n.Range, true
+	case GoComment:
+		return n.Range, true
+	case HTMLComment:
+		return n.Range, true
+	case ProcessingInstruction:
+		return n.Range, true
+	case CDATA:
+		return n.Range, true
+	case DocType:
+		return n.Range, true
+	case CallTemplateExpression:
+		return n.Range, true
+	case TemplElementExpression:
+		return n.Range, true
+	case ChildrenExpression:
+		return n.Range, true
+	case IfExpression:
+		return n.Range, true
+	case ElseIfExpression:
+		return n.Range, true
+	case SwitchExpression:
+		return n.Range, true
+	case CaseExpression:
+		return n.Range, true
+	case ForExpression:
+		return n.Range, true
+	case StringExpression:
+		return n.Range, true
+	case BoolConstantAttribute:
+		return n.Range, true
+	case ConstantAttribute:
+		return n.Range, true
+	case BoolExpressionAttribute:
+		return n.Range, true
+	case ExpressionAttribute:
+		return n.Range, true
+	case SpreadAttributes:
+		return n.Range, true
+	case ConditionalAttribute:
+		return n.Range, true
+	}
+	return r, false
+}
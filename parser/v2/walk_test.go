@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// ignoreNodeRanges ignores the source ranges of nodes and attributes, so that parser tests can
+// focus on structure. The ranges of expressions and diagnostics are still compared. Node
+// ranges are tested by TestRanges.
+var ignoreNodeRanges = cmp.FilterPath(func(p cmp.Path) bool {
+	sf, ok := p.Last().(cmp.StructField)
+	if !ok {
+		return false
+	}
+	switch p.Index(-2).Type() {
+	case reflect.TypeOf(Expression{}), reflect.TypeOf(Diagnostic{}):
+		return false
+	}
+	switch sf.Name() {
+	case "Range", "NameRange", "ValueRange":
+		return true
+	}
+	return false
+}, cmp.Ignore())
+
+const walkTestTemplate = `package main
+
+templ page(items []string) {
+	<ul class="list" hidden>
+		for _, item := range items {
+			<li data-item={ item }>{ item }</li>
+		}
+	</ul>
+	if len(items) == 0 {
+		<p>None</p>
+	} else if len(items) == 1 {
+		@one()
+	}
+	<!-- comment -->
+}
+
+css red() {
+	color: red;
+}
+`
+
+func TestWalk(t *testing.T) {
+	tf, err := ParseString(walkTestTemplate)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	var actual []string
+	Inspect(tf, func(n any) bool {
+		switch n := n.(type) {
+		case nil:
+		case Whitespace:
+		case Element:
+			actual = append(actual, "<"+n.Name+">")
+		case ConstantAttribute:
+			actual = append(actual, n.Name+"=")
+		case BoolConstantAttribute:
+			actual = append(actual, n.Name)
+		case ExpressionAttribute:
+			actual = append(actual, n.Name+"={}")
+		case TemplElementExpression:
+			actual = append(actual, "@"+n.Expression.Value)
+		case ElseIfExpression:
+			actual = append(actual, "else if")
+		case CSSProperty:
+			actual = append(actual, "css:"+reflect.TypeOf(n).Name())
+		default:
+			actual = append(actual, reflect.TypeOf(n).Name())
+		}
+		return true
+	})
+	expected := []string{
+		"TemplateFile",
+		"HTMLTemplate",
+		"<ul>", "class=", "hidden",
+		"ForExpression",
+		"<li>", "data-item={}", "StringExpression",
+		"IfExpression",
+		"<p>", "Text",
+		"else if",
+		"@one()",
+		"HTMLComment",
+		"CSSTemplate",
+		"css:ConstantCSSProperty",
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestWalkSkipsChildren(t *testing.T) {
+	tf, err := ParseString(walkTestTemplate)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	var elements []string
+	Inspect(tf, func(n any) bool {
+		if e, ok := n.(Element); ok {
+			elements = append(elements, e.Name)
+			// Don't visit the contents of lists.
+			return e.Name != "ul"
+		}
+		return true
+	})
+	if diff := cmp.Diff([]string{"ul", "p"}, elements); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tf, err := ParseString(walkTestTemplate)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	source := func(r Range) string {
+		return walkTestTemplate[r.From.Index:r.To.Index]
+	}
+	var actual []string
+	Inspect(tf, func(n any) bool {
+		if n == nil {
+			return false
+		}
+		if _, isWhitespace := n.(Whitespace); isWhitespace {
+			return false
+		}
+		v := reflect.ValueOf(n)
+		if v.Kind() != reflect.Struct {
+			return true
+		}
+		if r := v.FieldByName("Range"); r.IsValid() {
+			actual = append(actual, fmt.Sprintf("%s: %q", v.Type().Name(), source(r.Interface().(Range))))
+			if rangeOf, ok := RangeOf(n); !ok || rangeOf != r.Interface().(Range) {
+				t.Errorf("%s: expected RangeOf to return the range of the node, got %v", v.Type().Name(), rangeOf)
+			}
+		}
+		if r := v.FieldByName("NameRange"); r.IsValid() {
+			actual = append(actual, fmt.Sprintf("%s name: %q", v.Type().Name(), source(r.Interface().(Range))))
+		}
+		if r := v.FieldByName("ValueRange"); r.IsValid() {
+			actual = append(actual, fmt.Sprintf("%s value: %q", v.Type().Name(), source(r.Interface().(Range))))
+		}
+		return true
+	})
+	expected := []string{
+		`HTMLTemplate: "templ page(items []string) {\n\t<ul class=\"list\" hidden>\n\t\tfor _, item := range items {\n\t\t\t<li data-item={ item }>{ item }</li>\n\t\t}\n\t</ul>\n\tif len(items) == 0 {\n\t\t<p>None</p>\n\t} else if len(items) == 1 {\n\t\t@one()\n\t}\n\t<!-- comment -->\n}"`,
+		`Element: "<ul class=\"list\" hidden>\n\t\tfor _, item := range items {\n\t\t\t<li data-item={ item }>{ item }</li>\n\t\t}\n\t</ul>"`,
+		`Element name: "ul"`,
+		`ConstantAttribute: "class=\"list\""`,
+		`ConstantAttribute name: "class"`,
+		`ConstantAttribute value: "list"`,
+		`BoolConstantAttribute: "hidden"`,
+		`BoolConstantAttribute name: "hidden"`,
+		`ForExpression: "for _, item := range items {\n\t\t\t<li data-item={ item }>{ item }</li>\n\t\t}"`,
+		`Element: "<li data-item={ item }>{ item }</li>"`,
+		`Element name: "li"`,
+		`ExpressionAttribute: "data-item={ item }"`,
+		`ExpressionAttribute name: "data-item"`,
+		`StringExpression: "{ item }"`,
+		`IfExpression: "if len(items) == 0 {\n\t\t<p>None</p>\n\t} else if len(items) == 1 {\n\t\t@one()\n\t}"`,
+		`Element: "<p>None</p>"`,
+		`Element name: "p"`,
+		`Text: "None"`,
+		`ElseIfExpression: "else if len(items) == 1 {\n\t\t@one()\n\t"`,
+		`TemplElementExpression: "@one()"`,
+		`HTMLComment: "<!-- comment -->"`,
+		`CSSTemplate: "css red() {\n\tcolor: red;\n}"`,
+		`ConstantCSSProperty: "color: red;"`,
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/a-h/parse"
+)
+
+// Whitespace-sensitive elements.
+//
+// The whitespace within whitespace-sensitive elements such as <pre> is rendered exactly as it's
+// written, and isn't reformatted by templ fmt. The contents are parsed as usual, but whitespace
+// is parsed as text, instead of being collapsed into the trailing space of the previous node.
+var defaultWhitespaceSensitiveElements = map[string]struct{}{
+	"pre": {}, "textarea": {}, "code": {},
+}
+
+// isWhitespaceSensitive returns true if whitespace within the named element is significant.
+func (s *parseState) isWhitespaceSensitive(name string) bool {
+	if _, ok := defaultWhitespaceSensitiveElements[name]; ok {
+		return true
+	}
+	_, ok := s.whitespaceSensitiveElements[name]
+	return ok
+}
+
+// preservingWhitespace returns a node that was parsed from start within a whitespace-sensitive
+// element. Whitespace nodes become text, and the whitespace after other nodes is unread, so that
+// it's parsed as text.
+func preservingWhitespace(pi *parse.Input, start int, n Node) Node {
+	switch n := n.(type) {
+	case Whitespace:
+		return Text{Value: n.Value, Range: n.Range}
+	case Text:
+		// Text can end with whitespace, e.g. the space before an expression.
+		unreadTrailingWhitespaceAfter(pi, start, int(n.Range.To.Index))
+		n.TrailingSpace = SpaceNone
+		return n
+	case GoComment:
+		unreadTrailingWhitespaceAfter(pi, start, int(n.Range.To.Index))
+		return n
+	case Element:
+		n.TrailingSpace = SpaceNone
+		unreadTrailingWhitespace(pi, start)
+		return n
+	case StringExpression:
+		n.TrailingSpace = SpaceNone
+		unreadTrailingWhitespace(pi, start)
+		return n
+	case CDATA:
+		n.TrailingSpace = SpaceNone
+		unreadTrailingWhitespace(pi, start)
+		return n
+	}
+	unreadTrailingWhitespace(pi, start)
+	return n
+}
+
+// unreadTrailingWhitespace moves the input back to the start of any whitespace read since start.
+func unreadTrailingWhitespace(pi *parse.Input, start int) {
+	unreadTrailingWhitespaceAfter(pi, start, start)
+}
+
+// unreadTrailingWhitespaceAfter moves the input back to the start of any whitespace read since
+// start, but not before end.
+func unreadTrailingWhitespaceAfter(pi *parse.Input, start, end int) {
+	current := pi.Index()
+	pi.Seek(start)
+	read, _ := pi.Take(current - start)
+	trimmed := start + len(strings.TrimRightFunc(read, unicode.IsSpace))
+	if trimmed < end {
+		trimmed = end
+	}
+	pi.Seek(trimmed)
+}
+
+// writePreservingWhitespace writes the nodes within a whitespace-sensitive element exactly as
+// they were parsed. The contents of statements aren't indented, because their whitespace is
+// rendered.
+func writePreservingWhitespace(w io.Writer, nodes []Node) error {
+	for _, n := range nodes {
+		var err error
+		switch n := n.(type) {
+		case IfExpression:
+			err = n.writePreservingWhitespace(w)
+		case ForExpression:
+			err = n.writePreservingWhitespace(w)
+		case SwitchExpression:
+			err = n.writePreservingWhitespace(w)
+		case TemplElementExpression:
+			err = n.writePreservingWhitespace(w)
+		case StringExpression:
+			if strings.TrimSpace(n.Expression.Value) != "" {
+				err = n.Write(w, 0)
+				break
+			}
+			// Empty expressions, which are probably misplaced braces, are written as they are.
+			_, err = io.WriteString(w, "{"+n.Expression.Value+"}")
+		default:
+			err = n.Write(w, 0)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The whitespace before the closing brace of a statement is part of its contents, but the
+// whitespace before } else and case isn't, so a line break is written before them.
+
+func (n IfExpression) writePreservingWhitespace(w io.Writer) error {
+	if _, err := io.WriteString(w, "if "+n.Expression.Value+" {\n"); err != nil {
+		return err
+	}
+	if err := writePreservingWhitespace(w, n.Then); err != nil {
+		return err
+	}
+	for _, elseIf := range n.ElseIfs {
+		if _, err := io.WriteString(w, "\n} else if "+elseIf.Expression.Value+" {\n"); err != nil {
+			return err
+		}
+		if err := writePreservingWhitespace(w, elseIf.Then); err != nil {
+			return err
+		}
+	}
+	if len(n.Else) > 0 {
+		if _, err := io.WriteString(w, "\n} else {\n"); err != nil {
+			return err
+		}
+		if err := writePreservingWhitespace(w, n.Else); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+func (fe ForExpression) writePreservingWhitespace(w io.Writer) error {
+	if _, err := io.WriteString(w, "for "+fe.Expression.Value+" {\n"); err != nil {
+		return err
+	}
+	if err := writePreservingWhitespace(w, fe.Children); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+func (se SwitchExpression) writePreservingWhitespace(w io.Writer) error {
+	if _, err := io.WriteString(w, "switch "+se.Expression.Value+" {\n"); err != nil {
+		return err
+	}
+	for i, c := range se.Cases {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, c.Expression.Value+"\n"); err != nil {
+			return err
+		}
+		if err := writePreservingWhitespace(w, c.Children); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+func (tee TemplElementExpression) writePreservingWhitespace(w io.Writer) error {
+	if len(tee.Children) == 0 {
+		return tee.Write(w, 0)
+	}
+	// Unlike statements, the line break after the opening brace is part of the children.
+	if _, err := io.WriteString(w, "@"+tee.Expression.Value+" {"); err != nil {
+		return err
+	}
+	if err := writePreservingWhitespace(w, tee.Children); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
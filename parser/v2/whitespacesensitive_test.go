@@ -0,0 +1,203 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/a-h/parse"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWhitespaceSensitiveElementParser(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Element
+	}{
+		{
+			name:  "pre: whitespace is retained",
+			input: "<pre>\n  a\n\tb  </pre>",
+			expected: Element{
+				Name:               "pre",
+				PreserveWhitespace: true,
+				Children: []Node{
+					Text{Value: "\n  "},
+					Text{Value: "a"},
+					Text{Value: "\n\t"},
+					Text{Value: "b  "},
+				},
+			},
+		},
+		{
+			name:  "pre: expressions are allowed, and whitespace after them is retained",
+			input: "<pre>{ a }\n  { b }</pre>",
+			expected: Element{
+				Name:               "pre",
+				PreserveWhitespace: true,
+				Children: []Node{
+					StringExpression{Expression: Expression{Value: "a", Range: Range{From: Position{Index: 7, Line: 0, Col: 7}, To: Position{Index: 8, Line: 0, Col: 8}}}},
+					Text{Value: "\n  "},
+					StringExpression{Expression: Expression{Value: "b", Range: Range{From: Position{Index: 15, Line: 1, Col: 4}, To: Position{Index: 16, Line: 1, Col: 5}}}},
+				},
+			},
+		},
+		{
+			name:  "pre: child elements also retain whitespace",
+			input: "<pre><b> a </b> <br/> x</pre>",
+			expected: Element{
+				Name:               "pre",
+				PreserveWhitespace: true,
+				Children: []Node{
+					Element{
+						Name:               "b",
+						PreserveWhitespace: true,
+						Children: []Node{
+							Text{Value: " "},
+							Text{Value: "a "},
+						},
+					},
+					Text{Value: " "},
+					Element{Name: "br"},
+					Text{Value: " "},
+					Text{Value: "x"},
+				},
+			},
+		},
+		{
+			name:  "pre: statements, components and children are parsed",
+			input: "<pre>\nif x {\n  @item()\n}\n{ children... }</pre>",
+			expected: Element{
+				Name:               "pre",
+				PreserveWhitespace: true,
+				Children: []Node{
+					Text{Value: "\n"},
+					IfExpression{
+						Expression: Expression{Value: "x", Range: Range{From: Position{Index: 9, Line: 1, Col: 3}, To: Position{Index: 10, Line: 1, Col: 4}}},
+						Then: []Node{
+							Text{Value: "  "},
+							TemplElementExpression{Expression: Expression{Value: "item()", Range: Range{From: Position{Index: 16, Line: 2, Col: 3}, To: Position{Index: 22, Line: 2, Col: 9}}}},
+							Text{Value: "\n"},
+						},
+					},
+					Text{Value: "\n"},
+					ChildrenExpression{},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			input := parse.NewInput(tt.input)
+			result, ok, err := element.Parse(input)
+			if err != nil {
+				t.Fatalf("parser error: %v", err)
+			}
+			if !ok {
+				t.Fatalf("failed to parse at %d", input.Index())
+			}
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestWithWhitespaceSensitiveElements(t *testing.T) {
+	input := "package main\n\ntempl f() {\n\t<x-pre>  a  </x-pre>\n\t<div>  a  </div>\n}\n"
+
+	tf, err := ParseString(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e := elementsOf(tf)[0]; e.PreserveWhitespace {
+		t.Errorf("expected <x-pre> not to be whitespace-sensitive by default, got %#v", e)
+	}
+
+	tf, err = ParseString(input, WithWhitespaceSensitiveElements("x-pre"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	elements := elementsOf(tf)
+	expected := Element{
+		Name:               "x-pre",
+		PreserveWhitespace: true,
+		Children: []Node{
+			Text{Value: "  "},
+			Text{Value: "a  "},
+		},
+		TrailingSpace: SpaceVertical,
+	}
+	if diff := cmp.Diff(expected, elements[0], ignoreNodeRanges); diff != "" {
+		t.Error(diff)
+	}
+	if e := elements[1]; e.PreserveWhitespace {
+		t.Errorf("expected other elements not to be whitespace-sensitive, got %#v", e)
+	}
+}
+
+func TestWhitespaceSensitiveElementEmptyExpression(t *testing.T) {
+	input := "package main\n\ntempl f() {\n\t<pre>\nfunc() {\n}\n</pre>\n}\n"
+	tf, err := ParseString(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tf.Diagnostics) != 1 {
+		t.Errorf("expected a warning that the braces render nothing, got %v", tf.Diagnostics)
+	}
+	var formatted bytes.Buffer
+	if err = tf.Write(&formatted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if formatted.String() != input {
+		t.Errorf("expected the braces not to be reformatted, got:\n%s", formatted.String())
+	}
+}
+
+func elementsOf(tf TemplateFile) (elements []Element) {
+	for _, n := range tf.Nodes[0].(HTMLTemplate).Children {
+		if e, ok := n.(Element); ok {
+			elements = append(elements, e)
+		}
+	}
+	return elements
+}
+
+func TestWhitespaceSensitiveElementFormatting(t *testing.T) {
+	// Formatting doesn't change the contents of whitespace-sensitive elements, so the
+	// formatted template is parsed to the same nodes.
+	input := "package main\n\n" +
+		"templ f(items []string) {\n" +
+		"\t<pre>\n" +
+		"for _, item := range items {\n" +
+		"  - { item }\n" +
+		"}\n" +
+		"if len(items) == 0 {\n" +
+		"  none\n" +
+		"} else if len(items) == 1 {\n" +
+		"  one\n" +
+		"} else {\n" +
+		"\t@many() {\n" +
+		"  { children... }\n" +
+		"}\n" +
+		"}\n" +
+		"switch len(items) {\n" +
+		"case 0:\n" +
+		"zero\n" +
+		"default:\n" +
+		"  some\n" +
+		"}\n" +
+		"</pre>\n" +
+		"}\n"
+	tf, err := ParseString(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var formatted bytes.Buffer
+	if err = tf.Write(&formatted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if formatted.String() != input {
+		t.Errorf("expected the template not to be reformatted, got:\n%s", formatted.String())
+	}
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"github.com/a-h/parse"
+)
+
+// Processing instruction, e.g. <?xml version="1.0" encoding="UTF-8"?>
+var processingInstructionStart = parse.String("<?")
+var processingInstructionEnd = parse.String("?>")
+
+var processingInstruction = parse.Func(func(pi *parse.Input) (n Node, ok bool, err error) {
+	var r ProcessingInstruction
+	from := pi.Position()
+	if _, ok, err = processingInstructionStart.Parse(pi); err != nil || !ok {
+		return
+	}
+	if r.Contents, ok, err = parse.StringUntil(processingInstructionEnd).Parse(pi); err != nil || !ok {
+		err = newParseError(codeUnterminatedDeclaration, "expected end of processing instruction '?>' not found", pi.Position())
+		return
+	}
+	// Cut the end.
+	_, _, _ = processingInstructionEnd.Parse(pi)
+	r.Range = NewRange(from, pi.Position())
+	return r, true, nil
+})
+
+// CDATA section, e.g. <![CDATA[<p>Hello</p>]]>
+var cdataStart = parse.String("<![CDATA[")
+var cdataEnd = parse.String("]]>")
+
+var cdata = parse.Func(func(pi *parse.Input) (n Node, ok bool, err error) {
+	var r CDATA
+	from := pi.Position()
+	if _, ok, err = cdataStart.Parse(pi); err != nil || !ok {
+		return
+	}
+	if r.Contents, ok, err = parse.StringUntil(cdataEnd).Parse(pi); err != nil || !ok {
+		err = newParseError(codeUnterminatedCDATA, "expected end of CDATA section ']]>' not found", pi.Position())
+		return
+	}
+	// Cut the end.
+	_, _, _ = cdataEnd.Parse(pi)
+	r.Range = NewRange(from, pi.Position())
+
+	// Parse trailing whitespace.
+	ws, _, err := parse.Whitespace.Parse(pi)
+	if err != nil {
+		return r, false, err
+	}
+	if r.TrailingSpace, err = NewTrailingSpace(ws); err != nil {
+		return r, false, err
+	}
+	return r, true, nil
+})
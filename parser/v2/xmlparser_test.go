@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/a-h/parse"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestXMLParser(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		parser   parse.Parser[Node]
+		expected Node
+	}{
+		{
+			name:   "xml declaration",
+			input:  `<?xml version="1.0" encoding="UTF-8"?>`,
+			parser: processingInstruction,
+			expected: ProcessingInstruction{
+				Contents: `xml version="1.0" encoding="UTF-8"`,
+			},
+		},
+		{
+			name:   "processing instruction",
+			input:  `<?xml-stylesheet href="style.xsl" type="text/xsl"?>`,
+			parser: processingInstruction,
+			expected: ProcessingInstruction{
+				Contents: `xml-stylesheet href="style.xsl" type="text/xsl"`,
+			},
+		},
+		{
+			name:   "cdata",
+			input:  `<![CDATA[<p>{ not an expression }</p>]]>`,
+			parser: cdata,
+			expected: CDATA{
+				Contents: `<p>{ not an expression }</p>`,
+			},
+		},
+		{
+			name:   "cdata with trailing space",
+			input:  "<![CDATA[x]]>\n",
+			parser: cdata,
+			expected: CDATA{
+				Contents:      "x",
+				TrailingSpace: SpaceVertical,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			input := parse.NewInput(tt.input)
+			result, ok, err := tt.parser.Parse(input)
+			if err != nil {
+				t.Fatalf("parser error: %v", err)
+			}
+			if !ok {
+				t.Fatalf("failed to parse at %d", input.Index())
+			}
+			if diff := cmp.Diff(tt.expected, result, ignoreNodeRanges); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestXMLParserErrors(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		parser   parse.Parser[Node]
+		expected string
+	}{
+		{
+			name:     "unclosed processing instruction",
+			input:    `<?xml version="1.0"`,
+			parser:   processingInstruction,
+			expected: "expected end of processing instruction '?>' not found: line 0, col 19",
+		},
+		{
+			name:     "unclosed cdata",
+			input:    `<![CDATA[abc`,
+			parser:   cdata,
+			expected: "expected end of CDATA section ']]>' not found: line 0, col 12",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.parser.Parse(parse.NewInput(tt.input))
+			if err == nil {
+				t.Fatalf("expected error %q, got nil", tt.expected)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("expected error %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestXMLTemplateParser(t *testing.T) {
+	input := parse.NewInput(`xml Sitemap() {
+	<urlset>
+		<url><loc>/</loc><image:image/></url>
+		<Link Enabled></Link>
+	</urlset>
+}`)
+	tmpl, ok, err := xmlTemplate.Parse(input)
+	if err != nil {
+		t.Fatalf("parser error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("failed to parse at %d", input.Index())
+	}
+	if tmpl.Expression.Value != "Sitemap()" {
+		t.Errorf("expected expression %q, got %q", "Sitemap()", tmpl.Expression.Value)
+	}
+	var names []string
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for _, n := range nodes {
+			e, isElement := n.(Element)
+			if !isElement {
+				continue
+			}
+			if e.Namespace != NamespaceXML {
+				t.Errorf("<%s>: expected namespace %q, got %q", e.Name, NamespaceXML, e.Namespace)
+			}
+			if !e.IsBlockElement() {
+				t.Errorf("<%s>: expected XML elements to be block elements", e.Name)
+			}
+			names = append(names, e.Name)
+			walk(e.Children)
+		}
+	}
+	walk(tmpl.Children)
+	expected := []string{"urlset", "url", "loc", "image:image", "Link"}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -783,14 +783,15 @@ func ToGoHTML(ctx context.Context, c Component) (s template.HTML, err error) {
 }
 
 // WriteWatchModeString is used when rendering templates in development mode.
-// the generator would have written non-go code to the _templ.txt file, which
-// is then read by this function and written to the output.
+// the generator would have written non-go code to the .txt file next to the
+// generated Go file, e.g. _templ.txt, which is then read by this function and
+// written to the output.
 func WriteWatchModeString(w *bytes.Buffer, lineNum int) error {
 	_, path, _, _ := runtime.Caller(1)
-	if !strings.HasSuffix(path, "_templ.go") {
-		return errors.New("templ: WriteWatchModeString can only be called from _templ.go")
+	if !strings.HasSuffix(path, ".go") {
+		return errors.New("templ: WriteWatchModeString can only be called from generated Go code")
 	}
-	txtFilePath := strings.Replace(path, "_templ.go", "_templ.txt", 1)
+	txtFilePath := strings.TrimSuffix(path, ".go") + ".txt"
 
 	literals, err := getWatchedStrings(txtFilePath)
 	if err != nil {